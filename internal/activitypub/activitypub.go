@@ -0,0 +1,345 @@
+package activitypub
+
+import (
+	"encoding/json"
+	"html"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/database"
+)
+
+const (
+	ContentType    = "application/activity+json"
+	JRDContentType = "application/jrd+json"
+
+	// PublicCollection addresses an object to everyone.
+	PublicCollection = "https://www.w3.org/ns/activitystreams#Public"
+
+	ActivityStreamsContext = "https://www.w3.org/ns/activitystreams"
+
+	securityContext = "https://w3id.org/security/v1"
+	ldContentType   = "application/ld+json"
+)
+
+const (
+	TypeCreate   = "Create"
+	TypeDelete   = "Delete"
+	TypeFollow   = "Follow"
+	TypeAccept   = "Accept"
+	TypeReject   = "Reject"
+	TypeUndo     = "Undo"
+	TypeLike     = "Like"
+	TypeAnnounce = "Announce"
+)
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type Actor struct {
+	Context           any        `json:"@context,omitempty"`
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	PreferredUsername string     `json:"preferredUsername"`
+	Name              string     `json:"name,omitempty"`
+	URL               string     `json:"url,omitempty"`
+	Inbox             string     `json:"inbox"`
+	Outbox            string     `json:"outbox,omitempty"`
+	Followers         string     `json:"followers,omitempty"`
+	Endpoints         *Endpoints `json:"endpoints,omitempty"`
+	// ManuallyApprovesFollowers is true for private accounts.
+	ManuallyApprovesFollowers bool      `json:"manuallyApprovesFollowers"`
+	PublicKey                 PublicKey `json:"publicKey"`
+}
+
+// SharedInbox is where activities for several of the actor's followers can
+// be delivered at once.
+func (a Actor) SharedInbox() string {
+	if a.Endpoints != nil && a.Endpoints.SharedInbox != "" {
+		return a.Endpoints.SharedInbox
+	}
+	return a.Inbox
+}
+
+type Note struct {
+	Context      any      `json:"@context,omitempty"`
+	ID           string   `json:"id"`
+	Type         string   `json:"type"`
+	AttributedTo string   `json:"attributedTo"`
+	Content      string   `json:"content"`
+	Published    string   `json:"published"`
+	Updated      string   `json:"updated,omitempty"`
+	URL          string   `json:"url"`
+	InReplyTo    string   `json:"inReplyTo,omitempty"`
+	To           []string `json:"to"`
+	Cc           []string `json:"cc"`
+}
+
+// Activity is any activity, its object is kept as is since it can be a link
+// or an embedded object.
+type Activity struct {
+	Context   any             `json:"@context,omitempty"`
+	ID        string          `json:"id" validate:"required"`
+	Type      string          `json:"type" validate:"required"`
+	Actor     string          `json:"actor" validate:"required"`
+	Object    json.RawMessage `json:"object" validate:"required"`
+	Published string          `json:"published,omitempty"`
+	To        []string        `json:"to,omitempty"`
+	Cc        []string        `json:"cc,omitempty"`
+}
+
+// ObjectID is the ID of the object of the activity, whether it was sent as a
+// link or embedded.
+func (a Activity) ObjectID() string {
+	id := ""
+	if json.Unmarshal(a.Object, &id) == nil {
+		return id
+	}
+
+	object := struct {
+		ID string `json:"id"`
+	}{}
+	json.Unmarshal(a.Object, &object)
+	return object.ID
+}
+
+// ObjectType is the type of the embedded object of the activity, empty when
+// it was sent as a link.
+func (a Activity) ObjectType() string {
+	object := struct {
+		Type string `json:"type"`
+	}{}
+	json.Unmarshal(a.Object, &object)
+	return object.Type
+}
+
+type OrderedCollection struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	TotalItems   int64  `json:"totalItems"`
+	OrderedItems []any  `json:"orderedItems,omitempty"`
+}
+
+type Link struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+// JRD is a WebFinger response.
+type JRD struct {
+	Subject string   `json:"subject"`
+	Aliases []string `json:"aliases,omitempty"`
+	Links   []Link   `json:"links"`
+}
+
+// Accepts tells if the client asked for ActivityStreams rather than the
+// regular API representation.
+func Accepts(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if mediaType == ContentType {
+			return true
+		}
+		if mediaType == ldContentType && params["profile"] == ActivityStreamsContext {
+			return true
+		}
+	}
+	return false
+}
+
+// URLs of the objects of this instance, they're also their IDs so they must
+// never change.
+
+func ActorURL(baseURL string, userId uuid.UUID) string {
+	return baseURL + "/api/ap/users/" + userId.String()
+}
+
+func KeyID(actorURL string) string {
+	return actorURL + "#main-key"
+}
+
+func NoteURL(baseURL string, chirpId uuid.UUID) string {
+	return baseURL + "/api/ap/chirps/" + chirpId.String()
+}
+
+func SharedInboxURL(baseURL string) string {
+	return baseURL + "/api/ap/inbox"
+}
+
+func parseURL(prefix, raw string) (uuid.UUID, bool) {
+	rest, ok := strings.CutPrefix(raw, prefix)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(rest)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+// ParseActorURL finds the local user an actor ID is for.
+func ParseActorURL(baseURL, raw string) (uuid.UUID, bool) {
+	return parseURL(baseURL+"/api/ap/users/", raw)
+}
+
+// ParseNoteURL finds the local chirp an object ID is for. Both the note and
+// the API URL of the chirp are accepted.
+func ParseNoteURL(baseURL, raw string) (uuid.UUID, bool) {
+	id, ok := parseURL(baseURL+"/api/ap/chirps/", raw)
+	if ok {
+		return id, true
+	}
+	return parseURL(baseURL+"/api/chirps/", raw)
+}
+
+// NewActor is the actor document of a local user. Users don't have handles,
+// so their ID is their username too.
+func NewActor(baseURL string, user database.User, publicKeyPEM string) Actor {
+	actorURL := ActorURL(baseURL, user.ID)
+	return Actor{
+		Context:                   []string{ActivityStreamsContext, securityContext},
+		ID:                        actorURL,
+		Type:                      "Person",
+		PreferredUsername:         user.ID.String(),
+		URL:                       baseURL + "/api/chirps?author_id=" + user.ID.String(),
+		Inbox:                     actorURL + "/inbox",
+		Outbox:                    actorURL + "/outbox",
+		Followers:                 actorURL + "/followers",
+		Endpoints:                 &Endpoints{SharedInbox: SharedInboxURL(baseURL)},
+		ManuallyApprovesFollowers: user.IsPrivate,
+		PublicKey: PublicKey{
+			ID:           KeyID(actorURL),
+			Owner:        actorURL,
+			PublicKeyPem: publicKeyPEM,
+		},
+	}
+}
+
+// addressing maps the visibility of a chirp to who its note is addressed
+// to. Chirps of private accounts only ever go to their followers.
+func addressing(actorURL, visibility string, authorPrivate bool) (to, cc []string) {
+	followers := actorURL + "/followers"
+	if authorPrivate {
+		return []string{followers}, []string{}
+	}
+
+	switch visibility {
+	case "public":
+		return []string{PublicCollection}, []string{followers}
+	case "unlisted":
+		return []string{followers}, []string{PublicCollection}
+	default:
+		return []string{followers}, []string{}
+	}
+}
+
+// Federates tells if a chirp with the visibility is sent to remote followers.
+// Mentions are of local users only, so mentioned-only chirps stay here.
+func Federates(visibility string) bool {
+	return visibility == "public" || visibility == "unlisted" || visibility == "followers-only"
+}
+
+// NewNote is the note of a chirp. The body is plain text, so it's escaped
+// into the HTML content.
+func NewNote(baseURL string, chirp database.Chirp, authorPrivate bool) Note {
+	actorURL := ActorURL(baseURL, chirp.UserID)
+	to, cc := addressing(actorURL, chirp.Visibility, authorPrivate)
+
+	note := Note{
+		ID:           NoteURL(baseURL, chirp.ID),
+		Type:         "Note",
+		AttributedTo: actorURL,
+		Content:      "<p>" + html.EscapeString(chirp.Body) + "</p>",
+		Published:    chirp.CreatedAt.UTC().Format(time.RFC3339),
+		URL:          baseURL + "/api/chirps/" + chirp.ID.String(),
+		To:           to,
+		Cc:           cc,
+	}
+	if chirp.UpdatedAt.After(chirp.CreatedAt) {
+		note.Updated = chirp.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	if chirp.ReplyToID.Valid {
+		note.InReplyTo = NoteURL(baseURL, chirp.ReplyToID.UUID)
+	}
+
+	return note
+}
+
+// NewCreate wraps the note of a chirp in the activity announcing it.
+func NewCreate(baseURL string, chirp database.Chirp, authorPrivate bool) (Activity, error) {
+	note := NewNote(baseURL, chirp, authorPrivate)
+	object, err := json.Marshal(note)
+	if err != nil {
+		return Activity{}, err
+	}
+
+	return Activity{
+		Context:   ActivityStreamsContext,
+		ID:        note.ID + "/activity",
+		Type:      TypeCreate,
+		Actor:     note.AttributedTo,
+		Object:    object,
+		Published: note.Published,
+		To:        note.To,
+		Cc:        note.Cc,
+	}, nil
+}
+
+// NewDelete tells followers a chirp is gone, leaving a tombstone in its
+// place.
+func NewDelete(baseURL string, chirp database.Chirp) (Activity, error) {
+	noteURL := NoteURL(baseURL, chirp.ID)
+	object, err := json.Marshal(map[string]string{
+		"id":   noteURL,
+		"type": "Tombstone",
+	})
+	if err != nil {
+		return Activity{}, err
+	}
+
+	actorURL := ActorURL(baseURL, chirp.UserID)
+	return Activity{
+		Context: ActivityStreamsContext,
+		ID:      noteURL + "#delete",
+		Type:    TypeDelete,
+		Actor:   actorURL,
+		Object:  object,
+		To:      []string{PublicCollection},
+		Cc:      []string{actorURL + "/followers"},
+	}, nil
+}
+
+// NewResponse accepts or rejects a follow request of a remote actor.
+func NewResponse(responseType, actorURL string, follow Activity) (Activity, error) {
+	follow.Context = nil
+	object, err := json.Marshal(follow)
+	if err != nil {
+		return Activity{}, err
+	}
+
+	return Activity{
+		Context: ActivityStreamsContext,
+		ID:      actorURL + "#" + strings.ToLower(responseType) + "s/" + uuid.NewString(),
+		Type:    responseType,
+		Actor:   actorURL,
+		Object:  object,
+		To:      []string{follow.Actor},
+	}, nil
+}
@@ -0,0 +1,110 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const userAgent = "Chirpy-ActivityPub/1.0"
+
+// maxDocumentSize is the largest document fetched from another instance, in
+// bytes.
+const maxDocumentSize = 1 << 20
+
+// ValidateRemoteURL checks an ID of another instance is an absolute URL the
+// server can fetch. Plain HTTP is only allowed for local development.
+func ValidateRemoteURL(raw string, allowHTTP bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", raw)
+	}
+
+	if u.Scheme != "https" && !(allowHTTP && u.Scheme == "http") {
+		return fmt.Errorf("%q must use https", raw)
+	}
+
+	return nil
+}
+
+// Deliver POSTs the activity to an inbox, signed with the key of the actor
+// sending it. It returns the response status, if any, and an error unless the
+// inbox answered with a 2xx.
+func Deliver(ctx context.Context, client *http.Client, inbox string, activity []byte, keyID string, key *rsa.PrivateKey, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(activity))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("User-Agent", userAgent)
+
+	err = SignRequest(req, activity, keyID, key, now)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("inbox responded with %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// FetchActor gets the actor document at actorURL. Its ID must be the URL it
+// was fetched from, so an instance can't speak for actors of another one.
+func FetchActor(ctx context.Context, client *http.Client, actorURL string) (Actor, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, actorURL, nil)
+	if err != nil {
+		return Actor{}, err
+	}
+	req.Header.Set("Accept", ContentType)
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Actor{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Actor{}, fmt.Errorf("fetching %s responded with %d", actorURL, resp.StatusCode)
+	}
+
+	actor := Actor{}
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(&actor)
+	if err != nil {
+		return Actor{}, fmt.Errorf("invalid actor document at %s: %w", actorURL, err)
+	}
+
+	if actor.ID != actorURL {
+		return Actor{}, fmt.Errorf("the actor at %s has ID %s", actorURL, actor.ID)
+	}
+	if actor.Inbox == "" || actor.PublicKey.PublicKeyPem == "" || actor.PublicKey.Owner != actor.ID {
+		return Actor{}, fmt.Errorf("the actor at %s has no inbox or key", actorURL)
+	}
+
+	return actor, nil
+}
+
+// KeyOwnerURL is the URL of the actor document a key ID points into.
+func KeyOwnerURL(keyID string) string {
+	u, err := url.Parse(keyID)
+	if err != nil {
+		return keyID
+	}
+	u.Fragment = ""
+	return u.String()
+}
@@ -0,0 +1,184 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "Signature"
+	DigestHeader    = "Digest"
+
+	signatureAlgorithm = "rsa-sha256"
+	// maxClockSkew is how far the Date of a signed request can be from now.
+	maxClockSkew = time.Hour
+)
+
+var (
+	ErrMissingSignature = errors.New("the request is not signed")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Signature is the parsed Signature header of a request, as in the
+// draft-cavage-http-signatures spec used across the fediverse.
+type Signature struct {
+	KeyID     string
+	Algorithm string
+	Headers   []string
+	Signature []byte
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// signingString builds what is signed from the headers of the request, in
+// the order given.
+func signingString(req *http.Request, headers []string) (string, error) {
+	lines := make([]string, len(headers))
+	for i, header := range headers {
+		switch header {
+		case "(request-target)":
+			lines[i] = "(request-target): " + strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			host := req.Host
+			if host == "" {
+				host = req.URL.Host
+			}
+			lines[i] = "host: " + host
+		default:
+			values := req.Header.Values(header)
+			if len(values) == 0 {
+				return "", fmt.Errorf("signed header %s is missing", header)
+			}
+			lines[i] = header + ": " + strings.Join(values, ", ")
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// SignRequest signs the request with the key of keyID. The body, if any, is
+// covered through its Digest.
+func SignRequest(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey, now time.Time) error {
+	headers := []string{"(request-target)", "host", "date"}
+	req.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	if body != nil {
+		req.Header.Set(DigestHeader, digest(body))
+		headers = append(headers, "digest")
+	}
+
+	toSign, err := signingString(req, headers)
+	if err != nil {
+		return err
+	}
+
+	hashed := sha256.Sum256([]byte(toSign))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(SignatureHeader, fmt.Sprintf(`keyId="%s",algorithm="%s",headers="%s",signature="%s"`,
+		keyID, signatureAlgorithm, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+
+	return nil
+}
+
+// ParseSignature reads the Signature header of the request, without
+// verifying it.
+func ParseSignature(req *http.Request) (Signature, error) {
+	header := req.Header.Get(SignatureHeader)
+	if header == "" {
+		return Signature{}, ErrMissingSignature
+	}
+
+	params := map[string]string{}
+	for header != "" {
+		name, rest, ok := strings.Cut(header, "=")
+		if !ok || !strings.HasPrefix(rest, `"`) {
+			return Signature{}, ErrInvalidSignature
+		}
+		value, rest, ok := strings.Cut(rest[1:], `"`)
+		if !ok {
+			return Signature{}, ErrInvalidSignature
+		}
+		params[strings.TrimSpace(name)] = value
+		header = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil || params["keyId"] == "" || len(signature) == 0 {
+		return Signature{}, ErrInvalidSignature
+	}
+
+	// only the date is signed when no headers are listed
+	headers := []string{"date"}
+	if params["headers"] != "" {
+		headers = strings.Fields(strings.ToLower(params["headers"]))
+	}
+
+	return Signature{
+		KeyID:     params["keyId"],
+		Algorithm: params["algorithm"],
+		Headers:   headers,
+		Signature: signature,
+	}, nil
+}
+
+// VerifyRequest checks that the request was signed with key, recently, and
+// that the signature covers its target, host, date and body.
+func VerifyRequest(req *http.Request, body []byte, key *rsa.PublicKey, now time.Time) error {
+	signature, err := ParseSignature(req)
+	if err != nil {
+		return err
+	}
+
+	if signature.Algorithm != "" && signature.Algorithm != signatureAlgorithm && signature.Algorithm != "hs2019" {
+		return fmt.Errorf("unsupported signature algorithm %s", signature.Algorithm)
+	}
+
+	required := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		required = append(required, "digest")
+	}
+	for _, header := range required {
+		if !slices.Contains(signature.Headers, header) {
+			return fmt.Errorf("the signature must cover %s", header)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return fmt.Errorf("invalid Date header")
+	}
+	if date.Before(now.Add(-maxClockSkew)) || date.After(now.Add(maxClockSkew)) {
+		return fmt.Errorf("the request is too old or from the future")
+	}
+
+	if body != nil && req.Header.Get(DigestHeader) != digest(body) {
+		return fmt.Errorf("the digest doesn't match the body")
+	}
+
+	signed, err := signingString(req, signature.Headers)
+	if err != nil {
+		return err
+	}
+
+	hashed := sha256.Sum256([]byte(signed))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature.Signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package activitypub
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustKey(t *testing.T) (string, string) {
+	t.Helper()
+	privatePEM, publicPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return privatePEM, publicPEM
+}
+
+func TestSignAndVerifyRequest(t *testing.T) {
+	privatePEM, publicPEM := mustKey(t)
+	privateKey, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() error = %v", err)
+	}
+	publicKey, err := ParsePublicKey(publicPEM)
+	if err != nil {
+		t.Fatalf("ParsePublicKey() error = %v", err)
+	}
+
+	_, otherPublicPEM := mustKey(t)
+	otherPublicKey, err := ParsePublicKey(otherPublicPEM)
+	if err != nil {
+		t.Fatalf("ParsePublicKey() error = %v", err)
+	}
+
+	keyID := "https://chirpy.example/api/ap/users/1#main-key"
+	body := []byte(`{"type":"Follow"}`)
+	now := time.Now()
+
+	cases := []struct {
+		name    string
+		tamper  func(req *http.Request) []byte
+		verify  time.Time
+		wantErr bool
+	}{
+		{
+			name:    "untouched request",
+			tamper:  func(req *http.Request) []byte { return body },
+			verify:  now,
+			wantErr: false,
+		},
+		{
+			name:    "body changed",
+			tamper:  func(req *http.Request) []byte { return []byte(`{"type":"Like"}`) },
+			verify:  now,
+			wantErr: true,
+		},
+		{
+			name: "digest changed along with the body",
+			tamper: func(req *http.Request) []byte {
+				tampered := []byte(`{"type":"Like"}`)
+				req.Header.Set(DigestHeader, digest(tampered))
+				return tampered
+			},
+			verify:  now,
+			wantErr: true,
+		},
+		{
+			name: "path changed",
+			tamper: func(req *http.Request) []byte {
+				req.URL.Path = "/api/ap/users/2/inbox"
+				return body
+			},
+			verify:  now,
+			wantErr: true,
+		},
+		{
+			name: "host changed",
+			tamper: func(req *http.Request) []byte {
+				req.Host = "evil.example"
+				return body
+			},
+			verify:  now,
+			wantErr: true,
+		},
+		{
+			name: "date changed",
+			tamper: func(req *http.Request) []byte {
+				req.Header.Set("Date", now.Add(time.Minute).UTC().Format(http.TimeFormat))
+				return body
+			},
+			verify:  now,
+			wantErr: true,
+		},
+		{
+			name:    "replayed later",
+			tamper:  func(req *http.Request) []byte { return body },
+			verify:  now.Add(maxClockSkew + time.Minute),
+			wantErr: true,
+		},
+		{
+			name: "signature only covers the date",
+			tamper: func(req *http.Request) []byte {
+				header := req.Header.Get(SignatureHeader)
+				req.Header.Set(SignatureHeader, strings.Replace(header, `headers="(request-target) host date digest"`, `headers="date"`, 1))
+				return body
+			},
+			verify:  now,
+			wantErr: true,
+		},
+		{
+			name: "not signed",
+			tamper: func(req *http.Request) []byte {
+				req.Header.Del(SignatureHeader)
+				return body
+			},
+			verify:  now,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "https://chirpy.example/api/ap/users/1/inbox", bytes.NewReader(body))
+			err := SignRequest(req, body, keyID, privateKey, now)
+			if err != nil {
+				t.Fatalf("SignRequest() error = %v", err)
+			}
+
+			got := tc.tamper(req)
+			err = VerifyRequest(req, got, publicKey, tc.verify)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("VerifyRequest() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	t.Run("other key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "https://chirpy.example/api/ap/users/1/inbox", bytes.NewReader(body))
+		err := SignRequest(req, body, keyID, privateKey, now)
+		if err != nil {
+			t.Fatalf("SignRequest() error = %v", err)
+		}
+
+		err = VerifyRequest(req, body, otherPublicKey, now)
+		if err != ErrInvalidSignature {
+			t.Errorf("VerifyRequest() error = %v, want %v", err, ErrInvalidSignature)
+		}
+	})
+}
+
+func TestParseSignature(t *testing.T) {
+	cases := []struct {
+		name        string
+		header      string
+		wantKeyID   string
+		wantHeaders []string
+		wantErr     bool
+	}{
+		{
+			name:        "all parameters",
+			header:      `keyId="https://a.example/users/b#main-key",algorithm="rsa-sha256",headers="(request-target) host date",signature="c2ln"`,
+			wantKeyID:   "https://a.example/users/b#main-key",
+			wantHeaders: []string{"(request-target)", "host", "date"},
+		},
+		{
+			name:        "spaces between parameters and no headers",
+			header:      `keyId="https://a.example/users/b#main-key", signature="c2ln"`,
+			wantKeyID:   "https://a.example/users/b#main-key",
+			wantHeaders: []string{"date"},
+		},
+		{
+			name:    "no key ID",
+			header:  `signature="c2ln"`,
+			wantErr: true,
+		},
+		{
+			name:    "signature isn't base64",
+			header:  `keyId="k",signature="not base64!"`,
+			wantErr: true,
+		},
+		{
+			name:    "unquoted value",
+			header:  `keyId=k,signature="c2ln"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(SignatureHeader, tc.header)
+
+			got, err := ParseSignature(req)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseSignature() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			if got.KeyID != tc.wantKeyID {
+				t.Errorf("KeyID = %q, want %q", got.KeyID, tc.wantKeyID)
+			}
+			if strings.Join(got.Headers, " ") != strings.Join(tc.wantHeaders, " ") {
+				t.Errorf("Headers = %v, want %v", got.Headers, tc.wantHeaders)
+			}
+		})
+	}
+}
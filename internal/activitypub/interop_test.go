@@ -0,0 +1,364 @@
+package activitypub
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/database"
+)
+
+// fakeInstance is another server in the fediverse, with one actor. Its inbox
+// checks signatures the way real instances do, by fetching the key of the
+// sender from its actor document.
+type fakeInstance struct {
+	server     *httptest.Server
+	actor      Actor
+	privateKey string
+
+	mu       sync.Mutex
+	received []Activity
+}
+
+func newFakeInstance(t *testing.T) *fakeInstance {
+	t.Helper()
+	privatePEM, publicPEM := mustKey(t)
+
+	instance := &fakeInstance{privateKey: privatePEM}
+	mux := http.NewServeMux()
+	instance.server = httptest.NewServer(mux)
+	t.Cleanup(instance.server.Close)
+
+	actorURL := instance.server.URL + "/users/alice"
+	instance.actor = Actor{
+		ID:                actorURL,
+		Type:              "Person",
+		PreferredUsername: "alice",
+		Inbox:             actorURL + "/inbox",
+		Endpoints:         &Endpoints{SharedInbox: instance.server.URL + "/inbox"},
+		PublicKey:         PublicKey{ID: KeyID(actorURL), Owner: actorURL, PublicKeyPem: publicPEM},
+	}
+
+	mux.HandleFunc("GET /users/alice", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		json.NewEncoder(w).Encode(instance.actor)
+	})
+	mux.HandleFunc("POST /inbox", func(w http.ResponseWriter, r *http.Request) {
+		activity, err := receive(r, instance.server.Client())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		instance.mu.Lock()
+		instance.received = append(instance.received, activity)
+		instance.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	return instance
+}
+
+// receive is what an inbox does with a request: verify it was signed by the
+// actor of the activity.
+func receive(r *http.Request, client *http.Client) (Activity, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return Activity{}, err
+	}
+
+	signature, err := ParseSignature(r)
+	if err != nil {
+		return Activity{}, err
+	}
+
+	sender, err := FetchActor(r.Context(), client, KeyOwnerURL(signature.KeyID))
+	if err != nil {
+		return Activity{}, err
+	}
+
+	key, err := ParsePublicKey(sender.PublicKey.PublicKeyPem)
+	if err != nil {
+		return Activity{}, err
+	}
+
+	err = VerifyRequest(r, body, key, time.Now())
+	if err != nil {
+		return Activity{}, err
+	}
+
+	activity := Activity{}
+	err = json.Unmarshal(body, &activity)
+	if err != nil {
+		return Activity{}, err
+	}
+	if activity.Actor != sender.ID {
+		return Activity{}, ErrInvalidSignature
+	}
+
+	return activity, nil
+}
+
+// newLocalInstance serves the actor of one local user, like the handlers do.
+func newLocalInstance(t *testing.T) (baseURL string, user database.User, privateKey string) {
+	t.Helper()
+	privatePEM, publicPEM := mustKey(t)
+	user = database.User{ID: uuid.New()}
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("GET /api/ap/users/{userID}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		json.NewEncoder(w).Encode(NewActor(server.URL, user, publicPEM))
+	})
+
+	return server.URL, user, privatePEM
+}
+
+func TestDeliverToRemoteInstance(t *testing.T) {
+	remote := newFakeInstance(t)
+	baseURL, user, privatePEM := newLocalInstance(t)
+	key, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() error = %v", err)
+	}
+
+	chirp := database.Chirp{
+		ID:         uuid.New(),
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		Body:       "Hello <fediverse> & friends",
+		UserID:     user.ID,
+		Visibility: "public",
+	}
+
+	create, err := NewCreate(baseURL, chirp, false)
+	if err != nil {
+		t.Fatalf("NewCreate() error = %v", err)
+	}
+	remove, err := NewDelete(baseURL, chirp)
+	if err != nil {
+		t.Fatalf("NewDelete() error = %v", err)
+	}
+
+	keyID := KeyID(ActorURL(baseURL, user.ID))
+	for _, activity := range []Activity{create, remove} {
+		payload, err := json.Marshal(activity)
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+
+		status, err := Deliver(context.Background(), http.DefaultClient, remote.actor.SharedInbox(), payload, keyID, key, time.Now())
+		if err != nil {
+			t.Fatalf("Deliver(%s) error = %v", activity.Type, err)
+		}
+		if status != http.StatusAccepted {
+			t.Errorf("Deliver(%s) status = %d, want %d", activity.Type, status, http.StatusAccepted)
+		}
+	}
+
+	remote.mu.Lock()
+	defer remote.mu.Unlock()
+	if len(remote.received) != 2 {
+		t.Fatalf("remote received %d activities, want 2", len(remote.received))
+	}
+
+	got := remote.received[0]
+	if got.Type != TypeCreate || got.ObjectType() != "Note" || got.ObjectID() != NoteURL(baseURL, chirp.ID) {
+		t.Errorf("received %s of %s %s, want Create of the note", got.Type, got.ObjectType(), got.ObjectID())
+	}
+	note := Note{}
+	json.Unmarshal(got.Object, &note)
+	if note.Content != "<p>Hello &lt;fediverse&gt; &amp; friends</p>" {
+		t.Errorf("note content = %q, want the escaped body", note.Content)
+	}
+	if len(note.To) != 1 || note.To[0] != PublicCollection {
+		t.Errorf("note is addressed to %v, want the public collection", note.To)
+	}
+
+	got = remote.received[1]
+	if got.Type != TypeDelete || got.ObjectType() != "Tombstone" || got.ObjectID() != NoteURL(baseURL, chirp.ID) {
+		t.Errorf("received %s of %s %s, want Delete of the note", got.Type, got.ObjectType(), got.ObjectID())
+	}
+}
+
+func TestReceiveFromRemoteInstance(t *testing.T) {
+	remote := newFakeInstance(t)
+	impostor := newFakeInstance(t)
+	baseURL, user, _ := newLocalInstance(t)
+	localActor := ActorURL(baseURL, user.ID)
+
+	remoteKey, err := ParsePrivateKey(remote.privateKey)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() error = %v", err)
+	}
+	impostorKey, err := ParsePrivateKey(impostor.privateKey)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() error = %v", err)
+	}
+
+	activity := func(activityType, object string) []byte {
+		payload, _ := json.Marshal(Activity{
+			ID:     remote.actor.ID + "#" + uuid.NewString(),
+			Type:   activityType,
+			Actor:  remote.actor.ID,
+			Object: json.RawMessage(`"` + object + `"`),
+		})
+		return payload
+	}
+
+	cases := []struct {
+		name     string
+		payload  []byte
+		keyID    string
+		key      *rsa.PrivateKey
+		wantType string
+		wantErr  bool
+	}{
+		{
+			name:     "follow signed by the actor",
+			payload:  activity(TypeFollow, localActor),
+			keyID:    remote.actor.PublicKey.ID,
+			key:      remoteKey,
+			wantType: TypeFollow,
+		},
+		{
+			name:     "like signed by the actor",
+			payload:  activity(TypeLike, NoteURL(baseURL, uuid.New())),
+			keyID:    remote.actor.PublicKey.ID,
+			key:      remoteKey,
+			wantType: TypeLike,
+		},
+		{
+			name:    "signed with the key of another actor",
+			payload: activity(TypeAnnounce, NoteURL(baseURL, uuid.New())),
+			keyID:   impostor.actor.PublicKey.ID,
+			key:     impostorKey,
+			wantErr: true,
+		},
+		{
+			name:    "claims the key of the actor",
+			payload: activity(TypeFollow, localActor),
+			keyID:   remote.actor.PublicKey.ID,
+			key:     impostorKey,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got Activity
+			var gotErr error
+			inbox := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, gotErr = receive(r, http.DefaultClient)
+			}))
+			defer inbox.Close()
+
+			_, err := Deliver(context.Background(), http.DefaultClient, inbox.URL+"/api/ap/inbox", tc.payload, tc.keyID, tc.key, time.Now())
+			if err != nil {
+				t.Fatalf("Deliver() error = %v", err)
+			}
+
+			if (gotErr != nil) != tc.wantErr {
+				t.Fatalf("receive() error = %v, wantErr %v", gotErr, tc.wantErr)
+			}
+			if !tc.wantErr && got.Type != tc.wantType {
+				t.Errorf("received %s, want %s", got.Type, tc.wantType)
+			}
+		})
+	}
+}
+
+func TestFetchActor(t *testing.T) {
+	remote := newFakeInstance(t)
+
+	mux := http.NewServeMux()
+	other := httptest.NewServer(mux)
+	defer other.Close()
+	mux.HandleFunc("GET /users/mallory", func(w http.ResponseWriter, r *http.Request) {
+		// claims to be an actor of the other instance
+		json.NewEncoder(w).Encode(remote.actor)
+	})
+	mux.HandleFunc("GET /users/nokey", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Actor{ID: other.URL + "/users/nokey", Inbox: other.URL + "/inbox"})
+	})
+
+	cases := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "actor document", url: remote.actor.ID, wantErr: false},
+		{name: "actor of another instance", url: other.URL + "/users/mallory", wantErr: true},
+		{name: "actor without key", url: other.URL + "/users/nokey", wantErr: true},
+		{name: "missing actor", url: other.URL + "/users/nobody", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			actor, err := FetchActor(context.Background(), http.DefaultClient, tc.url)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("FetchActor() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && actor.SharedInbox() != remote.server.URL+"/inbox" {
+				t.Errorf("SharedInbox() = %q, want the instance's shared inbox", actor.SharedInbox())
+			}
+		})
+	}
+}
+
+func TestValidateRemoteURL(t *testing.T) {
+	cases := []struct {
+		name      string
+		url       string
+		allowHTTP bool
+		wantErr   bool
+	}{
+		{name: "https", url: "https://a.example/users/b", allowHTTP: false, wantErr: false},
+		{name: "http in production", url: "http://a.example/users/b", allowHTTP: false, wantErr: true},
+		{name: "http in development", url: "http://localhost:8080/users/b", allowHTTP: true, wantErr: false},
+		{name: "relative", url: "/users/b", allowHTTP: true, wantErr: true},
+		{name: "other scheme", url: "file:///etc/passwd", allowHTTP: true, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateRemoteURL(tc.url, tc.allowHTTP)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("ValidateRemoteURL(%q) error = %v, wantErr %v", tc.url, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	cases := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "application/activity+json", want: true},
+		{accept: `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`, want: true},
+		{accept: "text/html, application/activity+json;q=0.9", want: true},
+		{accept: "application/ld+json", want: false},
+		{accept: "application/json", want: false},
+		{accept: "", want: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(strings.ReplaceAll(tc.accept, "/", "_"), func(t *testing.T) {
+			if got := Accepts(tc.accept); got != tc.want {
+				t.Errorf("Accepts(%q) = %v, want %v", tc.accept, got, tc.want)
+			}
+		})
+	}
+}
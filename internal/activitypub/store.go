@@ -0,0 +1,108 @@
+package activitypub
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/database"
+)
+
+// LocalKey returns the key pair of a local user, generating it the first
+// time the user federates.
+func LocalKey(ctx context.Context, db *database.Queries, userID uuid.UUID) (database.ActorKey, error) {
+	key, err := db.GetActorKey(ctx, userID)
+	if err != sql.ErrNoRows {
+		return key, err
+	}
+
+	privatePEM, publicPEM, err := GenerateKey()
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+
+	// another request may have generated one in the meantime, which wins
+	return db.CreateActorKey(ctx, database.CreateActorKeyParams{
+		UserID:        userID,
+		PublicKeyPem:  publicPEM,
+		PrivateKeyPem: privatePEM,
+	})
+}
+
+// Enqueue queues the activity of a local user for the inboxes of all their
+// remote followers, once per instance. Pass a transaction-bound db so the
+// delivery only exists if the change that caused it was committed.
+func Enqueue(ctx context.Context, db *database.Queries, baseURL string, userID uuid.UUID, activity Activity) error {
+	encoded, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	return db.EnqueueActivityPubFollowerDeliveries(ctx, database.EnqueueActivityPubFollowerDeliveriesParams{
+		UserID:   userID,
+		KeyID:    KeyID(ActorURL(baseURL, userID)),
+		Activity: encoded,
+	})
+}
+
+// EnqueueTo queues the activity of a local user for a single inbox.
+func EnqueueTo(ctx context.Context, db *database.Queries, baseURL string, userID uuid.UUID, inbox string, activity Activity) error {
+	encoded, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	return db.EnqueueActivityPubDelivery(ctx, database.EnqueueActivityPubDeliveryParams{
+		UserID:   userID,
+		KeyID:    KeyID(ActorURL(baseURL, userID)),
+		Inbox:    inbox,
+		Activity: encoded,
+	})
+}
+
+// ResolveKey finds the remote actor owning keyID. Actors are fetched the
+// first time they're seen and cached, pass refresh to fetch them again when
+// their key may have been rotated. keyID comes from the request, so client
+// should refuse to connect to private addresses, like outbound.NewClient.
+func ResolveKey(ctx context.Context, db *database.Queries, client *http.Client, keyID string, allowHTTP, refresh bool) (database.RemoteActor, error) {
+	if !refresh {
+		actor, err := db.GetRemoteActorByKeyID(ctx, keyID)
+		if err != sql.ErrNoRows {
+			return actor, err
+		}
+	}
+
+	actorURL := KeyOwnerURL(keyID)
+	err := ValidateRemoteURL(actorURL, allowHTTP)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+
+	actor, err := FetchActor(ctx, client, actorURL)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	if actor.PublicKey.ID != keyID {
+		return database.RemoteActor{}, fmt.Errorf("%s isn't the key of %s", keyID, actor.ID)
+	}
+
+	err = ValidateRemoteURL(actor.Inbox, allowHTTP)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	err = ValidateRemoteURL(actor.SharedInbox(), allowHTTP)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+
+	return db.UpsertRemoteActor(ctx, database.UpsertRemoteActorParams{
+		ID:           actor.ID,
+		Inbox:        actor.Inbox,
+		SharedInbox:  actor.SharedInbox(),
+		KeyID:        actor.PublicKey.ID,
+		PublicKeyPem: actor.PublicKey.PublicKeyPem,
+	})
+}
@@ -0,0 +1,133 @@
+// Package apiversion tells the versions of the JSON API apart. v1 is served
+// under /api and v2 under /api/v2, and requests to /api can ask for v2 with
+// their Accept header.
+package apiversion
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Version int
+
+const (
+	V1 Version = 1
+	V2 Version = 2
+
+	Latest = V2
+)
+
+const prefix = "/api"
+
+// deprecations holds when the deprecated versions were deprecated and when
+// they stop being served.
+var deprecations = map[Version]struct{ deprecated, sunset time.Time }{
+	V1: {
+		deprecated: time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+		sunset:     time.Date(2027, time.October, 19, 0, 0, 0, 0, time.UTC),
+	},
+}
+
+func (v Version) String() string {
+	return "v" + strconv.Itoa(int(v))
+}
+
+// MediaType asks for v in an Accept header.
+func (v Version) MediaType() string {
+	return "application/vnd.chirpy." + v.String() + "+json"
+}
+
+// Path is where the v1 path is served in v, /api/chirps is /api/v2/chirps in
+// v2.
+func (v Version) Path(path string) string {
+	if v == V1 {
+		return path
+	}
+
+	rest, ok := strings.CutPrefix(path, prefix+"/")
+	if !ok {
+		panic(fmt.Sprintf("apiversion: %s is not under %s", path, prefix))
+	}
+	return prefix + "/" + v.String() + "/" + rest
+}
+
+// Deprecated tells whether v is deprecated.
+func (v Version) Deprecated() bool {
+	_, ok := deprecations[v]
+	return ok
+}
+
+// SetHeaders tells the client which version answered, and when it is
+// deprecated, since when (RFC 9745) and until when it's served (RFC 8594).
+func (v Version) SetHeaders(header http.Header) {
+	header.Set("Chirpy-Version", v.String())
+
+	deprecation, ok := deprecations[v]
+	if !ok {
+		return
+	}
+	header.Set("Deprecation", "@"+strconv.FormatInt(deprecation.deprecated.Unix(), 10))
+	header.Set("Sunset", deprecation.sunset.Format(http.TimeFormat))
+}
+
+// FromAccept is the version asked for in an Accept header, if any. The
+// version with the highest quality wins.
+func FromAccept(accept string) (Version, bool) {
+	best, bestQuality := Version(0), -1.0
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+
+		name, ok := strings.CutPrefix(mediaType, "application/vnd.chirpy.v")
+		if !ok {
+			continue
+		}
+		name, ok = strings.CutSuffix(name, "+json")
+		if !ok {
+			continue
+		}
+		number, err := strconv.Atoi(name)
+		if err != nil || number < int(V1) || number > int(Latest) {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = Version(number), quality
+		}
+	}
+
+	if bestQuality <= 0 {
+		return 0, false
+	}
+	return best, true
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, v Version) context.Context {
+	return context.WithValue(ctx, contextKey{}, v)
+}
+
+// FromContext is the version a request is served in, v1 outside of the
+// versioned routes.
+func FromContext(ctx context.Context) Version {
+	v, ok := ctx.Value(contextKey{}).(Version)
+	if !ok {
+		return V1
+	}
+	return v
+}
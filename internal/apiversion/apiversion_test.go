@@ -0,0 +1,53 @@
+package apiversion
+
+import "testing"
+
+func TestFromAccept(t *testing.T) {
+	cases := []struct {
+		name   string
+		accept string
+		want   Version
+		wantOK bool
+	}{
+		{name: "empty", accept: ""},
+		{name: "json", accept: "application/json"},
+		{name: "anything", accept: "*/*"},
+		{name: "v1", accept: "application/vnd.chirpy.v1+json", want: V1, wantOK: true},
+		{name: "v2", accept: "application/vnd.chirpy.v2+json", want: V2, wantOK: true},
+		{name: "v2 among others", accept: "text/html, application/vnd.chirpy.v2+json, */*;q=0.1", want: V2, wantOK: true},
+		{name: "highest quality wins", accept: "application/vnd.chirpy.v2+json;q=0.4, application/vnd.chirpy.v1+json;q=0.9", want: V1, wantOK: true},
+		{name: "refused", accept: "application/vnd.chirpy.v2+json;q=0"},
+		{name: "unknown version", accept: "application/vnd.chirpy.v9+json"},
+		{name: "not json", accept: "application/vnd.chirpy.v2+xml"},
+		{name: "malformed", accept: "application/vnd.chirpy.v2+json;q"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := FromAccept(c.accept)
+			if got != c.want || ok != c.wantOK {
+				t.Errorf("FromAccept(%q) = %v, %v, want %v, %v", c.accept, got, ok, c.want, c.wantOK)
+			}
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	cases := []struct {
+		version Version
+		path    string
+		want    string
+	}{
+		{version: V1, path: "/api/chirps", want: "/api/chirps"},
+		{version: V2, path: "/api/chirps", want: "/api/v2/chirps"},
+		{version: V2, path: "/api/chirps/{chirpID}", want: "/api/v2/chirps/{chirpID}"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.version.String()+c.path, func(t *testing.T) {
+			if got := c.version.Path(c.path); got != c.want {
+				t.Errorf("Path(%q) = %q, want %q", c.path, got, c.want)
+			}
+		})
+	}
+}
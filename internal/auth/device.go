@@ -0,0 +1,8 @@
+package auth
+
+// DeviceFingerprint identifies the device a login link was requested from.
+// The device secret is a random value the server keeps in a cookie on that
+// device, the user agent makes a stolen cookie alone not enough.
+func DeviceFingerprint(deviceSecret, userAgent string) string {
+	return HashToken(deviceSecret + "\n" + userAgent)
+}
@@ -0,0 +1,44 @@
+package auth
+
+import "testing"
+
+func TestDeviceFingerprint(t *testing.T) {
+	secret := MakeRefreshToken()
+	userAgent := "Mozilla/5.0 (X11; Linux x86_64)"
+	fingerprint := DeviceFingerprint(secret, userAgent)
+
+	cases := []struct {
+		name      string
+		secret    string
+		userAgent string
+		wantMatch bool
+	}{
+		{
+			name:      "same device",
+			secret:    secret,
+			userAgent: userAgent,
+			wantMatch: true,
+		},
+		{
+			name:      "other device secret",
+			secret:    MakeRefreshToken(),
+			userAgent: userAgent,
+			wantMatch: false,
+		},
+		{
+			name:      "other user agent",
+			secret:    secret,
+			userAgent: "curl/8.5.0",
+			wantMatch: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := DeviceFingerprint(c.secret, c.userAgent) == fingerprint
+			if got != c.wantMatch {
+				t.Errorf("expected match: %v, got %v", c.wantMatch, got)
+			}
+		})
+	}
+}
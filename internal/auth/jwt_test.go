@@ -2,6 +2,7 @@ package auth
 
 import (
 	"net/http"
+	"slices"
 	"testing"
 	"time"
 
@@ -154,3 +155,73 @@ func TestGetBearerToken(t *testing.T) {
 		})
 	}
 }
+
+func TestParseJWT(t *testing.T) {
+	userID := uuid.New()
+	secret := "testsecret"
+
+	scoped, err := MakeScopedJWT(userID, secret, time.Hour, "client", []string{ScopeChirpsRead, ScopeChirpsWrite})
+	if err != nil {
+		t.Fatalf("MakeScopedJWT returned error: %v", err)
+	}
+	unscoped, err := MakeJWT(userID, secret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	expired, err := MakeScopedJWT(userID, secret, -time.Hour, "client", []string{ScopeChirpsRead})
+	if err != nil {
+		t.Fatalf("MakeScopedJWT returned error: %v", err)
+	}
+
+	cases := []struct {
+		name         string
+		token        string
+		wantErr      bool
+		wantClientID string
+		wantScopes   []string
+	}{
+		{
+			name:         "scoped token",
+			token:        scoped,
+			wantErr:      false,
+			wantClientID: "client",
+			wantScopes:   []string{ScopeChirpsRead, ScopeChirpsWrite},
+		},
+		{
+			name:         "login token",
+			token:        unscoped,
+			wantErr:      false,
+			wantClientID: "",
+			wantScopes:   nil,
+		},
+		{
+			name:    "expired token",
+			token:   expired,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := ParseJWT(tc.token, secret)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("ParseJWT should fail but did not")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseJWT returned error: %v", err)
+			}
+			if claims.Subject != userID.String() {
+				t.Errorf("got subject %q, want %q", claims.Subject, userID)
+			}
+			if claims.ClientID != tc.wantClientID {
+				t.Errorf("got client id %q, want %q", claims.ClientID, tc.wantClientID)
+			}
+			if !slices.Equal(claims.Scopes(), tc.wantScopes) {
+				t.Errorf("got scopes %v, want %v", claims.Scopes(), tc.wantScopes)
+			}
+		})
+	}
+}
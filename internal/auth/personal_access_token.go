@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	PersonalAccessTokenPrefix = "chirpy_pat_"
+
+	ScopeChirpsRead    = "chirps:read"
+	ScopeChirpsWrite   = "chirps:write"
+	ScopeMessagesRead  = "messages:read"
+	ScopeMessagesWrite = "messages:write"
+	ScopeProfileWrite  = "profile:write"
+	ScopeWebhooksWrite = "webhooks:write"
+
+	displayPrefixLength = len(PersonalAccessTokenPrefix) + 6
+)
+
+var Scopes = []string{
+	ScopeChirpsRead,
+	ScopeChirpsWrite,
+	ScopeMessagesRead,
+	ScopeMessagesWrite,
+	ScopeProfileWrite,
+	ScopeWebhooksWrite,
+}
+
+func MakePersonalAccessToken() string {
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	return PersonalAccessTokenPrefix + hex.EncodeToString(key)
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// HashToken hashes high-entropy tokens for storage. Unlike passwords they
+// can't be brute forced, so a fast hash is enough and keeps lookups cheap.
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// TokenDisplayPrefix is the part of a token that is safe to show back to the
+// user so they can tell their tokens apart.
+func TokenDisplayPrefix(token string) string {
+	if len(token) < displayPrefixLength {
+		return token
+	}
+	return token[:displayPrefixLength]
+}
+
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return fmt.Errorf("unknown scope %s", scope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMakePersonalAccessToken(t *testing.T) {
+	token := MakePersonalAccessToken()
+	if !IsPersonalAccessToken(token) {
+		t.Errorf("token %q is missing the %q prefix", token, PersonalAccessTokenPrefix)
+	}
+	if token == MakePersonalAccessToken() {
+		t.Error("MakePersonalAccessToken returned the same token twice")
+	}
+
+	hash := HashToken(token)
+	if hash != HashToken(token) {
+		t.Error("HashToken is not deterministic")
+	}
+	if strings.Contains(hash, token) {
+		t.Error("HashToken leaks the token")
+	}
+
+	prefix := TokenDisplayPrefix(token)
+	if !strings.HasPrefix(token, prefix) || len(prefix) >= len(token) {
+		t.Errorf("got display prefix %q for token %q", prefix, token)
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	cases := []struct {
+		name    string
+		scopes  []string
+		wantErr bool
+	}{
+		{
+			name:    "known scopes",
+			scopes:  []string{ScopeChirpsRead, ScopeChirpsWrite},
+			wantErr: false,
+		},
+		{
+			name:    "no scopes",
+			scopes:  []string{},
+			wantErr: true,
+		},
+		{
+			name:    "unknown scope",
+			scopes:  []string{ScopeChirpsRead, "admin"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateScopes(tc.scopes)
+			if tc.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+)
+
+const PKCEMethodS256 = "S256"
+
+// MakePKCEChallenge derives the S256 code challenge for a code verifier.
+func MakePKCEChallenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// VerifyPKCE checks a code verifier against the challenge sent when the
+// authorization code was requested (RFC 7636, S256 only).
+func VerifyPKCE(verifier, challenge string) error {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return fmt.Errorf("code verifier must be between 43 and 128 characters")
+	}
+
+	expected := MakePKCEChallenge(verifier)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) != 1 {
+		return fmt.Errorf("code verifier does not match challenge")
+	}
+
+	return nil
+}
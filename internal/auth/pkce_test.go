@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// example from RFC 7636, appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	cases := []struct {
+		name      string
+		verifier  string
+		challenge string
+		wantErr   bool
+	}{
+		{
+			name:      "matching verifier",
+			verifier:  verifier,
+			challenge: challenge,
+			wantErr:   false,
+		},
+		{
+			name:      "wrong verifier",
+			verifier:  strings.Repeat("a", 43),
+			challenge: challenge,
+			wantErr:   true,
+		},
+		{
+			name:      "verifier too short",
+			verifier:  "short",
+			challenge: MakePKCEChallenge("short"),
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := VerifyPKCE(tc.verifier, tc.challenge)
+			if tc.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
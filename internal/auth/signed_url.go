@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func signURLPath(secret, path string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "signed-url\n%s\n%d", path, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignURL returns the path with an expiry and signature, so it can be fetched
+// without authentication until it expires.
+func SignURL(secret, path string, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", signURLPath(secret, path, expires))
+
+	return path + "?" + query.Encode()
+}
+
+// VerifySignedURL checks the query of a URL built by SignURL.
+func VerifySignedURL(secret, path string, query url.Values, now time.Time) error {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid expiry")
+	}
+
+	expected := signURLPath(secret, path, expires)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	if now.Unix() > expires {
+		return fmt.Errorf("url expired")
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifySignedURL(t *testing.T) {
+	secret := "app-secret"
+	path := "/api/exports/2c9b1c52-7e0e-4d8a-9a57-1f0c3b6a9d11/download"
+	now := time.Now()
+
+	signed := SignURL(secret, path, now.Add(time.Hour))
+	parsed, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("invalid url: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		secret  string
+		path    string
+		query   string
+		now     time.Time
+		wantErr bool
+	}{
+		{
+			name:    "valid url",
+			secret:  secret,
+			path:    path,
+			query:   parsed.RawQuery,
+			now:     now,
+			wantErr: false,
+		},
+		{
+			name:    "expired url",
+			secret:  secret,
+			path:    path,
+			query:   parsed.RawQuery,
+			now:     now.Add(2 * time.Hour),
+			wantErr: true,
+		},
+		{
+			name:    "other path",
+			secret:  secret,
+			path:    strings.Replace(path, "2c9b", "3c9b", 1),
+			query:   parsed.RawQuery,
+			now:     now,
+			wantErr: true,
+		},
+		{
+			name:    "wrong secret",
+			secret:  "another-secret",
+			path:    path,
+			query:   parsed.RawQuery,
+			now:     now,
+			wantErr: true,
+		},
+		{
+			name:    "extended expiry",
+			secret:  secret,
+			path:    path,
+			query:   strings.Replace(parsed.RawQuery, "expires=", "expires=9", 1),
+			now:     now,
+			wantErr: true,
+		},
+		{
+			name:    "missing signature",
+			secret:  secret,
+			path:    path,
+			query:   "",
+			now:     now,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(c.query)
+			err := VerifySignedURL(c.secret, c.path, query, c.now)
+			if (err != nil) != c.wantErr {
+				t.Errorf("expected error: %v, got %v", c.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	PolkaSignatureHeader = "X-Polka-Signature"
+	PolkaTimestampHeader = "X-Polka-Timestamp"
+	PolkaEventIDHeader   = "X-Polka-Event-ID"
+
+	signatureVersion = "v1="
+)
+
+// SignWebhook returns the hex encoded HMAC-SHA256 of "<timestamp>.<body>".
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignPolkaWebhook returns the hex encoded HMAC-SHA256 of
+// "<timestamp>.<event ID>.<body>", the signature of Polka's webhooks. The
+// event ID is signed so a captured webhook can't be replayed under another.
+func SignPolkaWebhook(secret string, timestamp int64, eventID string, body []byte) string {
+	return SignWebhook(secret, timestamp, polkaSignedContent(eventID, body))
+}
+
+func polkaSignedContent(eventID string, body []byte) []byte {
+	return append([]byte(eventID+"."), body...)
+}
+
+// GetWebhookSignature returns the timestamp, event ID and signature of a
+// signed Polka webhook. Signed webhooks must have an event ID, it's what
+// retries are deduplicated by.
+func GetWebhookSignature(headers *http.Header) (int64, string, string, error) {
+	signature := strings.TrimPrefix(headers.Get(PolkaSignatureHeader), signatureVersion)
+	if signature == "" {
+		return 0, "", "", fmt.Errorf("no signature provided")
+	}
+
+	timestamp, err := strconv.ParseInt(headers.Get(PolkaTimestampHeader), 10, 64)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("invalid signature timestamp")
+	}
+
+	eventID := headers.Get(PolkaEventIDHeader)
+	if eventID == "" {
+		return 0, "", "", fmt.Errorf("no event ID provided")
+	}
+
+	return timestamp, eventID, signature, nil
+}
+
+// ValidateWebhookSignature checks the signature in constant time and rejects
+// timestamps further than tolerance away from now, so a captured request
+// can't be replayed later on.
+func ValidateWebhookSignature(secret string, timestamp int64, body []byte, signature string, tolerance time.Duration, now time.Time) error {
+	sentAt := time.Unix(timestamp, 0)
+	if now.Sub(sentAt).Abs() > tolerance {
+		return fmt.Errorf("signature timestamp outside tolerance")
+	}
+
+	expected := SignWebhook(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
+
+// ValidatePolkaSignature is ValidateWebhookSignature for the signatures of
+// SignPolkaWebhook.
+func ValidatePolkaSignature(secret string, timestamp int64, eventID string, body []byte, signature string, tolerance time.Duration, now time.Time) error {
+	return ValidateWebhookSignature(secret, timestamp, polkaSignedContent(eventID, body), signature, tolerance, now)
+}
+
+func CompareAPIKey(expected, provided string) bool {
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(provided)) == 1
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestValidateWebhookSignature(t *testing.T) {
+	secret := "testsecret"
+	body := []byte(`{"event":"user.upgraded"}`)
+	now := time.Unix(1_700_000_000, 0)
+	tolerance := 5 * time.Minute
+
+	cases := []struct {
+		name      string
+		timestamp int64
+		body      []byte
+		signature string
+		wantErr   bool
+	}{
+		{
+			name:      "valid signature",
+			timestamp: now.Unix(),
+			body:      body,
+			signature: SignWebhook(secret, now.Unix(), body),
+			wantErr:   false,
+		},
+		{
+			name:      "wrong secret",
+			timestamp: now.Unix(),
+			body:      body,
+			signature: SignWebhook("wrongsecret", now.Unix(), body),
+			wantErr:   true,
+		},
+		{
+			name:      "tampered body",
+			timestamp: now.Unix(),
+			body:      []byte(`{"event":"user.downgraded"}`),
+			signature: SignWebhook(secret, now.Unix(), body),
+			wantErr:   true,
+		},
+		{
+			name:      "timestamp too old",
+			timestamp: now.Add(-10 * time.Minute).Unix(),
+			body:      body,
+			signature: SignWebhook(secret, now.Add(-10*time.Minute).Unix(), body),
+			wantErr:   true,
+		},
+		{
+			name:      "timestamp too far in the future",
+			timestamp: now.Add(10 * time.Minute).Unix(),
+			body:      body,
+			signature: SignWebhook(secret, now.Add(10*time.Minute).Unix(), body),
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateWebhookSignature(secret, tc.timestamp, tc.body, tc.signature, tolerance, now)
+			if tc.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidatePolkaSignature(t *testing.T) {
+	secret := "testsecret"
+	body := []byte(`{"event":"user.upgraded"}`)
+	now := time.Unix(1_700_000_000, 0)
+	signature := SignPolkaWebhook(secret, now.Unix(), "evt_1", body)
+
+	cases := []struct {
+		name    string
+		eventID string
+		wantErr bool
+	}{
+		{name: "signed event ID", eventID: "evt_1"},
+		{name: "replayed under another event ID", eventID: "evt_2", wantErr: true},
+		{name: "replayed without the event ID", eventID: "", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidatePolkaSignature(secret, now.Unix(), tc.eventID, body, signature, 5*time.Minute, now)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("ValidatePolkaSignature() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetWebhookSignature(t *testing.T) {
+	cases := []struct {
+		name          string
+		headers       map[string]string
+		wantTimestamp int64
+		wantEventID   string
+		wantSignature string
+		wantErr       bool
+	}{
+		{
+			name: "versioned signature",
+			headers: map[string]string{
+				PolkaSignatureHeader: "v1=abc123",
+				PolkaTimestampHeader: "1700000000",
+				PolkaEventIDHeader:   "evt_1",
+			},
+			wantTimestamp: 1700000000,
+			wantEventID:   "evt_1",
+			wantSignature: "abc123",
+			wantErr:       false,
+		},
+		{
+			name:    "missing signature",
+			headers: map[string]string{PolkaTimestampHeader: "1700000000", PolkaEventIDHeader: "evt_1"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid timestamp",
+			headers: map[string]string{PolkaSignatureHeader: "abc123", PolkaTimestampHeader: "yesterday", PolkaEventIDHeader: "evt_1"},
+			wantErr: true,
+		},
+		{
+			name:    "missing event ID",
+			headers: map[string]string{PolkaSignatureHeader: "abc123", PolkaTimestampHeader: "1700000000"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := make(http.Header)
+			for k, v := range tc.headers {
+				h.Set(k, v)
+			}
+			timestamp, eventID, signature, err := GetWebhookSignature(&h)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if timestamp != tc.wantTimestamp {
+				t.Errorf("got timestamp %d, want %d", timestamp, tc.wantTimestamp)
+			}
+			if eventID != tc.wantEventID {
+				t.Errorf("got event ID %q, want %q", eventID, tc.wantEventID)
+			}
+			if signature != tc.wantSignature {
+				t.Errorf("got signature %q, want %q", signature, tc.wantSignature)
+			}
+		})
+	}
+}
@@ -3,14 +3,25 @@ package config
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"slices"
+	"strings"
 	"sync/atomic"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/lucashthiele/chirpy/internal/auth"
 	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/encryption"
+	"github.com/lucashthiele/chirpy/internal/gateway"
+	"github.com/lucashthiele/chirpy/internal/mailer"
+	"github.com/lucashthiele/chirpy/internal/oidc"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/stream"
 	"github.com/lucashthiele/chirpy/pkg/response"
 )
 
@@ -18,24 +29,40 @@ type contextKey string
 
 const UserIDKey contextKey = "userID"
 
+// ScopesKey holds the scopes of the personal access token or OAuth access
+// token used to authenticate. It is not set for login JWTs, which have full
+// access.
+const ScopesKey contextKey = "scopes"
+
+const polkaSignatureTolerance = 5 * time.Minute
+
 type ApiConfig struct {
 	Platform       string
 	FileServerHits *atomic.Int32
 	Db             *database.Queries
+	DbConn         *sql.DB
 	AppSecret      string
 	PolkaKey       string
+	PolkaSecret    string
+	AdminKey       string
+	OIDCProviders  map[string]*oidc.Provider
+	Mailer         mailer.Mailer
+	BaseURL        string
+	MessageKeys    *encryption.Keyring
+	ChirpEvents    *stream.Broker
+	Gateway        *gateway.Hub
 }
 
 var instance *ApiConfig
 
-func createDatabaseInstance() (*database.Queries, error) {
+func createDatabaseInstance() (*sql.DB, error) {
 	dbURL := os.Getenv("DB_URL")
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
-		return &database.Queries{}, fmt.Errorf("error opening database connection: %s", err.Error())
+		return &sql.DB{}, fmt.Errorf("error opening database connection: %s", err.Error())
 	}
 
-	return database.New(db), nil
+	return db, nil
 }
 
 func New() (*ApiConfig, error) {
@@ -45,12 +72,36 @@ func New() (*ApiConfig, error) {
 			return &ApiConfig{}, nil
 		}
 
+		oidcProviders, err := oidc.ProvidersFromEnv()
+		if err != nil {
+			return &ApiConfig{}, err
+		}
+
+		emailMailer, err := mailer.FromEnv(os.Getenv("PLATFORM"))
+		if err != nil {
+			return &ApiConfig{}, err
+		}
+
+		messageKeys, err := encryption.KeyringFromEnv("MESSAGE_ENCRYPTION")
+		if err != nil {
+			return &ApiConfig{}, err
+		}
+
 		instance = &ApiConfig{
 			Platform:       os.Getenv("PLATFORM"),
 			FileServerHits: &atomic.Int32{},
-			Db:             db,
+			Db:             database.New(db),
+			DbConn:         db,
 			AppSecret:      os.Getenv("APP_SECRET"),
 			PolkaKey:       os.Getenv("POLKA_KEY"),
+			PolkaSecret:    os.Getenv("POLKA_SIGNING_SECRET"),
+			AdminKey:       os.Getenv("ADMIN_KEY"),
+			OIDCProviders:  oidcProviders,
+			Mailer:         emailMailer,
+			BaseURL:        strings.TrimSuffix(os.Getenv("BASE_URL"), "/"),
+			MessageKeys:    messageKeys,
+			ChirpEvents:    stream.NewBroker(),
+			Gateway:        gateway.NewHub(),
 		}
 		instance.FileServerHits.Store(0)
 	}
@@ -65,35 +116,223 @@ func (cfg *ApiConfig) MiddlewareMetricsInc(next http.Handler) http.Handler {
 	})
 }
 
+// ErrUnauthorized is returned by Authenticate for tokens that are invalid,
+// expired or revoked.
+var ErrUnauthorized = errors.New("Unauthorized")
+
+// Authenticate checks a login JWT, OAuth access token or personal access token
+// and returns ctx with the user and scopes it grants.
+func (cfg *ApiConfig) Authenticate(ctx context.Context, token string) (context.Context, error) {
+	if auth.IsPersonalAccessToken(token) {
+		pat, err := cfg.Db.GetActivePersonalAccessToken(ctx, auth.HashToken(token))
+		if err == sql.ErrNoRows {
+			return ctx, ErrUnauthorized
+		}
+		if err != nil {
+			return ctx, err
+		}
+
+		err = cfg.Db.TouchPersonalAccessToken(ctx, pat.ID)
+		if err != nil {
+			log.Printf("Error updating personal access token %s: %s", pat.ID, err)
+		}
+
+		ctx = context.WithValue(ctx, UserIDKey, pat.UserID)
+		return context.WithValue(ctx, ScopesKey, pat.Scopes), nil
+	}
+
+	claims, err := auth.ParseJWT(token, cfg.AppSecret)
+	if err != nil {
+		return ctx, ErrUnauthorized
+	}
+
+	userId, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return ctx, ErrUnauthorized
+	}
+	ctx = context.WithValue(ctx, UserIDKey, userId)
+	if claims.ClientID != "" {
+		ctx = context.WithValue(ctx, ScopesKey, claims.Scopes())
+	}
+
+	return ctx, nil
+}
+
 func (cfg *ApiConfig) MiddlewareAuth(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		token, err := auth.GetBearerToken(&req.Header)
 		if err != nil {
-			response.RespondWithError(resp, http.StatusUnauthorized, err.Error())
+			response.RespondWithError(resp, req, http.StatusUnauthorized, err.Error())
 			return
 		}
 
-		userId, err := auth.ValidateJWT(token, cfg.AppSecret)
+		ctx, err := cfg.Authenticate(req.Context(), token)
+		if err == ErrUnauthorized {
+			response.RespondWithError(resp, req, http.StatusUnauthorized, err.Error())
+			return
+		}
 		if err != nil {
-			response.RespondWithError(resp, http.StatusUnauthorized, "Unauthorized")
+			response.RespondWithInternalServerError(resp, req, err)
 			return
 		}
-		ctx := context.WithValue(req.Context(), UserIDKey, userId)
 
 		next.ServeHTTP(resp, req.WithContext(ctx))
 	})
 }
 
+// MiddlewareOptionalAuth lets anonymous requests through, but authenticates
+// the ones that carry a token like MiddlewareAuth does.
+func (cfg *ApiConfig) MiddlewareOptionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	authenticated := cfg.MiddlewareAuth(next)
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") == "" {
+			next.ServeHTTP(resp, req)
+			return
+		}
+
+		authenticated.ServeHTTP(resp, req)
+	})
+}
+
+// PublicURL is where the API can be reached from outside, for the absolute
+// links in documents meant for other servers. It is BASE_URL when set and the
+// host the request was sent to otherwise.
+func (cfg *ApiConfig) PublicURL(req *http.Request) string {
+	if cfg.BaseURL != "" {
+		return cfg.BaseURL
+	}
+	if req.TLS != nil {
+		return "https://" + req.Host
+	}
+	return "http://" + req.Host
+}
+
+// ViewerID returns the authenticated user, or uuid.Nil for anonymous requests
+// that went through MiddlewareOptionalAuth.
+func ViewerID(ctx context.Context) uuid.UUID {
+	userId, ok := ctx.Value(UserIDKey).(uuid.UUID)
+	if !ok {
+		return uuid.Nil
+	}
+	return userId
+}
+
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, ok := ctx.Value(ScopesKey).([]string)
+	if !ok {
+		return true
+	}
+	return slices.Contains(scopes, scope)
+}
+
+// RequireScope rejects requests authenticated with a personal access token
+// that wasn't granted scope. It must run after MiddlewareAuth.
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if !HasScope(req.Context(), scope) {
+			response.RespondWithError(resp, req, http.StatusForbidden, "insufficient scope: requires "+scope)
+			return
+		}
+
+		next.ServeHTTP(resp, req)
+	})
+}
+
+// RequireSession only lets through requests authenticated with a login JWT,
+// so personal access tokens and OAuth clients can't mint or revoke tokens.
+func RequireSession(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if _, ok := req.Context().Value(ScopesKey).([]string); ok {
+			response.RespondWithError(resp, req, http.StatusForbidden, "only login tokens can be used here")
+			return
+		}
+
+		next.ServeHTTP(resp, req)
+	})
+}
+
+// MiddlewarePolka accepts both the signed scheme (X-Polka-Signature over the
+// timestamp, event ID and raw body) and the legacy ApiKey header while Polka
+// rolls the signatures out.
 func (cfg *ApiConfig) MiddlewarePolka(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		body, ok := readWebhookBody(resp, req)
+		if !ok {
+			return
+		}
+
+		if req.Header.Get(auth.PolkaSignatureHeader) != "" {
+			timestamp, eventID, signature, err := auth.GetWebhookSignature(&req.Header)
+			if err != nil {
+				response.RespondWithError(resp, req, http.StatusUnauthorized, err.Error())
+				return
+			}
+
+			err = auth.ValidatePolkaSignature(cfg.PolkaSecret, timestamp, eventID, body, signature, polkaSignatureTolerance, time.Now())
+			if cfg.PolkaSecret == "" || err != nil {
+				response.RespondWithError(resp, req, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+		} else {
+			APIKey, err := auth.GetAPIKey(&req.Header)
+			if err != nil {
+				response.RespondWithError(resp, req, http.StatusUnauthorized, err.Error())
+				return
+			}
+
+			if !auth.CompareAPIKey(cfg.PolkaKey, APIKey) {
+				response.RespondWithError(resp, req, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+		}
+
+		next.ServeHTTP(resp, req)
+	})
+}
+
+// MiddlewarePolkaDedupe makes sure requests carrying an X-Polka-Event-ID are
+// only processed once. Failed attempts release the ID so they can be retried.
+func (cfg *ApiConfig) MiddlewarePolkaDedupe(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		eventID := req.Header.Get(auth.PolkaEventIDHeader)
+		if eventID == "" {
+			next.ServeHTTP(resp, req)
+			return
+		}
+
+		claimed, err := cfg.Db.ClaimPolkaEvent(req.Context(), eventID)
+		if err != nil {
+			response.RespondWithInternalServerError(resp, req, err)
+			return
+		}
+		if claimed == 0 { // already processed, acknowledge so Polka stops retrying
+			response.RespondWithJSON(resp, http.StatusNoContent, nil)
+			return
+		}
+
+		recorder := recorderOf(resp)
+		next.ServeHTTP(recorder, req)
+
+		if recorder.status >= http.StatusInternalServerError {
+			err = cfg.Db.ReleasePolkaEvent(req.Context(), eventID)
+			if err != nil {
+				log.Printf("Error releasing polka event %s: %s", eventID, err)
+			}
+		}
+	})
+}
+
+func (cfg *ApiConfig) MiddlewareAdmin(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		APIKey, err := auth.GetAPIKey(&req.Header)
 		if err != nil {
-			response.RespondWithError(resp, http.StatusUnauthorized, err.Error())
+			response.RespondWithError(resp, req, http.StatusUnauthorized, err.Error())
 			return
 		}
 
-		if cfg.PolkaKey != APIKey {
-			response.RespondWithError(resp, http.StatusUnauthorized, "Unauthorized")
+		if cfg.AdminKey == "" || !auth.CompareAPIKey(cfg.AdminKey, APIKey) {
+			response.RespondWithError(resp, req, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 
@@ -101,15 +340,26 @@ func (cfg *ApiConfig) MiddlewarePolka(next http.HandlerFunc) http.HandlerFunc {
 	})
 }
 
+var ResetOperation = openapi.Operation{
+	ID:          "resetApp",
+	Tag:         "Admin",
+	Summary:     "Reset everything",
+	Description: "Resets application data. It will only work when PLATFORM is dev.",
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusOK, "Reset successfully"),
+		openapi.Error(http.StatusForbidden, "Forbidden"),
+	},
+}
+
 func (cfg *ApiConfig) HandleReset() http.HandlerFunc {
 	return http.HandlerFunc(func(resp http.ResponseWriter, r *http.Request) {
 		if cfg.Platform != "dev" {
-			response.RespondWithError(resp, http.StatusForbidden, "Forbidden")
+			response.RespondWithError(resp, r, http.StatusForbidden, "Forbidden")
 			return
 		}
 		err := cfg.Db.DeleteAllUsers(r.Context())
 		if err != nil {
-			response.RespondWithInternalServerError(resp, err)
+			response.RespondWithInternalServerError(resp, r, err)
 			return
 		}
 		cfg.FileServerHits.Store(0)
@@ -118,6 +368,16 @@ func (cfg *ApiConfig) HandleReset() http.HandlerFunc {
 	})
 }
 
+var MetricsOperation = openapi.Operation{
+	ID:          "getMetrics",
+	Tag:         "Admin",
+	Summary:     "Metrics endpoint",
+	Description: "Returns internal metrics for the application.",
+	Responses: []openapi.Response{
+		openapi.Content(http.StatusOK, "Successful response with metrics", "text/html"),
+	},
+}
+
 func (cfg *ApiConfig) HandleMetrics() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		metricsResp := fmt.Sprintf(`<html>
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/requestid"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on the responses replayed to retries.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	MaxIdempotencyKeyLength  = 255
+
+	// IdempotencyKeyTTL is how long the response to a request is replayed
+	// to its retries.
+	IdempotencyKeyTTL = 24 * time.Hour
+	// idempotencyLockTimeout is how long a request can be handled before
+	// its key is considered abandoned, like when the instance handling it
+	// went down, and retries can claim it. The lock isn't renewed, so a
+	// request still being handled after this long is handled a second time
+	// by its retry. Keep it above the time any idempotent route can take.
+	idempotencyLockTimeout = time.Minute
+)
+
+// unstoredHeaders are about the request that was answered rather than its
+// response, replays get their own.
+var unstoredHeaders = []string{"Date", "Set-Cookie", requestid.Header}
+
+// capturingWriter keeps a copy of the response it writes.
+type capturingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *capturingWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// MiddlewareIdempotency makes requests sent with an Idempotency-Key safe to
+// retry. The first request with a key is handled and its response stored;
+// retries with the same key get the stored response, until the key expires.
+//
+// Keys are scoped to the authenticated user, so it goes after MiddlewareAuth
+// on authenticated routes. Anonymous requests must use random UUIDs as keys.
+// A key sent again with another method, path or body is refused with a 422,
+// and a retry sent while the first request is still being handled gets a 409.
+// Server errors aren't stored, so their retries are handled again.
+func (cfg *ApiConfig) MiddlewareIdempotency(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		if len(key) > MaxIdempotencyKeyLength {
+			response.RespondWithError(resp, req, http.StatusBadRequest, "the Idempotency-Key can't be longer than "+strconv.Itoa(MaxIdempotencyKeyLength)+" characters")
+			return
+		}
+
+		// anonymous requests share a scope, only keys no other client can
+		// guess keep them from getting each other's responses
+		scope := ""
+		if userId, ok := req.Context().Value(UserIDKey).(uuid.UUID); ok {
+			scope = userId.String()
+		} else if _, err := uuid.Parse(key); err != nil {
+			response.RespondWithError(resp, req, http.StatusBadRequest, "the Idempotency-Key must be a UUID when not authenticated")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(resp, req.Body, parser.MaxBodySize))
+		tooLarge := &http.MaxBytesError{}
+		if errors.As(err, &tooLarge) {
+			response.RespondWithErr(resp, req, response.Errorf(response.CodePayloadTooLarge, "the body can't be larger than %d bytes", tooLarge.Limit))
+			return
+		}
+		if err != nil {
+			response.RespondWithError(resp, req, http.StatusBadRequest, "the body couldn't be read")
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := requestFingerprint(req, body)
+		lockID := uuid.New()
+
+		claimed, err := cfg.Db.ClaimIdempotencyKey(req.Context(), database.ClaimIdempotencyKeyParams{
+			Scope:           scope,
+			Key:             key,
+			Fingerprint:     fingerprint,
+			LockID:          lockID,
+			ExpiresAt:       time.Now().Add(IdempotencyKeyTTL),
+			AbandonedBefore: time.Now().Add(-idempotencyLockTimeout),
+		})
+		if err != nil {
+			response.RespondWithInternalServerError(resp, req, err)
+			return
+		}
+		if claimed == 0 {
+			cfg.replayIdempotent(resp, req, scope, key, fingerprint)
+			return
+		}
+
+		writer := &capturingWriter{ResponseWriter: resp}
+		next.ServeHTTP(writer, req)
+
+		// the response is stored even if the client went away, its retry
+		// is what it's for
+		ctx := context.WithoutCancel(req.Context())
+		if writer.status == 0 || writer.status >= http.StatusInternalServerError {
+			err = cfg.Db.ReleaseIdempotencyKey(ctx, database.ReleaseIdempotencyKeyParams{
+				Scope:  scope,
+				Key:    key,
+				LockID: lockID,
+			})
+			if err != nil {
+				log.Printf("Error releasing idempotency key %s: %s", key, err)
+			}
+			return
+		}
+
+		headers := writer.Header().Clone()
+		for _, name := range unstoredHeaders {
+			headers.Del(name)
+		}
+		encodedHeaders, err := json.Marshal(headers)
+		if err == nil {
+			err = cfg.Db.SaveIdempotentResponse(ctx, database.SaveIdempotentResponseParams{
+				Scope:           scope,
+				Key:             key,
+				ResponseStatus:  sql.NullInt32{Int32: int32(writer.status), Valid: true},
+				ResponseHeaders: encodedHeaders,
+				ResponseBody:    writer.body.Bytes(),
+				LockID:          lockID,
+			})
+		}
+		if err != nil {
+			log.Printf("Error saving the response of idempotency key %s: %s", key, err)
+		}
+	})
+}
+
+// requestFingerprint tells requests sent with the same key apart.
+func requestFingerprint(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// replayIdempotent answers a retry of a request whose key is already claimed
+// with the stored response.
+func (cfg *ApiConfig) replayIdempotent(resp http.ResponseWriter, req *http.Request, scope, key, fingerprint string) {
+	stored, err := cfg.Db.GetIdempotencyKey(req.Context(), database.GetIdempotencyKeyParams{
+		Scope: scope,
+		Key:   key,
+	})
+	// released by a failed request since the claim, or expired
+	if err == sql.ErrNoRows {
+		resp.Header().Set("Retry-After", "1")
+		response.RespondWithErr(resp, req, response.NewError(response.CodeIdempotencyKeyInUse, "the request with this Idempotency-Key failed, retry it"))
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(resp, req, err)
+		return
+	}
+
+	if stored.Fingerprint != fingerprint {
+		response.RespondWithErr(resp, req, response.NewError(response.CodeIdempotencyKeyReused, "the Idempotency-Key was already used for another request"))
+		return
+	}
+	if !stored.ResponseStatus.Valid {
+		resp.Header().Set("Retry-After", "1")
+		response.RespondWithErr(resp, req, response.NewError(response.CodeIdempotencyKeyInUse, "a request with this Idempotency-Key is still being handled"))
+		return
+	}
+
+	headers := http.Header{}
+	err = json.Unmarshal(stored.ResponseHeaders, &headers)
+	if err != nil {
+		response.RespondWithInternalServerError(resp, req, err)
+		return
+	}
+	for name, values := range headers {
+		resp.Header()[name] = values
+	}
+	resp.Header().Set(IdempotentReplayedHeader, "true")
+	resp.WriteHeader(int(stored.ResponseStatus.Int32))
+	resp.Write(stored.ResponseBody)
+}
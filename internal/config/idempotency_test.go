@@ -0,0 +1,123 @@
+package config
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/database/dbtest"
+)
+
+func TestMiddlewareIdempotencyRetries(t *testing.T) {
+	const body = `{"email":"user@example.com","password":"hunter2"}`
+	fingerprint := requestFingerprint(httptest.NewRequest(http.MethodPost, "/api/users", nil), []byte(body))
+
+	cases := []struct {
+		name              string
+		storedFingerprint string
+		storedStatus      any
+		wantStatus        int
+		wantRetryAfter    bool
+	}{
+		{name: "with another request", storedFingerprint: "another request", storedStatus: int64(http.StatusCreated), wantStatus: http.StatusUnprocessableEntity},
+		{name: "while the first is handled", storedFingerprint: fingerprint, storedStatus: nil, wantStatus: http.StatusConflict, wantRetryAfter: true},
+		{name: "after the first was handled", storedFingerprint: fingerprint, storedStatus: int64(http.StatusCreated), wantStatus: http.StatusCreated},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			key := uuid.NewString()
+			db, conn := dbtest.New(t, map[string]dbtest.Query{
+				// the key is already claimed by the first request
+				"ClaimIdempotencyKey": func([]driver.Value) (dbtest.Result, error) { return dbtest.Affected(0), nil },
+				"GetIdempotencyKey": func(args []driver.Value) (dbtest.Result, error) {
+					now := time.Now()
+					return dbtest.Row(args[0], args[1], c.storedFingerprint, uuid.NewString(), now, now.Add(IdempotencyKeyTTL), c.storedStatus, []byte(`{}`), []byte(`{}`)), nil
+				},
+			})
+			cfg := &ApiConfig{Db: database.New(conn)}
+
+			handled := false
+			handler := cfg.MiddlewareIdempotency(func(w http.ResponseWriter, req *http.Request) {
+				handled = true
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(body))
+			req.Header.Set(IdempotencyKeyHeader, key)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, c.wantStatus)
+			}
+			if (rec.Header().Get("Retry-After") != "") != c.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want one: %v", rec.Header().Get("Retry-After"), c.wantRetryAfter)
+			}
+			if handled {
+				t.Errorf("the retry was handled again")
+			}
+			claims := db.Calls("ClaimIdempotencyKey")
+			if len(claims) != 1 || claims[0].Args[0] != "" || claims[0].Args[1] != key {
+				t.Errorf("ClaimIdempotencyKey calls = %v, want one for the anonymous key", claims)
+			}
+		})
+	}
+}
+
+func TestMiddlewareIdempotencyKeys(t *testing.T) {
+	userId := uuid.New()
+
+	cases := []struct {
+		name       string
+		key        string
+		userId     *uuid.UUID
+		wantStatus int
+		wantScope  string
+	}{
+		{name: "anonymous with a UUID", key: uuid.NewString(), wantStatus: http.StatusCreated, wantScope: ""},
+		{name: "anonymous with a guessable key", key: "signup", wantStatus: http.StatusBadRequest},
+		{name: "authenticated with any key", key: "signup", userId: &userId, wantStatus: http.StatusCreated, wantScope: userId.String()},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			db, conn := dbtest.New(t, map[string]dbtest.Query{
+				"ClaimIdempotencyKey":    func([]driver.Value) (dbtest.Result, error) { return dbtest.Affected(1), nil },
+				"SaveIdempotentResponse": func([]driver.Value) (dbtest.Result, error) { return dbtest.Affected(1), nil },
+			})
+			cfg := &ApiConfig{Db: database.New(conn)}
+
+			handler := cfg.MiddlewareIdempotency(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{}`))
+			req.Header.Set(IdempotencyKeyHeader, c.key)
+			if c.userId != nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserIDKey, *c.userId))
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, c.wantStatus)
+			}
+			claims := db.Calls("ClaimIdempotencyKey")
+			if c.wantStatus == http.StatusBadRequest {
+				if len(claims) != 0 {
+					t.Errorf("the key was claimed")
+				}
+				return
+			}
+			if len(claims) != 1 || claims[0].Args[0] != c.wantScope {
+				t.Errorf("ClaimIdempotencyKey calls = %v, want one in scope %q", claims, c.wantScope)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+const (
+	WebhookStatusReceived  = "received"
+	WebhookStatusProcessed = "processed"
+	WebhookStatusRejected  = "rejected"
+	WebhookStatusFailed    = "failed"
+)
+
+var redactedHeaders = []string{"Authorization", "Cookie"}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	err    error
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// recorderOf returns w when it's already a recorder, so the status and
+// error of the response reach the middleware that logs them.
+func recorderOf(w http.ResponseWriter) *responseRecorder {
+	if recorder, ok := w.(*responseRecorder); ok {
+		return recorder
+	}
+	return newResponseRecorder(w)
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) RecordError(err error) {
+	r.err = err
+}
+
+// discardWriter is used when replaying events, nobody is waiting for the
+// response so only the recorder's status and error matter.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func webhookStatus(code int) string {
+	switch {
+	case code >= http.StatusInternalServerError:
+		return WebhookStatusFailed
+	case code >= http.StatusBadRequest:
+		return WebhookStatusRejected
+	default:
+		return WebhookStatusProcessed
+	}
+}
+
+func (cfg *ApiConfig) saveWebhookResult(ctx context.Context, event database.WebhookEvent, recorder *responseRecorder) error {
+	errMsg := sql.NullString{}
+	if recorder.err != nil {
+		errMsg = sql.NullString{String: recorder.err.Error(), Valid: true}
+	} else if recorder.status >= http.StatusBadRequest {
+		errMsg = sql.NullString{String: http.StatusText(recorder.status), Valid: true}
+	}
+
+	return cfg.Db.RecordWebhookEventResult(ctx, database.RecordWebhookEventResultParams{
+		Status:         webhookStatus(recorder.status),
+		ResponseStatus: sql.NullInt32{Int32: int32(recorder.status), Valid: true},
+		Error:          errMsg,
+		ID:             event.ID,
+	})
+}
+
+// MiddlewareWebhookLog persists every inbound webhook request before it is
+// handled, and its outcome afterwards, so failed payloads can be replayed. It
+// goes after the middleware authenticating the source, so only its requests
+// are stored.
+func (cfg *ApiConfig) MiddlewareWebhookLog(source string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		body, ok := readWebhookBody(resp, req)
+		if !ok {
+			return
+		}
+
+		headers := req.Header.Clone()
+		for _, h := range redactedHeaders {
+			headers.Del(h)
+		}
+
+		encodedHeaders, err := json.Marshal(headers)
+		if err != nil {
+			response.RespondWithInternalServerError(resp, req, err)
+			return
+		}
+
+		event, err := cfg.Db.CreateWebhookEvent(req.Context(), database.CreateWebhookEventParams{
+			Source:  source,
+			Headers: encodedHeaders,
+			Body:    body,
+		})
+		if err != nil {
+			response.RespondWithInternalServerError(resp, req, err)
+			return
+		}
+
+		recorder := newResponseRecorder(resp)
+		next.ServeHTTP(recorder, req)
+
+		err = cfg.saveWebhookResult(context.WithoutCancel(req.Context()), event, recorder)
+		if err != nil {
+			log.Printf("Error recording webhook event %s: %s", event.ID, err)
+		}
+	})
+}
+
+// readWebhookBody reads the body of a webhook and puts it back for the next
+// handler. It's limited like the bodies of the handlers, since it's read
+// before the request is authenticated.
+func readWebhookBody(resp http.ResponseWriter, req *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(resp, req.Body, parser.MaxBodySize))
+	tooLarge := &http.MaxBytesError{}
+	if errors.As(err, &tooLarge) {
+		response.RespondWithErr(resp, req, response.Errorf(response.CodePayloadTooLarge, "the body can't be larger than %d bytes", tooLarge.Limit))
+		return nil, false
+	}
+	if err != nil {
+		response.RespondWithError(resp, req, http.StatusBadRequest, "the body couldn't be read")
+		return nil, false
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, true
+}
+
+// ReplayWebhookEvent runs a stored event through next as if it was just
+// received and records the new outcome. Authentication already happened when
+// the event was first received, so next should not check it again.
+func (cfg *ApiConfig) ReplayWebhookEvent(ctx context.Context, event database.WebhookEvent, next http.HandlerFunc) error {
+	headers := http.Header{}
+	err := json.Unmarshal(event.Headers, &headers)
+	if err != nil {
+		return fmt.Errorf("error decoding stored headers: %s", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader(event.Body))
+	if err != nil {
+		return err
+	}
+	req.Header = headers
+
+	recorder := newResponseRecorder(&discardWriter{header: http.Header{}})
+	next.ServeHTTP(recorder, req)
+
+	return cfg.saveWebhookResult(ctx, event, recorder)
+}
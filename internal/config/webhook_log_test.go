@@ -0,0 +1,128 @@
+package config
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/database/dbtest"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+func newWebhookLogDB(t *testing.T, eventID uuid.UUID) (*dbtest.DB, *ApiConfig) {
+	db, conn := dbtest.New(t, map[string]dbtest.Query{
+		"CreateWebhookEvent": func(args []driver.Value) (dbtest.Result, error) {
+			now := time.Now()
+			return dbtest.Row(eventID.String(), now, now, args[0], args[1], args[2], WebhookStatusReceived, nil, nil, int64(0)), nil
+		},
+		"ClaimPolkaEvent":          func([]driver.Value) (dbtest.Result, error) { return dbtest.Affected(1), nil },
+		"ReleasePolkaEvent":        func([]driver.Value) (dbtest.Result, error) { return dbtest.Affected(1), nil },
+		"RecordWebhookEventResult": func([]driver.Value) (dbtest.Result, error) { return dbtest.Affected(1), nil },
+	})
+	return db, &ApiConfig{Db: database.New(conn), PolkaKey: "polka-key"}
+}
+
+func TestMiddlewareWebhookLogRecordsTheCauseOfFailures(t *testing.T) {
+	cases := []struct {
+		name    string
+		eventID string
+	}{
+		{name: "without an event ID"},
+		{name: "with an event ID", eventID: "evt_1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			eventID := uuid.New()
+			db, cfg := newWebhookLogDB(t, eventID)
+
+			failing := func(w http.ResponseWriter, req *http.Request) {
+				response.RespondWithInternalServerError(w, req, errors.New("subscriptions are locked"))
+			}
+			handler := cfg.MiddlewarePolka(cfg.MiddlewareWebhookLog("polka", cfg.MiddlewarePolkaDedupe(failing)))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/polka/webhooks", strings.NewReader(`{"event":"user.upgraded"}`))
+			req.Header.Set("Authorization", "ApiKey polka-key")
+			if c.eventID != "" {
+				req.Header.Set(auth.PolkaEventIDHeader, c.eventID)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+			}
+
+			results := db.Calls("RecordWebhookEventResult")
+			if len(results) != 1 {
+				t.Fatalf("RecordWebhookEventResult ran %d times, want 1", len(results))
+			}
+			args := results[0].Args
+			if args[0] != WebhookStatusFailed || args[2] != "subscriptions are locked" || args[3] != eventID.String() {
+				t.Errorf("RecordWebhookEventResult(%v), want the failure of event %s and its cause", args, eventID)
+			}
+			if c.eventID != "" && len(db.Calls("ReleasePolkaEvent")) != 1 {
+				t.Errorf("the failed event ID wasn't released for a retry")
+			}
+		})
+	}
+}
+
+func TestMiddlewareWebhookLogLimitsTheBody(t *testing.T) {
+	db, cfg := newWebhookLogDB(t, uuid.New())
+	handler := cfg.MiddlewareWebhookLog("polka", func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("the handler ran for a body that's too large")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/polka/webhooks", strings.NewReader(strings.Repeat("a", parser.MaxBodySize+1)))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	if len(db.Calls("CreateWebhookEvent")) != 0 {
+		t.Errorf("the body was stored")
+	}
+}
+
+func TestMiddlewareWebhookLogOnlyStoresAuthenticatedRequests(t *testing.T) {
+	db, cfg := newWebhookLogDB(t, uuid.New())
+	handler := cfg.MiddlewarePolka(cfg.MiddlewareWebhookLog("polka", func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("the handler ran for an unauthenticated request")
+	}))
+
+	cases := []struct {
+		name          string
+		authorization string
+		body          string
+		wantStatus    int
+	}{
+		{name: "wrong key", authorization: "ApiKey not-the-key", body: `{"event":"user.upgraded"}`, wantStatus: http.StatusUnauthorized},
+		{name: "too large", authorization: "ApiKey not-the-key", body: strings.Repeat("a", parser.MaxBodySize+1), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/polka/webhooks", strings.NewReader(c.body))
+			req.Header.Set("Authorization", c.authorization)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, c.wantStatus)
+			}
+			if len(db.Calls("CreateWebhookEvent")) != 0 {
+				t.Errorf("the request was stored")
+			}
+		})
+	}
+}
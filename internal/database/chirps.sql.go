@@ -7,34 +7,74 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countVisibleChirpsByAuthor = `-- name: CountVisibleChirpsByAuthor :one
+SELECT COUNT(*)
+  FROM CHIRPS
+ WHERE USER_ID = $1
+   AND CHIRP_VISIBLE_TO(CHIRPS.USER_ID, CHIRPS.VISIBILITY, CHIRPS.MENTIONS, $2)
+`
+
+type CountVisibleChirpsByAuthorParams struct {
+	AuthorID uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) CountVisibleChirpsByAuthor(ctx context.Context, arg CountVisibleChirpsByAuthorParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countVisibleChirpsByAuthor, arg.AuthorID, arg.ViewerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
 INSERT INTO CHIRPS(
   ID,
   CREATED_AT,
   UPDATED_AT,
   BODY,
-  USER_ID
+  USER_ID,
+  VISIBILITY,
+  REPLY_POLICY,
+  MENTIONS,
+  REPLY_TO_ID
 ) VALUES (
   GEN_RANDOM_UUID(),
   NOW(),
   NOW(),
   $1,
-  $2
+  $2,
+  $3,
+  $4,
+  $5,
+  $6
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, visibility, reply_policy, mentions, reply_to_id
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body        string
+	UserID      uuid.UUID
+	Visibility  string
+	ReplyPolicy string
+	Mentions    []uuid.UUID
+	ReplyToID   uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.Visibility,
+		arg.ReplyPolicy,
+		pq.Array(arg.Mentions),
+		arg.ReplyToID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -42,6 +82,10 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Visibility,
+		&i.ReplyPolicy,
+		pq.Array(&i.Mentions),
+		&i.ReplyToID,
 	)
 	return i, err
 }
@@ -61,7 +105,11 @@ SELECT ID,
        CREATED_AT,
        UPDATED_AT,
        BODY,
-       USER_ID
+       USER_ID,
+       VISIBILITY,
+       REPLY_POLICY,
+       MENTIONS,
+       REPLY_TO_ID
   FROM CHIRPS
  WHERE ID = $1
 `
@@ -75,6 +123,47 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Visibility,
+		&i.ReplyPolicy,
+		pq.Array(&i.Mentions),
+		&i.ReplyToID,
+	)
+	return i, err
+}
+
+const getVisibleChirpByID = `-- name: GetVisibleChirpByID :one
+SELECT ID,
+       CREATED_AT,
+       UPDATED_AT,
+       BODY,
+       USER_ID,
+       VISIBILITY,
+       REPLY_POLICY,
+       MENTIONS,
+       REPLY_TO_ID
+  FROM CHIRPS
+ WHERE ID = $1
+   AND CHIRP_VISIBLE_TO(CHIRPS.USER_ID, CHIRPS.VISIBILITY, CHIRPS.MENTIONS, $2)
+`
+
+type GetVisibleChirpByIDParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetVisibleChirpByID(ctx context.Context, arg GetVisibleChirpByIDParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getVisibleChirpByID, arg.ID, arg.ViewerID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Visibility,
+		&i.ReplyPolicy,
+		pq.Array(&i.Mentions),
+		&i.ReplyToID,
 	)
 	return i, err
 }
@@ -84,7 +173,11 @@ SELECT ID,
        CREATED_AT,
        UPDATED_AT,
        BODY,
-       USER_ID
+       USER_ID,
+       VISIBILITY,
+       REPLY_POLICY,
+       MENTIONS,
+       REPLY_TO_ID
   FROM CHIRPS
  WHERE USER_ID = COALESCE(NULLIF($1, '00000000-0000-0000-0000-000000000000'::UUID), USER_ID)
  ORDER BY CREATED_AT ASC
@@ -105,6 +198,230 @@ func (q *Queries) ListAllChirps(ctx context.Context, dollar_1 interface{}) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.Visibility,
+			&i.ReplyPolicy,
+			pq.Array(&i.Mentions),
+			&i.ReplyToID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVisibleChirps = `-- name: ListVisibleChirps :many
+SELECT ID,
+       CREATED_AT,
+       UPDATED_AT,
+       BODY,
+       USER_ID,
+       VISIBILITY,
+       REPLY_POLICY,
+       MENTIONS,
+       REPLY_TO_ID
+  FROM CHIRPS
+ WHERE USER_ID = COALESCE(NULLIF($1::UUID, '00000000-0000-0000-0000-000000000000'::UUID), USER_ID)
+   AND CHIRP_VISIBLE_TO(CHIRPS.USER_ID, CHIRPS.VISIBILITY, CHIRPS.MENTIONS, $2)
+   AND (
+         CHIRPS.USER_ID = $1::UUID
+         OR NOT EXISTS (
+              SELECT 1
+                FROM USER_MUTES
+               WHERE USER_MUTES.MUTER_ID = $2
+                 AND USER_MUTES.MUTED_ID = CHIRPS.USER_ID
+            )
+       )
+   AND (CHIRPS.VISIBILITY <> 'unlisted' OR CHIRPS.USER_ID = $1::UUID)
+   AND (
+         $3::TEXT = ''
+         OR CHIRPS.BODY ~* ('(^|[^[:alnum:]_])#' || $3::TEXT || '($|[^[:alnum:]_])')
+       )
+ ORDER BY CREATED_AT ASC
+`
+
+type ListVisibleChirpsParams struct {
+	AuthorID uuid.UUID
+	ViewerID uuid.UUID
+	Hashtag  string
+}
+
+func (q *Queries) ListVisibleChirps(ctx context.Context, arg ListVisibleChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listVisibleChirps, arg.AuthorID, arg.ViewerID, arg.Hashtag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Visibility,
+			&i.ReplyPolicy,
+			pq.Array(&i.Mentions),
+			&i.ReplyToID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVisibleChirpsByIDs = `-- name: ListVisibleChirpsByIDs :many
+SELECT ID,
+       CREATED_AT,
+       UPDATED_AT,
+       BODY,
+       USER_ID,
+       VISIBILITY,
+       REPLY_POLICY,
+       MENTIONS,
+       REPLY_TO_ID
+  FROM CHIRPS
+ WHERE ID = ANY($1::UUID[])
+   AND CHIRP_VISIBLE_TO(CHIRPS.USER_ID, CHIRPS.VISIBILITY, CHIRPS.MENTIONS, $2)
+`
+
+type ListVisibleChirpsByIDsParams struct {
+	Ids      []uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) ListVisibleChirpsByIDs(ctx context.Context, arg ListVisibleChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listVisibleChirpsByIDs, pq.Array(arg.Ids), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Visibility,
+			&i.ReplyPolicy,
+			pq.Array(&i.Mentions),
+			&i.ReplyToID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVisibleChirpsPage = `-- name: ListVisibleChirpsPage :many
+SELECT ID,
+       CREATED_AT,
+       UPDATED_AT,
+       BODY,
+       USER_ID,
+       VISIBILITY,
+       REPLY_POLICY,
+       MENTIONS,
+       REPLY_TO_ID
+  FROM CHIRPS
+ WHERE USER_ID = COALESCE(NULLIF($1::UUID, '00000000-0000-0000-0000-000000000000'::UUID), USER_ID)
+   AND CHIRP_VISIBLE_TO(CHIRPS.USER_ID, CHIRPS.VISIBILITY, CHIRPS.MENTIONS, $2)
+   AND (
+         CHIRPS.USER_ID = $1::UUID
+         OR NOT EXISTS (
+              SELECT 1
+                FROM USER_MUTES
+               WHERE USER_MUTES.MUTER_ID = $2
+                 AND USER_MUTES.MUTED_ID = CHIRPS.USER_ID
+            )
+       )
+   AND (
+         CHIRPS.VISIBILITY <> 'unlisted'
+         OR CHIRPS.USER_ID = $1::UUID
+         OR ($3::BOOLEAN AND CHIRPS.USER_ID = $2)
+       )
+   AND (
+         NOT $3::BOOLEAN
+         OR CHIRPS.USER_ID = $2
+         OR EXISTS (
+              SELECT 1
+                FROM FOLLOWS
+               WHERE FOLLOWS.FOLLOWER_ID = $2
+                 AND FOLLOWS.FOLLOWED_ID = CHIRPS.USER_ID
+                 AND FOLLOWS.STATUS = 'approved'
+            )
+       )
+   AND (
+         $4::TEXT = ''
+         OR CHIRPS.BODY ~* ('(^|[^[:alnum:]_])#' || $4::TEXT || '($|[^[:alnum:]_])')
+       )
+   AND (CHIRPS.CREATED_AT, CHIRPS.ID) < ($5::TIMESTAMP, $6::UUID)
+ ORDER BY CHIRPS.CREATED_AT DESC, CHIRPS.ID DESC
+ LIMIT $7
+`
+
+type ListVisibleChirpsPageParams struct {
+	AuthorID   uuid.UUID
+	ViewerID   uuid.UUID
+	Timeline   bool
+	Hashtag    string
+	BeforeTime time.Time
+	BeforeID   uuid.UUID
+	RowLimit   int32
+}
+
+func (q *Queries) ListVisibleChirpsPage(ctx context.Context, arg ListVisibleChirpsPageParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listVisibleChirpsPage,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.Timeline,
+		arg.Hashtag,
+		arg.BeforeTime,
+		arg.BeforeID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Visibility,
+			&i.ReplyPolicy,
+			pq.Array(&i.Mentions),
+			&i.ReplyToID,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,164 @@
+// Package dbtest fakes the database for tests of code that runs the sqlc
+// queries of package database, without a Postgres to run them against.
+// Queries are answered by name, the name sqlc gives them.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+// Result is the answer to a query, Rows for the queries that return rows
+// and RowsAffected for the others. Values are in the order of the columns
+// the query selects.
+type Result struct {
+	Rows         [][]driver.Value
+	RowsAffected int64
+}
+
+// Row is the Result of a query returning a single row.
+func Row(values ...driver.Value) Result {
+	return Result{Rows: [][]driver.Value{values}}
+}
+
+// NoRows is the Result of a query that didn't find anything.
+var NoRows = Result{}
+
+// Affected is the Result of a query that changed n rows.
+func Affected(n int64) Result {
+	return Result{RowsAffected: n}
+}
+
+// Query answers a query with its arguments, after they were converted to
+// driver values: UUIDs are strings, for example.
+type Query func(args []driver.Value) (Result, error)
+
+// Call is a query that was run.
+type Call struct {
+	Name string
+	Args []driver.Value
+}
+
+// DB answers queries with the Query of their name, running a query it
+// doesn't know fails the test.
+type DB struct {
+	t       testing.TB
+	mu      sync.Mutex
+	queries map[string]Query
+	calls   []Call
+}
+
+var queryName = regexp.MustCompile(`^-- name: (\w+)`)
+
+// New returns the fake and a *sql.DB running its queries against it, the
+// *sql.DB is closed with the test.
+func New(t testing.TB, queries map[string]Query) (*DB, *sql.DB) {
+	t.Helper()
+
+	db := &DB{t: t, queries: queries}
+	conn := sql.OpenDB(connector{db})
+	t.Cleanup(func() { conn.Close() })
+	return db, conn
+}
+
+// Calls returns the queries run named name, in order.
+func (db *DB) Calls(name string) []Call {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	calls := []Call{}
+	for _, call := range db.calls {
+		if call.Name == name {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func (db *DB) run(query string, args []driver.NamedValue) (Result, error) {
+	match := queryName.FindStringSubmatch(query)
+	if match == nil {
+		db.t.Errorf("dbtest: query without a name: %s", query)
+		return Result{}, fmt.Errorf("dbtest: query without a name")
+	}
+
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	db.mu.Lock()
+	db.calls = append(db.calls, Call{Name: match[1], Args: values})
+	answer, ok := db.queries[match[1]]
+	db.mu.Unlock()
+
+	if !ok {
+		db.t.Errorf("dbtest: unexpected query %s", match[1])
+		return Result{}, fmt.Errorf("dbtest: unexpected query %s", match[1])
+	}
+	return answer(values)
+}
+
+type connector struct{ db *DB }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return conn(c), nil }
+func (c connector) Driver() driver.Driver                        { return nil }
+
+type conn struct{ db *DB }
+
+func (c conn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("dbtest: prepared statements aren't supported")
+}
+func (c conn) Close() error              { return nil }
+func (c conn) Begin() (driver.Tx, error) { return tx{}, nil }
+
+func (c conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(result.RowsAffected), nil
+}
+
+func (c conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{result: result}, nil
+}
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type rows struct {
+	result Result
+	next   int
+}
+
+func (r *rows) Columns() []string {
+	if len(r.result.Rows) == 0 {
+		return nil
+	}
+	// only their number matters to Scan
+	return make([]string, len(r.result.Rows[0]))
+}
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.Rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.Rows[r.next])
+	r.next++
+	return nil
+}
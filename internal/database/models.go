@@ -6,17 +6,160 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AccountDeletion struct {
+	UserID      uuid.UUID
+	RequestedAt time.Time
+	DeleteAfter time.Time
+}
+
+type ActivitypubDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	KeyID         string
+	Inbox         string
+	Activity      json.RawMessage
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LockedUntil   sql.NullTime
+	DeliveredAt   sql.NullTime
+}
+
+type ActorKey struct {
+	UserID        uuid.UUID
+	CreatedAt     time.Time
+	PublicKeyPem  string
+	PrivateKeyPem string
+}
+
 type Chirp struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Body        string
+	UserID      uuid.UUID
+	Visibility  string
+	ReplyPolicy string
+	Mentions    []uuid.UUID
+	ReplyToID   uuid.NullUUID
+}
+
+type Conversation struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
-	UserID    uuid.UUID
+	IsGroup   bool
+	DirectKey sql.NullString
+}
+
+type ConversationMember struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	JoinedAt       time.Time
+	LastReadAt     sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FollowedID uuid.UUID
+	Status     string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type IdempotencyKey struct {
+	Scope           string
+	Key             string
+	Fingerprint     string
+	LockID          uuid.UUID
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+	ResponseStatus  sql.NullInt32
+	ResponseHeaders json.RawMessage
+	ResponseBody    []byte
+}
+
+type MagicLinkRateLimit struct {
+	Email       string
+	WindowStart time.Time
+	Requests    int32
+}
+
+type MagicLinkToken struct {
+	TokenHash  string
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	DeviceHash string
+	ExpiresAt  time.Time
+	UsedAt     sql.NullTime
+}
+
+type Message struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	KeyID          string
+	Body           []byte
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string
+	CreatedAt     time.Time
+	ClientID      string
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scopes        []string
+	CodeChallenge string
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+}
+
+type OauthClient struct {
+	ID           string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	OwnerID      uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+	Scopes       []string
+}
+
+type OidcLoginState struct {
+	StateHash    string
+	CreatedAt    time.Time
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+type PersonalAccessToken struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Name        string
+	TokenHash   string
+	TokenPrefix string
+	Scopes      []string
+	ExpiresAt   sql.NullTime
+	LastUsedAt  sql.NullTime
+	RevokedAt   sql.NullTime
+}
+
+type PolkaEvent struct {
+	EventID   string
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -26,6 +169,51 @@ type RefreshToken struct {
 	UserID    uuid.UUID
 	ExpiresAt time.Time
 	RevokedAt sql.NullTime
+	ClientID  sql.NullString
+	Scopes    []string
+}
+
+type RemoteActor struct {
+	ID           string
+	FetchedAt    time.Time
+	Inbox        string
+	SharedInbox  string
+	KeyID        string
+	PublicKeyPem string
+}
+
+type RemoteFollower struct {
+	UserID    uuid.UUID
+	ActorID   string
+	FollowID  string
+	CreatedAt time.Time
+}
+
+type RemoteReaction struct {
+	ActivityID string
+	CreatedAt  time.Time
+	Type       string
+	ActorID    string
+	ChirpID    uuid.UUID
+}
+
+type Subscription struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	Plan             string
+	Status           string
+	CurrentPeriodEnd time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+type SubscriptionEvent struct {
+	ID               uuid.UUID
+	SubscriptionID   uuid.UUID
+	Event            string
+	Status           string
+	CurrentPeriodEnd time.Time
+	CreatedAt        time.Time
 }
 
 type User struct {
@@ -34,5 +222,87 @@ type User struct {
 	UpdatedAt      sql.NullTime
 	Email          string
 	HashedPassword string
-	IsChirpyRed    bool
+	IsPrivate      bool
+}
+
+type UserBlock struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type UserExport struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Status      string
+	Archive     []byte
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+type UserIdentity struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Provider  string
+	Subject   string
+	Email     string
+}
+
+type UserMute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	EndpointID    uuid.UUID
+	Event         string
+	Payload       json.RawMessage
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LockedUntil   sql.NullTime
+	DeliveredAt   sql.NullTime
+}
+
+type WebhookDeliveryAttempt struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	DeliveryID     uuid.UUID
+	EndpointID     uuid.UUID
+	ResponseStatus sql.NullInt32
+	Error          sql.NullString
+	DurationMs     int32
+}
+
+type WebhookEndpoint struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	UserID              uuid.UUID
+	Url                 string
+	Secret              string
+	Events              []string
+	Enabled             bool
+	ConsecutiveFailures int32
+	DisabledAt          sql.NullTime
+}
+
+type WebhookEvent struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Source         string
+	Headers        json.RawMessage
+	Body           []byte
+	Status         string
+	ResponseStatus sql.NullInt32
+	Error          sql.NullString
+	Attempts       int32
 }
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
@@ -20,16 +21,20 @@ INSERT INTO REFRESH_TOKEN (
   UPDATED_AT,
   USER_ID,
   EXPIRES_AT,
-  REVOKED_AT
+  REVOKED_AT,
+  CLIENT_ID,
+  SCOPES
 ) VALUES (
   $1,
   NOW(),
   NOW(),
   $2,
   $3,
-  $4
+  $4,
+  $5,
+  $6
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes
 `
 
 type CreateRefreshTokenParams struct {
@@ -37,6 +42,8 @@ type CreateRefreshTokenParams struct {
 	UserID    uuid.UUID
 	ExpiresAt time.Time
 	RevokedAt sql.NullTime
+	ClientID  sql.NullString
+	Scopes    []string
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
@@ -45,6 +52,8 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		arg.UserID,
 		arg.ExpiresAt,
 		arg.RevokedAt,
+		arg.ClientID,
+		pq.Array(arg.Scopes),
 	)
 	var i RefreshToken
 	err := row.Scan(
@@ -54,23 +63,59 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+	)
+	return i, err
+}
+
+const getRefreshToken = `-- name: GetRefreshToken :one
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes
+  FROM REFRESH_TOKEN
+ WHERE TOKEN = $1
+`
+
+func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshToken, token)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
 	)
 	return i, err
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT USER_ID
+SELECT USER_ID,
+       CLIENT_ID,
+       SCOPES
   FROM REFRESH_TOKEN
  WHERE TOKEN = $1
    AND EXPIRES_AT > NOW()
    AND REVOKED_AT IS NULL
 `
 
-func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (uuid.UUID, error) {
+type GetUserFromRefreshTokenRow struct {
+	UserID   uuid.UUID
+	ClientID sql.NullString
+	Scopes   []string
+}
+
+func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (GetUserFromRefreshTokenRow, error) {
 	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, token)
-	var user_id uuid.UUID
-	err := row.Scan(&user_id)
-	return user_id, err
+	var i GetUserFromRefreshTokenRow
+	err := row.Scan(
+		&i.UserID,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+	)
+	return i, err
 }
 
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
@@ -10,8 +10,22 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countExistingUsers = `-- name: CountExistingUsers :one
+SELECT COUNT(*)
+  FROM USERS
+ WHERE ID = ANY($1::UUID[])
+`
+
+func (q *Queries) CountExistingUsers(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countExistingUsers, pq.Array(ids))
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email, hashed_password)
 VALUES (
@@ -21,7 +35,12 @@ VALUES (
   $1,
   $2
 )
-RETURNING ID, CREATED_AT, UPDATED_AT, EMAIL, IS_CHIRPY_RED
+RETURNING ID,
+          CREATED_AT,
+          UPDATED_AT,
+          EMAIL,
+          IS_CHIRPY_RED(ID) AS IS_CHIRPY_RED,
+          IS_PRIVATE
 `
 
 type CreateUserParams struct {
@@ -35,6 +54,7 @@ type CreateUserRow struct {
 	UpdatedAt   sql.NullTime
 	Email       string
 	IsChirpyRed bool
+	IsPrivate   bool
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
@@ -46,6 +66,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.IsPrivate,
 	)
 	return i, err
 }
@@ -65,7 +86,7 @@ SELECT ID,
        UPDATED_AT,
        EMAIL,
        HASHED_PASSWORD,
-       IS_CHIRPY_RED
+       IS_PRIVATE
   FROM USERS
  WHERE EMAIL = $1
 `
@@ -79,7 +100,114 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.IsPrivate,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT ID,
+       CREATED_AT,
+       UPDATED_AT,
+       EMAIL,
+       HASHED_PASSWORD,
+       IS_PRIVATE
+  FROM USERS
+ WHERE ID = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPrivate,
+	)
+	return i, err
+}
+
+const listUsersByIDs = `-- name: ListUsersByIDs :many
+SELECT ID,
+       CREATED_AT,
+       UPDATED_AT,
+       EMAIL,
+       HASHED_PASSWORD,
+       IS_PRIVATE
+  FROM USERS
+ WHERE ID = ANY($1::UUID[])
+`
+
+func (q *Queries) ListUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsPrivate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setUserPrivacy = `-- name: SetUserPrivacy :one
+UPDATE USERS
+   SET IS_PRIVATE = $1,
+       UPDATED_AT = NOW()
+ WHERE ID = $2
+ RETURNING ID,
+           CREATED_AT,
+           UPDATED_AT,
+           EMAIL,
+           IS_CHIRPY_RED(ID) AS IS_CHIRPY_RED,
+           IS_PRIVATE
+`
+
+type SetUserPrivacyParams struct {
+	IsPrivate bool
+	ID        uuid.UUID
+}
+
+type SetUserPrivacyRow struct {
+	ID          uuid.UUID
+	CreatedAt   sql.NullTime
+	UpdatedAt   sql.NullTime
+	Email       string
+	IsChirpyRed bool
+	IsPrivate   bool
+}
+
+func (q *Queries) SetUserPrivacy(ctx context.Context, arg SetUserPrivacyParams) (SetUserPrivacyRow, error) {
+	row := q.db.QueryRowContext(ctx, setUserPrivacy, arg.IsPrivate, arg.ID)
+	var i SetUserPrivacyRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
 		&i.IsChirpyRed,
+		&i.IsPrivate,
 	)
 	return i, err
 }
@@ -89,7 +217,12 @@ UPDATE USERS
    SET EMAIL = $1,
        HASHED_PASSWORD = $2
  WHERE ID = $3
- RETURNING ID, CREATED_AT, UPDATED_AT, EMAIL, IS_CHIRPY_RED
+ RETURNING ID,
+           CREATED_AT,
+           UPDATED_AT,
+           EMAIL,
+           IS_CHIRPY_RED(ID) AS IS_CHIRPY_RED,
+           IS_PRIVATE
 `
 
 type UpdateUserEmailAndPasswordParams struct {
@@ -104,6 +237,7 @@ type UpdateUserEmailAndPasswordRow struct {
 	UpdatedAt   sql.NullTime
 	Email       string
 	IsChirpyRed bool
+	IsPrivate   bool
 }
 
 func (q *Queries) UpdateUserEmailAndPassword(ctx context.Context, arg UpdateUserEmailAndPasswordParams) (UpdateUserEmailAndPasswordRow, error) {
@@ -115,17 +249,7 @@ func (q *Queries) UpdateUserEmailAndPassword(ctx context.Context, arg UpdateUser
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.IsPrivate,
 	)
 	return i, err
 }
-
-const upgradeUser = `-- name: UpgradeUser :exec
-UPDATE USERS
-   SET IS_CHIRPY_RED = TRUE
- WHERE ID = $1
-`
-
-func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, upgradeUser, id)
-	return err
-}
@@ -0,0 +1,117 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const keySize = 32
+
+// Keyring encrypts data at rest with AES-256-GCM. New data is always encrypted
+// with the current key, older keys are kept so data encrypted before a
+// rotation can still be read and re-encrypted.
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+func NewKeyring(current string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("current key %q is not in the keyring", current)
+	}
+
+	keyring := &Keyring{
+		current: current,
+		keys:    make(map[string]cipher.AEAD, len(keys)),
+	}
+	for id, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q must be %d bytes", id, keySize)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		keyring.keys[id], err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return keyring, nil
+}
+
+// KeyringFromEnv reads the keys from ENV_PREFIX_KEYS, a comma separated list
+// of id:base64-key pairs, and the id of the current key from ENV_PREFIX_KEY_ID.
+// It returns nil when no keys are configured.
+func KeyringFromEnv(prefix string) (*Keyring, error) {
+	encoded := os.Getenv(prefix + "_KEYS")
+	if encoded == "" {
+		return nil, nil
+	}
+
+	keys := map[string][]byte{}
+	for _, pair := range strings.Split(encoded, ",") {
+		id, value, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("%s_KEYS must be a list of id:base64-key pairs", prefix)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("key %q in %s_KEYS is not valid base64", id, prefix)
+		}
+		keys[id] = key
+	}
+
+	current := os.Getenv(prefix + "_KEY_ID")
+	if current == "" {
+		return nil, fmt.Errorf("%s_KEY_ID is required when %s_KEYS is set", prefix, prefix)
+	}
+
+	return NewKeyring(current, keys)
+}
+
+func (k *Keyring) CurrentKeyID() string {
+	return k.current
+}
+
+// Encrypt seals plaintext with the current key. additionalData isn't stored
+// but has to be given again to decrypt, it ties the ciphertext to the row it
+// belongs to.
+func (k *Keyring) Encrypt(plaintext, additionalData []byte) (keyID string, ciphertext []byte, err error) {
+	aead := k.keys[k.current]
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return k.current, aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (k *Keyring) Decrypt(keyID string, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", keyID)
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt with key %q", keyID)
+	}
+
+	return plaintext, nil
+}
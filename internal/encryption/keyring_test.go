@@ -0,0 +1,127 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, keySize)
+}
+
+func TestKeyringRoundTrip(t *testing.T) {
+	keyring, err := NewKeyring("v1", map[string][]byte{"v1": testKey(1)})
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+
+	keyID, ciphertext, err := keyring.Encrypt([]byte("hello"), []byte("message-1"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if keyID != "v1" {
+		t.Errorf("Encrypt() key = %q, want v1", keyID)
+	}
+	if bytes.Contains(ciphertext, []byte("hello")) {
+		t.Errorf("ciphertext contains the plaintext")
+	}
+
+	plaintext, err := keyring.Decrypt(keyID, ciphertext, []byte("message-1"))
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if string(plaintext) != "hello" {
+		t.Errorf("Decrypt() = %q, want hello", plaintext)
+	}
+
+	_, secondCiphertext, _ := keyring.Encrypt([]byte("hello"), []byte("message-1"))
+	if bytes.Equal(ciphertext, secondCiphertext) {
+		t.Errorf("encrypting twice gave the same ciphertext")
+	}
+}
+
+func TestKeyringDecryptFailures(t *testing.T) {
+	keyring, _ := NewKeyring("v1", map[string][]byte{"v1": testKey(1), "v2": testKey(2)})
+	_, ciphertext, _ := keyring.Encrypt([]byte("hello"), []byte("message-1"))
+
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 1
+
+	cases := []struct {
+		name           string
+		keyID          string
+		ciphertext     []byte
+		additionalData string
+	}{
+		{"other additional data", "v1", ciphertext, "message-2"},
+		{"other key", "v2", ciphertext, "message-1"},
+		{"unknown key", "v3", ciphertext, "message-1"},
+		{"tampered ciphertext", "v1", tampered, "message-1"},
+		{"truncated ciphertext", "v1", ciphertext[:4], "message-1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := keyring.Decrypt(c.keyID, c.ciphertext, []byte(c.additionalData))
+			if err == nil {
+				t.Errorf("Decrypt() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old, _ := NewKeyring("v1", map[string][]byte{"v1": testKey(1)})
+	keyID, ciphertext, _ := old.Encrypt([]byte("hello"), nil)
+
+	rotated, err := NewKeyring("v2", map[string][]byte{"v1": testKey(1), "v2": testKey(2)})
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	if rotated.CurrentKeyID() != "v2" {
+		t.Errorf("CurrentKeyID() = %q, want v2", rotated.CurrentKeyID())
+	}
+
+	plaintext, err := rotated.Decrypt(keyID, ciphertext, nil)
+	if err != nil || string(plaintext) != "hello" {
+		t.Errorf("Decrypt() = %q, %v, want data from before the rotation", plaintext, err)
+	}
+}
+
+func TestKeyringFromEnv(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(testKey(1))
+	otherKey := base64.StdEncoding.EncodeToString(testKey(2))
+
+	cases := []struct {
+		name    string
+		keys    string
+		keyID   string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "not configured", wantNil: true},
+		{name: "single key", keys: "v1:" + key, keyID: "v1"},
+		{name: "several keys", keys: "v1:" + key + ", v2:" + otherKey, keyID: "v2"},
+		{name: "missing key id", keys: "v1:" + key, wantErr: true},
+		{name: "unknown key id", keys: "v1:" + key, keyID: "v2", wantErr: true},
+		{name: "invalid base64", keys: "v1:not-base64!", keyID: "v1", wantErr: true},
+		{name: "short key", keys: "v1:" + base64.StdEncoding.EncodeToString([]byte("short")), keyID: "v1", wantErr: true},
+		{name: "missing id", keys: key, keyID: "v1", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("TEST_ENCRYPTION_KEYS", c.keys)
+			t.Setenv("TEST_ENCRYPTION_KEY_ID", c.keyID)
+
+			keyring, err := KeyringFromEnv("TEST_ENCRYPTION")
+			if (err != nil) != c.wantErr {
+				t.Fatalf("KeyringFromEnv() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if !c.wantErr && (keyring == nil) != c.wantNil {
+				t.Errorf("KeyringFromEnv() = %v, wantNil %v", keyring, c.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StatusPending  = "pending"
+	StatusBuilding = "building"
+	StatusReady    = "ready"
+	StatusFailed   = "failed"
+)
+
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	IsPrivate   bool      `json:"is_private"`
+}
+
+type Chirp struct {
+	ID          uuid.UUID   `json:"id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	Body        string      `json:"body"`
+	Visibility  string      `json:"visibility"`
+	ReplyPolicy string      `json:"reply_policy"`
+	Mentions    []uuid.UUID `json:"mentions"`
+	ReplyToID   *uuid.UUID  `json:"reply_to_id"`
+}
+
+type Follow struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Data struct {
+	Profile Profile
+	Chirps  []Chirp
+	Follows []Follow
+}
+
+// BuildArchive writes every part of the export as its own JSON file in a ZIP
+// archive. Empty lists are written as [] so the files are always there.
+func BuildArchive(data Data, createdAt time.Time) ([]byte, error) {
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"profile.json", data.Profile},
+		{"chirps.json", nonNil(data.Chirps)},
+		{"follows.json", nonNil(data.Follows)},
+	}
+
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+
+	for _, file := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: createdAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		err = encoder.Encode(file.content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := archive.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
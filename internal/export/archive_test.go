@@ -0,0 +1,74 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBuildArchive(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	userID := uuid.New()
+
+	content, err := BuildArchive(Data{
+		Profile: Profile{ID: userID, CreatedAt: now, UpdatedAt: now, Email: "jane@example.com"},
+		Chirps:  []Chirp{{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Body: "Hello, world!"}},
+	}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("error opening %s: %v", file.Name, err)
+		}
+		files[file.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+
+	cases := []struct {
+		name      string
+		wantItems int
+	}{
+		{name: "chirps.json", wantItems: 1},
+		{name: "follows.json", wantItems: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			items := []json.RawMessage{}
+			err := json.Unmarshal(files[c.name], &items)
+			if err != nil {
+				t.Fatalf("expected a json array, got %q: %v", files[c.name], err)
+			}
+			if len(items) != c.wantItems {
+				t.Errorf("expected %d items, got %d", c.wantItems, len(items))
+			}
+		})
+	}
+
+	if len(files) != 3 {
+		t.Errorf("expected profile.json, chirps.json and follows.json, got %d files", len(files))
+	}
+
+	profile := Profile{}
+	err = json.Unmarshal(files["profile.json"], &profile)
+	if err != nil {
+		t.Fatalf("invalid profile.json: %v", err)
+	}
+	if profile.ID != userID || profile.Email != "jane@example.com" {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+}
@@ -0,0 +1,39 @@
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETag is a strong entity tag for the rendered feed.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified tells if the client's copy of the feed is still fresh, going by
+// If-None-Match first and If-Modified-Since when there is none, as RFC 9110
+// asks.
+func NotModified(req *http.Request, etag string, lastModified time.Time) bool {
+	ifNoneMatch := req.Header.Get("If-None-Match")
+	if ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	ifModifiedSince, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	// HTTP dates only have a precision of a second
+	return !lastModified.Truncate(time.Second).After(ifModifiedSince)
+}
@@ -0,0 +1,237 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"time"
+)
+
+const (
+	ContentTypeRSS  = "application/rss+xml; charset=utf-8"
+	ContentTypeAtom = "application/atom+xml; charset=utf-8"
+	ContentTypeJSON = "application/feed+json; charset=utf-8"
+)
+
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type Author struct {
+	Name string
+	URL  string
+}
+
+type Item struct {
+	// ID is the permanent URL of the item, which never changes.
+	ID        string
+	URL       string
+	Title     string
+	Content   string
+	Author    Author
+	Published time.Time
+	Updated   time.Time
+}
+
+// Feed is rendered the same way whatever the format, except for FeedURL,
+// which is the URL of the feed in the format being rendered.
+type Feed struct {
+	Title       string
+	Description string
+	HomeURL     string
+	FeedURL     string
+	Author      Author
+	Updated     time.Time
+	Items       []Item
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          rssLink   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// RSS renders the feed as RSS 2.0.
+func RSS(f Feed) ([]byte, error) {
+	items := make([]rssItem, len(f.Items))
+	for i, item := range f.Items {
+		items[i] = rssItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			Description: item.Content,
+			GUID:        rssGUID{Value: item.ID, IsPermaLink: item.ID == item.URL},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		}
+	}
+
+	return encodeXML(rss{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.HomeURL,
+			Description:   f.Description,
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+			Self:          rssLink{Href: f.FeedURL, Rel: "self", Type: "application/rss+xml"},
+			Items:         items,
+		},
+	})
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Link      atomLink    `xml:"link"`
+	Author    atomAuthor  `xml:"author"`
+	Content   atomContent `xml:"content"`
+}
+
+type atom struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+// Atom renders the feed as Atom 1.0.
+func Atom(f Feed) ([]byte, error) {
+	entries := make([]atomEntry, len(f.Items))
+	for i, item := range f.Items {
+		entries[i] = atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: item.URL, Rel: "alternate"},
+			Author:    atomAuthor{Name: item.Author.Name, URI: item.Author.URL},
+			Content:   atomContent{Type: "text", Value: item.Content},
+		}
+	}
+
+	return encodeXML(atom{
+		ID:       f.FeedURL,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.FeedURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.HomeURL, Rel: "alternate"},
+		},
+		Author:  atomAuthor{Name: f.Author.Name, URI: f.Author.URL},
+		Entries: entries,
+	})
+}
+
+func encodeXML(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(buf)
+	encoder.Indent("", "  ")
+	err := encoder.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type jsonItem struct {
+	ID            string       `json:"id"`
+	URL           string       `json:"url"`
+	Title         string       `json:"title"`
+	ContentText   string       `json:"content_text"`
+	DatePublished string       `json:"date_published"`
+	DateModified  string       `json:"date_modified"`
+	Authors       []jsonAuthor `json:"authors"`
+}
+
+type jsonFeed struct {
+	Version     string       `json:"version"`
+	Title       string       `json:"title"`
+	HomePageURL string       `json:"home_page_url"`
+	FeedURL     string       `json:"feed_url"`
+	Description string       `json:"description,omitempty"`
+	Authors     []jsonAuthor `json:"authors"`
+	Items       []jsonItem   `json:"items"`
+}
+
+// JSON renders the feed as JSON Feed 1.1.
+func JSON(f Feed) ([]byte, error) {
+	items := make([]jsonItem, len(f.Items))
+	for i, item := range f.Items {
+		items[i] = jsonItem{
+			ID:            item.ID,
+			URL:           item.URL,
+			Title:         item.Title,
+			ContentText:   item.Content,
+			DatePublished: item.Published.UTC().Format(time.RFC3339),
+			DateModified:  item.Updated.UTC().Format(time.RFC3339),
+			Authors:       []jsonAuthor{{Name: item.Author.Name, URL: item.Author.URL}},
+		}
+	}
+
+	return json.MarshalIndent(jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       f.Title,
+		HomePageURL: f.HomeURL,
+		FeedURL:     f.FeedURL,
+		Description: f.Description,
+		Authors:     []jsonAuthor{{Name: f.Author.Name, URL: f.Author.URL}},
+		Items:       items,
+	}, "", "  ")
+}
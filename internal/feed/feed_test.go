@@ -0,0 +1,243 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var published = time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC)
+
+func testFeed() Feed {
+	return Feed{
+		Title:       "Chirps by <someone> & friends",
+		Description: "The latest chirps",
+		HomeURL:     "https://chirpy.example/api/chirps?author_id=1",
+		FeedURL:     "https://chirpy.example/api/users/1/feed.rss",
+		Author:      Author{Name: "someone", URL: "https://chirpy.example/api/users/1"},
+		Updated:     published.Add(time.Hour),
+		Items: []Item{
+			{
+				ID:        "https://chirpy.example/api/chirps/2",
+				URL:       "https://chirpy.example/api/chirps/2",
+				Title:     "1 < 2 && \"quotes\" \x01",
+				Content:   "1 < 2 && \"quotes\" \x01",
+				Author:    Author{Name: "someone"},
+				Published: published,
+				Updated:   published.Add(time.Hour),
+			},
+		},
+	}
+}
+
+func TestRSS(t *testing.T) {
+	body, err := RSS(testFeed())
+	if err != nil {
+		t.Fatalf("RSS() error = %v", err)
+	}
+
+	parsed := struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title       string `xml:"title"`
+			Description string `xml:"description"`
+			// the decoder can't tell link and atom:link apart by tag
+			Links []struct {
+				XMLName xml.Name
+				Href    string `xml:"href,attr"`
+				Rel     string `xml:"rel,attr"`
+				Value   string `xml:",chardata"`
+			} `xml:"link"`
+			Items []struct {
+				Title   string `xml:"title"`
+				GUID    string `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}{}
+	err = xml.Unmarshal(body, &parsed)
+	if err != nil {
+		t.Fatalf("rendered RSS is not valid XML: %v\n%s", err, body)
+	}
+
+	if parsed.Version != "2.0" {
+		t.Errorf("version = %q, want 2.0", parsed.Version)
+	}
+	link, self := "", ""
+	for _, l := range parsed.Channel.Links {
+		if l.XMLName.Space == "http://www.w3.org/2005/Atom" && l.Rel == "self" {
+			self = l.Href
+		} else if l.XMLName.Space == "" {
+			link = l.Value
+		}
+	}
+	// title, link and description are required for the channel
+	if parsed.Channel.Title != testFeed().Title || link != testFeed().HomeURL || parsed.Channel.Description == "" {
+		t.Errorf("channel is missing required elements: %+v", parsed.Channel)
+	}
+	if self != testFeed().FeedURL {
+		t.Errorf("self link = %q, want %q", self, testFeed().FeedURL)
+	}
+	if len(parsed.Channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(parsed.Channel.Items))
+	}
+
+	item := parsed.Channel.Items[0]
+	if item.Title != "1 < 2 && \"quotes\" �" {
+		t.Errorf("item title = %q, escaping went wrong", item.Title)
+	}
+	if _, err := time.Parse(time.RFC1123Z, item.PubDate); err != nil {
+		t.Errorf("pubDate %q is not an RFC 822 date: %v", item.PubDate, err)
+	}
+	if item.GUID != "https://chirpy.example/api/chirps/2" {
+		t.Errorf("guid = %q", item.GUID)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	body, err := Atom(testFeed())
+	if err != nil {
+		t.Fatalf("Atom() error = %v", err)
+	}
+
+	parsed := struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Title   string   `xml:"title"`
+		Updated string   `xml:"updated"`
+		Author  struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Title   string `xml:"title"`
+			Updated string `xml:"updated"`
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}{}
+	err = xml.Unmarshal(body, &parsed)
+	if err != nil {
+		t.Fatalf("rendered Atom is not valid XML: %v\n%s", err, body)
+	}
+
+	// id, title and updated are required for the feed and every entry, and
+	// the feed needs an author since the entries could lack one
+	if parsed.ID == "" || parsed.Title == "" || parsed.Author.Name == "" {
+		t.Errorf("feed is missing required elements: %+v", parsed)
+	}
+	if _, err := time.Parse(time.RFC3339, parsed.Updated); err != nil {
+		t.Errorf("updated %q is not an RFC 3339 date: %v", parsed.Updated, err)
+	}
+
+	hasSelf := false
+	for _, link := range parsed.Links {
+		hasSelf = hasSelf || (link.Rel == "self" && link.Href == testFeed().FeedURL)
+	}
+	if !hasSelf {
+		t.Errorf("links = %+v, want a self link", parsed.Links)
+	}
+
+	if len(parsed.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(parsed.Entries))
+	}
+	entry := parsed.Entries[0]
+	if entry.ID == "" || entry.Updated == "" {
+		t.Errorf("entry is missing required elements: %+v", entry)
+	}
+	if entry.Content != "1 < 2 && \"quotes\" �" {
+		t.Errorf("entry content = %q, escaping went wrong", entry.Content)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	body, err := JSON(testFeed())
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+
+	parsed := struct {
+		Version string `json:"version"`
+		Title   string `json:"title"`
+		FeedURL string `json:"feed_url"`
+		Items   []struct {
+			ID            string `json:"id"`
+			ContentText   string `json:"content_text"`
+			DatePublished string `json:"date_published"`
+		} `json:"items"`
+	}{}
+	err = json.Unmarshal(body, &parsed)
+	if err != nil {
+		t.Fatalf("rendered JSON Feed is not valid JSON: %v", err)
+	}
+
+	if parsed.Version != jsonFeedVersion {
+		t.Errorf("version = %q, want %q", parsed.Version, jsonFeedVersion)
+	}
+	if parsed.Title != testFeed().Title || parsed.FeedURL != testFeed().FeedURL {
+		t.Errorf("feed = %+v", parsed)
+	}
+	if len(parsed.Items) != 1 || parsed.Items[0].ID == "" || parsed.Items[0].ContentText == "" {
+		t.Fatalf("items = %+v", parsed.Items)
+	}
+	if _, err := time.Parse(time.RFC3339, parsed.Items[0].DatePublished); err != nil {
+		t.Errorf("date_published %q is not an RFC 3339 date: %v", parsed.Items[0].DatePublished, err)
+	}
+}
+
+func TestEmptyFeed(t *testing.T) {
+	f := testFeed()
+	f.Items = nil
+
+	for name, render := range map[string]func(Feed) ([]byte, error){"rss": RSS, "atom": Atom, "json": JSON} {
+		body, err := render(f)
+		if err != nil {
+			t.Errorf("%s: error = %v", name, err)
+		}
+		if name == "json" && !strings.Contains(string(body), `"items": []`) {
+			t.Errorf("json: items must be an array, got %s", body)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	etag := ETag([]byte("feed"))
+	lastModified := published.Add(500 * time.Millisecond)
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "no validators", want: false},
+		{name: "matching etag", headers: map[string]string{"If-None-Match": etag}, want: true},
+		{name: "one of the etags", headers: map[string]string{"If-None-Match": `"other", ` + etag}, want: true},
+		{name: "weak etag", headers: map[string]string{"If-None-Match": "W/" + etag}, want: true},
+		{name: "any etag", headers: map[string]string{"If-None-Match": "*"}, want: true},
+		{name: "other etag", headers: map[string]string{"If-None-Match": `"other"`}, want: false},
+		{name: "not modified since", headers: map[string]string{"If-Modified-Since": published.Format(http.TimeFormat)}, want: true},
+		{name: "modified since", headers: map[string]string{"If-Modified-Since": published.Add(-time.Second).Format(http.TimeFormat)}, want: false},
+		{name: "etag wins over date", headers: map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": published.Format(http.TimeFormat)}, want: false},
+		{name: "invalid date", headers: map[string]string{"If-Modified-Since": "yesterday"}, want: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/feed.rss", nil)
+			for key, value := range c.headers {
+				req.Header.Set(key, value)
+			}
+
+			if got := NotModified(req, etag, lastModified); got != c.want {
+				t.Errorf("NotModified() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ChannelNotifications = "notifications"
+	ChannelMessages      = "messages"
+	ChannelTyping        = "typing"
+	ChannelChirps        = "chirps"
+)
+
+var Channels = []string{ChannelNotifications, ChannelMessages, ChannelTyping, ChannelChirps}
+
+// sendBufferSize is how many messages a client can fall behind before it is
+// disconnected.
+const sendBufferSize = 64
+
+// Message is one frame sent to the clients. Replies to the client's own
+// requests, like errors, have no channel.
+type Message struct {
+	Channel string          `json:"channel,omitempty"`
+	Type    string          `json:"type"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func NewMessage(channel, messageType string, data any) (Message, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{Channel: channel, Type: messageType, Data: encoded}, nil
+}
+
+type Client struct {
+	UserID uuid.UUID
+	// Send is closed when the client fell too far behind or was unregistered.
+	Send <-chan Message
+	send chan Message
+	// channels is guarded by the hub's lock.
+	channels map[string]bool
+}
+
+// Hub routes messages to the connected clients of each user, on the channels
+// they subscribed to. It only knows about the clients of this instance.
+type Hub struct {
+	mu      sync.Mutex
+	clients map[uuid.UUID]map[*Client]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		clients: map[uuid.UUID]map[*Client]struct{}{},
+	}
+}
+
+// Register connects a new client for the user, subscribed to nothing yet.
+func (h *Hub) Register(userID uuid.UUID) *Client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	send := make(chan Message, sendBufferSize)
+	client := &Client{
+		UserID:   userID,
+		Send:     send,
+		send:     send,
+		channels: map[string]bool{},
+	}
+
+	if h.clients[userID] == nil {
+		h.clients[userID] = map[*Client]struct{}{}
+	}
+	h.clients[userID][client] = struct{}{}
+
+	return client
+}
+
+func (h *Hub) Unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(client)
+}
+
+// remove must be called with the lock held.
+func (h *Hub) remove(client *Client) {
+	clients, ok := h.clients[client.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := clients[client]; !ok {
+		return
+	}
+
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(h.clients, client.UserID)
+	}
+	close(client.send)
+}
+
+func (h *Hub) Subscribe(client *Client, channels ...string) error {
+	for _, channel := range channels {
+		if !slices.Contains(Channels, channel) {
+			return fmt.Errorf("unknown channel %q, must be one of %v", channel, Channels)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, channel := range channels {
+		client.channels[channel] = true
+	}
+
+	return nil
+}
+
+func (h *Hub) Unsubscribe(client *Client, channels ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, channel := range channels {
+		delete(client.channels, channel)
+	}
+}
+
+func (h *Hub) Subscribed(client *Client, channel string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return client.channels[channel]
+}
+
+// Online tells if the user has at least one client connected to this
+// instance.
+func (h *Hub) Online(userID uuid.UUID) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.clients[userID]) > 0
+}
+
+// Send hands the message to every client of the users subscribed to its
+// channel. It never blocks, clients that can't keep up are disconnected.
+func (h *Hub) Send(userIDs []uuid.UUID, message Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, userID := range userIDs {
+		for client := range h.clients[userID] {
+			if client.channels[message.Channel] {
+				h.deliver(client, message)
+			}
+		}
+	}
+}
+
+// Deliver hands the message to the client whatever it is subscribed to, like
+// Send it never blocks.
+func (h *Hub) Deliver(client *Client, message Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client.UserID][client]; ok {
+		h.deliver(client, message)
+	}
+}
+
+// deliver must be called with the lock held.
+func (h *Hub) deliver(client *Client, message Message) {
+	select {
+	case client.send <- message:
+	default:
+		h.remove(client)
+	}
+}
@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/encryption"
+)
+
+func TestHubSubscriptions(t *testing.T) {
+	hub := NewHub()
+	userID := uuid.New()
+	messages := hub.Register(userID)
+	chirps := hub.Register(userID)
+
+	if err := hub.Subscribe(messages, ChannelMessages, ChannelTyping); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if err := hub.Subscribe(chirps, ChannelChirps); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if err := hub.Subscribe(chirps, "likes"); err == nil {
+		t.Errorf("subscribing to an unknown channel didn't fail")
+	}
+
+	hub.Send([]uuid.UUID{userID}, Message{Channel: ChannelTyping, Type: "typing"})
+	hub.Send([]uuid.UUID{userID}, Message{Channel: ChannelChirps, Type: "chirp.created"})
+	hub.Send([]uuid.UUID{uuid.New()}, Message{Channel: ChannelMessages, Type: "message.created"})
+
+	if message := <-messages.Send; message.Type != "typing" {
+		t.Errorf("messages client got %q, want typing", message.Type)
+	}
+	if message := <-chirps.Send; message.Type != "chirp.created" {
+		t.Errorf("chirps client got %q, want chirp.created", message.Type)
+	}
+
+	hub.Unsubscribe(messages, ChannelTyping)
+	hub.Send([]uuid.UUID{userID}, Message{Channel: ChannelTyping, Type: "typing"})
+	hub.Deliver(messages, Message{Type: "error"})
+	if message := <-messages.Send; message.Type != "error" {
+		t.Errorf("unsubscribed client got %q, want only the delivered error", message.Type)
+	}
+
+	hub.Unregister(messages)
+	if _, ok := <-messages.Send; ok {
+		t.Errorf("unregistered send channel is still open")
+	}
+	if !hub.Online(userID) {
+		t.Errorf("user with a client left is not online")
+	}
+
+	hub.Unregister(chirps)
+	if hub.Online(userID) {
+		t.Errorf("user without clients is still online")
+	}
+
+	// unregistering twice is harmless
+	hub.Unregister(chirps)
+}
+
+func TestHubDisconnectsSlowClients(t *testing.T) {
+	hub := NewHub()
+	userID := uuid.New()
+	slow := hub.Register(userID)
+	fast := hub.Register(userID)
+	hub.Subscribe(slow, ChannelNotifications)
+	hub.Subscribe(fast, ChannelNotifications)
+
+	for i := 0; i <= sendBufferSize; i++ {
+		hub.Send([]uuid.UUID{userID}, Message{Channel: ChannelNotifications, Type: "follow.created"})
+		<-fast.Send
+	}
+
+	received := 0
+	for range slow.Send {
+		received++
+	}
+	if received != sendBufferSize {
+		t.Errorf("slow client got %d messages before being disconnected, want %d", received, sendBufferSize)
+	}
+
+	// nothing is delivered to a disconnected client
+	hub.Deliver(slow, Message{Type: "error"})
+
+	hub.Send([]uuid.UUID{userID}, Message{Channel: ChannelNotifications, Type: "follow.approved"})
+	if message := <-fast.Send; message.Type != "follow.approved" {
+		t.Errorf("fast client got %q, want follow.approved", message.Type)
+	}
+}
+
+func TestHubConcurrentClients(t *testing.T) {
+	const users = 50
+	const clientsPerUser = 4
+	const messagesPerUser = 20
+
+	hub := NewHub()
+	userIDs := make([]uuid.UUID, users)
+	for i := range userIDs {
+		userIDs[i] = uuid.New()
+	}
+
+	registered := sync.WaitGroup{}
+	done := sync.WaitGroup{}
+	counts := make(chan int, users*clientsPerUser)
+	for _, userID := range userIDs {
+		for range clientsPerUser {
+			registered.Add(1)
+			done.Add(1)
+			go func() {
+				defer done.Done()
+
+				client := hub.Register(userID)
+				hub.Subscribe(client, ChannelMessages)
+				registered.Done()
+
+				received := 0
+				for message := range client.Send {
+					if message.Type == "done" {
+						break
+					}
+					received++
+				}
+				hub.Unregister(client)
+				counts <- received
+			}()
+		}
+	}
+	registered.Wait()
+
+	senders := sync.WaitGroup{}
+	for _, userID := range userIDs {
+		senders.Add(1)
+		go func() {
+			defer senders.Done()
+			for range messagesPerUser {
+				hub.Send([]uuid.UUID{userID}, Message{Channel: ChannelMessages, Type: "message.created"})
+			}
+		}()
+	}
+	senders.Wait()
+	hub.Send(userIDs, Message{Channel: ChannelMessages, Type: "done"})
+
+	done.Wait()
+	close(counts)
+	for received := range counts {
+		if received != messagesPerUser {
+			t.Errorf("client got %d messages, want %d", received, messagesPerUser)
+		}
+	}
+	for _, userID := range userIDs {
+		if hub.Online(userID) {
+			t.Errorf("user %s is still online after all their clients left", userID)
+		}
+	}
+}
+
+func TestOpenEnvelope(t *testing.T) {
+	keys, err := encryption.NewKeyring("k1", map[string][]byte{"k1": make([]byte, 32)})
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+
+	userID := uuid.New()
+	plain, _ := json.Marshal(envelope{UserIDs: []uuid.UUID{userID}, Message: Message{Channel: ChannelMessages, Type: "message.created"}})
+	keyID, ciphertext, _ := keys.Encrypt(plain, []byte(Channel))
+	sealed, _ := json.Marshal(sealedEnvelope{KeyID: keyID, Envelope: ciphertext})
+
+	cases := []struct {
+		name    string
+		payload []byte
+		keys    *encryption.Keyring
+		wantErr bool
+	}{
+		{name: "plain", payload: plain},
+		{name: "sealed", payload: sealed, keys: keys},
+		{name: "sealed without keys", payload: sealed, wantErr: true},
+		{name: "not json", payload: []byte("nope"), wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			opened, err := openEnvelope(c.payload, c.keys)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("openEnvelope() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if !c.wantErr && (len(opened.UserIDs) != 1 || opened.UserIDs[0] != userID || opened.Message.Type != "message.created") {
+				t.Errorf("openEnvelope() = %+v", opened)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/encryption"
+)
+
+// Channel is the Postgres NOTIFY channel messages for the clients are
+// published on.
+const Channel = "user_events"
+
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	pingInterval         = time.Minute
+)
+
+type envelope struct {
+	UserIDs []uuid.UUID `json:"user_ids"`
+	Message Message     `json:"message"`
+}
+
+// sealedEnvelope is an envelope encrypted with the message keys, since it may
+// carry direct messages.
+type sealedEnvelope struct {
+	KeyID    string `json:"key_id"`
+	Envelope []byte `json:"envelope"`
+}
+
+// Notify sends the message to the clients of the users on every instance once
+// the transaction db is bound to commits, or right away outside of a
+// transaction. It is encrypted with keys when they are set.
+func Notify(ctx context.Context, db *database.Queries, keys *encryption.Keyring, userIDs []uuid.UUID, message Message) error {
+	payload, err := json.Marshal(envelope{UserIDs: userIDs, Message: message})
+	if err != nil {
+		return err
+	}
+
+	if keys != nil {
+		keyID, ciphertext, err := keys.Encrypt(payload, []byte(Channel))
+		if err != nil {
+			return err
+		}
+
+		payload, err = json.Marshal(sealedEnvelope{KeyID: keyID, Envelope: ciphertext})
+		if err != nil {
+			return err
+		}
+	}
+
+	return db.NotifyUserEvent(ctx, string(payload))
+}
+
+func openEnvelope(payload []byte, keys *encryption.Keyring) (envelope, error) {
+	sealed := sealedEnvelope{}
+	err := json.Unmarshal(payload, &sealed)
+	if err != nil {
+		return envelope{}, err
+	}
+
+	if sealed.KeyID != "" {
+		if keys == nil {
+			return envelope{}, fmt.Errorf("no keys to open the envelope with")
+		}
+
+		payload, err = keys.Decrypt(sealed.KeyID, sealed.Envelope, []byte(Channel))
+		if err != nil {
+			return envelope{}, err
+		}
+	}
+
+	opened := envelope{}
+	err = json.Unmarshal(payload, &opened)
+	if err != nil {
+		return envelope{}, err
+	}
+
+	return opened, nil
+}
+
+// Listen sends the messages NOTIFYed on Channel by any instance to the clients
+// connected to hub, until ctx is cancelled.
+func Listen(ctx context.Context, dbURL string, hub *Hub, keys *encryption.Keyring) error {
+	listener := pq.NewListener(dbURL, minReconnectInterval, maxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Error listening for user events: %s", err)
+		}
+	})
+	defer listener.Close()
+
+	err := listener.Listen(Channel)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			go listener.Ping()
+		case notification := <-listener.Notify:
+			// a nil notification means the connection was re-established,
+			// whatever was sent in between is lost
+			if notification == nil {
+				continue
+			}
+
+			opened, err := openEnvelope([]byte(notification.Extra), keys)
+			if err != nil {
+				log.Printf("Error decoding user event: %s", err)
+				continue
+			}
+
+			hub.Send(opened.UserIDs, opened.Message)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// Execute runs a query prepared with WithRequest, refusing it before it runs
+// when it's deeper or more complex than MaxDepth and MaxComplexity allow.
+func Execute(ctx context.Context, schema graphql.Schema, query string, operationName string, variables map[string]any) *graphql.Result {
+	cost, err := Measure(query, operationName, variables)
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if cost.Depth > MaxDepth {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(fmt.Errorf("the query is %d levels deep, the most allowed is %d", cost.Depth, MaxDepth))}
+	}
+	if cost.Complexity > MaxComplexity {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(fmt.Errorf("the query is too complex, the most complexity allowed is %d", MaxComplexity))}
+	}
+
+	return graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  query,
+		VariableValues: variables,
+		OperationName:  operationName,
+		Context:        ctx,
+	})
+}
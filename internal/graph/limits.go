@@ -0,0 +1,169 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/lucashthiele/chirpy/internal/pagination"
+)
+
+const (
+	MaxDepth      = 10
+	MaxComplexity = 1000
+)
+
+// connectionFields are the fields returning a page of results, whose
+// selections are paid once per item the page can have.
+var connectionFields = map[string]bool{
+	"chirps":    true,
+	"timeline":  true,
+	"followers": true,
+	"following": true,
+}
+
+// Cost is how deep a query nests and roughly how many fields it resolves.
+type Cost struct {
+	Depth      int
+	Complexity int
+}
+
+// Measure works out the cost of the operation of a query, before it runs.
+// Every field costs one, and the fields under a connection are paid as many
+// times as the page it asked for can have items. Measuring stops once the
+// complexity passes MaxComplexity, which is then reported as one over it.
+func Measure(query string, operationName string, variables map[string]any) (Cost, error) {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return Cost{}, err
+	}
+
+	var operation *ast.OperationDefinition
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || definition.Name != nil && definition.Name.Value == operationName {
+				if operation != nil {
+					return Cost{}, fmt.Errorf("operationName is required when the query has several operations")
+				}
+				operation = definition
+			}
+		case *ast.FragmentDefinition:
+			fragments[definition.Name.Value] = definition
+		}
+	}
+	if operation == nil {
+		return Cost{}, fmt.Errorf("unknown operation %q", operationName)
+	}
+
+	m := measurer{
+		fragments: fragments,
+		variables: variables,
+		visiting:  map[string]bool{},
+		measured:  map[string]Cost{},
+	}
+	return m.selectionSet(operation.SelectionSet), nil
+}
+
+type measurer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	visiting  map[string]bool
+	// measured is the cost of the fragments already measured, a fragment
+	// costs the same wherever it's spread
+	measured map[string]Cost
+}
+
+func (m *measurer) selectionSet(set *ast.SelectionSet) Cost {
+	cost := Cost{}
+	if set == nil {
+		return cost
+	}
+
+	for _, selection := range set.Selections {
+		var child Cost
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			child = m.field(selection)
+		case *ast.InlineFragment:
+			child = m.selectionSet(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			// cycles are rejected when the query is validated, they're only
+			// cut short here so measuring ends
+			name := selection.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok || m.visiting[name] {
+				continue
+			}
+			child, ok = m.measured[name]
+			if !ok {
+				m.visiting[name] = true
+				child = m.selectionSet(fragment.SelectionSet)
+				m.visiting[name] = false
+				m.measured[name] = child
+			}
+		}
+
+		cost.Depth = max(cost.Depth, child.Depth)
+		cost.Complexity = capComplexity(cost.Complexity + child.Complexity)
+		if cost.Complexity > MaxComplexity {
+			break
+		}
+	}
+
+	return cost
+}
+
+func (m *measurer) field(field *ast.Field) Cost {
+	children := m.selectionSet(field.SelectionSet)
+
+	multiplier := 1
+	if connectionFields[field.Name.Value] {
+		multiplier = m.first(field)
+	}
+
+	return Cost{
+		Depth:      children.Depth + 1,
+		Complexity: capComplexity(1 + children.Complexity*multiplier),
+	}
+}
+
+// capComplexity keeps complexities past MaxComplexity from growing further,
+// the query is refused either way.
+func capComplexity(complexity int) int {
+	return min(complexity, MaxComplexity+1)
+}
+
+// first is the page size a connection field asks for, clamped to the sizes
+// the field accepts.
+func (m *measurer) first(field *ast.Field) int {
+	first := pagination.DefaultLimit
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			parsed, err := strconv.Atoi(value.Value)
+			if err == nil {
+				first = parsed
+			}
+		case *ast.Variable:
+			switch variable := m.variables[value.Name.Value].(type) {
+			case float64:
+				first = int(variable)
+			case int:
+				first = variable
+			}
+		}
+	}
+
+	return min(max(first, 1), pagination.MaxLimit)
+}
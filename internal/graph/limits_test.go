@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// repeatedFragments is a query of n fragments, each spreading the next one
+// twice, that costs 2^n fields once expanded.
+func repeatedFragments(n int) string {
+	var query strings.Builder
+	query.WriteString(`{ viewer { ...F0 } }`)
+	for i := range n {
+		fmt.Fprintf(&query, ` fragment F%d on User { ...F%d ...F%d }`, i, i+1, i+1)
+	}
+	fmt.Fprintf(&query, ` fragment F%d on User { id }`, n)
+	return query.String()
+}
+
+func TestMeasure(t *testing.T) {
+	cases := []struct {
+		name           string
+		query          string
+		operationName  string
+		variables      map[string]any
+		wantDepth      int
+		wantComplexity int
+		wantErr        bool
+	}{
+		{
+			name:           "flat fields",
+			query:          `{ viewer { id email } }`,
+			wantDepth:      2,
+			wantComplexity: 3,
+		},
+		{
+			name:           "connection pays for every item",
+			query:          `{ chirps(first: 10) { edges { node { id } } } }`,
+			wantDepth:      4,
+			wantComplexity: 1 + 10*3,
+		},
+		{
+			name:           "connection without first pays for the default page",
+			query:          `{ timeline { edges { node { id } } } }`,
+			wantDepth:      4,
+			wantComplexity: 1 + 20*3,
+		},
+		{
+			name:           "first from a variable",
+			query:          `query Q($n: Int) { chirps(first: $n) { edges { cursor } } }`,
+			variables:      map[string]any{"n": float64(5)},
+			wantDepth:      3,
+			wantComplexity: 1 + 5*2,
+		},
+		{
+			name:           "first is clamped",
+			query:          `{ chirps(first: 1000) { edges { cursor } } }`,
+			wantDepth:      3,
+			wantComplexity: 1 + 100*2,
+		},
+		{
+			name:           "nested connections multiply",
+			query:          `{ chirps(first: 10) { edges { node { author { followers(first: 10) { edges { cursor } } } } } } }`,
+			wantDepth:      7,
+			wantComplexity: 1 + 10*(1+1+1+(1+10*2)),
+		},
+		{
+			name:           "fragments are expanded",
+			query:          `{ viewer { ...F } } fragment F on User { id isPrivate }`,
+			wantDepth:      2,
+			wantComplexity: 3,
+		},
+		{
+			name:           "fragment cycles end",
+			query:          `{ viewer { ...A } } fragment A on User { id ...B } fragment B on User { isPrivate ...A }`,
+			wantDepth:      2,
+			wantComplexity: 3,
+		},
+		{
+			name:           "repeated fragments are measured once",
+			query:          repeatedFragments(30),
+			wantDepth:      2,
+			wantComplexity: MaxComplexity + 1,
+		},
+		{
+			name:           "complexity past the limit is cut short",
+			query:          `{ chirps(first: 100) { edges { node { author { followers(first: 100) { edges { node { chirps(first: 100) { edges { cursor } } } } } } } } } }`,
+			wantDepth:      10,
+			wantComplexity: MaxComplexity + 1,
+		},
+		{
+			name:           "introspection is free",
+			query:          `{ __typename viewer { __typename id } }`,
+			wantDepth:      2,
+			wantComplexity: 2,
+		},
+		{
+			name:           "named operation",
+			query:          `query A { viewer { id } } query B { chirp(id: "x") { id body } }`,
+			operationName:  "B",
+			wantDepth:      2,
+			wantComplexity: 3,
+		},
+		{
+			name:    "several operations without a name",
+			query:   `query A { viewer { id } } query B { viewer { id } }`,
+			wantErr: true,
+		},
+		{
+			name:          "unknown operation",
+			query:         `query A { viewer { id } }`,
+			operationName: "B",
+			wantErr:       true,
+		},
+		{
+			name:    "syntax error",
+			query:   `{ viewer { id }`,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := Measure(c.query, c.operationName, c.variables)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("Measure() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if c.wantErr {
+				return
+			}
+
+			if got.Depth != c.wantDepth {
+				t.Errorf("Depth = %d, want %d", got.Depth, c.wantDepth)
+			}
+			if got.Complexity != c.wantComplexity {
+				t.Errorf("Complexity = %d, want %d", got.Complexity, c.wantComplexity)
+			}
+		})
+	}
+}
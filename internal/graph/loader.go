@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"context"
+	"sync"
+)
+
+// Loader batches the lookups of a request by key. Resolvers call Load, which
+// only returns a thunk; the executor calls the thunks once all sibling fields
+// were resolved, so the first one fetches every key loaded so far at once.
+// Results are cached for the rest of the request.
+type Loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending *batch[K, V]
+	loaded  map[K]*batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys   []K
+	once   sync.Once
+	values map[K]V
+	err    error
+}
+
+// NewLoader returns a loader fetching keys with fetch. Keys missing from the
+// map fetch returns weren't found.
+func NewLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:  fetch,
+		loaded: map[K]*batch[K, V]{},
+	}
+}
+
+// Load queues key for the next batch, unless it was already loaded, and
+// returns a thunk resolving to its value and whether it was found.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) func() (V, bool, error) {
+	l.mu.Lock()
+	b, ok := l.loaded[key]
+	if !ok {
+		if l.pending == nil {
+			l.pending = &batch[K, V]{}
+		}
+		b = l.pending
+		b.keys = append(b.keys, key)
+		l.loaded[key] = b
+	}
+	l.mu.Unlock()
+
+	return func() (V, bool, error) {
+		b.once.Do(func() {
+			l.mu.Lock()
+			if l.pending == b {
+				l.pending = nil
+			}
+			l.mu.Unlock()
+
+			b.values, b.err = l.fetch(ctx, b.keys)
+		})
+
+		value, found := b.values[key]
+		return value, found, b.err
+	}
+}
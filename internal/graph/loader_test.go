@@ -0,0 +1,166 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+// recorder is a fetch function that keeps the batches it was called with.
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]int
+	err     error
+}
+
+func (r *recorder) fetch(ctx context.Context, keys []int) (map[int]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, slices.Clone(keys))
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	values := map[int]string{}
+	for _, key := range keys {
+		// odd keys don't exist
+		if key%2 == 0 {
+			values[key] = string(rune('a' + key))
+		}
+	}
+	return values, nil
+}
+
+func TestLoader(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+
+	cases := []struct {
+		name        string
+		rounds      [][]int
+		err         error
+		wantBatches [][]int
+	}{
+		{
+			name:        "keys loaded together share a batch",
+			rounds:      [][]int{{0, 2, 4}},
+			wantBatches: [][]int{{0, 2, 4}},
+		},
+		{
+			name:        "repeated keys are fetched once",
+			rounds:      [][]int{{0, 2, 0, 2}},
+			wantBatches: [][]int{{0, 2}},
+		},
+		{
+			name:        "loaded keys are cached across batches",
+			rounds:      [][]int{{0, 2}, {2, 4}},
+			wantBatches: [][]int{{0, 2}, {4}},
+		},
+		{
+			name:        "missing keys aren't found",
+			rounds:      [][]int{{1, 2}},
+			wantBatches: [][]int{{1, 2}},
+		},
+		{
+			name:        "errors reach every key of the batch",
+			rounds:      [][]int{{0, 2}},
+			err:         errFetch,
+			wantBatches: [][]int{{0, 2}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := &recorder{err: c.err}
+			loader := NewLoader(r.fetch)
+
+			for _, round := range c.rounds {
+				thunks := make([]func() (string, bool, error), len(round))
+				for i, key := range round {
+					thunks[i] = loader.Load(context.Background(), key)
+				}
+
+				for i, thunk := range thunks {
+					key := round[i]
+					value, found, err := thunk()
+					if err != c.err {
+						t.Fatalf("thunk() error = %v, want %v", err, c.err)
+					}
+					if c.err != nil {
+						continue
+					}
+
+					wantFound := key%2 == 0
+					if found != wantFound {
+						t.Errorf("key %d found = %v, want %v", key, found, wantFound)
+					}
+					if found && value != string(rune('a'+key)) {
+						t.Errorf("key %d = %q, want %q", key, value, string(rune('a'+key)))
+					}
+				}
+			}
+
+			if !slices.EqualFunc(r.batches, c.wantBatches, slices.Equal) {
+				t.Errorf("batches = %v, want %v", r.batches, c.wantBatches)
+			}
+		})
+	}
+}
+
+// TestLoaderBatchesSiblings makes sure the executor resolves the fields of
+// every item of a list before calling their thunks, which is what the
+// batching relies on.
+func TestLoaderBatchesSiblings(t *testing.T) {
+	r := &recorder{}
+	loader := NewLoader(r.fetch)
+
+	item := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					thunk := loader.Load(p.Context, p.Source.(int))
+					return func() (any, error) {
+						value, _, err := thunk()
+						return value, err
+					}, nil
+				},
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"items": &graphql.Field{
+					Type: graphql.NewList(item),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return []int{0, 2, 4, 6}, nil
+					},
+				},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewSchema() error = %v", err)
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: "{ items { name } }",
+		Context:       context.Background(),
+	})
+	if result.HasErrors() {
+		t.Fatalf("Do() errors = %v", result.Errors)
+	}
+
+	want := [][]int{{0, 2, 4, 6}}
+	if !slices.EqualFunc(r.batches, want, slices.Equal) {
+		t.Errorf("batches = %v, want %v", r.batches, want)
+	}
+}
@@ -0,0 +1,595 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/moderation"
+	"github.com/lucashthiele/chirpy/internal/pagination"
+)
+
+var errUnauthenticated = errors.New("you must be logged in")
+
+type contextKey string
+
+const requestKey contextKey = "graph"
+
+// request holds what the resolvers of a single query share: who's asking and
+// the loaders batching their lookups.
+type request struct {
+	db       *database.Queries
+	viewerID uuid.UUID
+
+	users        *Loader[uuid.UUID, database.User]
+	chirps       *Loader[uuid.UUID, database.Chirp]
+	followCounts *Loader[uuid.UUID, database.ListFollowCountsRow]
+	likeCounts   *Loader[uuid.UUID, int64]
+	chirpyRed    *Loader[uuid.UUID, bool]
+}
+
+// WithRequest prepares ctx for running a query on behalf of the user
+// authenticated in it, if any.
+func WithRequest(ctx context.Context, db *database.Queries) context.Context {
+	viewerID := config.ViewerID(ctx)
+
+	r := &request{
+		db:       db,
+		viewerID: viewerID,
+		users: NewLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]database.User, error) {
+			users, err := db.ListUsersByIDs(ctx, ids)
+			if err != nil {
+				return nil, internalError(err)
+			}
+			byID := make(map[uuid.UUID]database.User, len(users))
+			for _, user := range users {
+				byID[user.ID] = user
+			}
+			return byID, nil
+		}),
+		chirps: NewLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]database.Chirp, error) {
+			chirps, err := db.ListVisibleChirpsByIDs(ctx, database.ListVisibleChirpsByIDsParams{
+				Ids:      ids,
+				ViewerID: viewerID,
+			})
+			if err != nil {
+				return nil, internalError(err)
+			}
+			byID := make(map[uuid.UUID]database.Chirp, len(chirps))
+			for _, chirp := range chirps {
+				byID[chirp.ID] = chirp
+			}
+			return byID, nil
+		}),
+		followCounts: NewLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]database.ListFollowCountsRow, error) {
+			counts, err := db.ListFollowCounts(ctx, ids)
+			if err != nil {
+				return nil, internalError(err)
+			}
+			byID := make(map[uuid.UUID]database.ListFollowCountsRow, len(counts))
+			for _, count := range counts {
+				byID[count.ID] = count
+			}
+			return byID, nil
+		}),
+		likeCounts: NewLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]int64, error) {
+			counts, err := db.ListLikeCounts(ctx, ids)
+			if err != nil {
+				return nil, internalError(err)
+			}
+			byID := make(map[uuid.UUID]int64, len(counts))
+			for _, count := range counts {
+				byID[count.ChirpID] = count.LikeCount
+			}
+			return byID, nil
+		}),
+		chirpyRed: NewLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+			redIDs, err := db.ListChirpyRedUsers(ctx, ids)
+			if err != nil {
+				return nil, internalError(err)
+			}
+			byID := make(map[uuid.UUID]bool, len(redIDs))
+			for _, id := range redIDs {
+				byID[id] = true
+			}
+			return byID, nil
+		}),
+	}
+
+	return context.WithValue(ctx, requestKey, r)
+}
+
+// internalError logs err and hides it from the client, like the 500s of the
+// REST API do.
+func internalError(err error) error {
+	log.Printf("Internal Server Error: %s", err)
+	return errors.New("something went wrong")
+}
+
+func fromContext(ctx context.Context) *request {
+	return ctx.Value(requestKey).(*request)
+}
+
+// Connection is a page of a list, in the shape of the Relay connection spec.
+type Connection struct {
+	Edges    []Edge
+	PageInfo PageInfo
+}
+
+type Edge struct {
+	Cursor string
+	Node   any
+}
+
+type PageInfo struct {
+	HasNextPage bool
+	EndCursor   *string
+}
+
+// newConnection makes a page out of the items fetched for it, which were
+// fetched with one more than first to know if there's a next page.
+func newConnection[T any](items []T, first int, cursor func(T) pagination.Cursor) Connection {
+	connection := Connection{
+		Edges: []Edge{},
+	}
+
+	if len(items) > first {
+		items = items[:first]
+		connection.PageInfo.HasNextPage = true
+	}
+
+	for _, item := range items {
+		connection.Edges = append(connection.Edges, Edge{
+			Cursor: cursor(item).Encode(),
+			Node:   item,
+		})
+	}
+
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+
+	return connection
+}
+
+// pageArgs reads the first and after arguments of a connection field.
+func pageArgs(p graphql.ResolveParams) (pagination.Cursor, int, error) {
+	first, _ := p.Args["first"].(int)
+	if first < 1 || first > pagination.MaxLimit {
+		return pagination.Cursor{}, 0, fmt.Errorf("first must be between 1 and %d", pagination.MaxLimit)
+	}
+
+	cursor := pagination.Start()
+	if after, ok := p.Args["after"].(string); ok && after != "" {
+		var err error
+		cursor, err = pagination.Decode(after)
+		if err != nil {
+			return pagination.Cursor{}, 0, err
+		}
+	}
+
+	return cursor, first, nil
+}
+
+func parseID(p graphql.ResolveParams, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(fmt.Sprint(p.Args[name]))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s must be a valid ID", name)
+	}
+	return id, nil
+}
+
+func connectionArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{
+			Type:         graphql.Int,
+			DefaultValue: pagination.DefaultLimit,
+		},
+		"after": &graphql.ArgumentConfig{
+			Type: graphql.String,
+		},
+	}
+}
+
+// connectionType builds the connection of node, resolving the nodes of its
+// edges with resolveNode when they need to be loaded first.
+func connectionType(name string, node graphql.Output, resolveNode graphql.FieldResolveFn) *graphql.Object {
+	edge := graphql.NewObject(graphql.ObjectConfig{
+		Name: name + "Edge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: graphql.NewNonNull(node), Resolve: resolveNode},
+		},
+	})
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name + "Connection",
+		Fields: graphql.Fields{
+			"edges":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edge)))},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+		},
+	})
+}
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"endCursor":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+// loadUser resolves to the user with id, or null when there's none.
+func loadUser(p graphql.ResolveParams, id uuid.UUID) (any, error) {
+	thunk := fromContext(p.Context).users.Load(p.Context, id)
+	return func() (any, error) {
+		user, found, err := thunk()
+		if err != nil || !found {
+			return nil, err
+		}
+		return user, nil
+	}, nil
+}
+
+// loadChirp resolves to the chirp with id, or null when the viewer can't see
+// it.
+func loadChirp(p graphql.ResolveParams, id uuid.UUID) (any, error) {
+	thunk := fromContext(p.Context).chirps.Load(p.Context, id)
+	return func() (any, error) {
+		chirp, found, err := thunk()
+		if err != nil || !found {
+			return nil, err
+		}
+		return chirp, nil
+	}, nil
+}
+
+func listChirps(p graphql.ResolveParams, arg database.ListVisibleChirpsPageParams) (any, error) {
+	cursor, first, err := pageArgs(p)
+	if err != nil {
+		return nil, err
+	}
+
+	r := fromContext(p.Context)
+	arg.ViewerID = r.viewerID
+	arg.BeforeTime = cursor.Time
+	arg.BeforeID = cursor.ID
+	arg.RowLimit = int32(first + 1)
+
+	chirps, err := r.db.ListVisibleChirpsPage(p.Context, arg)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return newConnection(chirps, first, func(chirp database.Chirp) pagination.Cursor {
+		return pagination.Cursor{Time: chirp.CreatedAt, ID: chirp.ID}
+	}), nil
+}
+
+// canListFollows tells whether the viewer can see who a user follows and is
+// followed by, which for private accounts is only them and their followers.
+func canListFollows(p graphql.ResolveParams, user database.User) (bool, error) {
+	r := fromContext(p.Context)
+	if !user.IsPrivate || user.ID == r.viewerID {
+		return true, nil
+	}
+	if r.viewerID == uuid.Nil {
+		return false, nil
+	}
+
+	relation, err := r.db.GetViewerRelation(p.Context, database.GetViewerRelationParams{
+		ViewerID: r.viewerID,
+		AuthorID: user.ID,
+	})
+	if err != nil {
+		return false, internalError(err)
+	}
+	return relation.Following && !relation.Blocked, nil
+}
+
+// listFollows pages through the follows of a user. The nodes of its edges are
+// the IDs of the users on the other end, which the edges load.
+func listFollows(p graphql.ResolveParams, user database.User, followers bool) (any, error) {
+	cursor, first, err := pageArgs(p)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := canListFollows(p, user)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return newConnection([]database.Follow{}, first, nil), nil
+	}
+
+	r := fromContext(p.Context)
+	var follows []database.Follow
+	other := func(follow database.Follow) uuid.UUID { return follow.FollowedID }
+	if followers {
+		other = func(follow database.Follow) uuid.UUID { return follow.FollowerID }
+		follows, err = r.db.ListFollowersPage(p.Context, database.ListFollowersPageParams{
+			FollowedID: user.ID,
+			BeforeTime: cursor.Time,
+			BeforeID:   cursor.ID,
+			RowLimit:   int32(first + 1),
+		})
+	} else {
+		follows, err = r.db.ListFollowingPage(p.Context, database.ListFollowingPageParams{
+			FollowerID: user.ID,
+			BeforeTime: cursor.Time,
+			BeforeID:   cursor.ID,
+			RowLimit:   int32(first + 1),
+		})
+	}
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	connection := newConnection(follows, first, func(follow database.Follow) pagination.Cursor {
+		return pagination.Cursor{Time: follow.CreatedAt, ID: other(follow)}
+	})
+	for i, edge := range connection.Edges {
+		connection.Edges[i].Node = other(edge.Node.(database.Follow))
+	}
+	return connection, nil
+}
+
+func followCount(p graphql.ResolveParams, followers bool) (any, error) {
+	user := p.Source.(database.User)
+	thunk := fromContext(p.Context).followCounts.Load(p.Context, user.ID)
+	return func() (any, error) {
+		counts, _, err := thunk()
+		if followers {
+			return counts.FollowerCount, err
+		}
+		return counts.FollowingCount, err
+	}, nil
+}
+
+// NewSchema builds the read-only graph of users, chirps and follows. Writes
+// stay on the REST API.
+func NewSchema() (graphql.Schema, error) {
+	var userType, chirpType *graphql.Object
+	var userConnection, chirpConnection *graphql.Object
+
+	userType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"createdAt": &graphql.Field{
+					Type: graphql.DateTime,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						user := p.Source.(database.User)
+						if !user.CreatedAt.Valid {
+							return nil, nil
+						}
+						return user.CreatedAt.Time, nil
+					},
+				},
+				"email": &graphql.Field{
+					Type:        graphql.String,
+					Description: "Only visible to the user themselves.",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						user := p.Source.(database.User)
+						if user.ID != fromContext(p.Context).viewerID {
+							return nil, nil
+						}
+						return user.Email, nil
+					},
+				},
+				"isChirpyRed": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Boolean),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						thunk := fromContext(p.Context).chirpyRed.Load(p.Context, p.Source.(database.User).ID)
+						return func() (any, error) {
+							isChirpyRed, _, err := thunk()
+							return isChirpyRed, err
+						}, nil
+					},
+				},
+				"isPrivate": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+				"followerCount": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.Int),
+					Description: "Local and remote followers.",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return followCount(p, true)
+					},
+				},
+				"followingCount": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return followCount(p, false)
+					},
+				},
+				"chirps": &graphql.Field{
+					Type: graphql.NewNonNull(chirpConnection),
+					Args: connectionArgs(),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						user := p.Source.(database.User)
+						return listChirps(p, database.ListVisibleChirpsPageParams{AuthorID: user.ID})
+					},
+				},
+				"followers": &graphql.Field{
+					Type:        graphql.NewNonNull(userConnection),
+					Description: "Empty for private accounts the viewer doesn't follow.",
+					Args:        connectionArgs(),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return listFollows(p, p.Source.(database.User), true)
+					},
+				},
+				"following": &graphql.Field{
+					Type:        graphql.NewNonNull(userConnection),
+					Description: "Empty for private accounts the viewer doesn't follow.",
+					Args:        connectionArgs(),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return listFollows(p, p.Source.(database.User), false)
+					},
+				},
+			}
+		}),
+	})
+
+	chirpType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Chirp",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"body":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"createdAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+				"updatedAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+				"visibility":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"replyPolicy": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"author": &graphql.Field{
+					Type: userType,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return loadUser(p, p.Source.(database.Chirp).UserID)
+					},
+				},
+				"mentions": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(userType)),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						mentions := p.Source.(database.Chirp).Mentions
+						users := make([]any, len(mentions))
+						for i, id := range mentions {
+							users[i], _ = loadUser(p, id)
+						}
+						return users, nil
+					},
+				},
+				"replyTo": &graphql.Field{
+					Type:        chirpType,
+					Description: "Null when the chirp isn't a reply or the viewer can't see the chirp it replies to.",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						replyTo := p.Source.(database.Chirp).ReplyToID
+						if !replyTo.Valid {
+							return nil, nil
+						}
+						return loadChirp(p, replyTo.UUID)
+					},
+				},
+				"likeCount": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.Int),
+					Description: "Likes from other instances, federated over ActivityPub.",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						thunk := fromContext(p.Context).likeCounts.Load(p.Context, p.Source.(database.Chirp).ID)
+						return func() (any, error) {
+							count, _, err := thunk()
+							return count, err
+						}, nil
+					},
+				},
+			}
+		}),
+	})
+
+	chirpConnection = connectionType("Chirp", chirpType, nil)
+	userConnection = connectionType("User", userType, func(p graphql.ResolveParams) (any, error) {
+		return loadUser(p, p.Source.(Edge).Node.(uuid.UUID))
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"viewer": &graphql.Field{
+				Type:        userType,
+				Description: "The authenticated user, null for anonymous requests.",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					viewerID := fromContext(p.Context).viewerID
+					if viewerID == uuid.Nil {
+						return nil, nil
+					}
+					return loadUser(p, viewerID)
+				},
+			},
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := parseID(p, "id")
+					if err != nil {
+						return nil, err
+					}
+
+					r := fromContext(p.Context)
+					relation, err := r.db.GetViewerRelation(p.Context, database.GetViewerRelationParams{
+						ViewerID: r.viewerID,
+						AuthorID: id,
+					})
+					if err != nil {
+						return nil, internalError(err)
+					}
+					if relation.Blocked {
+						return nil, nil
+					}
+
+					return loadUser(p, id)
+				},
+			},
+			"chirp": &graphql.Field{
+				Type: chirpType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := parseID(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					return loadChirp(p, id)
+				},
+			},
+			"chirps": &graphql.Field{
+				Type:        graphql.NewNonNull(chirpConnection),
+				Description: "The chirps the viewer can see, newest first.",
+				Args: graphql.FieldConfigArgument{
+					"first":    connectionArgs()["first"],
+					"after":    connectionArgs()["after"],
+					"authorId": &graphql.ArgumentConfig{Type: graphql.ID},
+					"hashtag":  &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					arg := database.ListVisibleChirpsPageParams{}
+					if _, ok := p.Args["authorId"]; ok {
+						authorID, err := parseID(p, "authorId")
+						if err != nil {
+							return nil, err
+						}
+						arg.AuthorID = authorID
+					}
+					if hashtag, ok := p.Args["hashtag"].(string); ok {
+						hashtag, err := moderation.ParseHashtag(hashtag)
+						if err != nil {
+							return nil, err
+						}
+						arg.Hashtag = hashtag
+					}
+					return listChirps(p, arg)
+				},
+			},
+			"timeline": &graphql.Field{
+				Type:        graphql.NewNonNull(chirpConnection),
+				Description: "The chirps of the viewer and the users they follow, newest first.",
+				Args:        connectionArgs(),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if fromContext(p.Context).viewerID == uuid.Nil {
+						return nil, errUnauthenticated
+					}
+					return listChirps(p, database.ListVisibleChirpsPageParams{Timeline: true})
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: query,
+	})
+}
@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func TestSchemaValidatesQueries(t *testing.T) {
+	schema, err := NewSchema()
+	if err != nil {
+		t.Fatalf("NewSchema() error = %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		query     string
+		wantValid bool
+	}{
+		{
+			name: "every field",
+			query: `{
+				viewer { id createdAt email isChirpyRed isPrivate followerCount followingCount }
+				user(id: "a") {
+					followers(first: 5, after: "c") { edges { cursor node { id } } pageInfo { hasNextPage endCursor } }
+					following { edges { node { id } } }
+				}
+				chirp(id: "b") { id body createdAt updatedAt visibility replyPolicy likeCount author { id } mentions { id } replyTo { id } }
+				chirps(authorId: "a", hashtag: "go") { edges { node { author { chirps { edges { node { id } } } } } } }
+				timeline(first: 10) { pageInfo { hasNextPage } }
+			}`,
+			wantValid: true,
+		},
+		{
+			name:      "unknown field",
+			query:     `{ viewer { password } }`,
+			wantValid: false,
+		},
+		{
+			name:      "user without id",
+			query:     `{ user { id } }`,
+			wantValid: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			document, err := parser.Parse(parser.ParseParams{Source: c.query})
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			result := graphql.ValidateDocument(&schema, document, nil)
+			if result.IsValid != c.wantValid {
+				t.Errorf("IsValid = %v, want %v (errors: %v)", result.IsValid, c.wantValid, result.Errors)
+			}
+		})
+	}
+}
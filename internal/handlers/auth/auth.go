@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/lucashthiele/chirpy/internal/auth"
 	"github.com/lucashthiele/chirpy/internal/config"
 	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
 	"github.com/lucashthiele/chirpy/pkg/parser"
 	"github.com/lucashthiele/chirpy/pkg/response"
 )
@@ -17,8 +19,8 @@ const expiresInOneHour time.Duration = time.Hour        // 1 hour
 const expiresInDays time.Duration = time.Hour * 24 * 60 // 60 days
 
 type params struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
 }
 
 type userJSON struct {
@@ -29,60 +31,98 @@ type userJSON struct {
 	Token        string    `json:"token"`
 	RefreshToken string    `json:"refresh_token"`
 	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	IsPrivate    bool      `json:"is_private"`
 }
 
 type tokenJSON struct {
 	Token string `json:"token"`
 }
 
+var LoginOperation = openapi.Operation{
+	ID:          "loginUser",
+	Tag:         "Login",
+	Summary:     "Login",
+	Description: "Login to the app.",
+	Request:     &openapi.Request{Type: params{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "User logged in", userJSON{}),
+		openapi.Error(http.StatusUnauthorized, "Incorrect email or password"),
+	},
+}
+
 func HandleLogin(resp http.ResponseWriter, req *http.Request) {
 	cfg, err := config.New()
 	if err != nil {
-		response.RespondWithInternalServerError(resp, err)
+		response.RespondWithInternalServerError(resp, req, err)
 		return
 	}
 
 	data := &params{}
 
-	err = parser.ParseBody(req.Body, data)
+	err = parser.ParseBody(resp, req, data)
 	if err != nil {
-		response.RespondWithInternalServerError(resp, err)
+		response.RespondWithErr(resp, req, err)
 		return
 	}
 
 	user, err := cfg.Db.GetUserByEmail(req.Context(), data.Email)
 	if err == sql.ErrNoRows {
-		response.RespondWithError(resp, http.StatusUnauthorized, "Unauthorized")
+		response.RespondWithError(resp, req, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 	if err != nil {
-		response.RespondWithInternalServerError(resp, err)
+		response.RespondWithInternalServerError(resp, req, err)
 		return
 	}
 
 	err = auth.CheckPassword(user.HashedPassword, data.Password)
 	if err != nil {
-		response.RespondWithError(resp, http.StatusUnauthorized, "Unauthorized")
+		response.RespondWithError(resp, req, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
+	respondWithSession(resp, req, cfg, user)
+}
+
+// Session is what a user gets when they log in.
+type Session struct {
+	Token        string
+	RefreshToken string
+}
+
+// NewSession issues a new JWT and refresh token for the user.
+func NewSession(ctx context.Context, cfg *config.ApiConfig, user database.User) (Session, error) {
 	token, err := auth.MakeJWT(user.ID, cfg.AppSecret, expiresInOneHour)
 	if err != nil {
-		response.RespondWithInternalServerError(resp, err)
-		return
+		return Session{}, err
 	}
 
-	refreshToken := auth.MakeRefreshToken()
-
 	args := database.CreateRefreshTokenParams{
-		Token:     refreshToken,
+		Token:     auth.MakeRefreshToken(),
 		UserID:    user.ID,
 		ExpiresAt: time.Now().Add(expiresInDays),
 	}
 
-	createdRefreshToken, err := cfg.Db.CreateRefreshToken(req.Context(), args)
+	createdRefreshToken, err := cfg.Db.CreateRefreshToken(ctx, args)
+	if err != nil {
+		return Session{}, err
+	}
+
+	return Session{Token: token, RefreshToken: createdRefreshToken.Token}, nil
+}
+
+// respondWithSession issues a new JWT and refresh token for the user and
+// answers with the same body as a password login.
+func respondWithSession(resp http.ResponseWriter, req *http.Request, cfg *config.ApiConfig, user database.User) {
+	session, err := NewSession(req.Context(), cfg, user)
 	if err != nil {
-		response.RespondWithInternalServerError(resp, err)
+		response.RespondWithInternalServerError(resp, req, err)
+		return
+	}
+
+	isChirpyRed, err := cfg.Db.IsChirpyRed(req.Context(), user.ID)
+	if err != nil {
+		response.RespondWithInternalServerError(resp, req, err)
 		return
 	}
 
@@ -91,39 +131,64 @@ func HandleLogin(resp http.ResponseWriter, req *http.Request) {
 		CreatedAt:    user.CreatedAt.Time,
 		UpdatedAt:    user.UpdatedAt.Time,
 		Email:        user.Email,
-		Token:        token,
-		RefreshToken: createdRefreshToken.Token,
-		IsChirpyRed:  user.IsChirpyRed,
+		Token:        session.Token,
+		RefreshToken: session.RefreshToken,
+		IsChirpyRed:  isChirpyRed,
+		IsPrivate:    user.IsPrivate,
 	}
 
 	response.RespondWithJSON(resp, http.StatusOK, userResp)
 }
 
+// RefreshAccessToken issues a new JWT for a refresh token, or returns
+// config.ErrUnauthorized when it's unknown, expired or revoked.
+func RefreshAccessToken(ctx context.Context, cfg *config.ApiConfig, refreshToken string) (string, error) {
+	storedToken, err := cfg.Db.GetUserFromRefreshToken(ctx, refreshToken)
+	if err == sql.ErrNoRows {
+		return "", config.ErrUnauthorized
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if storedToken.ClientID.Valid { // issued to an OAuth client, keep it within its scopes
+		return auth.MakeScopedJWT(storedToken.UserID, cfg.AppSecret, expiresInOneHour, storedToken.ClientID.String, storedToken.Scopes)
+	}
+	return auth.MakeJWT(storedToken.UserID, cfg.AppSecret, expiresInOneHour)
+}
+
+var RefreshTokenOperation = openapi.Operation{
+	ID:          "refreshToken",
+	Tag:         "Auth",
+	Summary:     "Refresh access token",
+	Description: "Refreshes the access token using a valid refresh token, sent as the bearer token.",
+	Security:    []openapi.Security{openapi.RefreshToken},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "Token refreshed", tokenJSON{}),
+		openapi.Unauthorized,
+	},
+}
+
 func HandleRefreshToken(resp http.ResponseWriter, req *http.Request) {
 	cfg, err := config.New()
 	if err != nil {
-		response.RespondWithInternalServerError(resp, err)
+		response.RespondWithInternalServerError(resp, req, err)
 		return
 	}
 
 	refreshToken, err := auth.GetBearerToken(&req.Header)
 	if err != nil {
-		response.RespondWithError(resp, http.StatusUnauthorized, err.Error())
+		response.RespondWithError(resp, req, http.StatusUnauthorized, err.Error())
+		return
 	}
 
-	userId, err := cfg.Db.GetUserFromRefreshToken(req.Context(), refreshToken)
-	if err == sql.ErrNoRows {
-		response.RespondWithError(resp, http.StatusUnauthorized, "Unauthorized")
+	token, err := RefreshAccessToken(req.Context(), cfg, refreshToken)
+	if err == config.ErrUnauthorized {
+		response.RespondWithError(resp, req, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 	if err != nil {
-		response.RespondWithInternalServerError(resp, err)
-		return
-	}
-
-	token, err := auth.MakeJWT(userId, cfg.AppSecret, expiresInOneHour)
-	if err != nil {
-		response.RespondWithInternalServerError(resp, err)
+		response.RespondWithInternalServerError(resp, req, err)
 		return
 	}
 
@@ -134,21 +199,34 @@ func HandleRefreshToken(resp http.ResponseWriter, req *http.Request) {
 	response.RespondWithJSON(resp, http.StatusOK, tokenResp)
 }
 
+var RevokeRefreshTokenOperation = openapi.Operation{
+	ID:          "revokeToken",
+	Tag:         "Auth",
+	Summary:     "Revoke refresh token",
+	Description: "Revokes the refresh token sent as the bearer token, logging the user out.",
+	Security:    []openapi.Security{openapi.RefreshToken},
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusNoContent, "Token revoked"),
+		openapi.Unauthorized,
+	},
+}
+
 func HandleRevokeRefreshToken(resp http.ResponseWriter, req *http.Request) {
 	cfg, err := config.New()
 	if err != nil {
-		response.RespondWithInternalServerError(resp, err)
+		response.RespondWithInternalServerError(resp, req, err)
 		return
 	}
 
 	refreshToken, err := auth.GetBearerToken(&req.Header)
 	if err != nil {
-		response.RespondWithError(resp, http.StatusUnauthorized, err.Error())
+		response.RespondWithError(resp, req, http.StatusUnauthorized, err.Error())
+		return
 	}
 
 	err = cfg.Db.RevokeRefreshToken(req.Context(), refreshToken)
 	if err != nil {
-		response.RespondWithInternalServerError(resp, err)
+		response.RespondWithInternalServerError(resp, req, err)
 		return
 	}
 
@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/mailer"
+	"github.com/lucashthiele/chirpy/internal/model"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+const deviceCookie = "chirpy_device"
+const magicLinkExpiry time.Duration = time.Minute * 15
+
+// at most magicLinkLimit links can be requested for an email address in each
+// magicLinkWindow
+const magicLinkLimit = 3
+const magicLinkWindow time.Duration = time.Minute * 15
+
+const magicLinkSendTimeout time.Duration = time.Second * 30
+
+type magicLinkParams struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// deviceSecret returns the secret kept in the device cookie, creating it on
+// the first request. The cookie is only sent over HTTPS when the links are,
+// browsers would drop it from the http links of the dev platform otherwise.
+func deviceSecret(res http.ResponseWriter, req *http.Request, baseURL string) string {
+	cookie, err := req.Cookie(deviceCookie)
+	if err == nil && len(cookie.Value) == 64 {
+		return cookie.Value
+	}
+
+	secret := auth.MakeRefreshToken()
+	http.SetCookie(res, &http.Cookie{
+		Name:     deviceCookie,
+		Value:    secret,
+		Path:     "/api/login/magic",
+		MaxAge:   int(time.Hour * 24 * 365 / time.Second),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(baseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return secret
+}
+
+func magicLinkBaseURL(req *http.Request, cfg *config.ApiConfig) string {
+	if cfg.BaseURL != "" {
+		return cfg.BaseURL
+	}
+	if cfg.Platform == "dev" {
+		return "http://" + req.Host
+	}
+	return ""
+}
+
+func sendMagicLink(emailMailer mailer.Mailer, email, link string) {
+	ctx, cancel := context.WithTimeout(context.Background(), magicLinkSendTimeout)
+	defer cancel()
+
+	err := emailMailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Your Chirpy login link",
+		Body: fmt.Sprintf("Use this link to log in to Chirpy:\n\n%s\n\n"+
+			"It expires in %d minutes and only works on the device you requested it from. "+
+			"If you didn't ask for it, you can ignore this email.\n", link, int(magicLinkExpiry.Minutes())),
+	})
+	if err != nil {
+		log.Printf("Error sending login link: %s", err)
+	}
+}
+
+var RequestMagicLinkOperation = openapi.Operation{
+	ID:      "requestMagicLink",
+	Tag:     "Auth",
+	Summary: "Email a login link",
+	Description: "Emails a single use login link that expires after 15 minutes. The link only works on the device that " +
+		"asked for it, which is tracked with a cookie. The response is the same whether the email belongs to a user or " +
+		"not. At most 3 links can be requested for an email every 15 minutes.",
+	Request: &openapi.Request{Type: magicLinkParams{}},
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusAccepted, "If the email belongs to a user, a login link is on its way"),
+		openapi.Error(http.StatusBadRequest, "Invalid body or missing email"),
+		{
+			Status:      http.StatusTooManyRequests,
+			Description: "Too many links requested for this email",
+			Type:        model.ErrorResponse{},
+			Headers:     map[string]string{"Retry-After": "Seconds until a link can be requested again."},
+		},
+		openapi.Error(http.StatusServiceUnavailable, "Email login is not configured"),
+	},
+}
+
+// HandleRequestMagicLink emails a single use login link. The answer is the
+// same whether the email belongs to a user or not, and the email is sent in
+// the background so the timing doesn't tell either.
+func HandleRequestMagicLink(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	baseURL := magicLinkBaseURL(req, cfg)
+	if cfg.Mailer == nil || baseURL == "" {
+		response.RespondWithError(res, req, http.StatusServiceUnavailable, "magic link login is not available")
+		return
+	}
+
+	params := &magicLinkParams{}
+
+	err = parser.ParseBody(res, req, params)
+	if err != nil {
+		response.RespondWithErr(res, req, err)
+		return
+	}
+
+	email := strings.TrimSpace(params.Email)
+	if email == "" {
+		response.RespondWithError(res, req, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	rateLimitKey := strings.ToLower(email)
+
+	_, err = cfg.Db.CountMagicLinkRequest(req.Context(), database.CountMagicLinkRequestParams{
+		Email:               rateLimitKey,
+		WindowStartedBefore: time.Now().Add(-magicLinkWindow),
+		MaxRequests:         magicLinkLimit,
+	})
+	if err == sql.ErrNoRows {
+		res.Header().Set("Retry-After", strconv.Itoa(int(magicLinkWindow.Seconds())))
+		response.RespondWithError(res, req, http.StatusTooManyRequests, "too many login links requested, try again later")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = cfg.Db.DeleteMagicLinkRateLimitsBefore(req.Context(), time.Now().Add(-magicLinkWindow))
+	if err != nil {
+		log.Printf("Error deleting old magic link rate limits: %s", err)
+	}
+
+	fingerprint := auth.DeviceFingerprint(deviceSecret(res, req, baseURL), req.UserAgent())
+
+	user, err := cfg.Db.GetUserByEmail(req.Context(), email)
+	if err == sql.ErrNoRows {
+		res.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	token := auth.MakeRefreshToken()
+
+	err = cfg.Db.CreateMagicLinkToken(req.Context(), database.CreateMagicLinkTokenParams{
+		TokenHash:  auth.HashToken(token),
+		UserID:     user.ID,
+		DeviceHash: fingerprint,
+		ExpiresAt:  time.Now().Add(magicLinkExpiry),
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	link := baseURL + "/api/login/magic/verify?" + url.Values{"token": {token}}.Encode()
+	go sendMagicLink(cfg.Mailer, user.Email, link)
+
+	res.WriteHeader(http.StatusAccepted)
+}
+
+var VerifyMagicLinkOperation = openapi.Operation{
+	ID:          "verifyMagicLink",
+	Tag:         "Auth",
+	Summary:     "Log in with a login link",
+	Description: "Logs in with the token from an emailed link. Must be opened on the device that requested the link.",
+	Parameters: []openapi.Parameter{
+		openapi.RequiredQuery("token", "", openapi3.NewStringSchema()),
+	},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "Logged in, same body as a password login", userJSON{}),
+		openapi.Error(http.StatusUnauthorized, "Invalid, used or expired link, or opened on another device"),
+	},
+}
+
+// HandleVerifyMagicLink logs the user in with a link sent by
+// HandleRequestMagicLink. It only works once, before it expires, and from the
+// device it was requested from.
+func HandleVerifyMagicLink(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	token := req.URL.Query().Get("token")
+	cookie, err := req.Cookie(deviceCookie)
+	if token == "" || err != nil {
+		response.RespondWithError(res, req, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	res.Header().Set("Cache-Control", "no-store")
+	res.Header().Set("Referrer-Policy", "no-referrer")
+
+	user, err := cfg.Db.ConsumeMagicLinkToken(req.Context(), database.ConsumeMagicLinkTokenParams{
+		TokenHash:  auth.HashToken(token),
+		DeviceHash: auth.DeviceFingerprint(cookie.Value, req.UserAgent()),
+	})
+	if err == sql.ErrNoRows {
+		response.RespondWithError(res, req, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	respondWithSession(res, req, cfg, user)
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/database/dbtest"
+	"github.com/lucashthiele/chirpy/internal/mailer"
+)
+
+func TestDeviceSecretCookie(t *testing.T) {
+	cases := []struct {
+		baseURL    string
+		wantSecure bool
+	}{
+		{baseURL: "https://chirpy.example", wantSecure: true},
+		{baseURL: "http://localhost:8080", wantSecure: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.baseURL, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			secret := deviceSecret(rec, httptest.NewRequest(http.MethodPost, "/api/login/magic", nil), c.baseURL)
+
+			cookies := rec.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Value != secret {
+				t.Fatalf("cookies = %v, want the device secret", cookies)
+			}
+			if cookies[0].Secure != c.wantSecure {
+				t.Errorf("Secure = %v, want %v", cookies[0].Secure, c.wantSecure)
+			}
+		})
+	}
+}
+
+func TestHandleRequestMagicLinkRateLimit(t *testing.T) {
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	db, conn := dbtest.New(t, map[string]dbtest.Query{
+		// the limit was reached, the conditional insert didn't count the request
+		"CountMagicLinkRequest": func([]driver.Value) (dbtest.Result, error) { return dbtest.NoRows, nil },
+	})
+	previousDb, previousMailer, previousBaseURL := cfg.Db, cfg.Mailer, cfg.BaseURL
+	cfg.Db, cfg.Mailer, cfg.BaseURL = database.New(conn), mailer.LogMailer{}, "https://chirpy.example"
+	t.Cleanup(func() {
+		cfg.Db, cfg.Mailer, cfg.BaseURL = previousDb, previousMailer, previousBaseURL
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/login/magic", strings.NewReader(`{"email":"User@example.com"}`))
+	rec := httptest.NewRecorder()
+	HandleRequestMagicLink(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("status = %d, Retry-After = %q, want %d with a Retry-After", rec.Code, rec.Header().Get("Retry-After"), http.StatusTooManyRequests)
+	}
+	calls := db.Calls("CountMagicLinkRequest")
+	if len(calls) != 1 || calls[0].Args[0] != "user@example.com" || calls[0].Args[2] != int64(magicLinkLimit) {
+		t.Errorf("CountMagicLinkRequest calls = %v, want one for the lowercased email and the limit", calls)
+	}
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/oidc"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+const oidcStateCookie = "chirpy_oidc_state"
+const oidcLoginExpiry time.Duration = time.Minute * 10
+
+// unsetPassword matches the column default, no bcrypt hash can match it, so
+// users created from an external identity can't sign in with a password.
+const unsetPassword = "unset"
+
+// setStateCookie binds a sign in to the browser, a negative maxAge removes
+// the cookie. Browsers drop Secure cookies over plain HTTP, so it is only
+// Secure when the API is served over HTTPS.
+func setStateCookie(res http.ResponseWriter, state string, maxAge int, baseURL string) {
+	http.SetCookie(res, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(baseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+var providerParameter = openapi.Path("provider", "The provider name, as configured in OIDC_PROVIDERS.", openapi3.NewStringSchema())
+
+func getProvider(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig) (*oidc.Provider, bool) {
+	provider, ok := cfg.OIDCProviders[req.PathValue("provider")]
+	if !ok {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return nil, false
+	}
+
+	return provider, true
+}
+
+var OIDCLoginOperation = openapi.Operation{
+	ID:      "oidcLogin",
+	Tag:     "Auth",
+	Summary: "Sign in with an external provider",
+	Description: "Redirects to the OpenID Connect provider to sign in, using the authorization code flow with PKCE. " +
+		"The sign in has to be finished within 10 minutes, from the same browser.",
+	Parameters: []openapi.Parameter{providerParameter},
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusFound, "Redirect to the provider"),
+		openapi.Error(http.StatusNotFound, "Unknown provider"),
+	},
+}
+
+// HandleOIDCLogin sends the user to the provider to sign in. The state is
+// stored server side and bound to the browser with a cookie, so a callback
+// can't be replayed from somewhere else.
+func HandleOIDCLogin(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	provider, ok := getProvider(res, req, cfg)
+	if !ok {
+		return
+	}
+
+	state := auth.MakeRefreshToken()
+	nonce := auth.MakeRefreshToken()
+	codeVerifier := auth.MakeRefreshToken()
+
+	authURL, err := provider.AuthCodeURL(req.Context(), state, nonce, auth.MakePKCEChallenge(codeVerifier))
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	// abandoned sign ins are cleaned up as new ones start
+	err = cfg.Db.DeleteExpiredOIDCLoginStates(req.Context())
+	if err != nil {
+		log.Printf("Error deleting expired oidc login states: %s", err)
+	}
+
+	err = cfg.Db.CreateOIDCLoginState(req.Context(), database.CreateOIDCLoginStateParams{
+		StateHash:    auth.HashToken(state),
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(oidcLoginExpiry),
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	setStateCookie(res, state, int(oidcLoginExpiry.Seconds()), cfg.PublicURL(req))
+
+	http.Redirect(res, req, authURL, http.StatusFound)
+}
+
+// linkIdentity links a new external identity to the user with the same
+// verified email, creating the user when there is none.
+func linkIdentity(ctx context.Context, cfg *config.ApiConfig, provider string, claims *oidc.IDTokenClaims) (database.User, error) {
+	tx, err := cfg.DbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.User{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.Db.WithTx(tx)
+
+	_, err = qtx.GetUserByEmail(ctx, claims.Email)
+	if err == sql.ErrNoRows {
+		_, err = qtx.CreateUser(ctx, database.CreateUserParams{
+			Email:          claims.Email,
+			HashedPassword: unsetPassword,
+		})
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+
+	user, err := qtx.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	_, err = qtx.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return user, tx.Commit()
+}
+
+var OIDCCallbackOperation = openapi.Operation{
+	ID:      "oidcCallback",
+	Tag:     "Auth",
+	Summary: "Finish signing in with an external provider",
+	Description: "The provider sends the user back here. The ID token is checked against the provider's keys. An " +
+		"identity seen for the first time is linked to the user with the same verified email, and a new user is " +
+		"created when there is none. Users created this way have no password.",
+	Parameters: []openapi.Parameter{
+		providerParameter,
+		openapi.Query("code", "", openapi3.NewStringSchema()),
+		openapi.Query("state", "", openapi3.NewStringSchema()),
+		openapi.Query("error", "Set by the provider when the sign in was denied.", openapi3.NewStringSchema()),
+	},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "Signed in, same body as a password login", userJSON{}),
+		openapi.Error(http.StatusBadRequest, "Invalid or expired state"),
+		openapi.Error(http.StatusUnauthorized, "The provider denied the sign in or the ID token is invalid"),
+		openapi.Error(http.StatusForbidden, "The provider did not share a verified email"),
+		openapi.Error(http.StatusNotFound, "Unknown provider"),
+	},
+}
+
+func HandleOIDCCallback(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	provider, ok := getProvider(res, req, cfg)
+	if !ok {
+		return
+	}
+
+	query := req.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		response.RespondWithError(res, req, http.StatusUnauthorized, providerErr)
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := req.Cookie(oidcStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		response.RespondWithError(res, req, http.StatusBadRequest, "invalid state")
+		return
+	}
+
+	setStateCookie(res, "", -1, cfg.PublicURL(req))
+
+	loginState, err := cfg.Db.ConsumeOIDCLoginState(req.Context(), auth.HashToken(state))
+	if err == sql.ErrNoRows || (err == nil && loginState.Provider != provider.Name) {
+		response.RespondWithError(res, req, http.StatusBadRequest, "invalid state")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	idToken, err := provider.Exchange(req.Context(), query.Get("code"), loginState.CodeVerifier)
+	if err != nil {
+		log.Printf("Error exchanging %s code: %s", provider.Name, err)
+		response.RespondWithError(res, req, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	claims, err := provider.VerifyIDToken(req.Context(), idToken, loginState.Nonce)
+	if err != nil {
+		log.Printf("Error verifying %s id token: %s", provider.Name, err)
+		response.RespondWithError(res, req, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := cfg.Db.GetUserByIdentity(req.Context(), database.GetUserByIdentityParams{
+		Provider: provider.Name,
+		Subject:  claims.Subject,
+	})
+	if err == sql.ErrNoRows {
+		if claims.Email == "" || !claims.EmailVerified {
+			response.RespondWithError(res, req, http.StatusForbidden, "a verified email is required to sign in")
+			return
+		}
+
+		user, err = linkIdentity(req.Context(), cfg, provider.Name, claims)
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	respondWithSession(res, req, cfg, user)
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStateCookie(t *testing.T) {
+	cases := []struct {
+		baseURL    string
+		wantSecure bool
+	}{
+		{baseURL: "https://chirpy.example", wantSecure: true},
+		{baseURL: "http://localhost:8080", wantSecure: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.baseURL, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			setStateCookie(rec, "state", 600, c.baseURL)
+
+			cookies := rec.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Name != oidcStateCookie || cookies[0].Value != "state" {
+				t.Fatalf("cookies = %v, want the state", cookies)
+			}
+			if cookies[0].Secure != c.wantSecure {
+				t.Errorf("Secure = %v, want %v", cookies[0].Secure, c.wantSecure)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package chirps
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/activitypub"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/gateway"
+)
+
+const (
+	VisibilityPublic        = "public"
+	VisibilityFollowersOnly = "followers-only"
+	VisibilityMentionedOnly = "mentioned-only"
+	VisibilityUnlisted      = "unlisted"
+)
+
+const (
+	ReplyPolicyEveryone  = "everyone"
+	ReplyPolicyFollowing = "following"
+	ReplyPolicyMentioned = "mentioned"
+)
+
+const maxMentions = 10
+
+const (
+	notificationMentioned = "chirp.mentioned"
+	notificationReplied   = "chirp.replied"
+)
+
+var visibilities = []string{VisibilityPublic, VisibilityFollowersOnly, VisibilityMentionedOnly, VisibilityUnlisted}
+var replyPolicies = []string{ReplyPolicyEveryone, ReplyPolicyFollowing, ReplyPolicyMentioned}
+
+// validateAudience fills in the defaults and checks who the chirp is for. The
+// visibility itself is enforced by the queries every chirp is read with.
+func validateAudience(params *CreateParams, userId uuid.UUID) error {
+	if params.Visibility == "" {
+		params.Visibility = VisibilityPublic
+	}
+	if !slices.Contains(visibilities, params.Visibility) {
+		return fmt.Errorf("visibility must be one of %v", visibilities)
+	}
+
+	if params.ReplyPolicy == "" {
+		params.ReplyPolicy = ReplyPolicyEveryone
+	}
+	if !slices.Contains(replyPolicies, params.ReplyPolicy) {
+		return fmt.Errorf("reply_policy must be one of %v", replyPolicies)
+	}
+
+	mentions := []uuid.UUID{}
+	for _, mention := range params.Mentions {
+		if mention != userId && !slices.Contains(mentions, mention) {
+			mentions = append(mentions, mention)
+		}
+	}
+	if len(mentions) > maxMentions {
+		return fmt.Errorf("a chirp can mention at most %d users", maxMentions)
+	}
+	params.Mentions = mentions
+
+	return nil
+}
+
+// canReply tells if the user is allowed to reply to parent under its reply
+// policy. The author can always reply to their own chirps.
+func canReply(ctx context.Context, cfg *config.ApiConfig, parent database.Chirp, userId uuid.UUID) (bool, error) {
+	if parent.UserID == userId {
+		return true, nil
+	}
+
+	switch parent.ReplyPolicy {
+	case ReplyPolicyFollowing:
+		follow, err := cfg.Db.GetFollow(ctx, database.GetFollowParams{
+			FollowerID: parent.UserID,
+			FollowedID: userId,
+		})
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return follow.Status == "approved", nil
+	case ReplyPolicyMentioned:
+		return slices.Contains(parent.Mentions, userId), nil
+	default:
+		return true, nil
+	}
+}
+
+// notifyAudience tells the mentioned users and the author of the chirp being
+// replied to about the chirp on their gateway notifications channel, once the
+// transaction qtx is bound to commits. Users blocked either way are left out.
+func notifyAudience(ctx context.Context, qtx *database.Queries, cfg *config.ApiConfig, chirp database.Chirp, parentAuthorId uuid.UUID) error {
+	recipients := map[uuid.UUID]string{}
+	for _, mention := range chirp.Mentions {
+		recipients[mention] = notificationMentioned
+	}
+	if parentAuthorId != uuid.Nil && parentAuthorId != chirp.UserID {
+		recipients[parentAuthorId] = notificationReplied
+	}
+
+	for recipientId, notificationType := range recipients {
+		blocked, err := qtx.IsBlockedEitherWay(ctx, database.IsBlockedEitherWayParams{
+			BlockerID: chirp.UserID,
+			BlockedID: recipientId,
+		})
+		if err != nil {
+			return err
+		}
+		if blocked {
+			continue
+		}
+
+		message, err := gateway.NewMessage(gateway.ChannelNotifications, notificationType, toResponseData(chirp))
+		if err != nil {
+			return err
+		}
+
+		err = gateway.Notify(ctx, qtx, cfg.MessageKeys, []uuid.UUID{recipientId}, message)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// federateChirp sends the chirp, or its deletion, to the remote followers of
+// its author, once the transaction qtx is bound to commits. Nothing federates
+// until the instance knows its public URL.
+func federateChirp(ctx context.Context, qtx *database.Queries, cfg *config.ApiConfig, chirp database.Chirp, deleted bool) error {
+	if cfg.BaseURL == "" || !activitypub.Federates(chirp.Visibility) {
+		return nil
+	}
+
+	if deleted {
+		activity, err := activitypub.NewDelete(cfg.BaseURL, chirp)
+		if err != nil {
+			return err
+		}
+		return activitypub.Enqueue(ctx, qtx, cfg.BaseURL, chirp.UserID, activity)
+	}
+
+	author, err := qtx.GetUserByID(ctx, chirp.UserID)
+	if err != nil {
+		return err
+	}
+
+	activity, err := activitypub.NewCreate(cfg.BaseURL, chirp, author.IsPrivate)
+	if err != nil {
+		return err
+	}
+	return activitypub.Enqueue(ctx, qtx, cfg.BaseURL, chirp.UserID, activity)
+}
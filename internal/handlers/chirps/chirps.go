@@ -1,103 +1,266 @@
 package chirps
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"slices"
-	"strings"
 	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/google/uuid"
 	"github.com/lucashthiele/chirpy/internal/config"
 	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/moderation"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/outbound"
+	"github.com/lucashthiele/chirpy/internal/pagination"
+	"github.com/lucashthiele/chirpy/internal/stream"
 	"github.com/lucashthiele/chirpy/pkg/parser"
 	"github.com/lucashthiele/chirpy/pkg/response"
 )
 
-type params struct {
-	Body string `json:"body"`
+// CreateParams is what a chirp is created from.
+type CreateParams struct {
+	Body        string      `json:"body" description:"The chirp message, at most 140 bytes."`
+	Visibility  string      `json:"visibility" enum:"public,followers-only,mentioned-only,unlisted" description:"Who can see the chirp, public when it's empty. followers-only chirps are only visible to approved followers, mentioned-only chirps to the mentioned users, and unlisted chirps are visible to everyone but only listed when filtering by their author."`
+	ReplyPolicy string      `json:"reply_policy" enum:"everyone,following,mentioned" description:"Who can reply, everyone when it's empty: everyone who can see the chirp, only users you follow, or only the mentioned users. You can always reply to your own chirps."`
+	Mentions    []uuid.UUID `json:"mentions" description:"IDs of the users the chirp mentions, at most 10."`
+	ReplyToId   *uuid.UUID  `json:"reply_to_id" description:"The chirp this one replies to. It has to be visible to you and its reply policy has to allow you to reply."`
 }
 
 type responseData struct {
-	Id        string    `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserId    string    `json:"user_id"`
+	Id          string      `json:"id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	Body        string      `json:"body"`
+	UserId      string      `json:"user_id"`
+	Visibility  string      `json:"visibility" enum:"public,followers-only,mentioned-only,unlisted"`
+	ReplyPolicy string      `json:"reply_policy" enum:"everyone,following,mentioned"`
+	Mentions    []uuid.UUID `json:"mentions"`
+	ReplyToId   *string     `json:"reply_to_id"`
 }
 
-func validateChirp(str string) error {
-	if len(str) > 140 {
-		return fmt.Errorf("chirp is too long")
+func toResponseData(chirp database.Chirp) responseData {
+	data := responseData{
+		Id:          chirp.ID.String(),
+		CreatedAt:   chirp.CreatedAt,
+		UpdatedAt:   chirp.UpdatedAt,
+		Body:        chirp.Body,
+		UserId:      chirp.UserID.String(),
+		Visibility:  chirp.Visibility,
+		ReplyPolicy: chirp.ReplyPolicy,
+		Mentions:    chirp.Mentions,
+	}
+	if chirp.ReplyToID.Valid {
+		replyToId := chirp.ReplyToID.UUID.String()
+		data.ReplyToId = &replyToId
 	}
 
-	return nil
+	return data
 }
 
-func removeProfaneWords(str string) string {
-	badWords := map[string]string{
-		"kerfuffle": "****",
-		"sharbert":  "****",
-		"fornax":    "****",
+// notifyChirpEvent streams the chirp to the clients allowed to see it, once
+// the transaction qtx is bound to commits.
+func notifyChirpEvent(ctx context.Context, qtx *database.Queries, eventType string, chirp database.Chirp) error {
+	encoded, err := json.Marshal(toResponseData(chirp))
+	if err != nil {
+		return err
 	}
 
-	splittedWords := strings.Split(str, " ")
-	for i, word := range splittedWords {
-		if replace, found := badWords[strings.ToLower(word)]; found {
-			splittedWords[i] = replace
-		}
-	}
+	return stream.Notify(ctx, qtx, eventType, stream.ChirpEvent{
+		AuthorID:   chirp.UserID,
+		Visibility: chirp.Visibility,
+		Mentions:   chirp.Mentions,
+		Chirp:      encoded,
+	})
+}
 
-	return strings.Join(splittedWords, " ")
+// maxChirpLength is the longest a chirp can be, in bytes.
+const maxChirpLength = 140
+
+// Error is a chirp operation the client got wrong, with the status the REST
+// API answers it with. Other transports map the status to their own codes.
+type Error struct {
+	Status  int
+	Message string
 }
 
-func HandleCreateChirp(res http.ResponseWriter, req *http.Request) {
-	cfg, err := config.New()
-	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// respondWithChirpError answers with the status of an *Error, or 500 for any
+// other error.
+func respondWithChirpError(res http.ResponseWriter, req *http.Request, err error) {
+	chirpErr, ok := err.(*Error)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, err)
+		return
 	}
+	response.RespondWithError(res, req, chirpErr.Status, chirpErr.Message)
+}
 
-	params := &params{}
+var CreateChirpOperation = openapi.Operation{
+	ID:          "createChirp",
+	Tag:         "Chirps",
+	Summary:     "Create a chirp",
+	Description: "Validates a chirp (message) and creates it. Requires authentication.",
+	Security:    openapi.Authenticated,
+	Parameters:  []openapi.Parameter{openapi.IdempotencyKey},
+	Request:     &openapi.Request{Type: CreateParams{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusCreated, "Chirp created", responseData{}),
+		openapi.Error(http.StatusBadRequest, "Invalid request or chirp"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the chirps:write scope, a mentioned user is blocked either way, or the reply policy of the chirp you're replying to doesn't allow you to reply"),
+		openapi.IdempotencyKeyInUse,
+		openapi.IdempotencyKeyReused,
+	},
+}
 
-	err = parser.ParseBody(req.Body, params)
+func HandleCreateChirp(res http.ResponseWriter, req *http.Request) {
+	params := &CreateParams{}
+
+	err := parser.ParseBody(res, req, params)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithErr(res, req, err)
+		return
 	}
 
 	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
 	if !ok {
-		response.RespondWithInternalServerError(res, fmt.Errorf("omg you're so bad at this"))
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
 	}
 
-	err = validateChirp(params.Body)
+	createdChirp, err := CreateChirp(req.Context(), userId, params)
 	if err != nil {
-		response.RespondWithError(res, http.StatusBadRequest, err.Error())
+		respondWithChirpError(res, req, err)
 		return
 	}
 
-	cleanedBody := removeProfaneWords(params.Body)
+	response.RespondWithJSON(res, http.StatusCreated, toResponseData(createdChirp))
+}
+
+// CreateChirp posts a chirp as the user, along with its webhooks, stream
+// events, notifications and federated activities.
+func CreateChirp(ctx context.Context, userId uuid.UUID, params *CreateParams) (database.Chirp, error) {
+	cfg, err := config.New()
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	cleanedBody, err := moderation.Moderate(params.Body, maxChirpLength)
+	if err == moderation.ErrTooLong {
+		return database.Chirp{}, &Error{Status: http.StatusBadRequest, Message: "chirp is too long"}
+	}
+	if err != nil {
+		return database.Chirp{}, &Error{Status: http.StatusBadRequest, Message: err.Error()}
+	}
+
+	err = validateAudience(params, userId)
+	if err != nil {
+		return database.Chirp{}, &Error{Status: http.StatusBadRequest, Message: err.Error()}
+	}
+
+	if len(params.Mentions) > 0 {
+		found, err := cfg.Db.CountExistingUsers(ctx, params.Mentions)
+		if err != nil {
+			return database.Chirp{}, err
+		}
+		if found != int64(len(params.Mentions)) {
+			return database.Chirp{}, &Error{Status: http.StatusBadRequest, Message: "mentioned user not found"}
+		}
+
+		blocked, err := cfg.Db.IsBlockedWithAny(ctx, database.IsBlockedWithAnyParams{
+			UserID:   userId,
+			OtherIds: params.Mentions,
+		})
+		if err != nil {
+			return database.Chirp{}, err
+		}
+		if blocked {
+			return database.Chirp{}, &Error{Status: http.StatusForbidden, Message: "you can't mention this user"}
+		}
+	}
+
+	replyToId := uuid.NullUUID{}
+	parentAuthorId := uuid.Nil
+	if params.ReplyToId != nil {
+		parent, err := cfg.Db.GetVisibleChirpByID(ctx, database.GetVisibleChirpByIDParams{
+			ID:       *params.ReplyToId,
+			ViewerID: userId,
+		})
+		if err == sql.ErrNoRows {
+			return database.Chirp{}, &Error{Status: http.StatusBadRequest, Message: "the chirp you're replying to doesn't exist"}
+		}
+		if err != nil {
+			return database.Chirp{}, err
+		}
+
+		allowed, err := canReply(ctx, cfg, parent, userId)
+		if err != nil {
+			return database.Chirp{}, err
+		}
+		if !allowed {
+			return database.Chirp{}, &Error{Status: http.StatusForbidden, Message: "you can't reply to this chirp"}
+		}
+
+		replyToId = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		parentAuthorId = parent.UserID
+	}
 
 	chirp := database.CreateChirpParams{
-		Body:   cleanedBody,
-		UserID: userId,
+		Body:        cleanedBody,
+		UserID:      userId,
+		Visibility:  params.Visibility,
+		ReplyPolicy: params.ReplyPolicy,
+		Mentions:    params.Mentions,
+		ReplyToID:   replyToId,
 	}
 
-	createdChirp, err := cfg.Db.CreateChirp(req.Context(), chirp)
+	tx, err := cfg.DbConn.BeginTx(ctx, nil)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
-		return
+		return database.Chirp{}, err
 	}
+	defer tx.Rollback()
 
-	bodyResp := responseData{
-		Id:        createdChirp.ID.String(),
-		CreatedAt: createdChirp.CreatedAt,
-		UpdatedAt: createdChirp.UpdatedAt,
-		Body:      createdChirp.Body,
-		UserId:    createdChirp.UserID.String(),
+	qtx := cfg.Db.WithTx(tx)
+
+	createdChirp, err := qtx.CreateChirp(ctx, chirp)
+	if err != nil {
+		return database.Chirp{}, err
 	}
 
-	response.RespondWithJSON(res, http.StatusCreated, bodyResp)
+	err = outbound.Enqueue(ctx, qtx, createdChirp.UserID, outbound.EventChirpCreated, toResponseData(createdChirp))
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	err = notifyChirpEvent(ctx, qtx, outbound.EventChirpCreated, createdChirp)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	err = notifyAudience(ctx, qtx, cfg, createdChirp, parentAuthorId)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	err = federateChirp(ctx, qtx, cfg, createdChirp, false)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	return createdChirp, nil
 }
 
 func getAuthorIDQueryParam(req *http.Request) (uuid.UUID, error) {
@@ -115,6 +278,14 @@ func getAuthorIDQueryParam(req *http.Request) (uuid.UUID, error) {
 	return authorUUID, nil
 }
 
+func getHashtagQueryParam(req *http.Request) (string, error) {
+	hashtag := req.URL.Query().Get("hashtag")
+	if hashtag == "" {
+		return "", nil
+	}
+	return moderation.ParseHashtag(hashtag)
+}
+
 func getSortByQueryParam(req *http.Request) string {
 	sort := req.URL.Query().Get("sort")
 	if sort == "" {
@@ -123,24 +294,69 @@ func getSortByQueryParam(req *http.Request) string {
 	return sort
 }
 
+var GetAllChirpsOperation = openapi.Operation{
+	ID:      "getAllChirps",
+	Tag:     "Chirps",
+	Summary: "Get all chirps.",
+	Description: "List all chirps. Order by created at. Optionally filter by author_id or hashtag. Supports sorting. " +
+		"Authentication is optional. Authenticated users don't see chirps of users they blocked or who blocked them, " +
+		"nor chirps of users they muted unless filtering by that author. Chirps of private accounts are only listed for " +
+		"their approved followers, chirps are only listed for the audience of their visibility, and unlisted chirps only " +
+		"when filtering by their author.",
+	Security: openapi.OptionallyAuthenticated,
+	Parameters: append([]openapi.Parameter{
+		openapi.Query("author_id", "Filter chirps by the author's user ID", openapi3.NewUUIDSchema()),
+		openapi.Query("sort", "Sort order for chirps by created_at.", openapi3.NewStringSchema().WithEnum("asc", "desc").WithDefault("asc")),
+		openapi.Query("hashtag", "Only list chirps with this hashtag in their body, with or without the #.", openapi3.NewStringSchema()),
+	}, pagination.Parameters("The cursor from the Link header of the previous page. Asking for a cursor or limit lists the chirps in pages, newest first.")...),
+	Responses: []openapi.Response{
+		{
+			Status:      http.StatusOK,
+			Description: "List of chirps, or a page of them when cursor or limit is set",
+			Type:        []responseData{},
+			Headers:     pagination.LinkHeader,
+		},
+		openapi.Error(http.StatusBadRequest, "Invalid author_id, hashtag, cursor or limit, or sort=asc with pages"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the chirps:read scope"),
+	},
+}
+
 func HandleGetAllChirps(res http.ResponseWriter, req *http.Request) {
 	cfg, err := config.New()
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithInternalServerError(res, req, err)
 		return
 	}
 
 	authorId, err := getAuthorIDQueryParam(req)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithError(res, req, http.StatusBadRequest, "invalid author_id")
+		return
+	}
+
+	hashtag, err := getHashtagQueryParam(req)
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := req.URL.Query()
+	if query.Has("cursor") || query.Has("limit") {
+		respondWithChirpsPage(res, req, cfg, authorId, hashtag)
 		return
 	}
 
 	sort := getSortByQueryParam(req)
 
-	chirps, err := cfg.Db.ListAllChirps(req.Context(), authorId)
+	chirps, err := cfg.Db.ListVisibleChirps(req.Context(), database.ListVisibleChirpsParams{
+		AuthorID: authorId,
+		ViewerID: config.ViewerID(req.Context()),
+		Hashtag:  hashtag,
+	})
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithInternalServerError(res, req, err)
+		return
 	}
 
 	if sort == "desc" { // chirps come pre-ordered with asc from db
@@ -152,92 +368,195 @@ func HandleGetAllChirps(res http.ResponseWriter, req *http.Request) {
 	bodyResp := make([]responseData, len(chirps))
 
 	for i, chirp := range chirps {
-		bodyResp[i] = responseData{
-			Id:        chirp.ID.String(),
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserId:    chirp.UserID.String(),
-		}
+		bodyResp[i] = toResponseData(chirp)
 	}
 
-	response.RespondWithJSON(res, http.StatusOK, bodyResp)
+	response.RespondWithList(res, req, bodyResp, nil)
+}
+
+// respondWithChirpsPage answers GET /api/chirps with a page of chirps, newest
+// first, when the client asked for pages with cursor or limit.
+func respondWithChirpsPage(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig, authorId uuid.UUID, hashtag string) {
+	if req.URL.Query().Get("sort") == "asc" {
+		response.RespondWithError(res, req, http.StatusBadRequest, "pages are sorted newest first, sort=asc can't be used with cursor or limit")
+		return
+	}
+
+	cursor, limit, err := pagination.FromRequest(req)
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	chirps, err := cfg.Db.ListVisibleChirpsPage(req.Context(), database.ListVisibleChirpsPageParams{
+		AuthorID:   authorId,
+		ViewerID:   config.ViewerID(req.Context()),
+		Hashtag:    hashtag,
+		BeforeTime: cursor.Time,
+		BeforeID:   cursor.ID,
+		RowLimit:   int32(limit),
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	bodyResp := make([]responseData, len(chirps))
+	for i, chirp := range chirps {
+		bodyResp[i] = toResponseData(chirp)
+	}
+
+	page := &response.Page{Limit: limit}
+	if len(chirps) > 0 {
+		last := chirps[len(chirps)-1]
+		page = pagination.SetNextLink(res, req, pagination.Cursor{Time: last.CreatedAt, ID: last.ID}, len(chirps), limit)
+	}
+
+	response.RespondWithList(res, req, bodyResp, page)
+}
+
+var GetChirpByIDOperation = openapi.Operation{
+	ID:      "getChirpById",
+	Tag:     "Chirps",
+	Summary: "Get a chirp.",
+	Description: "Get a single chirp by its ID. Authentication is optional, chirps between blocked users are not found, " +
+		"and neither are chirps of private accounts unless you are an approved follower, nor chirps whose visibility " +
+		"doesn't include you.",
+	Security:   openapi.OptionallyAuthenticated,
+	Parameters: []openapi.Parameter{openapi.Path("chirpID", "The ID of the chirp.", openapi3.NewStringSchema())},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "The chirp.", responseData{}),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the chirps:read scope"),
+		openapi.Error(http.StatusNotFound, "Chirp not found"),
+	},
 }
 
 func HandleGetChirpByID(res http.ResponseWriter, req *http.Request) {
 	cfg, err := config.New()
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithInternalServerError(res, req, err)
+		return
 	}
 
 	chirpID := req.PathValue("chirpID")
 
 	chirpUUID, err := uuid.Parse(chirpID)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
 	}
 
-	chirp, err := cfg.Db.GetChirpByID(req.Context(), chirpUUID)
+	chirp, err := cfg.Db.GetVisibleChirpByID(req.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpUUID,
+		ViewerID: config.ViewerID(req.Context()),
+	})
 	if err == sql.ErrNoRows {
-		response.RespondWithError(res, http.StatusNotFound, "Not found")
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
 		return
 	}
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithInternalServerError(res, req, err)
 		return
 	}
 
-	bodyResp := responseData{
-		Id:        chirp.ID.String(),
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserId:    chirp.UserID.String(),
-	}
+	bodyResp := toResponseData(chirp)
 
 	response.RespondWithJSON(res, http.StatusOK, bodyResp)
 }
 
-func HandleDeleteChirp(res http.ResponseWriter, req *http.Request) {
-	cfg, err := config.New()
-	if err != nil {
-		response.RespondWithInternalServerError(res, err)
-	}
+var DeleteChirpOperation = openapi.Operation{
+	ID:          "deleteChirpById",
+	Tag:         "Chirps",
+	Summary:     "Delete a chirp",
+	Description: "Deletes a chirp by its ID. Requires authentication.",
+	Security:    openapi.Authenticated,
+	Parameters:  []openapi.Parameter{openapi.Path("chirpID", "The ID of the chirp.", openapi3.NewStringSchema())},
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusNoContent, "Chirp deleted"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the chirps:write scope, or the chirp isn't yours"),
+		openapi.Error(http.StatusNotFound, "Chirp not found"),
+	},
+}
 
+func HandleDeleteChirp(res http.ResponseWriter, req *http.Request) {
 	chirpID := req.PathValue("chirpID")
 
 	chirpUUID, err := uuid.Parse(chirpID)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
 	}
 
-	chirp, err := cfg.Db.GetChirpByID(req.Context(), chirpUUID)
-	if err == sql.ErrNoRows {
-		response.RespondWithError(res, http.StatusNotFound, "Not found")
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
 		return
 	}
+
+	err = DeleteChirp(req.Context(), userId, chirpUUID)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		respondWithChirpError(res, req, err)
 		return
 	}
 
-	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
-	if !ok {
-		response.RespondWithInternalServerError(res, fmt.Errorf("omg you're so bad at this"))
-		return
+	response.RespondWithJSON(res, http.StatusNoContent, "")
+
+}
+
+// DeleteChirp deletes a chirp of the user, along with its webhooks, stream
+// events and federated activities.
+func DeleteChirp(ctx context.Context, userId uuid.UUID, chirpId uuid.UUID) error {
+	cfg, err := config.New()
+	if err != nil {
+		return err
+	}
+
+	// chirps the user isn't allowed to see are not found rather than forbidden,
+	// so private accounts don't give away that they exist
+	chirp, err := cfg.Db.GetVisibleChirpByID(ctx, database.GetVisibleChirpByIDParams{
+		ID:       chirpId,
+		ViewerID: userId,
+	})
+	if err == sql.ErrNoRows {
+		return &Error{Status: http.StatusNotFound, Message: "Not found"}
+	}
+	if err != nil {
+		return err
 	}
 
 	if chirp.UserID != userId {
-		response.RespondWithError(res, http.StatusForbidden, "Forbidden")
-		return
+		return &Error{Status: http.StatusForbidden, Message: "Forbidden"}
 	}
 
-	err = cfg.Db.DeleteChirpByID(req.Context(), chirp.ID)
+	tx, err := cfg.DbConn.BeginTx(ctx, nil)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
-		return
+		return err
 	}
+	defer tx.Rollback()
 
-	response.RespondWithJSON(res, http.StatusNoContent, "")
+	qtx := cfg.Db.WithTx(tx)
+
+	err = qtx.DeleteChirpByID(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	err = outbound.Enqueue(ctx, qtx, chirp.UserID, outbound.EventChirpDeleted, toResponseData(chirp))
+	if err != nil {
+		return err
+	}
+
+	err = notifyChirpEvent(ctx, qtx, outbound.EventChirpDeleted, chirp)
+	if err != nil {
+		return err
+	}
+
+	err = federateChirp(ctx, qtx, cfg, chirp, true)
+	if err != nil {
+		return err
+	}
 
+	return tx.Commit()
 }
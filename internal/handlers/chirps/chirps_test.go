@@ -0,0 +1,111 @@
+package chirps
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/database/dbtest"
+)
+
+func TestCreateChirpRejectsBlockedMentions(t *testing.T) {
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	db, conn := dbtest.New(t, map[string]dbtest.Query{
+		"CountExistingUsers": func([]driver.Value) (dbtest.Result, error) { return dbtest.Row(int64(1)), nil },
+		"IsBlockedWithAny":   func([]driver.Value) (dbtest.Result, error) { return dbtest.Row(true), nil },
+	})
+	previousDb, previousConn := cfg.Db, cfg.DbConn
+	cfg.Db, cfg.DbConn = database.New(conn), conn
+	t.Cleanup(func() {
+		cfg.Db, cfg.DbConn = previousDb, previousConn
+	})
+
+	_, err = CreateChirp(context.Background(), uuid.New(), &CreateParams{
+		Body:     "Hello there",
+		Mentions: []uuid.UUID{uuid.New()},
+	})
+
+	chirpErr := &Error{}
+	if !errors.As(err, &chirpErr) || chirpErr.Status != http.StatusForbidden {
+		t.Fatalf("CreateChirp() error = %v, want a %d", err, http.StatusForbidden)
+	}
+	if len(db.Calls("CreateChirp")) != 0 {
+		t.Errorf("the chirp was created")
+	}
+}
+
+func TestValidateAudienceLimitsMentions(t *testing.T) {
+	userId := uuid.New()
+	mentions := func(n int) []uuid.UUID {
+		ids := make([]uuid.UUID, n)
+		for i := range ids {
+			ids[i] = uuid.New()
+		}
+		return ids
+	}
+
+	cases := []struct {
+		name     string
+		mentions []uuid.UUID
+		wantErr  bool
+	}{
+		{name: "at the limit", mentions: mentions(maxMentions)},
+		{name: "over the limit", mentions: mentions(maxMentions + 1), wantErr: true},
+		{name: "the author and duplicates don't count", mentions: append(mentions(maxMentions), userId, userId)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateAudience(&CreateParams{Mentions: c.mentions}, userId)
+			if (err != nil) != c.wantErr {
+				t.Errorf("validateAudience() error = %v, want an error: %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandleUserFeedOnlyReadsTheFeedSize(t *testing.T) {
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	userId := uuid.New()
+	db, conn := dbtest.New(t, map[string]dbtest.Query{
+		"GetUserByID": func([]driver.Value) (dbtest.Result, error) {
+			now := time.Now()
+			return dbtest.Row(userId.String(), now, now, "user@example.com", "hash", false), nil
+		},
+		"ListVisibleChirpsPage": func([]driver.Value) (dbtest.Result, error) { return dbtest.Result{}, nil },
+	})
+	previousDb, previousConn := cfg.Db, cfg.DbConn
+	cfg.Db, cfg.DbConn = database.New(conn), conn
+	t.Cleanup(func() {
+		cfg.Db, cfg.DbConn = previousDb, previousConn
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/"+userId.String()+"/feed.rss", nil)
+	req.SetPathValue("userID", userId.String())
+	req.SetPathValue("feed", "feed.rss")
+	rec := httptest.NewRecorder()
+	HandleUserFeed(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	pages := db.Calls("ListVisibleChirpsPage")
+	if len(pages) != 1 || pages[0].Args[6] != int64(feedSize) {
+		t.Errorf("ListVisibleChirpsPage calls = %v, want one limited to %d chirps", pages, feedSize)
+	}
+}
@@ -0,0 +1,229 @@
+package chirps
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/feed"
+	"github.com/lucashthiele/chirpy/internal/moderation"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/pagination"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+// feedSize is how many of the latest chirps a feed has.
+const feedSize = 50
+
+// feedMaxAge is how long feed readers and proxies can cache a feed, in
+// seconds.
+const feedMaxAge = 300
+
+// feedParameters are the path parameter of the feed file and the
+// conditional request headers of the feeds.
+var feedParameters = []openapi.Parameter{
+	openapi.Path("feed", "The feed file, whose extension is the format.", openapi3.NewStringSchema().WithEnum("feed.rss", "feed.atom", "feed.json")),
+	openapi.Header("If-None-Match", "The ETag of the copy of the feed you have.", openapi3.NewStringSchema()),
+	openapi.Header("If-Modified-Since", "The Last-Modified date of the copy of the feed you have, ignored with If-None-Match.", openapi3.NewStringSchema()),
+}
+
+// feedResponse is a feed in any format, whose body isn't checked.
+var feedResponse = openapi.Response{
+	Status:      http.StatusOK,
+	Description: "The latest 50 chirps, newest first, as RSS 2.0, Atom 1.0 or JSON Feed 1.1. Feeds can be cached for 5 minutes.",
+	Content: map[string]*openapi3.Schema{
+		"application/rss+xml":   nil,
+		"application/atom+xml":  nil,
+		"application/feed+json": nil,
+	},
+	Headers: map[string]string{
+		"ETag":          "The version of the feed, for If-None-Match.",
+		"Last-Modified": "When the latest chirp of the feed was updated, for If-Modified-Since.",
+	},
+}
+
+var UserFeedOperation = openapi.Operation{
+	ID:          "getUserFeed",
+	Tag:         "Feeds",
+	Summary:     "Get the feed of a user",
+	Description: "The public chirps of a user as a feed for ordinary feed readers. Only the chirps anyone could see are in it.",
+	Parameters:  feedParameters,
+	Responses: []openapi.Response{
+		feedResponse,
+		openapi.Empty(http.StatusNotModified, "Your copy of the feed is still fresh."),
+		openapi.Error(http.StatusNotFound, "User or feed not found"),
+	},
+}
+
+// HandleUserFeed serves the latest chirps of the user anyone can see as an
+// RSS, Atom or JSON feed, depending on the feed file in the path.
+func HandleUserFeed(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	user, err := cfg.Db.GetUserByID(req.Context(), userId)
+	if err == sql.ErrNoRows {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	// feeds are for people outside the app, so they only have public chirps
+	start := pagination.Start()
+	chirps, err := cfg.Db.ListVisibleChirpsPage(req.Context(), database.ListVisibleChirpsPageParams{
+		AuthorID:   userId,
+		ViewerID:   uuid.Nil,
+		BeforeTime: start.Time,
+		BeforeID:   start.ID,
+		RowLimit:   feedSize,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	baseURL := cfg.PublicURL(req)
+	serveFeed(res, req, baseURL, feed.Feed{
+		Title:       "Chirps by " + userId.String(),
+		Description: "The latest chirps by " + userId.String() + " on Chirpy",
+		HomeURL:     baseURL + "/api/chirps?author_id=" + userId.String(),
+		Author:      feedAuthor(baseURL, userId),
+		Updated:     user.CreatedAt.Time,
+	}, chirps)
+}
+
+var HashtagFeedOperation = openapi.Operation{
+	ID:          "getHashtagFeed",
+	Tag:         "Feeds",
+	Summary:     "Get the feed of a hashtag",
+	Description: "The public chirps with a hashtag as a feed for ordinary feed readers. Only the chirps anyone could see are in it.",
+	Parameters: append([]openapi.Parameter{
+		openapi.Path("hashtag", "The hashtag, without the #.", openapi3.NewStringSchema()),
+	}, feedParameters...),
+	Responses: []openapi.Response{
+		feedResponse,
+		openapi.Empty(http.StatusNotModified, "Your copy of the feed is still fresh."),
+		openapi.Error(http.StatusNotFound, "Invalid hashtag or feed not found"),
+	},
+}
+
+// HandleHashtagFeed serves the latest chirps with the hashtag anyone can see
+// as an RSS, Atom or JSON feed, depending on the feed file in the path.
+func HandleHashtagFeed(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	hashtag, err := moderation.ParseHashtag(req.PathValue("hashtag"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	start := pagination.Start()
+	chirps, err := cfg.Db.ListVisibleChirpsPage(req.Context(), database.ListVisibleChirpsPageParams{
+		AuthorID:   uuid.Nil,
+		ViewerID:   uuid.Nil,
+		Hashtag:    hashtag,
+		BeforeTime: start.Time,
+		BeforeID:   start.ID,
+		RowLimit:   feedSize,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	baseURL := cfg.PublicURL(req)
+	serveFeed(res, req, baseURL, feed.Feed{
+		Title:       "#" + hashtag,
+		Description: "The latest chirps with #" + hashtag + " on Chirpy",
+		HomeURL:     baseURL + "/api/chirps?hashtag=" + hashtag,
+		Author:      feed.Author{Name: "Chirpy", URL: baseURL},
+		// an empty feed never changes
+		Updated: time.Unix(0, 0),
+	}, chirps)
+}
+
+func feedAuthor(baseURL string, userId uuid.UUID) feed.Author {
+	return feed.Author{
+		Name: userId.String(),
+		URL:  baseURL + "/api/users/" + userId.String() + "/feed.atom",
+	}
+}
+
+// serveFeed adds the latest chirps to f and renders it in the format of the
+// feed file in the path. It answers with 304 Not Modified when the client's
+// copy is fresh.
+func serveFeed(res http.ResponseWriter, req *http.Request, baseURL string, f feed.Feed, chirps []database.Chirp) {
+	var render func(feed.Feed) ([]byte, error)
+	contentType := ""
+	switch req.PathValue("feed") {
+	case "feed.rss":
+		render, contentType = feed.RSS, feed.ContentTypeRSS
+	case "feed.atom":
+		render, contentType = feed.Atom, feed.ContentTypeAtom
+	case "feed.json":
+		render, contentType = feed.JSON, feed.ContentTypeJSON
+	default:
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	f.FeedURL = baseURL + req.URL.Path
+	f.Items = make([]feed.Item, len(chirps))
+	for i, chirp := range chirps {
+		chirpURL := baseURL + "/api/chirps/" + chirp.ID.String()
+		f.Items[i] = feed.Item{
+			ID:        chirpURL,
+			URL:       chirpURL,
+			Title:     chirp.Body,
+			Content:   chirp.Body,
+			Author:    feedAuthor(baseURL, chirp.UserID),
+			Published: chirp.CreatedAt,
+			Updated:   chirp.UpdatedAt,
+		}
+		if chirp.UpdatedAt.After(f.Updated) {
+			f.Updated = chirp.UpdatedAt
+		}
+	}
+
+	body, err := render(f)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	etag := feed.ETag(body)
+	res.Header().Set("ETag", etag)
+	res.Header().Set("Last-Modified", f.Updated.UTC().Format(http.TimeFormat))
+	res.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", feedMaxAge))
+
+	if feed.NotModified(req, etag, f.Updated) {
+		res.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	res.Header().Set("Content-Type", contentType)
+	res.WriteHeader(http.StatusOK)
+	res.Write(body)
+}
@@ -0,0 +1,452 @@
+package conversations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/gateway"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/pagination"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+// maxMembers is how many users, the creator included, a group conversation
+// can have.
+const maxMembers = 8
+
+type createParams struct {
+	ParticipantIds []uuid.UUID `json:"participant_ids" validate:"required"`
+}
+
+type memberJSON struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+type conversationJSON struct {
+	ID          uuid.UUID    `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	IsGroup     bool         `json:"is_group"`
+	Members     []memberJSON `json:"members"`
+	UnreadCount int64        `json:"unread_count"`
+}
+
+type unreadJSON struct {
+	UnreadCount int64 `json:"unread_count"`
+}
+
+type readJSON struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	LastReadAt     time.Time `json:"last_read_at"`
+}
+
+// directKey identifies the 1:1 conversation between two users, whichever of
+// them started it.
+func directKey(userId, otherId uuid.UUID) string {
+	ids := []string{userId.String(), otherId.String()}
+	slices.Sort(ids)
+	return strings.Join(ids, ":")
+}
+
+func toMembersJSON(members []database.ConversationMember) map[uuid.UUID][]memberJSON {
+	byConversation := map[uuid.UUID][]memberJSON{}
+	for _, member := range members {
+		memberResp := memberJSON{
+			UserID:   member.UserID,
+			JoinedAt: member.JoinedAt,
+		}
+		if member.LastReadAt.Valid {
+			memberResp.LastReadAt = &member.LastReadAt.Time
+		}
+		byConversation[member.ConversationID] = append(byConversation[member.ConversationID], memberResp)
+	}
+
+	return byConversation
+}
+
+var locationHeader = map[string]string{"Location": "The URL of the messages of the conversation"}
+
+var CreateConversationOperation = openapi.Operation{
+	ID:      "createConversation",
+	Tag:     "Messages",
+	Summary: "Start a conversation",
+	Description: "Starts a 1:1 conversation with one participant, or a group conversation with up to 7. There is only one " +
+		"1:1 conversation between two users, starting it again returns the existing one. Users who blocked each other " +
+		"can't start a conversation.",
+	Security: openapi.Authenticated,
+	Request:  &openapi.Request{Type: createParams{}},
+	Responses: []openapi.Response{
+		{Status: http.StatusOK, Description: "The existing 1:1 conversation", Type: conversationJSON{}, Headers: locationHeader},
+		{Status: http.StatusCreated, Description: "Conversation started", Type: conversationJSON{}, Headers: locationHeader},
+		openapi.Error(http.StatusBadRequest, "No participants, too many or unknown participants"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the messages:write scope, or blocked"),
+	},
+}
+
+// HandleCreateConversation starts a conversation with the participants. There
+// is only one 1:1 conversation between two users, asking for it again returns
+// the existing one.
+func HandleCreateConversation(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	data := &createParams{}
+
+	err = parser.ParseBody(res, req, data)
+	if err != nil {
+		response.RespondWithErr(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	participants := []uuid.UUID{}
+	for _, participantId := range data.ParticipantIds {
+		if participantId != userId && !slices.Contains(participants, participantId) {
+			participants = append(participants, participantId)
+		}
+	}
+	if len(participants) == 0 {
+		response.RespondWithError(res, req, http.StatusBadRequest, "at least one other participant is required")
+		return
+	}
+	if len(participants)+1 > maxMembers {
+		response.RespondWithError(res, req, http.StatusBadRequest, fmt.Sprintf("a conversation can have at most %d members", maxMembers))
+		return
+	}
+
+	found, err := cfg.Db.CountExistingUsers(req.Context(), participants)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if found != int64(len(participants)) {
+		response.RespondWithError(res, req, http.StatusBadRequest, "participant not found")
+		return
+	}
+
+	blocked, err := cfg.Db.IsBlockedWithAny(req.Context(), database.IsBlockedWithAnyParams{
+		UserID:   userId,
+		OtherIds: participants,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if blocked {
+		response.RespondWithError(res, req, http.StatusForbidden, "you can't message this user")
+		return
+	}
+
+	isGroup := len(participants) > 1
+	key := sql.NullString{}
+	if !isGroup {
+		key = sql.NullString{String: directKey(userId, participants[0]), Valid: true}
+
+		existing, err := cfg.Db.GetDirectConversation(req.Context(), key)
+		if err == nil {
+			respondWithConversation(res, req, cfg, http.StatusOK, existing)
+			return
+		}
+		if err != sql.ErrNoRows {
+			response.RespondWithInternalServerError(res, req, err)
+			return
+		}
+	}
+
+	tx, err := cfg.DbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.Db.WithTx(tx)
+
+	conversation, err := qtx.CreateConversation(req.Context(), database.CreateConversationParams{
+		IsGroup:   isGroup,
+		DirectKey: key,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	for _, memberId := range append([]uuid.UUID{userId}, participants...) {
+		err = qtx.AddConversationMember(req.Context(), database.AddConversationMemberParams{
+			ConversationID: conversation.ID,
+			UserID:         memberId,
+		})
+		if err != nil {
+			response.RespondWithInternalServerError(res, req, err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	respondWithConversation(res, req, cfg, http.StatusCreated, conversation)
+}
+
+func respondWithConversation(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig, status int, conversation database.Conversation) {
+	members, err := cfg.Db.ListMembersOfConversations(req.Context(), []uuid.UUID{conversation.ID})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	res.Header().Set("Location", "/api/conversations/"+conversation.ID.String()+"/messages")
+	response.RespondWithJSON(res, status, conversationJSON{
+		ID:        conversation.ID,
+		CreatedAt: conversation.CreatedAt,
+		UpdatedAt: conversation.UpdatedAt,
+		IsGroup:   conversation.IsGroup,
+		Members:   toMembersJSON(members)[conversation.ID],
+	})
+}
+
+var ListConversationsOperation = openapi.Operation{
+	ID:          "listConversations",
+	Tag:         "Messages",
+	Summary:     "List your conversations",
+	Description: "Lists your conversations, the one with the latest message first.",
+	Security:    openapi.Authenticated,
+	Parameters:  pagination.Parameters("The cursor from the Link header of the previous page."),
+	Responses: []openapi.Response{
+		{Status: http.StatusOK, Description: "A page of conversations", Type: []conversationJSON{}, Headers: pagination.LinkHeader},
+		openapi.Error(http.StatusBadRequest, "Invalid cursor or limit"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the messages:read scope"),
+	},
+}
+
+// HandleListConversations lists the user's conversations, the ones with the
+// latest messages first. The next page is linked in the Link header.
+func HandleListConversations(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	cursor, limit, err := pagination.FromRequest(req)
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversations, err := cfg.Db.ListConversationsForMember(req.Context(), database.ListConversationsForMemberParams{
+		UserID:     userId,
+		BeforeTime: cursor.Time,
+		BeforeID:   cursor.ID,
+		RowLimit:   int32(limit),
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	conversationIds := make([]uuid.UUID, len(conversations))
+	for i, conversation := range conversations {
+		conversationIds[i] = conversation.ID
+	}
+
+	members, err := cfg.Db.ListMembersOfConversations(req.Context(), conversationIds)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	membersByConversation := toMembersJSON(members)
+
+	conversationsResp := make([]conversationJSON, len(conversations))
+	for i, conversation := range conversations {
+		conversationsResp[i] = conversationJSON{
+			ID:          conversation.ID,
+			CreatedAt:   conversation.CreatedAt,
+			UpdatedAt:   conversation.UpdatedAt,
+			IsGroup:     conversation.IsGroup,
+			Members:     membersByConversation[conversation.ID],
+			UnreadCount: conversation.UnreadCount,
+		}
+	}
+
+	page := &response.Page{Limit: limit}
+	if len(conversations) > 0 {
+		last := conversations[len(conversations)-1]
+		page = pagination.SetNextLink(res, req, pagination.Cursor{Time: last.UpdatedAt, ID: last.ID}, len(conversations), limit)
+	}
+
+	response.RespondWithList(res, req, conversationsResp, page)
+}
+
+var GetUnreadCountOperation = openapi.Operation{
+	ID:          "getUnreadCount",
+	Tag:         "Messages",
+	Summary:     "Count unread messages",
+	Description: "Counts the messages from others you haven't read, across all your conversations.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "Unread messages", unreadJSON{}),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the messages:read scope"),
+	},
+}
+
+// HandleGetUnreadCount counts the messages from others the user hasn't read
+// yet, across all their conversations.
+func HandleGetUnreadCount(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	unread, err := cfg.Db.CountUnreadMessages(req.Context(), userId)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusOK, unreadJSON{UnreadCount: unread})
+}
+
+var MarkReadOperation = openapi.Operation{
+	ID:          "markConversationRead",
+	Tag:         "Messages",
+	Summary:     "Mark a conversation as read",
+	Description: "Marks every message in the conversation as read. The other members see it as your last_read_at.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusNoContent, "Conversation marked as read"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the messages:write scope"),
+		openapi.Error(http.StatusNotFound, "Conversation not found"),
+	},
+}
+
+// HandleMarkRead marks every message in the conversation as read by the user.
+// The time is shown to the other members as a read receipt.
+func HandleMarkRead(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	conversation, ok := getConversation(res, req, cfg, userId)
+	if !ok {
+		return
+	}
+
+	err = cfg.Db.MarkConversationRead(req.Context(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID:         userId,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	members, err := cfg.Db.ListMembersOfConversations(req.Context(), []uuid.UUID{conversation.ID})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	otherIds := []uuid.UUID{}
+	for _, member := range members {
+		if member.UserID != userId {
+			otherIds = append(otherIds, member.UserID)
+		}
+	}
+
+	err = notifyMembers(req.Context(), cfg.Db, cfg, otherIds, "conversation.read", readJSON{
+		ConversationID: conversation.ID,
+		UserID:         userId,
+		LastReadAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusNoContent, nil)
+}
+
+// getConversation loads the conversation in the path, which is only found for
+// its members. It writes the error response when it isn't.
+func getConversation(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig, userId uuid.UUID) (database.Conversation, bool) {
+	conversationId, err := uuid.Parse(req.PathValue("conversationID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return database.Conversation{}, false
+	}
+
+	conversation, err := cfg.Db.GetConversationForMember(req.Context(), database.GetConversationForMemberParams{
+		ID:     conversationId,
+		UserID: userId,
+	})
+	if err == sql.ErrNoRows {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return database.Conversation{}, false
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return database.Conversation{}, false
+	}
+
+	return conversation, true
+}
+
+// notifyMembers pushes the data to the gateway clients of the members on the
+// messages channel, once the transaction db is bound to commits.
+func notifyMembers(ctx context.Context, db *database.Queries, cfg *config.ApiConfig, memberIds []uuid.UUID, messageType string, data any) error {
+	message, err := gateway.NewMessage(gateway.ChannelMessages, messageType, data)
+	if err != nil {
+		return err
+	}
+
+	return gateway.Notify(ctx, db, cfg.MessageKeys, memberIds, message)
+}
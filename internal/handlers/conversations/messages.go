@@ -0,0 +1,276 @@
+package conversations
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/moderation"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/pagination"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+// maxMessageLength is the longest a direct message can be, in bytes.
+const maxMessageLength = 2000
+
+type messageParams struct {
+	Body string `json:"body" validate:"required"`
+}
+
+type messageJSON struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+func toMessageJSON(message database.Message, body []byte) messageJSON {
+	return messageJSON{
+		ID:             message.ID,
+		CreatedAt:      message.CreatedAt,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Body:           string(body),
+	}
+}
+
+var SendMessageOperation = openapi.Operation{
+	ID:      "sendMessage",
+	Tag:     "Messages",
+	Summary: "Send a message",
+	Description: "Sends a message of at most 2000 bytes to the conversation. Messages are encrypted at rest and go " +
+		"through the same profanity filter as chirps. You can't send messages to a conversation with someone you " +
+		"blocked or who blocked you.",
+	Security: openapi.Authenticated,
+	Request:  &openapi.Request{Type: messageParams{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusCreated, "Message sent", messageJSON{}),
+		openapi.Error(http.StatusBadRequest, "Empty or too long message"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the messages:write scope, or blocked"),
+		openapi.Error(http.StatusNotFound, "Conversation not found"),
+		openapi.Error(http.StatusServiceUnavailable, "Direct messages are not configured on this server"),
+	},
+}
+
+// HandleSendMessage encrypts the message with the current key and adds it to
+// the conversation. Nobody can send messages to a conversation with someone
+// they blocked or who blocked them.
+func HandleSendMessage(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	if cfg.MessageKeys == nil {
+		response.RespondWithError(res, req, http.StatusServiceUnavailable, "direct messages are not available")
+		return
+	}
+
+	data := &messageParams{}
+
+	err = parser.ParseBody(res, req, data)
+	if err != nil {
+		response.RespondWithErr(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	if strings.TrimSpace(data.Body) == "" {
+		response.RespondWithError(res, req, http.StatusBadRequest, "message can't be empty")
+		return
+	}
+
+	body, err := moderation.Moderate(data.Body, maxMessageLength)
+	if err == moderation.ErrTooLong {
+		response.RespondWithError(res, req, http.StatusBadRequest, "message is too long")
+		return
+	}
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversation, ok := getConversation(res, req, cfg, userId)
+	if !ok {
+		return
+	}
+
+	members, err := cfg.Db.ListMembersOfConversations(req.Context(), []uuid.UUID{conversation.ID})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	memberIds := []uuid.UUID{}
+	otherIds := []uuid.UUID{}
+	for _, member := range members {
+		memberIds = append(memberIds, member.UserID)
+		if member.UserID != userId {
+			otherIds = append(otherIds, member.UserID)
+		}
+	}
+
+	blocked, err := cfg.Db.IsBlockedWithAny(req.Context(), database.IsBlockedWithAnyParams{
+		UserID:   userId,
+		OtherIds: otherIds,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if blocked {
+		response.RespondWithError(res, req, http.StatusForbidden, "you can't message this user")
+		return
+	}
+
+	messageId := uuid.New()
+	keyID, ciphertext, err := cfg.MessageKeys.Encrypt([]byte(body), messageId[:])
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	tx, err := cfg.DbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.Db.WithTx(tx)
+
+	message, err := qtx.CreateMessage(req.Context(), database.CreateMessageParams{
+		ID:             messageId,
+		ConversationID: conversation.ID,
+		SenderID:       userId,
+		KeyID:          keyID,
+		Body:           ciphertext,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = qtx.TouchConversation(req.Context(), conversation.ID)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	// sending a message means the sender has read everything before it
+	err = qtx.MarkConversationRead(req.Context(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID:         userId,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	// the sender's other clients get it too
+	err = notifyMembers(req.Context(), qtx, cfg, memberIds, "message.created", toMessageJSON(message, []byte(body)))
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusCreated, toMessageJSON(message, []byte(body)))
+}
+
+var ListMessagesOperation = openapi.Operation{
+	ID:          "listMessages",
+	Tag:         "Messages",
+	Summary:     "List messages",
+	Description: "Lists the messages of a conversation you are a member of, newest first.",
+	Security:    openapi.Authenticated,
+	Parameters:  pagination.Parameters("The cursor from the Link header of the previous page."),
+	Responses: []openapi.Response{
+		{Status: http.StatusOK, Description: "A page of messages", Type: []messageJSON{}, Headers: pagination.LinkHeader},
+		openapi.Error(http.StatusBadRequest, "Invalid cursor or limit"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the messages:read scope"),
+		openapi.Error(http.StatusNotFound, "Conversation not found"),
+		openapi.Error(http.StatusServiceUnavailable, "Direct messages are not configured on this server"),
+	},
+}
+
+// HandleListMessages lists the messages of a conversation, newest first. The
+// next page is linked in the Link header.
+func HandleListMessages(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	if cfg.MessageKeys == nil {
+		response.RespondWithError(res, req, http.StatusServiceUnavailable, "direct messages are not available")
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	cursor, limit, err := pagination.FromRequest(req)
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversation, ok := getConversation(res, req, cfg, userId)
+	if !ok {
+		return
+	}
+
+	messages, err := cfg.Db.ListMessages(req.Context(), database.ListMessagesParams{
+		ConversationID: conversation.ID,
+		BeforeTime:     cursor.Time,
+		BeforeID:       cursor.ID,
+		RowLimit:       int32(limit),
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	messagesResp := make([]messageJSON, len(messages))
+	for i, message := range messages {
+		body, err := cfg.MessageKeys.Decrypt(message.KeyID, message.Body, message.ID[:])
+		if err != nil {
+			response.RespondWithInternalServerError(res, req, err)
+			return
+		}
+		messagesResp[i] = toMessageJSON(message, body)
+	}
+
+	page := &response.Page{Limit: limit}
+	if len(messages) > 0 {
+		last := messages[len(messages)-1]
+		page = pagination.SetNextLink(res, req, pagination.Cursor{Time: last.CreatedAt, ID: last.ID}, len(messages), limit)
+	}
+
+	response.RespondWithList(res, req, messagesResp, page)
+}
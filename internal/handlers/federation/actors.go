@@ -0,0 +1,324 @@
+package federation
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/activitypub"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/pagination"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+// outboxSize is how many of the latest chirps the outbox of a user has.
+const outboxSize = 20
+
+func respondWithActivity(res http.ResponseWriter, code int, payload any) {
+	res.Header().Set("Content-Type", activitypub.ContentType)
+	response.RespondWithJSON(res, code, payload)
+}
+
+// federatedConfig loads the config and answers 404 when federation is off,
+// which it is until the instance knows its public URL, since that's what
+// everything it federates is identified by.
+func federatedConfig(res http.ResponseWriter, req *http.Request) (*config.ApiConfig, bool) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return nil, false
+	}
+
+	if cfg.BaseURL == "" {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return nil, false
+	}
+
+	return cfg, true
+}
+
+// getUser finds the user in the path, answering 404 when there's none.
+func getUser(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig) (database.User, bool) {
+	userId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return database.User{}, false
+	}
+
+	user, err := cfg.Db.GetUserByID(req.Context(), userId)
+	if err == sql.ErrNoRows {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return database.User{}, false
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return database.User{}, false
+	}
+
+	return user, true
+}
+
+var WebFingerOperation = openapi.Operation{
+	ID:      "webFinger",
+	Tag:     "Federation",
+	Summary: "Find the ActivityPub actor of a user",
+	Description: "WebFinger lookup for other instances. Users have no handles, so their account is their ID at the host " +
+		"of the instance, like acct:0a5f...@chirpy.example. Federation is off, and every federation endpoint answers " +
+		"404, until BASE_URL is set.",
+	Parameters: []openapi.Parameter{
+		openapi.RequiredQuery("resource", "The acct URI or the actor URL of the user.", openapi3.NewStringSchema()),
+	},
+	Responses: []openapi.Response{
+		{
+			Status:      http.StatusOK,
+			Description: "The JRD of the user, linking to their actor.",
+			Type:        activitypub.JRD{},
+			ContentType: activitypub.JRDContentType,
+		},
+		openapi.Error(http.StatusBadRequest, "The resource is missing"),
+		openapi.Error(http.StatusNotFound, "User not found, or federation is off"),
+	},
+}
+
+// HandleWebFinger finds the actor of a user from its acct: URI, which is the
+// user's ID at the host of the instance, or from its actor URL.
+func HandleWebFinger(res http.ResponseWriter, req *http.Request) {
+	cfg, ok := federatedConfig(res, req)
+	if !ok {
+		return
+	}
+
+	resource := req.URL.Query().Get("resource")
+	if resource == "" {
+		response.RespondWithError(res, req, http.StatusBadRequest, "resource is required")
+		return
+	}
+
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := activitypub.ParseActorURL(cfg.BaseURL, resource)
+	if !ok {
+		username, host, found := strings.Cut(strings.TrimPrefix(resource, "acct:"), "@")
+		if !found || !strings.EqualFold(host, base.Host) {
+			response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+			return
+		}
+
+		userId, err = uuid.Parse(username)
+		if err != nil {
+			response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+			return
+		}
+	}
+
+	_, err = cfg.Db.GetUserByID(req.Context(), userId)
+	if err == sql.ErrNoRows {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	actorURL := activitypub.ActorURL(cfg.BaseURL, userId)
+	res.Header().Set("Content-Type", activitypub.JRDContentType)
+	res.Header().Set("Access-Control-Allow-Origin", "*")
+	response.RespondWithJSON(res, http.StatusOK, activitypub.JRD{
+		Subject: "acct:" + userId.String() + "@" + base.Host,
+		Aliases: []string{actorURL},
+		Links: []activitypub.Link{
+			{Rel: "self", Type: activitypub.ContentType, Href: actorURL},
+		},
+	})
+}
+
+var GetActorOperation = openapi.Operation{
+	ID:          "getActor",
+	Tag:         "Federation",
+	Summary:     "Get the ActivityPub actor of a user",
+	Description: "The Person of the user, with the public key their activities are signed with.",
+	Responses: []openapi.Response{
+		{Status: http.StatusOK, Description: "The actor document", Type: activitypub.Actor{}, ContentType: activitypub.ContentType},
+		openapi.Error(http.StatusNotFound, "User not found, or federation is off"),
+	},
+}
+
+// HandleGetActor serves the actor document of a user, with the public key
+// other instances verify their activities with.
+func HandleGetActor(res http.ResponseWriter, req *http.Request) {
+	cfg, ok := federatedConfig(res, req)
+	if !ok {
+		return
+	}
+
+	user, ok := getUser(res, req, cfg)
+	if !ok {
+		return
+	}
+
+	key, err := activitypub.LocalKey(req.Context(), cfg.Db, user.ID)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	respondWithActivity(res, http.StatusOK, activitypub.NewActor(cfg.BaseURL, user, key.PublicKeyPem))
+}
+
+var GetOutboxOperation = openapi.Operation{
+	ID:          "getOutbox",
+	Tag:         "Federation",
+	Summary:     "Get the outbox of a user",
+	Description: "The latest 20 chirps of the user anyone can see, newest first, as Create activities.",
+	Responses: []openapi.Response{
+		{Status: http.StatusOK, Description: "The outbox", Type: activitypub.OrderedCollection{}, ContentType: activitypub.ContentType},
+		openapi.Error(http.StatusNotFound, "User not found, or federation is off"),
+	},
+}
+
+// HandleGetOutbox serves the latest chirps of a user anyone can see, as the
+// activities that created them.
+func HandleGetOutbox(res http.ResponseWriter, req *http.Request) {
+	cfg, ok := federatedConfig(res, req)
+	if !ok {
+		return
+	}
+
+	user, ok := getUser(res, req, cfg)
+	if !ok {
+		return
+	}
+
+	start := pagination.Start()
+	chirps, err := cfg.Db.ListVisibleChirpsPage(req.Context(), database.ListVisibleChirpsPageParams{
+		AuthorID:   user.ID,
+		ViewerID:   uuid.Nil,
+		BeforeTime: start.Time,
+		BeforeID:   start.ID,
+		RowLimit:   outboxSize,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	total, err := cfg.Db.CountVisibleChirpsByAuthor(req.Context(), database.CountVisibleChirpsByAuthorParams{
+		AuthorID: user.ID,
+		ViewerID: uuid.Nil,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	items := make([]any, len(chirps))
+	for i, chirp := range chirps {
+		create, err := activitypub.NewCreate(cfg.BaseURL, chirp, user.IsPrivate)
+		if err != nil {
+			response.RespondWithInternalServerError(res, req, err)
+			return
+		}
+		create.Context = nil
+		items[i] = create
+	}
+
+	respondWithActivity(res, http.StatusOK, activitypub.OrderedCollection{
+		Context:      activitypub.ActivityStreamsContext,
+		ID:           activitypub.ActorURL(cfg.BaseURL, user.ID) + "/outbox",
+		Type:         "OrderedCollection",
+		TotalItems:   total,
+		OrderedItems: items,
+	})
+}
+
+var GetFollowersOperation = openapi.Operation{
+	ID:          "getFollowersCollection",
+	Tag:         "Federation",
+	Summary:     "Get the followers collection of a user",
+	Description: "How many local and remote followers the user has. Who they are isn't listed.",
+	Responses: []openapi.Response{
+		{Status: http.StatusOK, Description: "The followers collection", Type: activitypub.OrderedCollection{}, ContentType: activitypub.ContentType},
+		openapi.Error(http.StatusNotFound, "User not found, or federation is off"),
+	},
+}
+
+// HandleGetFollowers serves how many followers a user has, local and remote.
+// Who they are isn't shared.
+func HandleGetFollowers(res http.ResponseWriter, req *http.Request) {
+	cfg, ok := federatedConfig(res, req)
+	if !ok {
+		return
+	}
+
+	user, ok := getUser(res, req, cfg)
+	if !ok {
+		return
+	}
+
+	count, err := cfg.Db.CountFollowers(req.Context(), user.ID)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	respondWithActivity(res, http.StatusOK, activitypub.OrderedCollection{
+		Context:    activitypub.ActivityStreamsContext,
+		ID:         activitypub.ActorURL(cfg.BaseURL, user.ID) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: count,
+	})
+}
+
+var GetNoteOperation = openapi.Operation{
+	ID:      "getNote",
+	Tag:     "Federation",
+	Summary: "Get the ActivityPub note of a chirp",
+	Description: "Chirps anyone can see are Notes with their body as HTML. Public, unlisted and followers-only chirps " +
+		"are delivered to remote followers as Create activities, and as Delete when removed.",
+	Responses: []openapi.Response{
+		{Status: http.StatusOK, Description: "The note", Type: activitypub.Note{}, ContentType: activitypub.ContentType},
+		openapi.Error(http.StatusNotFound, "Chirp not found, or federation is off"),
+	},
+}
+
+// HandleGetNote serves the note of a chirp anyone can see.
+func HandleGetNote(res http.ResponseWriter, req *http.Request) {
+	cfg, ok := federatedConfig(res, req)
+	if !ok {
+		return
+	}
+
+	chirpId, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	chirp, err := cfg.Db.GetVisibleChirpByID(req.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpId,
+		ViewerID: uuid.Nil,
+	})
+	if err == sql.ErrNoRows {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	// chirps of private accounts are never visible to anonymous viewers
+	note := activitypub.NewNote(cfg.BaseURL, chirp, false)
+	note.Context = activitypub.ActivityStreamsContext
+	respondWithActivity(res, http.StatusOK, note)
+}
@@ -0,0 +1,289 @@
+package federation
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/activitypub"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/outbound"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+// maxActivitySize is the largest activity an inbox accepts, in bytes.
+const maxActivitySize = 1 << 18
+
+// federationClient fetches the actors whose keys sign activities. The key ID
+// comes from anyone posting to an inbox, so it only connects to public
+// addresses and doesn't follow redirects.
+var federationClient = outbound.NewClient(time.Second * 10)
+
+var inboxResponses = []openapi.Response{
+	openapi.Empty(
+		http.StatusAccepted,
+		"The activity was received. Follows of public accounts are accepted and follows of private accounts rejected "+
+			"with an Accept or Reject sent back to the actor. Likes and announces of chirps anyone can see are kept, undos "+
+			"take them back, and a Delete of the actor itself forgets it. Anything else is dropped.",
+	),
+	openapi.Error(http.StatusBadRequest, "The body is not an activity"),
+	openapi.Error(http.StatusUnauthorized, "The request is not signed by the actor of the activity"),
+	openapi.Error(http.StatusNotFound, "The object of the activity was not found, or federation is off"),
+	openapi.Error(http.StatusRequestEntityTooLarge, "The activity is larger than 256 KiB"),
+}
+
+var UserInboxOperation = openapi.Operation{
+	ID:        "postUserInbox",
+	Tag:       "Federation",
+	Summary:   "Send an activity to a user",
+	Request:   &openapi.Request{ContentType: activitypub.ContentType, Type: activitypub.Activity{}},
+	Responses: inboxResponses,
+}
+
+var SharedInboxOperation = openapi.Operation{
+	ID:          "postSharedInbox",
+	Tag:         "Federation",
+	Summary:     "Send an activity to the instance",
+	Description: "The shared inbox, which behaves like the inbox of each user.",
+	Request:     &openapi.Request{ContentType: activitypub.ContentType, Type: activitypub.Activity{}},
+	Responses:   inboxResponses,
+}
+
+// HandleInbox receives the activities of remote actors, both on the inbox of
+// each user and on the shared inbox. Only activities signed by their actor are
+// accepted. Follows, likes and announces of local users and chirps are kept,
+// along with their undos, anything else is acknowledged and dropped.
+func HandleInbox(res http.ResponseWriter, req *http.Request) {
+	cfg, ok := federatedConfig(res, req)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(res, req.Body, maxActivitySize))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusRequestEntityTooLarge, "the activity is too large")
+		return
+	}
+
+	actor, err := verifySender(req, cfg, body)
+	if err != nil {
+		log.Printf("Rejecting activity: %s", err)
+		response.RespondWithError(res, req, http.StatusUnauthorized, "the request must be signed by its actor")
+		return
+	}
+
+	activity := activitypub.Activity{}
+	err = json.Unmarshal(body, &activity)
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, "the body must be an activity")
+		return
+	}
+	// the body was read as is to check its digest, its fields are checked
+	// here
+	err = parser.Validate(&activity)
+	if err != nil {
+		response.RespondWithErr(res, req, err)
+		return
+	}
+	if activity.Actor != actor.ID {
+		response.RespondWithError(res, req, http.StatusUnauthorized, "the request must be signed by its actor")
+		return
+	}
+
+	switch activity.Type {
+	case activitypub.TypeFollow:
+		receiveFollow(res, req, cfg, actor, activity)
+	case activitypub.TypeUndo:
+		receiveUndo(res, req, cfg, actor, activity)
+	case activitypub.TypeLike, activitypub.TypeAnnounce:
+		receiveReaction(res, req, cfg, actor, activity)
+	case activitypub.TypeDelete:
+		receiveDelete(res, req, cfg, actor, activity)
+	default:
+		res.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// verifySender checks the signature of the request with the key of the
+// remote actor it names. The actor is fetched again if the signature doesn't
+// match the cached key, in case the key was rotated.
+func verifySender(req *http.Request, cfg *config.ApiConfig, body []byte) (database.RemoteActor, error) {
+	signature, err := activitypub.ParseSignature(req)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+
+	// instances running locally are only trusted in development
+	allowHTTP := cfg.Platform == "dev"
+
+	for _, refresh := range []bool{false, true} {
+		actor, err := activitypub.ResolveKey(req.Context(), cfg.Db, federationClient, signature.KeyID, allowHTTP, refresh)
+		if err != nil {
+			return database.RemoteActor{}, err
+		}
+
+		key, err := activitypub.ParsePublicKey(actor.PublicKeyPem)
+		if err != nil {
+			return database.RemoteActor{}, err
+		}
+
+		err = activitypub.VerifyRequest(req, body, key, time.Now())
+		if err == nil {
+			return actor, nil
+		}
+		if !errors.Is(err, activitypub.ErrInvalidSignature) {
+			return database.RemoteActor{}, err
+		}
+	}
+
+	return database.RemoteActor{}, activitypub.ErrInvalidSignature
+}
+
+// receiveFollow accepts follows of public accounts. Private accounts approve
+// their followers, which remote actors can't be yet, so they're rejected.
+func receiveFollow(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig, actor database.RemoteActor, follow activitypub.Activity) {
+	userId, ok := activitypub.ParseActorURL(cfg.BaseURL, follow.ObjectID())
+	if !ok {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	user, err := cfg.Db.GetUserByID(req.Context(), userId)
+	if err == sql.ErrNoRows {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	tx, err := cfg.DbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.Db.WithTx(tx)
+
+	responseType := activitypub.TypeReject
+	if !user.IsPrivate {
+		responseType = activitypub.TypeAccept
+		err = qtx.CreateRemoteFollower(req.Context(), database.CreateRemoteFollowerParams{
+			UserID:   user.ID,
+			ActorID:  actor.ID,
+			FollowID: follow.ID,
+		})
+		if err != nil {
+			response.RespondWithInternalServerError(res, req, err)
+			return
+		}
+	}
+
+	answer, err := activitypub.NewResponse(responseType, activitypub.ActorURL(cfg.BaseURL, user.ID), follow)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = activitypub.EnqueueTo(req.Context(), qtx, cfg.BaseURL, user.ID, actor.Inbox, answer)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	res.WriteHeader(http.StatusAccepted)
+}
+
+// receiveUndo takes back a follow, like or announce of the actor. Whichever
+// it was, it's found by the ID of the activity being undone.
+func receiveUndo(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig, actor database.RemoteActor, undo activitypub.Activity) {
+	undoneId := undo.ObjectID()
+
+	_, err := cfg.Db.DeleteRemoteFollower(req.Context(), database.DeleteRemoteFollowerParams{
+		ActorID:  actor.ID,
+		FollowID: undoneId,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = cfg.Db.DeleteRemoteReaction(req.Context(), database.DeleteRemoteReactionParams{
+		ActivityID: undoneId,
+		ActorID:    actor.ID,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	res.WriteHeader(http.StatusAccepted)
+}
+
+// receiveReaction keeps likes and announces of chirps anyone can see.
+// Announces of notes of other instances also reach the shared inbox, those
+// are dropped.
+func receiveReaction(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig, actor database.RemoteActor, reaction activitypub.Activity) {
+	chirpId, ok := activitypub.ParseNoteURL(cfg.BaseURL, reaction.ObjectID())
+	if !ok {
+		res.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	chirp, err := cfg.Db.GetVisibleChirpByID(req.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpId,
+		ViewerID: uuid.Nil,
+	})
+	if err == sql.ErrNoRows {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = cfg.Db.CreateRemoteReaction(req.Context(), database.CreateRemoteReactionParams{
+		ActivityID: reaction.ID,
+		Type:       reaction.Type,
+		ActorID:    actor.ID,
+		ChirpID:    chirp.ID,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	res.WriteHeader(http.StatusAccepted)
+}
+
+// receiveDelete forgets actors deleting their account, along with their
+// follows and reactions. Their notes never make it here, so there's nothing
+// else to delete.
+func receiveDelete(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig, actor database.RemoteActor, remove activitypub.Activity) {
+	if remove.ObjectID() == actor.ID {
+		err := cfg.Db.DeleteRemoteActor(req.Context(), actor.ID)
+		if err != nil {
+			response.RespondWithInternalServerError(res, req, err)
+			return
+		}
+	}
+
+	res.WriteHeader(http.StatusAccepted)
+}
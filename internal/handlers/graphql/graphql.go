@@ -0,0 +1,87 @@
+package graphql
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/graph"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+// maxQuerySize is the largest request the endpoint accepts, in bytes.
+const maxQuerySize = 1 << 16
+
+var schema = sync.OnceValues(graph.NewSchema)
+
+type params struct {
+	Query         string         `json:"query" validate:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// resultJSON documents the result of a query, which graphql-go encodes.
+type resultJSON struct {
+	Data       any              `json:"data"`
+	Errors     []map[string]any `json:"errors,omitempty"`
+	Extensions map[string]any   `json:"extensions,omitempty"`
+}
+
+var GraphQLOperation = openapi.Operation{
+	ID:      "runGraphQL",
+	Tag:     "GraphQL",
+	Summary: "Run a GraphQL query",
+	Description: "Runs a query against the read-only graph of users, chirps and follows. The root fields are `viewer`, " +
+		"`user(id)`, `chirp(id)`, `chirps(first, after, authorId, hashtag)` and `timeline(first, after)`, which needs " +
+		"authentication. Lists are Relay-style connections with `edges { cursor node }` and " +
+		"`pageInfo { hasNextPage endCursor }`, `first` is between 1 and 100 and defaults to 20. Introspect the schema " +
+		"for every field.\n\n" +
+		"Queries deeper than 10 levels, or whose complexity goes over 1000, are refused before they run. Every field " +
+		"costs 1, and the fields under a connection cost as many times as the page can have items. Failed queries " +
+		"answer 200 with their `errors`, like GraphQL clients expect. Authentication is optional; anonymous viewers " +
+		"only see what anyone can.",
+	Security: openapi.OptionallyAuthenticated,
+	Request:  &openapi.Request{Type: params{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "The result of the query, with its data, its errors or both", resultJSON{}),
+		openapi.Error(http.StatusBadRequest, "The body is not a GraphQL request"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the chirps:read scope"),
+	},
+}
+
+// HandleGraphQL runs a GraphQL query against the graph of users, chirps and
+// follows, as the authenticated user if there's one. Queries that fail still
+// answer 200, with their errors in the body like GraphQL clients expect.
+func HandleGraphQL(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	data := &params{}
+	err = parser.ParseBody(res, req, data, parser.MaxBytes(maxQuerySize))
+	if err != nil {
+		response.RespondWithErr(res, req, err)
+		return
+	}
+	if data.Query == "" {
+		response.RespondWithError(res, req, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	s, err := schema()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	ctx := graph.WithRequest(req.Context(), cfg.Db)
+	result := graph.Execute(ctx, s, data.Query, data.OperationName, data.Variables)
+
+	res.Header().Set("Content-Type", "application/json")
+	response.RespondWithJSON(res, http.StatusOK, result)
+}
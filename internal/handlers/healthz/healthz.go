@@ -1,6 +1,20 @@
 package healthz
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/lucashthiele/chirpy/internal/openapi"
+)
+
+var HealthzOperation = openapi.Operation{
+	ID:          "getHealthz",
+	Tag:         "Admin",
+	Summary:     "Health check endpoint",
+	Description: "Returns 200 OK if the service is running.",
+	Responses: []openapi.Response{
+		openapi.Content(http.StatusOK, "Service is healthy", "text/plain"),
+	},
+}
 
 func HandleHealthz(res http.ResponseWriter, req *http.Request) {
 	res.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -0,0 +1,317 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+const authorizationCodeExpiry time.Duration = time.Minute * 10
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<html>
+	<body>
+		<h1>Authorize {{.ClientName}}</h1>
+		<p>{{.ClientName}} wants to access your Chirpy account. It will be able to:</p>
+		<ul>
+			{{range .Scopes}}<li>{{.}}</li>{{end}}
+		</ul>
+		{{if .Error}}<p><strong>{{.Error}}</strong></p>{{end}}
+		<form method="POST" action="/api/oauth/authorize">
+			<input type="hidden" name="response_type" value="code">
+			<input type="hidden" name="client_id" value="{{.ClientID}}">
+			<input type="hidden" name="redirect_uri" value="{{.RedirectURI}}">
+			<input type="hidden" name="scope" value="{{.Scope}}">
+			<input type="hidden" name="state" value="{{.State}}">
+			<input type="hidden" name="code_challenge" value="{{.CodeChallenge}}">
+			<input type="hidden" name="code_challenge_method" value="{{.CodeChallengeMethod}}">
+			<p><label>Email <input type="email" name="email" value="{{.Email}}"></label></p>
+			<p><label>Password <input type="password" name="password"></label></p>
+			<button type="submit" name="decision" value="approve">Allow</button>
+			<button type="submit" name="decision" value="deny">Deny</button>
+		</form>
+	</body>
+</html>`))
+
+type authorizeRequest struct {
+	Client              database.OauthClient
+	ResponseType        string
+	RedirectURI         string
+	Scopes              []string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+type consentPage struct {
+	ClientName          string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	Scopes              []string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Email               string
+	Error               string
+}
+
+// errInvalidClient is returned when the client or redirect uri can't be
+// trusted, in which case we must not redirect back (RFC 6749, 4.1.2.1).
+var errInvalidClient = fmt.Errorf("invalid client_id or redirect_uri")
+
+func parseAuthorizeRequest(ctx context.Context, cfg *config.ApiConfig, values url.Values) (authorizeRequest, string, error) {
+	client, err := cfg.Db.GetOAuthClientByID(ctx, values.Get("client_id"))
+	if err == sql.ErrNoRows {
+		return authorizeRequest{}, "", errInvalidClient
+	}
+	if err != nil {
+		return authorizeRequest{}, "", err
+	}
+
+	authReq := authorizeRequest{
+		Client:              client,
+		ResponseType:        values.Get("response_type"),
+		RedirectURI:         values.Get("redirect_uri"),
+		Scopes:              strings.Fields(values.Get("scope")),
+		State:               values.Get("state"),
+		CodeChallenge:       values.Get("code_challenge"),
+		CodeChallengeMethod: values.Get("code_challenge_method"),
+	}
+
+	if !slices.Contains(client.RedirectUris, authReq.RedirectURI) {
+		return authorizeRequest{}, "", errInvalidClient
+	}
+
+	if authReq.ResponseType != "code" {
+		return authReq, "unsupported_response_type", fmt.Errorf("response_type must be code")
+	}
+	if authReq.CodeChallenge == "" || authReq.CodeChallengeMethod != auth.PKCEMethodS256 {
+		return authReq, "invalid_request", fmt.Errorf("a S256 code_challenge is required")
+	}
+	if len(authReq.Scopes) == 0 {
+		return authReq, "invalid_scope", fmt.Errorf("scope is required")
+	}
+	for _, scope := range authReq.Scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return authReq, "invalid_scope", fmt.Errorf("scope %s is not allowed for this client", scope)
+		}
+	}
+
+	return authReq, "", nil
+}
+
+func redirectWithParams(res http.ResponseWriter, req *http.Request, redirectURI string, params url.Values) {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+	target.RawQuery = query.Encode()
+
+	http.Redirect(res, req, target.String(), http.StatusFound)
+}
+
+func redirectWithError(res http.ResponseWriter, req *http.Request, authReq authorizeRequest, code, description string) {
+	params := url.Values{}
+	params.Set("error", code)
+	params.Set("error_description", description)
+	if authReq.State != "" {
+		params.Set("state", authReq.State)
+	}
+
+	redirectWithParams(res, req, authReq.RedirectURI, params)
+}
+
+func renderConsent(res http.ResponseWriter, code int, authReq authorizeRequest, email, errMsg string) {
+	page := consentPage{
+		ClientName:          authReq.Client.Name,
+		ClientID:            authReq.Client.ID,
+		RedirectURI:         authReq.RedirectURI,
+		Scope:               strings.Join(authReq.Scopes, " "),
+		Scopes:              authReq.Scopes,
+		State:               authReq.State,
+		CodeChallenge:       authReq.CodeChallenge,
+		CodeChallengeMethod: authReq.CodeChallengeMethod,
+		Email:               email,
+		Error:               errMsg,
+	}
+
+	res.Header().Set("Content-Type", "text/html; charset=utf-8")
+	res.Header().Set("Cache-Control", "no-store")
+	res.Header().Set("X-Frame-Options", "DENY")
+	res.WriteHeader(code)
+
+	err := consentTemplate.Execute(res, page)
+	if err != nil {
+		log.Printf("Error rendering consent page: %s", err)
+	}
+}
+
+var authorizeParameters = []openapi.Parameter{
+	openapi.RequiredQuery("response_type", "", openapi3.NewStringSchema().WithEnum("code")),
+	openapi.RequiredQuery("client_id", "", openapi3.NewStringSchema()),
+	openapi.RequiredQuery("redirect_uri", "", openapi3.NewStringSchema()),
+	openapi.RequiredQuery("scope", "Space separated scopes", openapi3.NewStringSchema()),
+	openapi.Query("state", "", openapi3.NewStringSchema()),
+	openapi.RequiredQuery("code_challenge", "", openapi3.NewStringSchema()),
+	openapi.RequiredQuery("code_challenge_method", "", openapi3.NewStringSchema().WithEnum(auth.PKCEMethodS256)),
+}
+
+var AuthorizeOperation = openapi.Operation{
+	ID:      "authorize",
+	Tag:     "OAuth",
+	Summary: "Start the authorization code flow",
+	Description: "Shows the consent page where the user signs in and approves or denies the client. PKCE with S256 is " +
+		"required for every client. Errors are sent back to the redirect uri, unless the client or redirect uri are invalid.",
+	Parameters: authorizeParameters,
+	Responses: []openapi.Response{
+		openapi.Content(http.StatusOK, "Consent page", "text/html"),
+		openapi.Empty(http.StatusFound, "Redirect back to the client with an error"),
+		openapi.Error(http.StatusBadRequest, "Invalid client_id or redirect_uri"),
+	},
+}
+
+// HandleAuthorize shows the consent screen for an authorization code request.
+// The user signs in on the screen itself, so the client never sees their
+// password.
+func HandleAuthorize(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	authReq, errCode, err := parseAuthorizeRequest(req.Context(), cfg, req.URL.Query())
+	if err == errInvalidClient {
+		response.RespondWithError(res, req, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil && errCode == "" {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if err != nil {
+		redirectWithError(res, req, authReq, errCode, err.Error())
+		return
+	}
+
+	renderConsent(res, http.StatusOK, authReq, "", "")
+}
+
+// decisionForm documents the form posted by the consent page, which repeats
+// the authorization request.
+type decisionForm struct {
+	ResponseType        string `json:"response_type" validate:"required"`
+	ClientID            string `json:"client_id" validate:"required"`
+	RedirectURI         string `json:"redirect_uri" validate:"required"`
+	Scope               string `json:"scope" validate:"required"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge" validate:"required"`
+	CodeChallengeMethod string `json:"code_challenge_method" validate:"required"`
+	Decision            string `json:"decision" enum:"approve,deny"`
+	Email               string `json:"email"`
+	Password            string `json:"password"`
+}
+
+var AuthorizeDecisionOperation = openapi.Operation{
+	ID:      "authorizeDecision",
+	Tag:     "OAuth",
+	Summary: "Submit the consent decision",
+	Description: "Posted by the consent page. When the user approves and the credentials are correct, redirects to the " +
+		"redirect uri with a single-use code that expires after 10 minutes.",
+	Request: &openapi.Request{ContentType: "application/x-www-form-urlencoded", Type: decisionForm{}},
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusFound, "Redirect back to the client with a code or an error"),
+		openapi.Error(http.StatusBadRequest, "Invalid client_id or redirect_uri"),
+		openapi.Content(http.StatusUnauthorized, "Incorrect email or password, the consent page is shown again", "text/html"),
+	},
+}
+
+func HandleAuthorizeDecision(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = req.ParseForm()
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authReq, errCode, err := parseAuthorizeRequest(req.Context(), cfg, req.PostForm)
+	if err == errInvalidClient {
+		response.RespondWithError(res, req, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil && errCode == "" {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if err != nil {
+		redirectWithError(res, req, authReq, errCode, err.Error())
+		return
+	}
+
+	if req.PostForm.Get("decision") != "approve" {
+		redirectWithError(res, req, authReq, "access_denied", "the user denied the request")
+		return
+	}
+
+	email := req.PostForm.Get("email")
+	user, err := cfg.Db.GetUserByEmail(req.Context(), email)
+	if err != nil && err != sql.ErrNoRows {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if err == sql.ErrNoRows || auth.CheckPassword(user.HashedPassword, req.PostForm.Get("password")) != nil {
+		renderConsent(res, http.StatusUnauthorized, authReq, email, "Incorrect email or password")
+		return
+	}
+
+	code := auth.MakeRefreshToken()
+
+	err = cfg.Db.CreateAuthorizationCode(req.Context(), database.CreateAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      authReq.Client.ID,
+		UserID:        user.ID,
+		RedirectUri:   authReq.RedirectURI,
+		Scopes:        authReq.Scopes,
+		CodeChallenge: authReq.CodeChallenge,
+		ExpiresAt:     time.Now().Add(authorizationCodeExpiry),
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	params := url.Values{}
+	params.Set("code", code)
+	if authReq.State != "" {
+		params.Set("state", authReq.State)
+	}
+
+	redirectWithParams(res, req, authReq.RedirectURI, params)
+}
@@ -0,0 +1,237 @@
+package oauth
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+const (
+	clientIDPrefix     = "chirpy_client_"
+	clientSecretPrefix = "chirpy_cs_"
+)
+
+type clientParams struct {
+	Name         string   `json:"name" validate:"required"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required" description:"Must use https, plain http is only allowed for loopback addresses"`
+	Scopes       []string `json:"scopes" description:"The most the client can ever ask for"`
+	Confidential bool     `json:"confidential"`
+}
+
+type clientJSON struct {
+	ClientID     string    `json:"client_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+}
+
+func toClientJSON(client database.OauthClient) clientJSON {
+	return clientJSON{
+		ClientID:     client.ID,
+		CreatedAt:    client.CreatedAt,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Scopes:       client.Scopes,
+		Confidential: client.SecretHash.Valid,
+	}
+}
+
+// validateRedirectURI only allows plain http for loopback redirects, which
+// native apps use to receive the code.
+func validateRedirectURI(redirectURI string) error {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || !parsed.IsAbs() || parsed.Host == "" {
+		return fmt.Errorf("invalid redirect uri %s", redirectURI)
+	}
+	if parsed.Fragment != "" {
+		return fmt.Errorf("redirect uri %s must not have a fragment", redirectURI)
+	}
+
+	loopback := parsed.Hostname() == "localhost" || parsed.Hostname() == "127.0.0.1" || parsed.Hostname() == "::1"
+	if parsed.Scheme != "https" && !(parsed.Scheme == "http" && loopback) {
+		return fmt.Errorf("redirect uri %s must use https", redirectURI)
+	}
+
+	return nil
+}
+
+var CreateClientOperation = openapi.Operation{
+	ID:      "createOAuthClient",
+	Tag:     "OAuth",
+	Summary: "Register an OAuth client",
+	Description: "Registers a third-party application that can ask users for scoped access through the authorization " +
+		"code flow. Confidential clients get a client secret, which is only returned here. Requires a login JWT.",
+	Security: openapi.Authenticated,
+	Request:  &openapi.Request{Type: clientParams{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusCreated, "Client registered", clientJSON{}),
+		openapi.Error(http.StatusBadRequest, "Invalid name, redirect uris or scopes"),
+		openapi.Unauthorized,
+		openapi.SessionRequired,
+	},
+}
+
+func HandleCreateClient(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	params := &clientParams{}
+
+	err = parser.ParseBody(res, req, params)
+	if err != nil {
+		response.RespondWithErr(res, req, err)
+		return
+	}
+
+	if params.Name == "" {
+		response.RespondWithError(res, req, http.StatusBadRequest, "name is required")
+		return
+	}
+	if len(params.RedirectURIs) == 0 {
+		response.RespondWithError(res, req, http.StatusBadRequest, "at least one redirect uri is required")
+		return
+	}
+	for _, redirectURI := range params.RedirectURIs {
+		err = validateRedirectURI(redirectURI)
+		if err != nil {
+			response.RespondWithError(res, req, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	err = auth.ValidateScopes(params.Scopes)
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	secret := ""
+	secretHash := sql.NullString{}
+	if params.Confidential {
+		secret = clientSecretPrefix + auth.MakeRefreshToken()
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	client, err := cfg.Db.CreateOAuthClient(req.Context(), database.CreateOAuthClientParams{
+		ID:           clientIDPrefix + auth.MakeRefreshToken()[:24],
+		OwnerID:      userId,
+		Name:         params.Name,
+		SecretHash:   secretHash,
+		RedirectUris: params.RedirectURIs,
+		Scopes:       params.Scopes,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	// only the hash of the secret is stored, it can't be shown again
+	clientResp := toClientJSON(client)
+	clientResp.ClientSecret = secret
+
+	response.RespondWithJSON(res, http.StatusCreated, clientResp)
+}
+
+var ListClientsOperation = openapi.Operation{
+	ID:          "listOAuthClients",
+	Tag:         "OAuth",
+	Summary:     "List OAuth clients",
+	Description: "Lists the OAuth clients registered by the authenticated user. Requires a login JWT.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "List of clients", []clientJSON{}),
+		openapi.Unauthorized,
+		openapi.SessionRequired,
+	},
+}
+
+func HandleListClients(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	clients, err := cfg.Db.ListOAuthClientsByOwner(req.Context(), userId)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	bodyResp := make([]clientJSON, len(clients))
+	for i, client := range clients {
+		bodyResp[i] = toClientJSON(client)
+	}
+
+	response.RespondWithList(res, req, bodyResp, nil)
+}
+
+var DeleteClientOperation = openapi.Operation{
+	ID:          "deleteOAuthClient",
+	Tag:         "OAuth",
+	Summary:     "Delete an OAuth client",
+	Description: "Deletes a client and every token issued to it. Requires a login JWT.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusNoContent, "Client deleted"),
+		openapi.Unauthorized,
+		openapi.SessionRequired,
+		openapi.Error(http.StatusNotFound, "Client not found"),
+	},
+}
+
+func HandleDeleteClient(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	deleted, err := cfg.Db.DeleteOAuthClient(req.Context(), database.DeleteOAuthClientParams{
+		ID:      req.PathValue("clientID"),
+		OwnerID: userId,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if deleted == 0 {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusNoContent, nil)
+}
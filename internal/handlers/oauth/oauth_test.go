@@ -0,0 +1,332 @@
+package oauth
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/database/dbtest"
+)
+
+const (
+	testClientID    = "chirpy-app"
+	testRedirectURI = "https://app.example/callback"
+	testVerifier    = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+var testUserID = uuid.New()
+
+// clientQuery answers GetOAuthClientByID with a public client that may ask
+// for chirps:read and chirps:write.
+func clientQuery(args []driver.Value) (dbtest.Result, error) {
+	if args[0] != testClientID {
+		return dbtest.NoRows, nil
+	}
+	now := time.Now()
+	return dbtest.Row(testClientID, now, now, uuid.NewString(), "Chirpy App", nil,
+		"{"+testRedirectURI+"}", "{"+auth.ScopeChirpsRead+","+auth.ScopeChirpsWrite+"}"), nil
+}
+
+// useFakeDatabase answers the queries of the handlers with queries, until
+// the end of the test.
+func useFakeDatabase(t *testing.T, queries map[string]dbtest.Query) (*dbtest.DB, *config.ApiConfig) {
+	t.Helper()
+
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	db, conn := dbtest.New(t, queries)
+	previousDb, previousConn, previousSecret := cfg.Db, cfg.DbConn, cfg.AppSecret
+	cfg.Db, cfg.DbConn, cfg.AppSecret = database.New(conn), conn, "test-secret"
+	t.Cleanup(func() {
+		cfg.Db, cfg.DbConn, cfg.AppSecret = previousDb, previousConn, previousSecret
+	})
+
+	return db, cfg
+}
+
+func authorizeValues() url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {testClientID},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {auth.ScopeChirpsRead},
+		"state":                 {"xyz"},
+		"code_challenge":        {auth.MakePKCEChallenge(testVerifier)},
+		"code_challenge_method": {auth.PKCEMethodS256},
+	}
+}
+
+func postForm(handler http.HandlerFunc, path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestHandleAuthorize(t *testing.T) {
+	useFakeDatabase(t, map[string]dbtest.Query{"GetOAuthClientByID": clientQuery})
+
+	cases := []struct {
+		name       string
+		change     func(url.Values)
+		wantStatus int
+		wantError  string
+	}{
+		{name: "valid request", change: func(url.Values) {}, wantStatus: http.StatusOK},
+		{name: "unknown client", change: func(v url.Values) { v.Set("client_id", "other") }, wantStatus: http.StatusBadRequest},
+		{name: "unregistered redirect uri", change: func(v url.Values) { v.Set("redirect_uri", "https://evil.example/") }, wantStatus: http.StatusBadRequest},
+		{name: "implicit flow", change: func(v url.Values) { v.Set("response_type", "token") }, wantStatus: http.StatusFound, wantError: "unsupported_response_type"},
+		{name: "without a code challenge", change: func(v url.Values) { v.Del("code_challenge") }, wantStatus: http.StatusFound, wantError: "invalid_request"},
+		{name: "plain code challenge", change: func(v url.Values) { v.Set("code_challenge_method", "plain") }, wantStatus: http.StatusFound, wantError: "invalid_request"},
+		{name: "scope not allowed", change: func(v url.Values) { v.Set("scope", auth.ScopeMessagesRead) }, wantStatus: http.StatusFound, wantError: "invalid_scope"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			values := authorizeValues()
+			c.change(values)
+
+			rec := httptest.NewRecorder()
+			HandleAuthorize(rec, httptest.NewRequest(http.MethodGet, "/api/oauth/authorize?"+values.Encode(), nil))
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, c.wantStatus)
+			}
+			if c.wantError == "" {
+				return
+			}
+
+			location, err := url.Parse(rec.Header().Get("Location"))
+			if err != nil {
+				t.Fatalf("Location error = %v", err)
+			}
+			if got := location.Query().Get("error"); got != c.wantError || location.Query().Get("state") != "xyz" {
+				t.Errorf("redirected to %s, want error %s and the state", location, c.wantError)
+			}
+		})
+	}
+}
+
+func TestHandleAuthorizeDecision(t *testing.T) {
+	hashedPassword, err := auth.HashPassword("hunter2")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		decision   string
+		password   string
+		wantStatus int
+		wantCode   bool
+		wantError  string
+	}{
+		{name: "approved", decision: "approve", password: "hunter2", wantStatus: http.StatusFound, wantCode: true},
+		{name: "wrong password", decision: "approve", password: "wrong", wantStatus: http.StatusUnauthorized},
+		{name: "denied", decision: "deny", wantStatus: http.StatusFound, wantError: "access_denied"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			db, _ := useFakeDatabase(t, map[string]dbtest.Query{
+				"GetOAuthClientByID": clientQuery,
+				"GetUserByEmail": func([]driver.Value) (dbtest.Result, error) {
+					return dbtest.Row(testUserID.String(), nil, nil, "user@example.com", hashedPassword, false), nil
+				},
+				"CreateAuthorizationCode": func([]driver.Value) (dbtest.Result, error) { return dbtest.Affected(1), nil },
+			})
+
+			form := authorizeValues()
+			form.Set("decision", c.decision)
+			form.Set("email", "user@example.com")
+			form.Set("password", c.password)
+			rec := postForm(HandleAuthorizeDecision, "/api/oauth/authorize", form)
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, c.wantStatus)
+			}
+
+			codes := db.Calls("CreateAuthorizationCode")
+			if !c.wantCode {
+				if len(codes) != 0 {
+					t.Errorf("an authorization code was created")
+				}
+				if c.wantError != "" && !strings.Contains(rec.Header().Get("Location"), "error="+c.wantError) {
+					t.Errorf("redirected to %s, want error %s", rec.Header().Get("Location"), c.wantError)
+				}
+				return
+			}
+
+			location, err := url.Parse(rec.Header().Get("Location"))
+			if err != nil {
+				t.Fatalf("Location error = %v", err)
+			}
+			code := location.Query().Get("code")
+			if len(codes) != 1 || code == "" || codes[0].Args[0] != auth.HashToken(code) {
+				t.Fatalf("redirected to %s, want the code that was stored", location)
+			}
+			if codes[0].Args[5] != auth.MakePKCEChallenge(testVerifier) {
+				t.Errorf("stored code challenge = %v, want the one of the request", codes[0].Args[5])
+			}
+		})
+	}
+}
+
+func TestHandleTokenAuthorizationCode(t *testing.T) {
+	code := "code-for-the-app"
+
+	cases := []struct {
+		name       string
+		change     func(url.Values)
+		codeClient string
+		wantStatus int
+		wantError  string
+	}{
+		{name: "valid exchange", change: func(url.Values) {}, wantStatus: http.StatusOK},
+		{name: "wrong code verifier", change: func(v url.Values) { v.Set("code_verifier", strings.Repeat("a", 43)) }, wantStatus: http.StatusBadRequest, wantError: "invalid_grant"},
+		{name: "short code verifier", change: func(v url.Values) { v.Set("code_verifier", "short") }, wantStatus: http.StatusBadRequest, wantError: "invalid_grant"},
+		{name: "without a code verifier", change: func(v url.Values) { v.Del("code_verifier") }, wantStatus: http.StatusBadRequest, wantError: "invalid_grant"},
+		{name: "another redirect uri", change: func(v url.Values) { v.Set("redirect_uri", "https://app.example/other") }, wantStatus: http.StatusBadRequest, wantError: "invalid_grant"},
+		{name: "code of another client", change: func(url.Values) {}, codeClient: "other-app", wantStatus: http.StatusBadRequest, wantError: "invalid_grant"},
+		{name: "used or unknown code", change: func(v url.Values) { v.Set("code", "unknown") }, wantStatus: http.StatusBadRequest, wantError: "invalid_grant"},
+		{name: "unknown client", change: func(v url.Values) { v.Set("client_id", "other-app") }, wantStatus: http.StatusUnauthorized, wantError: "invalid_client"},
+		{name: "unsupported grant", change: func(v url.Values) { v.Set("grant_type", "password") }, wantStatus: http.StatusBadRequest, wantError: "unsupported_grant_type"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			codeClient := testClientID
+			if c.codeClient != "" {
+				codeClient = c.codeClient
+			}
+
+			db, cfg := useFakeDatabase(t, map[string]dbtest.Query{
+				"GetOAuthClientByID": clientQuery,
+				"ConsumeAuthorizationCode": func(args []driver.Value) (dbtest.Result, error) {
+					if args[0] != auth.HashToken(code) {
+						return dbtest.NoRows, nil
+					}
+					now := time.Now()
+					return dbtest.Row(args[0], now, codeClient, testUserID.String(), testRedirectURI,
+						"{"+auth.ScopeChirpsRead+"}", auth.MakePKCEChallenge(testVerifier), now.Add(time.Minute), now), nil
+				},
+				"CreateRefreshToken": func(args []driver.Value) (dbtest.Result, error) {
+					now := time.Now()
+					return dbtest.Row(args[0], now, now, args[1], args[2], args[3], args[4], args[5]), nil
+				},
+			})
+
+			form := url.Values{
+				"grant_type":    {"authorization_code"},
+				"client_id":     {testClientID},
+				"code":          {code},
+				"redirect_uri":  {testRedirectURI},
+				"code_verifier": {testVerifier},
+			}
+			c.change(form)
+			rec := postForm(HandleToken, "/api/oauth/token", form)
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, c.wantStatus, rec.Body)
+			}
+
+			if c.wantError != "" {
+				var got errorJSON
+				if err := json.NewDecoder(rec.Body).Decode(&got); err != nil || got.Error != c.wantError {
+					t.Errorf("error = %q (%v), want %q", got.Error, err, c.wantError)
+				}
+				if len(db.Calls("CreateRefreshToken")) != 0 {
+					t.Errorf("tokens were issued")
+				}
+				return
+			}
+
+			var got tokenJSON
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			claims, err := auth.ParseJWT(got.AccessToken, cfg.AppSecret)
+			if err != nil || claims.ClientID != testClientID || claims.Subject != testUserID.String() {
+				t.Errorf("access token claims = %+v (%v), want the user and client of the code", claims, err)
+			}
+			if got.RefreshToken == "" || got.Scope != auth.ScopeChirpsRead {
+				t.Errorf("token response = %+v, want a refresh token and the scope of the code", got)
+			}
+		})
+	}
+}
+
+func TestHandleRevoke(t *testing.T) {
+	refreshToken := "refresh-of-the-app"
+	otherRefreshToken := "refresh-of-another-app"
+
+	accessToken, err := auth.MakeScopedJWT(testUserID, "test-secret", time.Hour, testClientID, []string{auth.ScopeChirpsRead})
+	if err != nil {
+		t.Fatalf("MakeScopedJWT() error = %v", err)
+	}
+
+	cases := []struct {
+		name        string
+		clientID    string
+		token       string
+		wantStatus  int
+		wantError   string
+		wantRevoked bool
+	}{
+		{name: "refresh token", clientID: testClientID, token: refreshToken, wantStatus: http.StatusOK, wantRevoked: true},
+		{name: "refresh token of another client", clientID: testClientID, token: otherRefreshToken, wantStatus: http.StatusOK},
+		{name: "unknown token", clientID: testClientID, token: "unknown", wantStatus: http.StatusOK},
+		{name: "access token", clientID: testClientID, token: accessToken, wantStatus: http.StatusBadRequest, wantError: "unsupported_token_type"},
+		{name: "unknown client", clientID: "other-app", token: refreshToken, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			db, _ := useFakeDatabase(t, map[string]dbtest.Query{
+				"GetOAuthClientByID": clientQuery,
+				"GetRefreshToken": func(args []driver.Value) (dbtest.Result, error) {
+					clientID := testClientID
+					switch args[0] {
+					case refreshToken:
+					case otherRefreshToken:
+						clientID = "other-app"
+					default:
+						return dbtest.NoRows, nil
+					}
+					now := time.Now()
+					return dbtest.Row(args[0], now, now, testUserID.String(), now.Add(time.Hour), nil, clientID, "{"+auth.ScopeChirpsRead+"}"), nil
+				},
+				"RevokeRefreshToken": func([]driver.Value) (dbtest.Result, error) { return dbtest.Affected(1), nil },
+			})
+
+			rec := postForm(HandleRevoke, "/api/oauth/revoke", url.Values{"client_id": {c.clientID}, "token": {c.token}})
+
+			if rec.Code != c.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, c.wantStatus, rec.Body)
+			}
+			if c.wantError != "" {
+				var got errorJSON
+				if err := json.NewDecoder(rec.Body).Decode(&got); err != nil || got.Error != c.wantError {
+					t.Errorf("error = %q (%v), want %q", got.Error, err, c.wantError)
+				}
+			}
+			revoked := db.Calls("RevokeRefreshToken")
+			if c.wantRevoked != (len(revoked) == 1) {
+				t.Errorf("RevokeRefreshToken ran %d times, want the token revoked: %v", len(revoked), c.wantRevoked)
+			}
+		})
+	}
+}
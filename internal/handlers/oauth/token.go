@@ -0,0 +1,378 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+const accessTokenExpiry time.Duration = time.Hour            // 1 hour
+const refreshTokenExpiry time.Duration = time.Hour * 24 * 60 // 60 days
+
+type errorJSON struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type tokenJSON struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+type introspectionJSON struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+func respondWithOAuthError(res http.ResponseWriter, code int, errCode, description string) {
+	res.Header().Set("Cache-Control", "no-store")
+	response.RespondWithJSON(res, code, errorJSON{
+		Error:            errCode,
+		ErrorDescription: description,
+	})
+}
+
+// authenticateClient accepts client credentials through HTTP Basic auth or the
+// client_id/client_secret form fields. Public clients only send client_id.
+func authenticateClient(ctx context.Context, cfg *config.ApiConfig, req *http.Request) (database.OauthClient, bool, error) {
+	clientID, clientSecret, ok := req.BasicAuth()
+	if !ok {
+		clientID = req.PostForm.Get("client_id")
+		clientSecret = req.PostForm.Get("client_secret")
+	}
+
+	client, err := cfg.Db.GetOAuthClientByID(ctx, clientID)
+	if err == sql.ErrNoRows {
+		return database.OauthClient{}, false, nil
+	}
+	if err != nil {
+		return database.OauthClient{}, false, err
+	}
+
+	if client.SecretHash.Valid && !auth.CompareAPIKey(client.SecretHash.String, auth.HashToken(clientSecret)) {
+		return database.OauthClient{}, false, nil
+	}
+
+	return client, true, nil
+}
+
+func issueTokens(ctx context.Context, cfg *config.ApiConfig, client database.OauthClient, code database.OauthAuthorizationCode) (tokenJSON, error) {
+	accessToken, err := auth.MakeScopedJWT(code.UserID, cfg.AppSecret, accessTokenExpiry, client.ID, code.Scopes)
+	if err != nil {
+		return tokenJSON{}, err
+	}
+
+	refreshToken, err := cfg.Db.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:     auth.MakeRefreshToken(),
+		UserID:    code.UserID,
+		ExpiresAt: time.Now().Add(refreshTokenExpiry),
+		ClientID:  sql.NullString{String: client.ID, Valid: true},
+		Scopes:    code.Scopes,
+	})
+	if err != nil {
+		return tokenJSON{}, err
+	}
+
+	return tokenJSON{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTokenExpiry.Seconds()),
+		RefreshToken: refreshToken.Token,
+		Scope:        strings.Join(code.Scopes, " "),
+	}, nil
+}
+
+func exchangeAuthorizationCode(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig, client database.OauthClient) {
+	code, err := cfg.Db.ConsumeAuthorizationCode(req.Context(), auth.HashToken(req.PostForm.Get("code")))
+	if err == sql.ErrNoRows {
+		respondWithOAuthError(res, http.StatusBadRequest, "invalid_grant", "invalid or expired code")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	if code.ClientID != client.ID || code.RedirectUri != req.PostForm.Get("redirect_uri") {
+		respondWithOAuthError(res, http.StatusBadRequest, "invalid_grant", "code was issued to another client or redirect_uri")
+		return
+	}
+
+	err = auth.VerifyPKCE(req.PostForm.Get("code_verifier"), code.CodeChallenge)
+	if err != nil {
+		respondWithOAuthError(res, http.StatusBadRequest, "invalid_grant", err.Error())
+		return
+	}
+
+	tokenResp, err := issueTokens(req.Context(), cfg, client, code)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	res.Header().Set("Cache-Control", "no-store")
+	response.RespondWithJSON(res, http.StatusOK, tokenResp)
+}
+
+func exchangeRefreshToken(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig, client database.OauthClient) {
+	storedToken, err := cfg.Db.GetUserFromRefreshToken(req.Context(), req.PostForm.Get("refresh_token"))
+	if err == sql.ErrNoRows || (err == nil && storedToken.ClientID.String != client.ID) {
+		respondWithOAuthError(res, http.StatusBadRequest, "invalid_grant", "invalid or expired refresh token")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	accessToken, err := auth.MakeScopedJWT(storedToken.UserID, cfg.AppSecret, accessTokenExpiry, client.ID, storedToken.Scopes)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	res.Header().Set("Cache-Control", "no-store")
+	response.RespondWithJSON(res, http.StatusOK, tokenJSON{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(accessTokenExpiry.Seconds()),
+		Scope:       strings.Join(storedToken.Scopes, " "),
+	})
+}
+
+// clientCredentials are the form fields of clients that don't use HTTP Basic
+// auth.
+type clientCredentials struct {
+	ClientID     string `json:"client_id" description:"Required unless the client authenticates with HTTP Basic auth"`
+	ClientSecret string `json:"client_secret" description:"Only for confidential clients"`
+}
+
+type tokenForm struct {
+	GrantType    string `json:"grant_type" validate:"required" enum:"authorization_code,refresh_token"`
+	Code         string `json:"code"`
+	RedirectURI  string `json:"redirect_uri"`
+	CodeVerifier string `json:"code_verifier"`
+	RefreshToken string `json:"refresh_token"`
+	clientCredentials
+}
+
+type tokenOnlyForm struct {
+	Token string `json:"token" validate:"required"`
+	clientCredentials
+}
+
+func oauthErrorResponse(status int, description string) openapi.Response {
+	return openapi.JSON(status, description, errorJSON{})
+}
+
+var TokenOperation = openapi.Operation{
+	ID:      "oauthToken",
+	Tag:     "OAuth",
+	Summary: "Exchange a code or refresh token",
+	Description: "Exchanges an authorization code, together with its PKCE verifier, for an access token and a refresh " +
+		"token. Refresh tokens issued here can get new access tokens with the same scopes.",
+	Request: &openapi.Request{ContentType: "application/x-www-form-urlencoded", Type: tokenForm{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "Tokens issued", tokenJSON{}),
+		oauthErrorResponse(http.StatusBadRequest, "Invalid grant or request"),
+		oauthErrorResponse(http.StatusUnauthorized, "Client authentication failed"),
+	},
+}
+
+func HandleToken(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = req.ParseForm()
+	if err != nil {
+		respondWithOAuthError(res, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	client, ok, err := authenticateClient(req.Context(), cfg, req)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if !ok {
+		respondWithOAuthError(res, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	switch req.PostForm.Get("grant_type") {
+	case "authorization_code":
+		exchangeAuthorizationCode(res, req, cfg, client)
+	case "refresh_token":
+		exchangeRefreshToken(res, req, cfg, client)
+	default:
+		respondWithOAuthError(res, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token")
+	}
+}
+
+var RevokeOperation = openapi.Operation{
+	ID:      "oauthRevoke",
+	Tag:     "OAuth",
+	Summary: "Revoke a token",
+	Description: "Revokes a refresh token issued to the client (RFC 7009). Unknown tokens are not an error. Access " +
+		"tokens can't be revoked and are refused with unsupported_token_type, they expire after an hour. The access " +
+		"tokens already issued with a revoked refresh token stay valid until they expire as well.",
+	Request: &openapi.Request{ContentType: "application/x-www-form-urlencoded", Type: tokenOnlyForm{}},
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusOK, "Token revoked"),
+		oauthErrorResponse(http.StatusBadRequest, "Invalid form, or the token is an access token"),
+		oauthErrorResponse(http.StatusUnauthorized, "Client authentication failed"),
+	},
+}
+
+// HandleRevoke implements RFC 7009. Only refresh tokens can be revoked, access
+// tokens are short lived JWTs that expire on their own, so they are refused
+// with unsupported_token_type rather than reported as revoked.
+func HandleRevoke(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = req.ParseForm()
+	if err != nil {
+		respondWithOAuthError(res, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	client, ok, err := authenticateClient(req.Context(), cfg, req)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if !ok {
+		respondWithOAuthError(res, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	token := req.PostForm.Get("token")
+
+	if _, err := auth.ParseJWT(token, cfg.AppSecret); err == nil {
+		respondWithOAuthError(res, http.StatusBadRequest, "unsupported_token_type", "access tokens can't be revoked, they expire after an hour")
+		return
+	}
+
+	storedToken, err := cfg.Db.GetRefreshToken(req.Context(), token)
+	if err != nil && err != sql.ErrNoRows {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	// unknown tokens, or tokens of other clients, are not an error (RFC 7009, 2.2)
+	if err == nil && storedToken.ClientID.String == client.ID {
+		err = cfg.Db.RevokeRefreshToken(req.Context(), token)
+		if err != nil {
+			response.RespondWithInternalServerError(res, req, err)
+			return
+		}
+	}
+
+	res.WriteHeader(http.StatusOK)
+}
+
+var IntrospectOperation = openapi.Operation{
+	ID:      "oauthIntrospect",
+	Tag:     "OAuth",
+	Summary: "Introspect a token",
+	Description: "Describes an access or refresh token (RFC 7662). Tokens issued to other clients are reported as " +
+		"inactive.",
+	Request: &openapi.Request{ContentType: "application/x-www-form-urlencoded", Type: tokenOnlyForm{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "Token description", introspectionJSON{}),
+		oauthErrorResponse(http.StatusBadRequest, "Invalid form"),
+		oauthErrorResponse(http.StatusUnauthorized, "Client authentication failed"),
+	},
+}
+
+// HandleIntrospect implements RFC 7662. Clients can only introspect tokens
+// that were issued to them, everything else is reported as inactive.
+func HandleIntrospect(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = req.ParseForm()
+	if err != nil {
+		respondWithOAuthError(res, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	client, ok, err := authenticateClient(req.Context(), cfg, req)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if !ok {
+		respondWithOAuthError(res, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	token := req.PostForm.Get("token")
+	inactive := introspectionJSON{Active: false}
+
+	res.Header().Set("Cache-Control", "no-store")
+
+	if claims, err := auth.ParseJWT(token, cfg.AppSecret); err == nil {
+		if claims.ClientID != client.ID {
+			response.RespondWithJSON(res, http.StatusOK, inactive)
+			return
+		}
+
+		response.RespondWithJSON(res, http.StatusOK, introspectionJSON{
+			Active:    true,
+			Scope:     claims.Scope,
+			ClientID:  claims.ClientID,
+			Subject:   claims.Subject,
+			TokenType: "access_token",
+			ExpiresAt: claims.ExpiresAt.Unix(),
+			IssuedAt:  claims.IssuedAt.Unix(),
+		})
+		return
+	}
+
+	storedToken, err := cfg.Db.GetRefreshToken(req.Context(), token)
+	if err != nil && err != sql.ErrNoRows {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if err == sql.ErrNoRows || storedToken.ClientID.String != client.ID || storedToken.RevokedAt.Valid || storedToken.ExpiresAt.Before(time.Now()) {
+		response.RespondWithJSON(res, http.StatusOK, inactive)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusOK, introspectionJSON{
+		Active:    true,
+		Scope:     strings.Join(storedToken.Scopes, " "),
+		ClientID:  storedToken.ClientID.String,
+		Subject:   storedToken.UserID.String(),
+		TokenType: "refresh_token",
+		ExpiresAt: storedToken.ExpiresAt.Unix(),
+		IssuedAt:  storedToken.CreatedAt.Unix(),
+	})
+}
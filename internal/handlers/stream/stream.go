@@ -0,0 +1,192 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	chirpstream "github.com/lucashthiele/chirpy/internal/stream"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+const heartbeatInterval time.Duration = time.Second * 15
+
+// includeFunc tells if an event the viewer is allowed to see belongs in the
+// stream.
+type includeFunc func(event chirpstream.ChirpEvent, relation chirpstream.Relation) bool
+
+var lastEventIDParameter = openapi.Header(
+	"Last-Event-ID",
+	"The id of the last event received. Recent events after it are sent first, so a dropped stream can resume where it stopped.",
+	openapi3.NewStringSchema(),
+)
+
+var eventsResponse = openapi.Content(
+	http.StatusOK,
+	"An endless stream of chirp.created and chirp.deleted events, whose data is the chirp. A comment is sent every 15 "+
+		"seconds to keep the connection open. Clients that fall too far behind are disconnected and should reconnect "+
+		"with Last-Event-ID.",
+	"text/event-stream",
+)
+
+var TimelineStreamOperation = openapi.Operation{
+	ID:      "streamTimeline",
+	Tag:     "Chirps",
+	Summary: "Stream your timeline",
+	Description: "Streams your chirps and the chirps of the accounts you follow as Server-Sent Events, as soon as they are " +
+		"created or deleted. Muted accounts and unlisted chirps are left out. Requires authentication.",
+	Security:   openapi.Authenticated,
+	Parameters: []openapi.Parameter{lastEventIDParameter},
+	Responses: []openapi.Response{
+		eventsResponse,
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the chirps:read scope"),
+	},
+}
+
+// HandleTimelineStream streams the chirps of the user and of the accounts they
+// follow, like a live home timeline. Muted accounts and unlisted chirps are
+// left out.
+func HandleTimelineStream(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	serveEvents(res, req, cfg, userId, func(event chirpstream.ChirpEvent, relation chirpstream.Relation) bool {
+		return chirpstream.InTimeline(userId, event, relation)
+	})
+}
+
+var ChirpStreamOperation = openapi.Operation{
+	ID:      "streamChirps",
+	Tag:     "Chirps",
+	Summary: "Stream the chirps of an author",
+	Description: "Streams the chirps of one author as Server-Sent Events, as soon as they are created or deleted. " +
+		"Authentication is optional, only the chirps you are allowed to see are sent.",
+	Security: openapi.OptionallyAuthenticated,
+	Parameters: []openapi.Parameter{
+		openapi.RequiredQuery("author_id", "", openapi3.NewUUIDSchema()),
+		lastEventIDParameter,
+	},
+	Responses: []openapi.Response{
+		eventsResponse,
+		openapi.Error(http.StatusBadRequest, "Missing or invalid author_id"),
+		openapi.Error(http.StatusUnauthorized, "Invalid token"),
+		openapi.Error(http.StatusForbidden, "Missing the chirps:read scope"),
+	},
+}
+
+// HandleChirpStream streams the chirps of one author the viewer is allowed to
+// see. Authentication is optional.
+func HandleChirpStream(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	authorId, err := uuid.Parse(req.URL.Query().Get("author_id"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, "author_id is required")
+		return
+	}
+
+	serveEvents(res, req, cfg, config.ViewerID(req.Context()), func(event chirpstream.ChirpEvent, relation chirpstream.Relation) bool {
+		return event.AuthorID == authorId
+	})
+}
+
+// serveEvents writes the events the viewer can see and include keeps as
+// Server-Sent Events, starting with the ones missed since Last-Event-ID, until
+// the client goes away or falls too far behind.
+func serveEvents(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig, viewerId uuid.UUID, include includeFunc) {
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	lastEventID, err := strconv.ParseInt(req.Header.Get("Last-Event-ID"), 10, 64)
+	if err != nil {
+		lastEventID = 0
+	}
+
+	subscription, missed := cfg.ChirpEvents.Subscribe(lastEventID)
+	defer cfg.ChirpEvents.Unsubscribe(subscription)
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	relations := chirpstream.NewRelationCache(cfg.Db, viewerId)
+	send := func(event chirpstream.Event) error {
+		relation, err := relations.Get(req.Context(), event.Payload.AuthorID)
+		if err != nil {
+			return err
+		}
+		if !chirpstream.CanSee(viewerId, event.Payload, relation) || !include(event.Payload, relation) {
+			return nil
+		}
+
+		data := &bytes.Buffer{}
+		err = json.Compact(data, event.Payload.Chirp)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		if err != nil {
+			return err
+		}
+		flusher.Flush()
+
+		return nil
+	}
+
+	for _, event := range missed {
+		if send(event) != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, err := fmt.Fprint(res, ": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			if send(event) != nil {
+				return
+			}
+		}
+	}
+}
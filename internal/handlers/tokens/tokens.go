@@ -0,0 +1,221 @@
+package tokens
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+type params struct {
+	Name          string   `json:"name" validate:"required" description:"A name to tell the token apart"`
+	Scopes        []string `json:"scopes" description:"Scopes granted to the token"`
+	ExpiresInDays int      `json:"expires_in_days" description:"Days until the token expires. Omit for a token that never expires."`
+}
+
+type tokenJSON struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	Token       string     `json:"token,omitempty"`
+}
+
+func toTokenJSON(pat database.PersonalAccessToken) tokenJSON {
+	tokenResp := tokenJSON{
+		ID:          pat.ID,
+		CreatedAt:   pat.CreatedAt,
+		Name:        pat.Name,
+		TokenPrefix: pat.TokenPrefix,
+		Scopes:      pat.Scopes,
+	}
+	if pat.ExpiresAt.Valid {
+		tokenResp.ExpiresAt = &pat.ExpiresAt.Time
+	}
+	if pat.LastUsedAt.Valid {
+		tokenResp.LastUsedAt = &pat.LastUsedAt.Time
+	}
+
+	return tokenResp
+}
+
+var CreateTokenOperation = openapi.Operation{
+	ID:      "createPersonalAccessToken",
+	Tag:     "Auth",
+	Summary: "Create a personal access token",
+	Description: "Creates a long-lived token that can be used as a bearer token instead of a JWT. Only the granted " +
+		"scopes are allowed when authenticating with it. Requires a login JWT, personal access tokens can't create " +
+		"other tokens.",
+	Security: openapi.Authenticated,
+	Request:  &openapi.Request{Type: params{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusCreated, "Token created. The token is only returned here.", tokenJSON{}),
+		openapi.Error(http.StatusBadRequest, "Invalid name, scopes or expiry"),
+		openapi.Unauthorized,
+		openapi.SessionRequired,
+	},
+}
+
+func HandleCreateToken(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	data := &params{}
+
+	err = parser.ParseBody(res, req, data)
+	if err != nil {
+		response.RespondWithErr(res, req, err)
+		return
+	}
+
+	if data.Name == "" {
+		response.RespondWithErr(res, req, response.InvalidField("name", "required", "name is required"))
+		return
+	}
+	if data.ExpiresInDays < 0 {
+		response.RespondWithErr(res, req, response.InvalidField("expires_in_days", "min", "expires_in_days must be positive"))
+		return
+	}
+	err = auth.ValidateScopes(data.Scopes)
+	if err != nil {
+		response.RespondWithErr(res, req, response.InvalidField("scopes", "enum", err.Error()))
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	expiresAt := sql.NullTime{}
+	if data.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().Add(time.Hour * 24 * time.Duration(data.ExpiresInDays)), Valid: true}
+	}
+
+	token := auth.MakePersonalAccessToken()
+
+	pat, err := cfg.Db.CreatePersonalAccessToken(req.Context(), database.CreatePersonalAccessTokenParams{
+		UserID:      userId,
+		Name:        data.Name,
+		TokenHash:   auth.HashToken(token),
+		TokenPrefix: auth.TokenDisplayPrefix(token),
+		Scopes:      data.Scopes,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	// only the hash is stored, this is the one chance to see the token
+	tokenResp := toTokenJSON(pat)
+	tokenResp.Token = token
+
+	response.RespondWithJSON(res, http.StatusCreated, tokenResp)
+}
+
+var ListTokensOperation = openapi.Operation{
+	ID:          "listPersonalAccessTokens",
+	Tag:         "Auth",
+	Summary:     "List personal access tokens",
+	Description: "Lists the authenticated user's active personal access tokens. Requires a login JWT.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "List of tokens", []tokenJSON{}),
+		openapi.Unauthorized,
+		openapi.SessionRequired,
+	},
+}
+
+func HandleListTokens(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	pats, err := cfg.Db.ListPersonalAccessTokensByUser(req.Context(), userId)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	bodyResp := make([]tokenJSON, len(pats))
+	for i, pat := range pats {
+		bodyResp[i] = toTokenJSON(pat)
+	}
+
+	response.RespondWithList(res, req, bodyResp, nil)
+}
+
+var RevokeTokenOperation = openapi.Operation{
+	ID:          "revokePersonalAccessToken",
+	Tag:         "Auth",
+	Summary:     "Revoke a personal access token",
+	Description: "Revokes one of the authenticated user's personal access tokens. Requires a login JWT.",
+	Security:    openapi.Authenticated,
+	Parameters:  []openapi.Parameter{openapi.Path("tokenID", "The ID of the token.", openapi3.NewStringSchema())},
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusNoContent, "Token revoked"),
+		openapi.Unauthorized,
+		openapi.SessionRequired,
+		openapi.Error(http.StatusNotFound, "Token not found"),
+	},
+}
+
+func HandleRevokeToken(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	tokenUUID, err := uuid.Parse(req.PathValue("tokenID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	revoked, err := cfg.Db.RevokePersonalAccessToken(req.Context(), database.RevokePersonalAccessTokenParams{
+		ID:     tokenUUID,
+		UserID: userId,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if revoked == 0 {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusNoContent, nil)
+}
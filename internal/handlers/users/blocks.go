@@ -0,0 +1,155 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+// relationFunc stores or removes a block or mute from the authenticated user
+// to the target user.
+type relationFunc func(ctx context.Context, cfg *config.ApiConfig, userId, targetId uuid.UUID) error
+
+// handleRelation resolves both users of a block or mute before applying it.
+// Both operations are idempotent.
+func handleRelation(res http.ResponseWriter, req *http.Request, apply relationFunc) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	targetId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	if targetId == userId {
+		response.RespondWithError(res, req, http.StatusBadRequest, "you can't do that to yourself")
+		return
+	}
+
+	_, err = cfg.Db.GetUserByID(req.Context(), targetId)
+	if err == sql.ErrNoRows {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = apply(req.Context(), cfg, userId, targetId)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusNoContent, nil)
+}
+
+func relationResponses(done, self string) []openapi.Response {
+	return []openapi.Response{
+		openapi.Empty(http.StatusNoContent, done),
+		openapi.Error(http.StatusBadRequest, self),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the profile:write scope"),
+		openapi.Error(http.StatusNotFound, "User not found"),
+	}
+}
+
+var BlockUserOperation = openapi.Operation{
+	ID:          "blockUser",
+	Tag:         "Users",
+	Summary:     "Block a user",
+	Description: "Blocking works both ways, neither user sees the other's chirps. Any follow between you is removed. Blocking a user twice is not an error.",
+	Security:    openapi.Authenticated,
+	Responses:   relationResponses("User blocked", "You can't block yourself"),
+}
+
+// HandleBlockUser hides both users' chirps from each other and removes any
+// follow between them.
+func HandleBlockUser(res http.ResponseWriter, req *http.Request) {
+	handleRelation(res, req, func(ctx context.Context, cfg *config.ApiConfig, userId, targetId uuid.UUID) error {
+		tx, err := cfg.DbConn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		qtx := cfg.Db.WithTx(tx)
+
+		err = qtx.BlockUser(ctx, database.BlockUserParams{BlockerID: userId, BlockedID: targetId})
+		if err != nil {
+			return err
+		}
+
+		err = qtx.DeleteFollowsBetween(ctx, database.DeleteFollowsBetweenParams{FollowerID: userId, FollowedID: targetId})
+		if err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	})
+}
+
+var UnblockUserOperation = openapi.Operation{
+	ID:          "unblockUser",
+	Tag:         "Users",
+	Summary:     "Unblock a user",
+	Description: "Removes the block. Unblocking a user that isn't blocked is not an error.",
+	Security:    openapi.Authenticated,
+	Responses:   relationResponses("User unblocked", "You can't block yourself"),
+}
+
+func HandleUnblockUser(res http.ResponseWriter, req *http.Request) {
+	handleRelation(res, req, func(ctx context.Context, cfg *config.ApiConfig, userId, targetId uuid.UUID) error {
+		return cfg.Db.UnblockUser(ctx, database.UnblockUserParams{BlockerID: userId, BlockedID: targetId})
+	})
+}
+
+var MuteUserOperation = openapi.Operation{
+	ID:          "muteUser",
+	Tag:         "Users",
+	Summary:     "Mute a user",
+	Description: "Hides the user's chirps from your chirp listings, unless you filter by them. They are not told. Muting a user twice is not an error.",
+	Security:    openapi.Authenticated,
+	Responses:   relationResponses("User muted", "You can't mute yourself"),
+}
+
+// HandleMuteUser hides the target's chirps from the user's chirp listings,
+// without the target noticing anything.
+func HandleMuteUser(res http.ResponseWriter, req *http.Request) {
+	handleRelation(res, req, func(ctx context.Context, cfg *config.ApiConfig, userId, targetId uuid.UUID) error {
+		return cfg.Db.MuteUser(ctx, database.MuteUserParams{MuterID: userId, MutedID: targetId})
+	})
+}
+
+var UnmuteUserOperation = openapi.Operation{
+	ID:          "unmuteUser",
+	Tag:         "Users",
+	Summary:     "Unmute a user",
+	Description: "Removes the mute. Unmuting a user that isn't muted is not an error.",
+	Security:    openapi.Authenticated,
+	Responses:   relationResponses("User unmuted", "You can't mute yourself"),
+}
+
+func HandleUnmuteUser(res http.ResponseWriter, req *http.Request) {
+	handleRelation(res, req, func(ctx context.Context, cfg *config.ApiConfig, userId, targetId uuid.UUID) error {
+		return cfg.Db.UnmuteUser(ctx, database.UnmuteUserParams{MuterID: userId, MutedID: targetId})
+	})
+}
@@ -0,0 +1,179 @@
+package users
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+// deletionGracePeriod is how long the user has to change their mind before
+// the account and everything in it is deleted for good.
+const deletionGracePeriod time.Duration = time.Hour * 24 * 30 // 30 days
+
+type deleteParams struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type deletionJSON struct {
+	RequestedAt time.Time `json:"requested_at"`
+	DeleteAfter time.Time `json:"delete_after"`
+}
+
+var DeleteUserOperation = openapi.Operation{
+	ID:      "deleteUser",
+	Tag:     "Users",
+	Summary: "Delete your account",
+	Description: "Schedules the account for deletion after a 30 day grace period, during which it can be cancelled. " +
+		"After that the user, chirps, tokens and everything else they own are deleted for good. The password has to be " +
+		"confirmed, accounts without one have to set one first with PUT /api/users. Requires a login JWT.",
+	Security: openapi.Authenticated,
+	Request:  &openapi.Request{Type: deleteParams{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusAccepted, "Account scheduled for deletion", deletionJSON{}),
+		openapi.Error(http.StatusBadRequest, "Invalid body"),
+		openapi.Error(http.StatusUnauthorized, "Unauthorized or incorrect password"),
+		openapi.SessionRequired,
+	},
+}
+
+// HandleDeleteUser schedules the account for deletion once the password is
+// confirmed. Asking again keeps the original schedule.
+func HandleDeleteUser(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	data := &deleteParams{}
+
+	err = parser.ParseBody(res, req, data)
+	if err != nil {
+		response.RespondWithErr(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	user, err := cfg.Db.GetUserByID(req.Context(), userId)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = auth.CheckPassword(user.HashedPassword, data.Password)
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusUnauthorized, "incorrect password")
+		return
+	}
+
+	deletion, err := cfg.Db.ScheduleAccountDeletion(req.Context(), database.ScheduleAccountDeletionParams{
+		UserID:      userId,
+		DeleteAfter: time.Now().Add(deletionGracePeriod),
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusAccepted, deletionJSON{
+		RequestedAt: deletion.RequestedAt,
+		DeleteAfter: deletion.DeleteAfter,
+	})
+}
+
+var GetDeletionOperation = openapi.Operation{
+	ID:          "getAccountDeletion",
+	Tag:         "Users",
+	Summary:     "Get the scheduled account deletion",
+	Description: "Requires a login JWT.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "The account is scheduled for deletion", deletionJSON{}),
+		openapi.Unauthorized,
+		openapi.SessionRequired,
+		openapi.Error(http.StatusNotFound, "The account is not scheduled for deletion"),
+	},
+}
+
+func HandleGetDeletion(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	deletion, err := cfg.Db.GetAccountDeletion(req.Context(), userId)
+	if err == sql.ErrNoRows {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusOK, deletionJSON{
+		RequestedAt: deletion.RequestedAt,
+		DeleteAfter: deletion.DeleteAfter,
+	})
+}
+
+var CancelDeletionOperation = openapi.Operation{
+	ID:          "cancelAccountDeletion",
+	Tag:         "Users",
+	Summary:     "Cancel the account deletion",
+	Description: "Keeps the account when it's still within the grace period. Requires a login JWT.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusNoContent, "Deletion cancelled"),
+		openapi.Unauthorized,
+		openapi.SessionRequired,
+		openapi.Error(http.StatusNotFound, "The account is not scheduled for deletion"),
+	},
+}
+
+func HandleCancelDeletion(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	cancelled, err := cfg.Db.CancelAccountDeletion(req.Context(), userId)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if cancelled == 0 {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusNoContent, nil)
+}
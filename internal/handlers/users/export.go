@@ -0,0 +1,197 @@
+package users
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/export"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+// downloadURLExpiry is how long a download link stays valid, a new one is
+// handed out every time the export is fetched.
+const downloadURLExpiry time.Duration = time.Hour
+
+type exportJSON struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Status      string     `json:"status" enum:"pending,building,ready,failed"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	DownloadURL string     `json:"download_url,omitempty" description:"A signed link that is valid for an hour, only set when the export is ready."`
+}
+
+func downloadPath(exportID uuid.UUID) string {
+	return "/api/exports/" + exportID.String() + "/download"
+}
+
+func toExportJSON(cfg *config.ApiConfig, userExport database.UserExport) exportJSON {
+	exportResp := exportJSON{
+		ID:        userExport.ID,
+		CreatedAt: userExport.CreatedAt,
+		Status:    userExport.Status,
+	}
+	if userExport.CompletedAt.Valid {
+		exportResp.CompletedAt = &userExport.CompletedAt.Time
+	}
+	if userExport.ExpiresAt.Valid {
+		exportResp.ExpiresAt = &userExport.ExpiresAt.Time
+	}
+	if userExport.Status == export.StatusReady {
+		exportResp.DownloadURL = auth.SignURL(cfg.AppSecret, downloadPath(userExport.ID), time.Now().Add(downloadURLExpiry))
+	}
+
+	return exportResp
+}
+
+var CreateExportOperation = openapi.Operation{
+	ID:      "createUserExport",
+	Tag:     "Users",
+	Summary: "Export your data",
+	Description: "Starts building a ZIP archive with the user's profile, chirps and follows, each as a JSON file. " +
+		"Poll the export until it's ready to get a download link. Requires a login JWT.",
+	Security: openapi.Authenticated,
+	Responses: []openapi.Response{
+		{
+			Status:      http.StatusAccepted,
+			Description: "Export queued",
+			Type:        exportJSON{},
+			Headers:     map[string]string{"Location": "The URL of the export"},
+		},
+		openapi.Unauthorized,
+		openapi.SessionRequired,
+	},
+}
+
+// HandleCreateExport queues a new export of the user's data, it is built in
+// the background.
+func HandleCreateExport(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	userExport, err := cfg.Db.CreateUserExport(req.Context(), userId)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	res.Header().Set("Location", "/api/users/export/"+userExport.ID.String())
+	response.RespondWithJSON(res, http.StatusAccepted, toExportJSON(cfg, userExport))
+}
+
+var GetExportOperation = openapi.Operation{
+	ID:          "getUserExport",
+	Tag:         "Users",
+	Summary:     "Get a data export",
+	Description: "Requires a login JWT.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "The export", exportJSON{}),
+		openapi.Unauthorized,
+		openapi.SessionRequired,
+		openapi.Error(http.StatusNotFound, "Export not found"),
+	},
+}
+
+func HandleGetExport(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	exportUUID, err := uuid.Parse(req.PathValue("exportID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	userExport, err := cfg.Db.GetUserExport(req.Context(), exportUUID)
+	if err == sql.ErrNoRows || (err == nil && userExport.UserID != userId) {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusOK, toExportJSON(cfg, userExport))
+}
+
+var DownloadExportOperation = openapi.Operation{
+	ID:          "downloadUserExport",
+	Tag:         "Users",
+	Summary:     "Download a data export",
+	Description: "Downloads the archive with a signed link from the export. No authentication is needed.",
+	Parameters: []openapi.Parameter{
+		openapi.RequiredQuery("expires", "", openapi3.NewInt64Schema()),
+		openapi.RequiredQuery("signature", "", openapi3.NewStringSchema()),
+	},
+	Responses: []openapi.Response{
+		openapi.Content(http.StatusOK, "The ZIP archive", "application/zip"),
+		openapi.Error(http.StatusForbidden, "Invalid or expired link"),
+		openapi.Error(http.StatusNotFound, "Export not found or expired"),
+	},
+}
+
+// HandleDownloadExport serves the archive to anyone holding a signed download
+// URL, so it can be opened straight from a browser.
+func HandleDownloadExport(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	exportUUID, err := uuid.Parse(req.PathValue("exportID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	err = auth.VerifySignedURL(cfg.AppSecret, downloadPath(exportUUID), req.URL.Query(), time.Now())
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusForbidden, err.Error())
+		return
+	}
+
+	userExport, err := cfg.Db.GetUserExport(req.Context(), exportUUID)
+	if err == sql.ErrNoRows || (err == nil && userExport.Status != export.StatusReady) {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/zip")
+	res.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chirpy-export-%s.zip"`, userExport.CreatedAt.Format("2006-01-02")))
+	res.Header().Set("Cache-Control", "private, no-store")
+	res.WriteHeader(http.StatusOK)
+	res.Write(userExport.Archive)
+}
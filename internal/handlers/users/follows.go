@@ -0,0 +1,505 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/gateway"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/outbound"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+const followStatusApproved = "approved"
+
+const (
+	notificationFollowRequested = "follow.requested"
+	notificationFollowCreated   = "follow.created"
+	notificationFollowApproved  = "follow.approved"
+)
+
+type followJSON struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Status    string    `json:"status" enum:"pending,approved"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type followRequestJSON struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type followedEventJSON struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FollowedID uuid.UUID `json:"followed_id"`
+}
+
+type privacyParams struct {
+	IsPrivate *bool `json:"is_private" validate:"required"`
+}
+
+// enqueueFollowed tells the followed user's webhook endpoints about a follow
+// once it is approved.
+func enqueueFollowed(ctx context.Context, qtx *database.Queries, followerId, followedId uuid.UUID) error {
+	return outbound.Enqueue(ctx, qtx, followedId, outbound.EventUserFollowed, followedEventJSON{
+		FollowerID: followerId,
+		FollowedID: followedId,
+	})
+}
+
+// notifyFollow pushes a notification about the follow to the recipient's
+// gateway clients once the transaction qtx is bound to commits.
+func notifyFollow(ctx context.Context, qtx *database.Queries, cfg *config.ApiConfig, recipientId uuid.UUID, notificationType string, followerId, followedId uuid.UUID) error {
+	message, err := gateway.NewMessage(gateway.ChannelNotifications, notificationType, followedEventJSON{
+		FollowerID: followerId,
+		FollowedID: followedId,
+	})
+	if err != nil {
+		return err
+	}
+
+	return gateway.Notify(ctx, qtx, cfg.MessageKeys, []uuid.UUID{recipientId}, message)
+}
+
+var FollowUserOperation = openapi.Operation{
+	ID:      "followUser",
+	Tag:     "Users",
+	Summary: "Follow a user",
+	Description: "Following a private account creates a pending request the owner has to approve. Following a user " +
+		"again returns the current follow. Users who blocked each other can't follow each other.",
+	Security: openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "Already following or requested", followJSON{}),
+		openapi.JSON(http.StatusCreated, "Followed, or follow requested", followJSON{}),
+		openapi.Error(http.StatusBadRequest, "You can't follow yourself"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the profile:write scope, or one of you blocked the other"),
+		openapi.Error(http.StatusNotFound, "User not found"),
+	},
+}
+
+// HandleFollowUser follows the target user. Following a private account only
+// creates a request the owner has to approve, following again returns the
+// current state of the follow.
+func HandleFollowUser(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	targetId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	if targetId == userId {
+		response.RespondWithError(res, req, http.StatusBadRequest, "you can't do that to yourself")
+		return
+	}
+
+	_, err = cfg.Db.GetUserByID(req.Context(), targetId)
+	if err == sql.ErrNoRows {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	blocked, err := cfg.Db.IsBlockedEitherWay(req.Context(), database.IsBlockedEitherWayParams{
+		BlockerID: userId,
+		BlockedID: targetId,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if blocked {
+		response.RespondWithError(res, req, http.StatusForbidden, "you can't follow this user")
+		return
+	}
+
+	tx, err := cfg.DbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.Db.WithTx(tx)
+
+	status := http.StatusOK
+	follow, err := qtx.GetFollow(req.Context(), database.GetFollowParams{
+		FollowerID: userId,
+		FollowedID: targetId,
+	})
+	if err == sql.ErrNoRows {
+		status = http.StatusCreated
+		follow, err = qtx.CreateFollow(req.Context(), database.CreateFollowParams{
+			FollowerID: userId,
+			FollowedID: targetId,
+		})
+		if err == nil && follow.Status == followStatusApproved {
+			err = enqueueFollowed(req.Context(), qtx, userId, targetId)
+		}
+		if err == nil {
+			notificationType := notificationFollowRequested
+			if follow.Status == followStatusApproved {
+				notificationType = notificationFollowCreated
+			}
+			err = notifyFollow(req.Context(), qtx, cfg, targetId, notificationType, userId, targetId)
+		}
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, status, followJSON{
+		UserID:    follow.FollowedID,
+		Status:    follow.Status,
+		CreatedAt: follow.CreatedAt,
+	})
+}
+
+var UnfollowUserOperation = openapi.Operation{
+	ID:          "unfollowUser",
+	Tag:         "Users",
+	Summary:     "Unfollow a user",
+	Description: "Stops following the user, or withdraws a pending follow request.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusNoContent, "User unfollowed"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the profile:write scope"),
+		openapi.Error(http.StatusNotFound, "Not following this user"),
+	},
+}
+
+// HandleUnfollowUser stops following the target user, or withdraws a pending
+// follow request.
+func HandleUnfollowUser(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	targetId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	deleted, err := cfg.Db.DeleteFollow(req.Context(), database.DeleteFollowParams{
+		FollowerID: userId,
+		FollowedID: targetId,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if deleted == 0 {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusNoContent, nil)
+}
+
+var ListFollowRequestsOperation = openapi.Operation{
+	ID:          "listFollowRequests",
+	Tag:         "Users",
+	Summary:     "List follow requests",
+	Description: "Lists the pending requests to follow you, oldest first.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "Pending follow requests", []followRequestJSON{}),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the profile:write scope"),
+	},
+}
+
+func HandleListFollowRequests(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	requests, err := cfg.Db.ListPendingFollowRequests(req.Context(), userId)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	requestsResp := make([]followRequestJSON, len(requests))
+	for i, request := range requests {
+		requestsResp[i] = followRequestJSON{
+			UserID:    request.FollowerID,
+			Email:     request.Email,
+			CreatedAt: request.CreatedAt,
+		}
+	}
+
+	response.RespondWithList(res, req, requestsResp, nil)
+}
+
+var ApproveFollowRequestOperation = openapi.Operation{
+	ID:          "approveFollowRequest",
+	Tag:         "Users",
+	Summary:     "Approve a follow request",
+	Description: "The user starts seeing your chirps. Sends the user.followed event to your webhooks.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusNoContent, "Follow request approved"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the profile:write scope"),
+		openapi.Error(http.StatusNotFound, "No pending request from this user"),
+	},
+}
+
+func HandleApproveFollowRequest(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	followerId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	tx, err := cfg.DbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.Db.WithTx(tx)
+
+	approved, err := qtx.ApproveFollowRequest(req.Context(), database.ApproveFollowRequestParams{
+		FollowerID: followerId,
+		FollowedID: userId,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if approved == 0 {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	err = enqueueFollowed(req.Context(), qtx, followerId, userId)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = notifyFollow(req.Context(), qtx, cfg, followerId, notificationFollowApproved, followerId, userId)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusNoContent, nil)
+}
+
+var DenyFollowRequestOperation = openapi.Operation{
+	ID:          "denyFollowRequest",
+	Tag:         "Users",
+	Summary:     "Deny a follow request",
+	Description: "Deletes the request, the user can ask again.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusNoContent, "Follow request denied"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the profile:write scope"),
+		openapi.Error(http.StatusNotFound, "No pending request from this user"),
+	},
+}
+
+func HandleDenyFollowRequest(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	followerId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	denied, err := cfg.Db.DenyFollowRequest(req.Context(), database.DenyFollowRequestParams{
+		FollowerID: followerId,
+		FollowedID: userId,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	if denied == 0 {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusNoContent, nil)
+}
+
+var UpdatePrivacyOperation = openapi.Operation{
+	ID:      "updatePrivacy",
+	Tag:     "Users",
+	Summary: "Make your account private or public",
+	Description: "Chirps of a private account are only visible to you and your approved followers, and following you " +
+		"needs your approval. Making the account public approves every pending follow request.",
+	Security: openapi.Authenticated,
+	Request:  &openapi.Request{Type: privacyParams{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "Privacy updated", userJSON{}),
+		openapi.Error(http.StatusBadRequest, "Invalid body"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the profile:write scope"),
+	},
+}
+
+// HandleUpdatePrivacy makes the account private or public. Going public
+// approves every pending follow request, since nothing is left to approve.
+func HandleUpdatePrivacy(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	data := &privacyParams{}
+
+	err = parser.ParseBody(res, req, data)
+	if err != nil {
+		response.RespondWithErr(res, req, err)
+		return
+	}
+	if data.IsPrivate == nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, "is_private is required")
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	tx, err := cfg.DbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.Db.WithTx(tx)
+
+	updatedUser, err := qtx.SetUserPrivacy(req.Context(), database.SetUserPrivacyParams{
+		IsPrivate: *data.IsPrivate,
+		ID:        userId,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	if !updatedUser.IsPrivate {
+		followerIds, err := qtx.ApproveAllFollowRequests(req.Context(), userId)
+		if err != nil {
+			response.RespondWithInternalServerError(res, req, err)
+			return
+		}
+
+		for _, followerId := range followerIds {
+			err = enqueueFollowed(req.Context(), qtx, followerId, userId)
+			if err != nil {
+				response.RespondWithInternalServerError(res, req, err)
+				return
+			}
+
+			err = notifyFollow(req.Context(), qtx, cfg, followerId, notificationFollowApproved, followerId, userId)
+			if err != nil {
+				response.RespondWithInternalServerError(res, req, err)
+				return
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusOK, userJSON{
+		ID:          updatedUser.ID,
+		CreatedAt:   updatedUser.CreatedAt.Time,
+		UpdatedAt:   updatedUser.UpdatedAt.Time,
+		Email:       updatedUser.Email,
+		IsChirpyRed: updatedUser.IsChirpyRed,
+		IsPrivate:   updatedUser.IsPrivate,
+	})
+}
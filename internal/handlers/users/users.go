@@ -9,13 +9,14 @@ import (
 	"github.com/lucashthiele/chirpy/internal/auth"
 	"github.com/lucashthiele/chirpy/internal/config"
 	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
 	"github.com/lucashthiele/chirpy/pkg/parser"
 	"github.com/lucashthiele/chirpy/pkg/response"
 )
 
 type params struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email" description:"The user's email"`
+	Password string `json:"password" validate:"required" description:"The user's password"`
 }
 
 type userJSON struct {
@@ -24,24 +25,43 @@ type userJSON struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 	Email       string    `json:"email"`
 	IsChirpyRed bool      `json:"is_chirpy_red"`
+	IsPrivate   bool      `json:"is_private"`
+}
+
+var CreateUserOperation = openapi.Operation{
+	ID:          "createUser",
+	Tag:         "Users",
+	Summary:     "Create user",
+	Description: "Creates a new user.",
+	Parameters:  []openapi.Parameter{openapi.IdempotencyKey},
+	Request:     &openapi.Request{Type: params{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusCreated, "User created successfully", userJSON{}),
+		openapi.Error(http.StatusBadRequest, "Invalid input"),
+		openapi.IdempotencyKeyInUse,
+		openapi.IdempotencyKeyReused,
+	},
 }
 
 func HandleCreateUsers(res http.ResponseWriter, req *http.Request) {
 	cfg, err := config.New()
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithInternalServerError(res, req, err)
+		return
 	}
 
 	data := &params{}
 
-	err = parser.ParseBody(req.Body, data)
+	err = parser.ParseBody(res, req, data)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithErr(res, req, err)
+		return
 	}
 
 	hashedPassword, err := auth.HashPassword(data.Password)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithInternalServerError(res, req, err)
+		return
 	}
 
 	userParams := database.CreateUserParams{
@@ -51,7 +71,8 @@ func HandleCreateUsers(res http.ResponseWriter, req *http.Request) {
 
 	createdUser, err := cfg.Db.CreateUser(req.Context(), userParams)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithInternalServerError(res, req, err)
+		return
 	}
 
 	userJSON := userJSON{
@@ -60,33 +81,52 @@ func HandleCreateUsers(res http.ResponseWriter, req *http.Request) {
 		UpdatedAt:   createdUser.UpdatedAt.Time,
 		Email:       createdUser.Email,
 		IsChirpyRed: createdUser.IsChirpyRed,
+		IsPrivate:   createdUser.IsPrivate,
 	}
 
 	response.RespondWithJSON(res, http.StatusCreated, userJSON)
 }
 
+var UpdateUserOperation = openapi.Operation{
+	ID:          "updateUser",
+	Tag:         "Users",
+	Summary:     "Update user",
+	Description: "Updates the authenticated user's email and password. Requires authentication.",
+	Security:    openapi.Authenticated,
+	Request:     &openapi.Request{Type: params{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "User updated successfully", userJSON{}),
+		openapi.Error(http.StatusBadRequest, "Invalid input"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the profile:write scope"),
+	},
+}
+
 func HandleUpdateUsers(res http.ResponseWriter, req *http.Request) {
 	cfg, err := config.New()
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithInternalServerError(res, req, err)
+		return
 	}
 
 	data := &params{}
 
-	err = parser.ParseBody(req.Body, data)
+	err = parser.ParseBody(res, req, data)
 	if err != nil {
-		response.RespondWithError(res, http.StatusBadRequest, err.Error())
+		response.RespondWithErr(res, req, err)
+		return
 	}
 
 	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
 	if !ok {
-		response.RespondWithInternalServerError(res, fmt.Errorf("omg you're so bad at this"))
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
 		return
 	}
 
 	hashedPassword, err := auth.HashPassword(data.Password)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithInternalServerError(res, req, err)
+		return
 	}
 
 	updateParams := database.UpdateUserEmailAndPasswordParams{
@@ -97,7 +137,8 @@ func HandleUpdateUsers(res http.ResponseWriter, req *http.Request) {
 
 	updatedUser, err := cfg.Db.UpdateUserEmailAndPassword(req.Context(), updateParams)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithInternalServerError(res, req, err)
+		return
 	}
 
 	userJSON := userJSON{
@@ -106,6 +147,7 @@ func HandleUpdateUsers(res http.ResponseWriter, req *http.Request) {
 		UpdatedAt:   updatedUser.UpdatedAt.Time,
 		Email:       updatedUser.Email,
 		IsChirpyRed: updatedUser.IsChirpyRed,
+		IsPrivate:   updatedUser.IsPrivate,
 	}
 
 	response.RespondWithJSON(res, http.StatusOK, userJSON)
@@ -0,0 +1,347 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/outbound"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+type endpointParams struct {
+	URL    string   `json:"url" validate:"required" description:"HTTPS URL deliveries are sent to, its host must resolve to public addresses"`
+	Events []string `json:"events" validate:"required" description:"Events to subscribe to"`
+}
+
+type endpointJSON struct {
+	ID                  uuid.UUID  `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	Secret              string     `json:"secret,omitempty"`
+}
+
+type attemptJSON struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	DeliveryID     uuid.UUID `json:"delivery_id"`
+	Event          string    `json:"event"`
+	ResponseStatus *int32    `json:"response_status"`
+	Error          *string   `json:"error"`
+	DurationMs     int32     `json:"duration_ms"`
+}
+
+func toEndpointJSON(endpoint database.WebhookEndpoint) endpointJSON {
+	endpointResp := endpointJSON{
+		ID:                  endpoint.ID,
+		CreatedAt:           endpoint.CreatedAt,
+		UpdatedAt:           endpoint.UpdatedAt,
+		URL:                 endpoint.Url,
+		Events:              endpoint.Events,
+		Enabled:             endpoint.Enabled,
+		ConsecutiveFailures: endpoint.ConsecutiveFailures,
+	}
+	if endpoint.DisabledAt.Valid {
+		endpointResp.DisabledAt = &endpoint.DisabledAt.Time
+	}
+
+	return endpointResp
+}
+
+func validateEndpoint(ctx context.Context, params *endpointParams) error {
+	endpointURL, err := url.Parse(params.URL)
+	if err != nil || endpointURL.Host == "" {
+		return fmt.Errorf("invalid url")
+	}
+	if endpointURL.Scheme != "https" {
+		return fmt.Errorf("url must use https")
+	}
+	err = outbound.CheckURL(ctx, params.URL)
+	if err != nil {
+		return fmt.Errorf("url must be reachable from the internet: %s", err)
+	}
+
+	if len(params.Events) == 0 {
+		return fmt.Errorf("at least one event is required")
+	}
+	for _, event := range params.Events {
+		if !outbound.IsValidEvent(event) {
+			return fmt.Errorf("unknown event %s", event)
+		}
+	}
+
+	return nil
+}
+
+// getOwnEndpoint loads the endpoint in the path, answering 404 when it doesn't
+// exist or belongs to someone else.
+func getOwnEndpoint(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig) (database.WebhookEndpoint, bool) {
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return database.WebhookEndpoint{}, false
+	}
+
+	endpointUUID, err := uuid.Parse(req.PathValue("endpointID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return database.WebhookEndpoint{}, false
+	}
+
+	endpoint, err := cfg.Db.GetWebhookEndpointByID(req.Context(), endpointUUID)
+	if err == sql.ErrNoRows || (err == nil && endpoint.UserID != userId) {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return database.WebhookEndpoint{}, false
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return database.WebhookEndpoint{}, false
+	}
+
+	return endpoint, true
+}
+
+var CreateEndpointOperation = openapi.Operation{
+	ID:      "createWebhookEndpoint",
+	Tag:     "Webhooks",
+	Summary: "Register a webhook endpoint",
+	Description: "Registers an HTTPS endpoint that receives the authenticated user's events. Every delivery is a POST " +
+		"signed with X-Chirpy-Signature (v1=<hex hmac-sha256 of \"<X-Chirpy-Timestamp>.<body>\">) using the endpoint's " +
+		"secret. Failed deliveries are retried with exponential backoff, and the endpoint is disabled after 20 " +
+		"consecutive failures. Requires authentication.",
+	Security: openapi.Authenticated,
+	Request:  &openapi.Request{Type: endpointParams{}},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusCreated, "Endpoint registered. The secret is only returned here.", endpointJSON{}),
+		openapi.Error(http.StatusBadRequest, "Invalid url or events"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the webhooks:write scope"),
+	},
+}
+
+func HandleCreateEndpoint(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	params := &endpointParams{}
+
+	err = parser.ParseBody(res, req, params)
+	if err != nil {
+		response.RespondWithErr(res, req, err)
+		return
+	}
+
+	err = validateEndpoint(req.Context(), params)
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	endpoint, err := cfg.Db.CreateWebhookEndpoint(req.Context(), database.CreateWebhookEndpointParams{
+		UserID: userId,
+		Url:    params.URL,
+		Secret: "whsec_" + auth.MakeRefreshToken(),
+		Events: params.Events,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	// the secret is only ever shown once, right after the endpoint is created
+	endpointResp := toEndpointJSON(endpoint)
+	endpointResp.Secret = endpoint.Secret
+
+	response.RespondWithJSON(res, http.StatusCreated, endpointResp)
+}
+
+var ListEndpointsOperation = openapi.Operation{
+	ID:          "listWebhookEndpoints",
+	Tag:         "Webhooks",
+	Summary:     "List webhook endpoints",
+	Description: "Lists the authenticated user's webhook endpoints. Requires authentication.",
+	Security:    openapi.Authenticated,
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "List of webhook endpoints", []endpointJSON{}),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the webhooks:write scope"),
+	},
+}
+
+func HandleListEndpoints(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	endpoints, err := cfg.Db.ListWebhookEndpointsByUser(req.Context(), userId)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	bodyResp := make([]endpointJSON, len(endpoints))
+	for i, endpoint := range endpoints {
+		bodyResp[i] = toEndpointJSON(endpoint)
+	}
+
+	response.RespondWithList(res, req, bodyResp, nil)
+}
+
+var DeleteEndpointOperation = openapi.Operation{
+	ID:          "deleteWebhookEndpoint",
+	Tag:         "Webhooks",
+	Summary:     "Delete a webhook endpoint",
+	Description: "Deletes the endpoint and its pending deliveries. Requires authentication.",
+	Security:    openapi.Authenticated,
+	Parameters:  []openapi.Parameter{openapi.Path("endpointID", "The ID of the endpoint.", openapi3.NewStringSchema())},
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusNoContent, "Endpoint deleted"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the webhooks:write scope"),
+		openapi.Error(http.StatusNotFound, "Endpoint not found"),
+	},
+}
+
+func HandleDeleteEndpoint(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	endpoint, ok := getOwnEndpoint(res, req, cfg)
+	if !ok {
+		return
+	}
+
+	err = cfg.Db.DeleteWebhookEndpoint(req.Context(), endpoint.ID)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusNoContent, nil)
+}
+
+var EnableEndpointOperation = openapi.Operation{
+	ID:          "enableWebhookEndpoint",
+	Tag:         "Webhooks",
+	Summary:     "Re-enable a webhook endpoint",
+	Description: "Re-enables an endpoint that was disabled after repeated delivery failures. Requires authentication.",
+	Security:    openapi.Authenticated,
+	Parameters:  []openapi.Parameter{openapi.Path("endpointID", "The ID of the endpoint.", openapi3.NewStringSchema())},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "The re-enabled endpoint", endpointJSON{}),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the webhooks:write scope"),
+		openapi.Error(http.StatusNotFound, "Endpoint not found"),
+	},
+}
+
+// HandleEnableEndpoint re-enables an endpoint that was disabled after failing
+// too many deliveries in a row.
+func HandleEnableEndpoint(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	endpoint, ok := getOwnEndpoint(res, req, cfg)
+	if !ok {
+		return
+	}
+
+	enabled, err := cfg.Db.EnableWebhookEndpoint(req.Context(), endpoint.ID)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusOK, toEndpointJSON(enabled))
+}
+
+var ListEndpointAttemptsOperation = openapi.Operation{
+	ID:          "listWebhookDeliveryAttempts",
+	Tag:         "Webhooks",
+	Summary:     "List delivery attempts",
+	Description: "Lists the latest 100 delivery attempts made to the endpoint. Requires authentication.",
+	Security:    openapi.Authenticated,
+	Parameters:  []openapi.Parameter{openapi.Path("endpointID", "The ID of the endpoint.", openapi3.NewStringSchema())},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "List of delivery attempts", []attemptJSON{}),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusForbidden, "Missing the webhooks:write scope"),
+		openapi.Error(http.StatusNotFound, "Endpoint not found"),
+	},
+}
+
+func HandleListEndpointAttempts(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	endpoint, ok := getOwnEndpoint(res, req, cfg)
+	if !ok {
+		return
+	}
+
+	attempts, err := cfg.Db.ListWebhookDeliveryAttempts(req.Context(), endpoint.ID)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	bodyResp := make([]attemptJSON, len(attempts))
+	for i, attempt := range attempts {
+		bodyResp[i] = attemptJSON{
+			ID:         attempt.ID,
+			CreatedAt:  attempt.CreatedAt,
+			DeliveryID: attempt.DeliveryID,
+			Event:      attempt.Event,
+			DurationMs: attempt.DurationMs,
+		}
+		if attempt.ResponseStatus.Valid {
+			bodyResp[i].ResponseStatus = &attempt.ResponseStatus.Int32
+		}
+		if attempt.Error.Valid {
+			bodyResp[i].Error = &attempt.Error.String
+		}
+	}
+
+	response.RespondWithList(res, req, bodyResp, nil)
+}
@@ -0,0 +1,208 @@
+package webhooks
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/pagination"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+type eventJSON struct {
+	ID             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	Source         string          `json:"source"`
+	Headers        json.RawMessage `json:"headers"`
+	Body           string          `json:"body"`
+	Status         string          `json:"status" enum:"received,processed,rejected,failed"`
+	ResponseStatus *int32          `json:"response_status"`
+	Error          *string         `json:"error"`
+	Attempts       int32           `json:"attempts"`
+}
+
+func toEventJSON(event database.WebhookEvent) eventJSON {
+	eventResp := eventJSON{
+		ID:        event.ID,
+		CreatedAt: event.CreatedAt,
+		UpdatedAt: event.UpdatedAt,
+		Source:    event.Source,
+		Headers:   event.Headers,
+		Body:      string(event.Body),
+		Status:    event.Status,
+		Attempts:  event.Attempts,
+	}
+	if event.ResponseStatus.Valid {
+		eventResp.ResponseStatus = &event.ResponseStatus.Int32
+	}
+	if event.Error.Valid {
+		eventResp.Error = &event.Error.String
+	}
+
+	return eventResp
+}
+
+func getEvent(res http.ResponseWriter, req *http.Request, cfg *config.ApiConfig) (database.WebhookEvent, bool) {
+	eventUUID, err := uuid.Parse(req.PathValue("eventID"))
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, "invalid event id")
+		return database.WebhookEvent{}, false
+	}
+
+	event, err := cfg.Db.GetWebhookEventByID(req.Context(), eventUUID)
+	if err == sql.ErrNoRows {
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return database.WebhookEvent{}, false
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return database.WebhookEvent{}, false
+	}
+
+	return event, true
+}
+
+var ListEventsOperation = openapi.Operation{
+	ID:          "listWebhookEvents",
+	Tag:         "Admin",
+	Summary:     "List webhook events",
+	Description: "Lists the authenticated inbound webhook requests, newest first. Requires the admin API key.",
+	Security:    []openapi.Security{openapi.AdminAPIKey},
+	Parameters: append([]openapi.Parameter{
+		openapi.Query("status", "Only return events with this status.", openapi3.NewStringSchema().WithEnum("received", "processed", "rejected", "failed")),
+	}, pagination.Parameters("The cursor from the Link header of the previous page.")...),
+	Responses: []openapi.Response{
+		{Status: http.StatusOK, Description: "A page of webhook events", Type: []eventJSON{}, Headers: pagination.LinkHeader},
+		openapi.Error(http.StatusBadRequest, "Invalid cursor or limit"),
+		openapi.Unauthorized,
+	},
+}
+
+// HandleListEvents lists the stored webhook events, newest first. The next
+// page is linked in the Link header.
+
+func HandleListEvents(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	cursor, limit, err := pagination.FromRequest(req)
+	if err != nil {
+		response.RespondWithError(res, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, err := cfg.Db.ListWebhookEvents(req.Context(), database.ListWebhookEventsParams{
+		Status:     req.URL.Query().Get("status"),
+		BeforeTime: cursor.Time,
+		BeforeID:   cursor.ID,
+		RowLimit:   int32(limit),
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	bodyResp := make([]eventJSON, len(events))
+	for i, event := range events {
+		bodyResp[i] = toEventJSON(event)
+	}
+
+	page := &response.Page{Limit: limit}
+	if len(events) > 0 {
+		last := events[len(events)-1]
+		page = pagination.SetNextLink(res, req, pagination.Cursor{Time: last.CreatedAt, ID: last.ID}, len(events), limit)
+	}
+
+	response.RespondWithList(res, req, bodyResp, page)
+}
+
+var GetEventOperation = openapi.Operation{
+	ID:          "getWebhookEvent",
+	Tag:         "Admin",
+	Summary:     "Get a webhook event",
+	Description: "Returns a stored webhook event with its headers, raw body and processing result. Requires the admin API key.",
+	Security:    []openapi.Security{openapi.AdminAPIKey},
+	Parameters:  []openapi.Parameter{openapi.Path("eventID", "The ID of the webhook event.", openapi3.NewUUIDSchema())},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "The webhook event", eventJSON{}),
+		openapi.Error(http.StatusBadRequest, "Invalid event ID"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusNotFound, "Event not found"),
+	},
+}
+
+func HandleGetEvent(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	event, ok := getEvent(res, req, cfg)
+	if !ok {
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusOK, toEventJSON(event))
+}
+
+var ReplayEventOperation = openapi.Operation{
+	ID:      "replayWebhookEvent",
+	Tag:     "Admin",
+	Summary: "Replay a failed webhook event",
+	Description: "Re-runs a failed webhook event through the same handler used for live requests and records the new " +
+		"result. Requires the admin API key.",
+	Security:   []openapi.Security{openapi.AdminAPIKey},
+	Parameters: []openapi.Parameter{openapi.Path("eventID", "The ID of the webhook event.", openapi3.NewUUIDSchema())},
+	Responses: []openapi.Response{
+		openapi.JSON(http.StatusOK, "The webhook event after the replay", eventJSON{}),
+		openapi.Error(http.StatusBadRequest, "Invalid event ID"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusNotFound, "Event not found"),
+		openapi.Error(http.StatusConflict, "Event is not in the failed state"),
+	},
+}
+
+// HandleReplayEvent re-runs a failed (dead-lettered) event through the same
+// handler chain live Polka requests go through, minus authentication.
+func HandleReplayEvent(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	event, ok := getEvent(res, req, cfg)
+	if !ok {
+		return
+	}
+
+	if event.Status != config.WebhookStatusFailed {
+		response.RespondWithError(res, req, http.StatusConflict, "only failed events can be replayed")
+		return
+	}
+
+	err = cfg.ReplayWebhookEvent(req.Context(), event, cfg.MiddlewarePolkaDedupe(HandlePolkaWebhook))
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	replayed, err := cfg.Db.GetWebhookEventByID(req.Context(), event.ID)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	response.RespondWithJSON(res, http.StatusOK, toEventJSON(replayed))
+}
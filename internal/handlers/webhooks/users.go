@@ -1,49 +1,234 @@
 package webhooks
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
+	"time"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/google/uuid"
 	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/outbound"
 	"github.com/lucashthiele/chirpy/pkg/parser"
 	"github.com/lucashthiele/chirpy/pkg/response"
 )
 
-type UpgradeUserParams struct {
-	Event string `json:"event"`
+const (
+	EventUserUpgraded          = "user.upgraded"
+	EventUserDowngraded        = "user.downgraded"
+	EventSubscriptionRenewed   = "subscription.renewed"
+	EventSubscriptionPastDue   = "subscription.past_due"
+	EventSubscriptionCancelled = "subscription.cancelled"
+
+	StatusActive    = "active"
+	StatusPastDue   = "past_due"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+
+	defaultPlan         = "chirpy_red"
+	defaultPeriodLength = time.Hour * 24 * 30 // 30 days
+)
+
+type PolkaEventParams struct {
+	Event string `json:"event" description:"The event type, unknown events are ignored"`
 	Data  struct {
-		UserId uuid.UUID `json:"user_id"`
+		UserId           uuid.UUID  `json:"user_id" description:"The subscriber's user ID"`
+		Plan             string     `json:"plan" description:"The subscribed plan. Defaults to chirpy_red."`
+		CurrentPeriodEnd *time.Time `json:"current_period_end" description:"End of the paid period. Defaults to 30 days from now on user.upgraded."`
 	} `json:"data"`
 }
 
-func HandleUpgradeUser(res http.ResponseWriter, req *http.Request) {
+type upgradedJSON struct {
+	UserID           uuid.UUID `json:"user_id"`
+	Plan             string    `json:"plan"`
+	CurrentPeriodEnd time.Time `json:"current_period_end"`
+}
+
+// nextSubscription returns the subscription after the event, current is the
+// zero Subscription when the user doesn't have one yet. Polka doesn't order
+// its events, so the paid period never shrinks unless the user is downgraded,
+// and past_due or cancelled events don't revive a lapsed subscription.
+func nextSubscription(current database.Subscription, params *PolkaEventParams, now time.Time) (database.Subscription, error) {
+	exists := current.ID != uuid.Nil
+	if exists && current.Status != StatusExpired && !current.CurrentPeriodEnd.After(now) {
+		current.Status = StatusExpired
+	}
+
+	if !exists {
+		switch params.Event {
+		case EventSubscriptionRenewed, EventSubscriptionPastDue, EventSubscriptionCancelled, EventUserDowngraded:
+			return database.Subscription{}, sql.ErrNoRows
+		}
+	}
+
+	next := current
+	next.UserID = params.Data.UserId
+
+	switch params.Event {
+	case EventUserUpgraded:
+		next.Plan = params.Data.Plan
+		if next.Plan == "" {
+			next.Plan = defaultPlan
+		}
+		next.CurrentPeriodEnd = now.Add(defaultPeriodLength)
+		if params.Data.CurrentPeriodEnd != nil {
+			next.CurrentPeriodEnd = *params.Data.CurrentPeriodEnd
+		}
+		if exists && current.CurrentPeriodEnd.After(next.CurrentPeriodEnd) {
+			next.CurrentPeriodEnd = current.CurrentPeriodEnd
+		}
+		next.Status = activeUntil(next.CurrentPeriodEnd, now)
+	case EventSubscriptionRenewed:
+		if params.Data.CurrentPeriodEnd != nil {
+			next.CurrentPeriodEnd = *params.Data.CurrentPeriodEnd
+		} else if current.CurrentPeriodEnd.After(now) {
+			next.CurrentPeriodEnd = current.CurrentPeriodEnd.Add(defaultPeriodLength)
+		} else {
+			next.CurrentPeriodEnd = now.Add(defaultPeriodLength)
+		}
+		if current.CurrentPeriodEnd.After(next.CurrentPeriodEnd) {
+			next.CurrentPeriodEnd = current.CurrentPeriodEnd
+		}
+		next.Status = activeUntil(next.CurrentPeriodEnd, now)
+	case EventSubscriptionPastDue:
+		if current.Status != StatusExpired {
+			next.Status = StatusPastDue
+		}
+	case EventSubscriptionCancelled: // stays red until the paid period is over
+		if current.Status != StatusExpired {
+			next.Status = StatusCancelled
+		}
+	case EventUserDowngraded:
+		next.Status = StatusExpired
+		if next.CurrentPeriodEnd.After(now) {
+			next.CurrentPeriodEnd = now
+		}
+	default:
+		return database.Subscription{}, nil
+	}
+
+	return next, nil
+}
+
+// activeUntil is the status of a subscription paid until periodEnd, events
+// can be about periods that are already over.
+func activeUntil(periodEnd, now time.Time) string {
+	if periodEnd.After(now) {
+		return StatusActive
+	}
+	return StatusExpired
+}
+
+func applyEvent(ctx context.Context, db *database.Queries, params *PolkaEventParams) (database.Subscription, error) {
+	current, err := db.GetSubscriptionByUserID(ctx, params.Data.UserId)
+	if err != nil && err != sql.ErrNoRows {
+		return database.Subscription{}, err
+	}
+
+	next, err := nextSubscription(current, params, time.Now())
+	if err != nil || next.UserID == uuid.Nil {
+		return next, err
+	}
+
+	return db.SaveSubscription(ctx, database.SaveSubscriptionParams{
+		Plan:             next.Plan,
+		Status:           next.Status,
+		CurrentPeriodEnd: next.CurrentPeriodEnd,
+		UserID:           next.UserID,
+	})
+}
+
+var PolkaWebhookOperation = openapi.Operation{
+	ID:      "polkaWebhook",
+	Tag:     "Webhooks",
+	Summary: "Polka webhook for Chirpy Red subscriptions",
+	Description: "Applies Polka subscription events to the user's Chirpy Red subscription. is_chirpy_red is derived " +
+		"from the subscription: it stays true until the paid period ends unless the subscription expires or the user " +
+		"is downgraded. Unknown events are ignored. Requests are authenticated either with an HMAC-SHA256 signature of " +
+		"\"<timestamp>.<event ID>.<raw body>\" or, during the rollout, with the legacy Polka API key. Signed requests " +
+		"must have an X-Polka-Event-ID. Requests with an already processed X-Polka-Event-ID are acknowledged without " +
+		"being handled again.",
+	Security: []openapi.Security{openapi.PolkaSignature, openapi.PolkaAPIKey},
+	Parameters: []openapi.Parameter{
+		openapi.Header("X-Polka-Timestamp", "Unix timestamp the request was signed at. Must be within 5 minutes of the server time.", openapi3.NewInt64Schema()),
+		openapi.Header("X-Polka-Event-ID", "Unique event ID used to deduplicate retries. Required with a signature, which covers it.", openapi3.NewStringSchema()),
+	},
+	Request: &openapi.Request{Type: PolkaEventParams{}},
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusNoContent, "Webhook processed"),
+		openapi.Unauthorized,
+		openapi.Error(http.StatusNotFound, "User or subscription not found"),
+	},
+}
+
+func HandlePolkaWebhook(res http.ResponseWriter, req *http.Request) {
 	cfg, err := config.New()
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithInternalServerError(res, req, err)
 		return
 	}
 
-	params := &UpgradeUserParams{}
+	params := &PolkaEventParams{}
 
-	err = parser.ParseBody(req.Body, params)
+	err = parser.ParseBody(res, req, params)
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithErr(res, req, err)
 		return
 	}
 
-	if params.Event != "user.upgraded" {
-		response.RespondWithJSON(res, http.StatusNoContent, nil)
+	tx, err := cfg.DbConn.BeginTx(req.Context(), nil)
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
 		return
 	}
+	defer tx.Rollback()
+
+	qtx := cfg.Db.WithTx(tx)
 
-	err = cfg.Db.UpgradeUser(req.Context(), params.Data.UserId)
+	subscription, err := applyEvent(req.Context(), qtx, params)
 	if err == sql.ErrNoRows {
-		response.RespondWithError(res, http.StatusNotFound, "Not found")
+		response.RespondWithError(res, req, http.StatusNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
 		return
 	}
+	if subscription.ID == uuid.Nil { // unknown events are acknowledged and ignored
+		response.RespondWithJSON(res, http.StatusNoContent, nil)
+		return
+	}
+
+	err = qtx.CreateSubscriptionEvent(req.Context(), database.CreateSubscriptionEventParams{
+		SubscriptionID:   subscription.ID,
+		Event:            params.Event,
+		Status:           subscription.Status,
+		CurrentPeriodEnd: subscription.CurrentPeriodEnd,
+	})
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	if params.Event == EventUserUpgraded {
+		upgraded := upgradedJSON{
+			UserID:           subscription.UserID,
+			Plan:             subscription.Plan,
+			CurrentPeriodEnd: subscription.CurrentPeriodEnd,
+		}
+		err = outbound.Enqueue(req.Context(), qtx, subscription.UserID, outbound.EventUserUpgraded, upgraded)
+		if err != nil {
+			response.RespondWithInternalServerError(res, req, err)
+			return
+		}
+	}
+
+	err = tx.Commit()
 	if err != nil {
-		response.RespondWithInternalServerError(res, err)
+		response.RespondWithInternalServerError(res, req, err)
 		return
 	}
 
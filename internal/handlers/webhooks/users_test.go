@@ -0,0 +1,87 @@
+package webhooks
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/database"
+)
+
+func TestNextSubscription(t *testing.T) {
+	now := time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC)
+	userID := uuid.New()
+	at := func(d time.Duration) *time.Time {
+		end := now.Add(d)
+		return &end
+	}
+	subscription := func(status string, periodEnd time.Duration) database.Subscription {
+		return database.Subscription{ID: uuid.New(), UserID: userID, Plan: defaultPlan, Status: status, CurrentPeriodEnd: now.Add(periodEnd)}
+	}
+	day := 24 * time.Hour
+
+	cases := []struct {
+		name          string
+		current       database.Subscription
+		event         string
+		periodEnd     *time.Time
+		wantStatus    string
+		wantPeriodEnd time.Time
+		wantErr       error
+	}{
+		{name: "upgrade", event: EventUserUpgraded, wantStatus: StatusActive, wantPeriodEnd: now.Add(defaultPeriodLength)},
+		{name: "upgrade with a period end", event: EventUserUpgraded, periodEnd: at(10 * day), wantStatus: StatusActive, wantPeriodEnd: now.Add(10 * day)},
+		{name: "upgrade of a lapsed subscription", current: subscription(StatusActive, -day), event: EventUserUpgraded, periodEnd: at(10 * day), wantStatus: StatusActive, wantPeriodEnd: now.Add(10 * day)},
+		{name: "renew", current: subscription(StatusActive, 5*day), event: EventSubscriptionRenewed, wantStatus: StatusActive, wantPeriodEnd: now.Add(5*day + defaultPeriodLength)},
+		{name: "renew with a period end", current: subscription(StatusPastDue, 5*day), event: EventSubscriptionRenewed, periodEnd: at(40 * day), wantStatus: StatusActive, wantPeriodEnd: now.Add(40 * day)},
+		{name: "renew a lapsed subscription", current: subscription(StatusExpired, -5*day), event: EventSubscriptionRenewed, wantStatus: StatusActive, wantPeriodEnd: now.Add(defaultPeriodLength)},
+		{name: "renew without a subscription", event: EventSubscriptionRenewed, wantErr: sql.ErrNoRows},
+		{name: "past due", current: subscription(StatusActive, 5*day), event: EventSubscriptionPastDue, wantStatus: StatusPastDue, wantPeriodEnd: now.Add(5 * day)},
+		{name: "cancel keeps the paid period", current: subscription(StatusActive, 5*day), event: EventSubscriptionCancelled, wantStatus: StatusCancelled, wantPeriodEnd: now.Add(5 * day)},
+		{name: "lapsed before the expiry job ran", current: subscription(StatusCancelled, -time.Minute), event: EventSubscriptionPastDue, wantStatus: StatusExpired, wantPeriodEnd: now.Add(-time.Minute)},
+		{name: "downgrade", current: subscription(StatusActive, 5*day), event: EventUserDowngraded, wantStatus: StatusExpired, wantPeriodEnd: now},
+		{name: "downgrade without a subscription", event: EventUserDowngraded, wantErr: sql.ErrNoRows},
+		{name: "late renewal with an older period end", current: subscription(StatusActive, 40*day), event: EventSubscriptionRenewed, periodEnd: at(10 * day), wantStatus: StatusActive, wantPeriodEnd: now.Add(40 * day)},
+		{name: "late upgrade with an older period end", current: subscription(StatusActive, 40*day), event: EventUserUpgraded, periodEnd: at(10 * day), wantStatus: StatusActive, wantPeriodEnd: now.Add(40 * day)},
+		{name: "late upgrade for a period that's over", event: EventUserUpgraded, periodEnd: at(-day), wantStatus: StatusExpired, wantPeriodEnd: now.Add(-day)},
+		{name: "late past due after a downgrade", current: subscription(StatusExpired, 0), event: EventSubscriptionPastDue, wantStatus: StatusExpired, wantPeriodEnd: now},
+		{name: "late cancel after a downgrade", current: subscription(StatusExpired, 0), event: EventSubscriptionCancelled, wantStatus: StatusExpired, wantPeriodEnd: now},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			params := &PolkaEventParams{Event: c.event}
+			params.Data.UserId = userID
+			params.Data.CurrentPeriodEnd = c.periodEnd
+
+			got, err := nextSubscription(c.current, params, now)
+			if err != c.wantErr {
+				t.Fatalf("nextSubscription() error = %v, want %v", err, c.wantErr)
+			}
+			if c.wantErr != nil {
+				return
+			}
+
+			if got.UserID != userID || got.Plan != defaultPlan {
+				t.Errorf("nextSubscription() user, plan = %s, %q, want %s, %q", got.UserID, got.Plan, userID, defaultPlan)
+			}
+			if got.Status != c.wantStatus {
+				t.Errorf("nextSubscription() status = %q, want %q", got.Status, c.wantStatus)
+			}
+			if !got.CurrentPeriodEnd.Equal(c.wantPeriodEnd) {
+				t.Errorf("nextSubscription() period end = %s, want %s", got.CurrentPeriodEnd, c.wantPeriodEnd)
+			}
+		})
+	}
+}
+
+func TestNextSubscriptionIgnoresUnknownEvents(t *testing.T) {
+	params := &PolkaEventParams{Event: "user.renamed"}
+	params.Data.UserId = uuid.New()
+
+	got, err := nextSubscription(database.Subscription{}, params, time.Now())
+	if err != nil || got.UserID != uuid.Nil {
+		t.Errorf("nextSubscription() = %+v, %v, want an empty subscription", got, err)
+	}
+}
@@ -0,0 +1,340 @@
+package ws
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	authz "github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/gateway"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	chirpstream "github.com/lucashthiele/chirpy/internal/stream"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+const (
+	pingInterval time.Duration = time.Second * 30
+	pongTimeout  time.Duration = time.Second * 10
+	writeTimeout time.Duration = time.Second * 10
+	// typingInterval is how often a client can tell a conversation the user
+	// is typing, more frequent frames are ignored.
+	typingInterval time.Duration = time.Second * 3
+)
+
+// maxFrameSize is the largest frame a client can send, in bytes.
+const maxFrameSize = 4096
+
+const (
+	opSubscribe   = "subscribe"
+	opUnsubscribe = "unsubscribe"
+	opTyping      = "typing"
+)
+
+// channelScopes are the scopes a token needs to subscribe to a channel.
+var channelScopes = map[string]string{
+	gateway.ChannelMessages: authz.ScopeMessagesRead,
+	gateway.ChannelTyping:   authz.ScopeMessagesRead,
+	gateway.ChannelChirps:   authz.ScopeChirpsRead,
+}
+
+type frame struct {
+	Op             string    `json:"op"`
+	Channels       []string  `json:"channels"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+}
+
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+type subscriptionsJSON struct {
+	Channels []string `json:"channels"`
+}
+
+type typingJSON struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+}
+
+// session is what the gateway knows about one connection.
+type session struct {
+	cfg    *config.ApiConfig
+	ctx    context.Context
+	userId uuid.UUID
+	client *gateway.Client
+	// typedAt is only used by the goroutine reading the frames.
+	typedAt map[uuid.UUID]time.Time
+}
+
+var GatewayOperation = openapi.Operation{
+	ID:      "openGateway",
+	Tag:     "Gateway",
+	Summary: "Open a WebSocket to the gateway",
+	Description: "Upgrades the connection to a WebSocket the server pushes live updates to, as JSON frames like " +
+		"`{\"channel\": \"messages\", \"type\": \"message.created\", \"data\": {...}}`. Clients choose their channels with " +
+		"the `channels` query parameter or by sending `{\"op\": \"subscribe\", \"channels\": [\"chirps\"]}` and " +
+		"`{\"op\": \"unsubscribe\", \"channels\": [...]}` frames, and tell the members of a conversation they are typing " +
+		"with `{\"op\": \"typing\", \"conversation_id\": \"...\"}`. Replies to these frames have no channel.\n\n" +
+		"The channels are `notifications` (follow.requested, follow.created, follow.approved, chirp.mentioned and " +
+		"chirp.replied), `messages` (message.created and conversation.read), `typing` (typing) and `chirps` " +
+		"(chirp.created and chirp.deleted on your timeline). The messages and typing channels need the messages:read " +
+		"scope, sending typing frames needs messages:write and the chirps channel needs chirps:read.\n\n" +
+		"The server pings every 30 seconds. Clients that fall too far behind are disconnected with the policy violation " +
+		"status and should reconnect. Requires authentication.",
+	Security: openapi.Authenticated,
+	Parameters: []openapi.Parameter{
+		openapi.Query("channels", "Comma-separated channels to subscribe to right away.", openapi3.NewStringSchema()),
+	},
+	Responses: []openapi.Response{
+		openapi.Empty(http.StatusSwitchingProtocols, "Switching to the WebSocket protocol."),
+		openapi.Content(http.StatusBadRequest, "Invalid WebSocket handshake", "text/plain"),
+		openapi.Unauthorized,
+		openapi.Content(http.StatusForbidden, "The Origin of the request is not allowed", "text/plain"),
+		openapi.Content(http.StatusUpgradeRequired, "Not a WebSocket handshake", "text/plain"),
+	},
+}
+
+// HandleGateway upgrades the request to a WebSocket the server pushes the
+// user's notifications, direct messages, typing indicators and timeline chirps
+// to, on the channels the client subscribes to. Clients that can't keep up
+// are disconnected and should reconnect.
+func HandleGateway(res http.ResponseWriter, req *http.Request) {
+	cfg, err := config.New()
+	if err != nil {
+		response.RespondWithInternalServerError(res, req, err)
+		return
+	}
+
+	userId, ok := req.Context().Value(config.UserIDKey).(uuid.UUID)
+	if !ok {
+		response.RespondWithInternalServerError(res, req, fmt.Errorf("omg you're so bad at this"))
+		return
+	}
+
+	// Accept writes the error response itself
+	conn, err := websocket.Accept(res, req, nil)
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(maxFrameSize)
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	client := cfg.Gateway.Register(userId)
+	defer cfg.Gateway.Unregister(client)
+
+	chirpEvents, _ := cfg.ChirpEvents.Subscribe(0)
+	defer cfg.ChirpEvents.Unsubscribe(chirpEvents)
+
+	s := &session{
+		cfg:     cfg,
+		ctx:     ctx,
+		userId:  userId,
+		client:  client,
+		typedAt: map[uuid.UUID]time.Time{},
+	}
+
+	channels := req.URL.Query().Get("channels")
+	if channels != "" {
+		s.subscribe(strings.Split(channels, ","))
+	}
+
+	go func() {
+		defer cancel()
+		s.readFrames(conn)
+	}()
+
+	go func() {
+		defer cancel()
+		keepAlive(ctx, conn)
+	}()
+
+	relations := chirpstream.NewRelationCache(cfg.Db, userId)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-client.Send:
+			if !ok {
+				conn.Close(websocket.StatusPolicyViolation, "too slow")
+				return
+			}
+
+			writeCtx, cancelWrite := context.WithTimeout(ctx, writeTimeout)
+			err := wsjson.Write(writeCtx, conn, message)
+			cancelWrite()
+			if err != nil {
+				return
+			}
+		case event, ok := <-chirpEvents.Events:
+			if !ok {
+				conn.Close(websocket.StatusPolicyViolation, "too slow")
+				return
+			}
+			if !cfg.Gateway.Subscribed(client, gateway.ChannelChirps) {
+				continue
+			}
+
+			relation, err := relations.Get(ctx, event.Payload.AuthorID)
+			if err != nil {
+				log.Printf("Error looking up the relation to %s: %s", event.Payload.AuthorID, err)
+				conn.Close(websocket.StatusInternalError, "internal error")
+				return
+			}
+			if !chirpstream.CanSee(userId, event.Payload, relation) || !chirpstream.InTimeline(userId, event.Payload, relation) {
+				continue
+			}
+
+			cfg.Gateway.Deliver(client, gateway.Message{
+				Channel: gateway.ChannelChirps,
+				Type:    event.Type,
+				Data:    event.Payload.Chirp,
+			})
+		}
+	}
+}
+
+// keepAlive pings the client until it stops answering or ctx is cancelled.
+func keepAlive(ctx context.Context, conn *websocket.Conn) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, pongTimeout)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readFrames handles the frames sent by the client until the connection is
+// closed. Replies go through the hub, so only one goroutine writes.
+func (s *session) readFrames(conn *websocket.Conn) {
+	for {
+		_, data, err := conn.Read(s.ctx)
+		if err != nil {
+			return
+		}
+
+		f := frame{}
+		err = json.Unmarshal(data, &f)
+		if err != nil {
+			s.reply("error", errorJSON{Error: "frames must be JSON objects"})
+			continue
+		}
+
+		switch f.Op {
+		case opSubscribe:
+			s.subscribe(f.Channels)
+		case opUnsubscribe:
+			s.cfg.Gateway.Unsubscribe(s.client, f.Channels...)
+			s.reply("unsubscribed", subscriptionsJSON{Channels: f.Channels})
+		case opTyping:
+			err = s.typing(f.ConversationID)
+			if err != nil {
+				log.Printf("Error sending typing indicator: %s", err)
+				s.reply("error", errorJSON{Error: "Something went wrong"})
+			}
+		default:
+			s.reply("error", errorJSON{Error: fmt.Sprintf("unknown op %q", f.Op)})
+		}
+	}
+}
+
+func (s *session) subscribe(channels []string) {
+	for _, channel := range channels {
+		scope, ok := channelScopes[channel]
+		if ok && !config.HasScope(s.ctx, scope) {
+			s.reply("error", errorJSON{Error: fmt.Sprintf("the %s channel needs the %s scope", channel, scope)})
+			return
+		}
+	}
+
+	err := s.cfg.Gateway.Subscribe(s.client, channels...)
+	if err != nil {
+		s.reply("error", errorJSON{Error: err.Error()})
+		return
+	}
+
+	s.reply("subscribed", subscriptionsJSON{Channels: channels})
+}
+
+// typing tells the other members of the conversation the user is typing.
+func (s *session) typing(conversationId uuid.UUID) error {
+	if !config.HasScope(s.ctx, authz.ScopeMessagesWrite) {
+		s.reply("error", errorJSON{Error: fmt.Sprintf("typing needs the %s scope", authz.ScopeMessagesWrite)})
+		return nil
+	}
+
+	if time.Since(s.typedAt[conversationId]) < typingInterval {
+		return nil
+	}
+	s.typedAt[conversationId] = time.Now()
+
+	_, err := s.cfg.Db.GetConversationForMember(s.ctx, database.GetConversationForMemberParams{
+		ID:     conversationId,
+		UserID: s.userId,
+	})
+	if err == sql.ErrNoRows {
+		s.reply("error", errorJSON{Error: "conversation not found"})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	members, err := s.cfg.Db.ListMembersOfConversations(s.ctx, []uuid.UUID{conversationId})
+	if err != nil {
+		return err
+	}
+
+	otherIds := []uuid.UUID{}
+	for _, member := range members {
+		if member.UserID != s.userId {
+			otherIds = append(otherIds, member.UserID)
+		}
+	}
+
+	message, err := gateway.NewMessage(gateway.ChannelTyping, "typing", typingJSON{
+		ConversationID: conversationId,
+		UserID:         s.userId,
+	})
+	if err != nil {
+		return err
+	}
+
+	return gateway.Notify(s.ctx, s.cfg.Db, s.cfg.MessageKeys, otherIds, message)
+}
+
+// reply sends a message about the client's own requests, outside of any
+// channel.
+func (s *session) reply(messageType string, data any) {
+	message, err := gateway.NewMessage("", messageType, data)
+	if err != nil {
+		log.Printf("Error encoding gateway reply: %s", err)
+		return
+	}
+
+	s.cfg.Gateway.Deliver(s.client, message)
+}
@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lucashthiele/chirpy/internal/config"
+)
+
+// RunAccountDeletion deletes the accounts whose grace period is over every
+// interval, until ctx is cancelled. Everything the user owns goes with them
+// through the ON DELETE CASCADE foreign keys.
+func RunAccountDeletion(ctx context.Context, cfg *config.ApiConfig, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := cfg.Db.DeleteDueAccounts(ctx)
+		if err != nil {
+			log.Printf("Error deleting accounts: %s", err)
+		}
+		for _, userID := range deleted {
+			log.Printf("Deleted account %s", userID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lucashthiele/chirpy/internal/activitypub"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/outbound"
+)
+
+func deliverActivity(ctx context.Context, cfg *config.ApiConfig, delivery database.ActivitypubDelivery) error {
+	actorKey, err := activitypub.LocalKey(ctx, cfg.Db, delivery.UserID)
+	if err != nil {
+		return err
+	}
+
+	key, err := activitypub.ParsePrivateKey(actorKey.PrivateKeyPem)
+	if err != nil {
+		return err
+	}
+
+	_, deliveryErr := activitypub.Deliver(ctx, deliveryClient, delivery.Inbox, delivery.Activity, delivery.KeyID, key, time.Now())
+	if deliveryErr == nil {
+		return cfg.Db.MarkActivityPubDeliverySucceeded(ctx, delivery.ID)
+	}
+
+	// inboxes are retried on the same schedule as webhooks
+	if delivery.Attempts >= outbound.MaxAttempts {
+		log.Printf("Giving up on delivering activity %s to %s: %s", delivery.ID, delivery.Inbox, deliveryErr)
+		return cfg.Db.MarkActivityPubDeliveryFailed(ctx, delivery.ID)
+	}
+
+	return cfg.Db.RescheduleActivityPubDelivery(ctx, database.RescheduleActivityPubDeliveryParams{
+		NextAttemptAt: time.Now().Add(outbound.Backoff(delivery.Attempts)),
+		ID:            delivery.ID,
+	})
+}
+
+// RunActivityPubDelivery drains the queue of activities for remote inboxes
+// every interval, until ctx is cancelled. Like webhooks, deliveries are
+// claimed with SKIP LOCKED so several instances can run it at the same time.
+func RunActivityPubDelivery(ctx context.Context, cfg *config.ApiConfig, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deliveries, err := cfg.Db.ClaimDueActivityPubDeliveries(ctx, deliveryBatchSize)
+		if err != nil {
+			log.Printf("Error claiming activity deliveries: %s", err)
+		}
+
+		for _, delivery := range deliveries {
+			err = deliverActivity(ctx, cfg, delivery)
+			if err != nil {
+				log.Printf("Error delivering activity %s: %s", delivery.ID, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
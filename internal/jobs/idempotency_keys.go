@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lucashthiele/chirpy/internal/config"
+)
+
+// RunIdempotencyKeyExpiry deletes the expired idempotency keys and their
+// stored responses every interval, until ctx is cancelled.
+func RunIdempotencyKeyExpiry(ctx context.Context, cfg *config.ApiConfig, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := cfg.Db.DeleteExpiredIdempotencyKeys(ctx)
+		if err != nil {
+			log.Printf("Error deleting expired idempotency keys: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
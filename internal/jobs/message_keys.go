@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+)
+
+const reencryptBatchSize = 100
+
+// RunMessageKeyRotation re-encrypts the messages still encrypted with an old
+// key with the current one every interval, until ctx is cancelled. Once no
+// message uses an old key anymore, it can be removed from the keyring.
+func RunMessageKeyRotation(ctx context.Context, cfg *config.ApiConfig, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if cfg.MessageKeys != nil {
+			afterID := uuid.Nil
+			for {
+				lastID, listed, err := reencryptMessages(ctx, cfg, afterID)
+				if err != nil {
+					log.Printf("Error re-encrypting messages: %s", err)
+				}
+				if err != nil || listed < reencryptBatchSize {
+					break
+				}
+				afterID = lastID
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reencryptMessages re-encrypts the next batch of messages after afterID,
+// returning the ID of the last one and how many were listed. Messages that
+// can't be decrypted are logged and left as they are, so they don't hold up
+// the others.
+func reencryptMessages(ctx context.Context, cfg *config.ApiConfig, afterID uuid.UUID) (uuid.UUID, int, error) {
+	messages, err := cfg.Db.ListMessagesNotEncryptedWith(ctx, database.ListMessagesNotEncryptedWithParams{
+		KeyID:    cfg.MessageKeys.CurrentKeyID(),
+		AfterID:  afterID,
+		RowLimit: reencryptBatchSize,
+	})
+	if err != nil {
+		return afterID, 0, err
+	}
+
+	reencrypted := 0
+	for _, message := range messages {
+		afterID = message.ID
+
+		body, err := cfg.MessageKeys.Decrypt(message.KeyID, message.Body, message.ID[:])
+		if err != nil {
+			log.Printf("Error decrypting message %s with key %s, skipping it: %s", message.ID, message.KeyID, err)
+			continue
+		}
+
+		keyID, ciphertext, err := cfg.MessageKeys.Encrypt(body, message.ID[:])
+		if err != nil {
+			return afterID, 0, err
+		}
+
+		// only replaced if nothing else re-encrypted it in the meantime
+		err = cfg.Db.ReencryptMessage(ctx, database.ReencryptMessageParams{
+			NewKeyID: keyID,
+			Body:     ciphertext,
+			ID:       message.ID,
+			OldKeyID: message.KeyID,
+		})
+		if err != nil {
+			return afterID, 0, err
+		}
+		reencrypted++
+	}
+
+	if reencrypted > 0 {
+		log.Printf("Re-encrypted %d messages with key %s", reencrypted, cfg.MessageKeys.CurrentKeyID())
+	}
+
+	return afterID, len(messages), nil
+}
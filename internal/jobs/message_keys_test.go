@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/database/dbtest"
+	"github.com/lucashthiele/chirpy/internal/encryption"
+)
+
+func TestReencryptMessagesSkipsCorruptMessages(t *testing.T) {
+	oldKeys, err := encryption.NewKeyring("v1", map[string][]byte{"v1": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	keys, err := encryption.NewKeyring("v2", map[string][]byte{"v1": bytes.Repeat([]byte{1}, 32), "v2": bytes.Repeat([]byte{2}, 32)})
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+
+	corruptID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	validID := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	_, validBody, err := oldKeys.Encrypt([]byte("hello"), validID[:])
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	messages := []struct {
+		id   uuid.UUID
+		body []byte
+	}{
+		{id: corruptID, body: []byte("not a ciphertext")},
+		{id: validID, body: validBody},
+	}
+
+	db, conn := dbtest.New(t, map[string]dbtest.Query{
+		"ListMessagesNotEncryptedWith": func(args []driver.Value) (dbtest.Result, error) {
+			result := dbtest.Result{}
+			for _, message := range messages {
+				if message.id.String() > args[1].(string) {
+					result.Rows = append(result.Rows, []driver.Value{message.id.String(), time.Now(), uuid.NewString(), uuid.NewString(), "v1", message.body})
+				}
+			}
+			return result, nil
+		},
+		"ReencryptMessage": func([]driver.Value) (dbtest.Result, error) { return dbtest.Affected(1), nil },
+	})
+	cfg := &config.ApiConfig{Db: database.New(conn), MessageKeys: keys}
+
+	lastID, listed, err := reencryptMessages(context.Background(), cfg, uuid.Nil)
+	if err != nil {
+		t.Fatalf("reencryptMessages() error = %v", err)
+	}
+	if lastID != validID || listed != 2 {
+		t.Errorf("reencryptMessages() = %s, %d, want %s, 2", lastID, listed, validID)
+	}
+
+	reencrypted := db.Calls("ReencryptMessage")
+	if len(reencrypted) != 1 || reencrypted[0].Args[2] != validID.String() {
+		t.Fatalf("ReencryptMessage calls = %v, want one for %s", reencrypted, validID)
+	}
+	body, err := keys.Decrypt(reencrypted[0].Args[0].(string), reencrypted[0].Args[1].([]byte), validID[:])
+	if err != nil || string(body) != "hello" {
+		t.Errorf("re-encrypted body = %q, %v, want %q", body, err, "hello")
+	}
+
+	// the next batch starts after the corrupt message, it doesn't come back
+	_, listed, err = reencryptMessages(context.Background(), cfg, lastID)
+	if err != nil || listed != 0 {
+		t.Errorf("reencryptMessages() after %s = %d, %v, want 0 messages", lastID, listed, err)
+	}
+}
@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+)
+
+const expiredEvent = "subscription.expired"
+
+func expireLapsedSubscriptions(ctx context.Context, cfg *config.ApiConfig) error {
+	tx, err := cfg.DbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.Db.WithTx(tx)
+
+	expired, err := qtx.ExpireLapsedSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range expired {
+		err = qtx.CreateSubscriptionEvent(ctx, database.CreateSubscriptionEventParams{
+			SubscriptionID:   subscription.ID,
+			Event:            expiredEvent,
+			Status:           subscription.Status,
+			CurrentPeriodEnd: subscription.CurrentPeriodEnd,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(expired) > 0 {
+		log.Printf("Expired %d lapsed subscriptions", len(expired))
+	}
+
+	return tx.Commit()
+}
+
+// RunSubscriptionExpiry expires subscriptions whose paid period is over every
+// interval, until ctx is cancelled. It keeps their status and history up to
+// date, is_chirpy_red doesn't wait for it.
+func RunSubscriptionExpiry(ctx context.Context, cfg *config.ApiConfig, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := expireLapsedSubscriptions(ctx, cfg)
+		if err != nil {
+			log.Printf("Error expiring subscriptions: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/export"
+)
+
+// exportRetention is how long a finished export can be downloaded.
+const exportRetention time.Duration = time.Hour * 24 * 7 // 7 days
+
+// errExportUserDeleted is returned for exports of users deleted before the
+// export was built, there is nothing left to export.
+var errExportUserDeleted = errors.New("the user was deleted")
+
+func collectExportData(ctx context.Context, cfg *config.ApiConfig, userExport database.UserExport) (export.Data, error) {
+	user, err := cfg.Db.GetUserByID(ctx, userExport.UserID)
+	if err == sql.ErrNoRows {
+		return export.Data{}, errExportUserDeleted
+	}
+	if err != nil {
+		return export.Data{}, err
+	}
+
+	isChirpyRed, err := cfg.Db.IsChirpyRed(ctx, user.ID)
+	if err != nil {
+		return export.Data{}, err
+	}
+
+	chirps, err := cfg.Db.ListAllChirps(ctx, user.ID)
+	if err != nil {
+		return export.Data{}, err
+	}
+
+	data := export.Data{
+		Profile: export.Profile{
+			ID:          user.ID,
+			CreatedAt:   user.CreatedAt.Time,
+			UpdatedAt:   user.UpdatedAt.Time,
+			Email:       user.Email,
+			IsChirpyRed: isChirpyRed,
+			IsPrivate:   user.IsPrivate,
+		},
+		Chirps: make([]export.Chirp, len(chirps)),
+	}
+	for i, chirp := range chirps {
+		data.Chirps[i] = export.Chirp{
+			ID:          chirp.ID,
+			CreatedAt:   chirp.CreatedAt,
+			UpdatedAt:   chirp.UpdatedAt,
+			Body:        chirp.Body,
+			Visibility:  chirp.Visibility,
+			ReplyPolicy: chirp.ReplyPolicy,
+			Mentions:    chirp.Mentions,
+		}
+		if chirp.ReplyToID.Valid {
+			data.Chirps[i].ReplyToID = &chirp.ReplyToID.UUID
+		}
+	}
+
+	follows, err := cfg.Db.ListFollowing(ctx, user.ID)
+	if err != nil {
+		return export.Data{}, err
+	}
+
+	data.Follows = make([]export.Follow, len(follows))
+	for i, follow := range follows {
+		data.Follows[i] = export.Follow{
+			UserID:    follow.FollowedID,
+			CreatedAt: follow.CreatedAt,
+		}
+	}
+
+	return data, nil
+}
+
+func buildUserExport(ctx context.Context, cfg *config.ApiConfig, userExport database.UserExport) error {
+	data, err := collectExportData(ctx, cfg, userExport)
+	if err != nil {
+		return err
+	}
+
+	archive, err := export.BuildArchive(data, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return cfg.Db.CompleteUserExport(ctx, database.CompleteUserExportParams{
+		Archive:   archive,
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(exportRetention), Valid: true},
+		ID:        userExport.ID,
+	})
+}
+
+func processUserExports(ctx context.Context, cfg *config.ApiConfig) error {
+	err := cfg.Db.DeleteExpiredUserExports(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		userExport, err := cfg.Db.ClaimPendingUserExport(ctx)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = buildUserExport(ctx, cfg, userExport)
+		if err != nil {
+			log.Printf("Error building export %s: %s", userExport.ID, err)
+
+			err = cfg.Db.FailUserExport(ctx, userExport.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// RunUserExports builds the pending data exports every interval, until ctx is
+// cancelled.
+func RunUserExports(ctx context.Context, cfg *config.ApiConfig, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := processUserExports(ctx, cfg)
+		if err != nil {
+			log.Printf("Error processing exports: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
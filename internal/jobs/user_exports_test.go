@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/database/dbtest"
+	"github.com/lucashthiele/chirpy/internal/export"
+)
+
+func TestProcessUserExportsFailsExportsOfDeletedUsers(t *testing.T) {
+	exportID := uuid.New()
+	claimed := false
+
+	db, conn := dbtest.New(t, map[string]dbtest.Query{
+		"DeleteExpiredUserExports": func([]driver.Value) (dbtest.Result, error) { return dbtest.Affected(0), nil },
+		"ClaimPendingUserExport": func([]driver.Value) (dbtest.Result, error) {
+			if claimed {
+				return dbtest.NoRows, nil
+			}
+			claimed = true
+			now := time.Now()
+			return dbtest.Row(exportID.String(), now, now, uuid.NewString(), export.StatusBuilding, nil, nil, nil), nil
+		},
+		"GetUserByID":    func([]driver.Value) (dbtest.Result, error) { return dbtest.NoRows, nil },
+		"FailUserExport": func([]driver.Value) (dbtest.Result, error) { return dbtest.Affected(1), nil },
+	})
+	cfg := &config.ApiConfig{Db: database.New(conn)}
+
+	err := processUserExports(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("processUserExports() error = %v", err)
+	}
+
+	failed := db.Calls("FailUserExport")
+	if len(failed) != 1 || failed[0].Args[0] != exportID.String() {
+		t.Errorf("FailUserExport calls = %v, want the export of the deleted user", failed)
+	}
+}
@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/outbound"
+)
+
+const deliveryBatchSize = 50
+
+var deliveryClient = outbound.NewClient(time.Second * 10)
+
+func deliverWebhook(ctx context.Context, cfg *config.ApiConfig, delivery database.WebhookDelivery) error {
+	endpoint, err := cfg.Db.GetWebhookEndpointByID(ctx, delivery.EndpointID)
+	if err != nil {
+		return err
+	}
+
+	if !endpoint.Enabled {
+		return cfg.Db.MarkWebhookDeliveryFailed(ctx, delivery.ID)
+	}
+
+	start := time.Now()
+	status, deliveryErr := outbound.Deliver(ctx, deliveryClient, outbound.Request{
+		DeliveryID: delivery.ID,
+		URL:        endpoint.Url,
+		Secret:     endpoint.Secret,
+		Event:      delivery.Event,
+		Payload:    delivery.Payload,
+	}, start)
+
+	attempt := database.CreateWebhookDeliveryAttemptParams{
+		DeliveryID: delivery.ID,
+		EndpointID: endpoint.ID,
+		DurationMs: int32(time.Since(start).Milliseconds()),
+	}
+	if status != 0 {
+		attempt.ResponseStatus = sql.NullInt32{Int32: int32(status), Valid: true}
+	}
+	if deliveryErr != nil {
+		attempt.Error = sql.NullString{String: deliveryErr.Error(), Valid: true}
+	}
+
+	err = cfg.Db.CreateWebhookDeliveryAttempt(ctx, attempt)
+	if err != nil {
+		return err
+	}
+
+	if deliveryErr == nil {
+		err = cfg.Db.MarkWebhookDeliverySucceeded(ctx, delivery.ID)
+		if err != nil {
+			return err
+		}
+		return cfg.Db.ResetWebhookEndpointFailures(ctx, endpoint.ID)
+	}
+
+	if delivery.Attempts >= outbound.MaxAttempts {
+		err = cfg.Db.MarkWebhookDeliveryFailed(ctx, delivery.ID)
+	} else {
+		err = cfg.Db.RescheduleWebhookDelivery(ctx, database.RescheduleWebhookDeliveryParams{
+			NextAttemptAt: time.Now().Add(outbound.Backoff(delivery.Attempts)),
+			ID:            delivery.ID,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	failures, err := cfg.Db.RecordWebhookEndpointFailure(ctx, endpoint.ID)
+	if err != nil {
+		return err
+	}
+	if failures >= outbound.DisableThreshold {
+		log.Printf("Disabling webhook endpoint %s after %d consecutive failures", endpoint.ID, failures)
+		return cfg.Db.DisableWebhookEndpoint(ctx, endpoint.ID)
+	}
+
+	return nil
+}
+
+// RunWebhookDelivery drains the webhook delivery queue every interval, until
+// ctx is cancelled. Deliveries are claimed with SKIP LOCKED so several
+// instances can run it at the same time.
+func RunWebhookDelivery(ctx context.Context, cfg *config.ApiConfig, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deliveries, err := cfg.Db.ClaimDueWebhookDeliveries(ctx, deliveryBatchSize)
+		if err != nil {
+			log.Printf("Error claiming webhook deliveries: %s", err)
+		}
+
+		for _, delivery := range deliveries {
+			err = deliverWebhook(ctx, cfg, delivery)
+			if err != nil {
+				log.Printf("Error delivering webhook %s: %s", delivery.ID, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
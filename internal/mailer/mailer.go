@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends transactional emails, like login links.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes emails to the log instead of sending them. It is only meant
+// for local development.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// FromEnv returns an SMTPMailer when SMTP_HOST is set. Without it emails are
+// only logged on the dev platform, and no mailer is configured elsewhere.
+func FromEnv(platform string) (Mailer, error) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		if platform != "dev" {
+			return nil, nil
+		}
+		return LogMailer{}, nil
+	}
+
+	smtpMailer := &SMTPMailer{
+		Host:     host,
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+	if smtpMailer.Port == "" {
+		smtpMailer.Port = "587"
+	}
+	if smtpMailer.From == "" {
+		return nil, fmt.Errorf("SMTP_FROM is required when SMTP_HOST is set")
+	}
+
+	return smtpMailer, nil
+}
+
+// buildMessage renders the message as RFC 5322 text. Header values can't
+// contain line breaks, so user input can't inject extra headers.
+func buildMessage(from string, msg Message, now time.Time) ([]byte, error) {
+	for _, value := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("invalid header value %q", value)
+		}
+	}
+
+	body := &bytes.Buffer{}
+	fmt.Fprintf(body, "From: %s\r\n", from)
+	fmt.Fprintf(body, "To: %s\r\n", msg.To)
+	fmt.Fprintf(body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(body, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(body, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(body, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(body, "\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return body.Bytes(), nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	content, err := buildMessage(m.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var smtpAuth smtp.Auth
+	if m.Username != "" {
+		smtpAuth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), smtpAuth, m.From, []string{msg.To}, content)
+}
@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMessage(t *testing.T) {
+	now := time.Date(2025, 5, 15, 8, 19, 18, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		msg     Message
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "plain message",
+			msg: Message{
+				To:      "jane@example.com",
+				Subject: "Your Chirpy login link",
+				Body:    "Hello\nClick the link",
+			},
+			want: []string{
+				"From: Chirpy <no-reply@chirpy.test>\r\n",
+				"To: jane@example.com\r\n",
+				"Subject: Your Chirpy login link\r\n",
+				"Date: Thu, 15 May 2025 08:19:18 +0000\r\n",
+				"\r\n\r\nHello\r\nClick the link",
+			},
+			wantErr: false,
+		},
+		{
+			name: "header injection in recipient",
+			msg: Message{
+				To:      "jane@example.com\r\nBcc: everyone@example.com",
+				Subject: "Your Chirpy login link",
+			},
+			wantErr: true,
+		},
+		{
+			name: "header injection in subject",
+			msg: Message{
+				To:      "jane@example.com",
+				Subject: "Hi\nBcc: everyone@example.com",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			content, err := buildMessage("Chirpy <no-reply@chirpy.test>", c.msg, now)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("expected error: %v, got %v", c.wantErr, err)
+			}
+
+			for _, want := range c.want {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("expected message to contain %q, got %q", want, content)
+				}
+			}
+		})
+	}
+}
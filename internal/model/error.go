@@ -3,3 +3,24 @@ package model
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
+
+// Problem is the body of errors from v2 of the API, an RFC 7807 problem
+// detail.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance"`
+	// Code is stable, unlike the detail, clients can act on it.
+	Code      string `json:"code" enum:"bad_request,unauthorized,forbidden,not_found,conflict,payload_too_large,validation_failed,too_many_requests,internal_error,unavailable,idempotency_key_in_use,idempotency_key_reused"`
+	RequestID string `json:"request_id" description:"The X-Request-ID of the request, to find it in the logs"`
+	// Errors lists what's wrong with each field of an invalid request.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field" description:"The JSON name of the field, nested fields are joined with dots"`
+	Code    string `json:"code" description:"The rule the field broke, like required or email"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,51 @@
+package moderation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrTooLong = errors.New("text is too long")
+
+var hashtagPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+var badWords = map[string]string{
+	"kerfuffle": "****",
+	"sharbert":  "****",
+	"fornax":    "****",
+}
+
+// Moderate checks text written by a user against maxLength and censors
+// profane words. Chirps and direct messages go through it with their own
+// limits.
+func Moderate(text string, maxLength int) (string, error) {
+	if len(text) > maxLength {
+		return "", ErrTooLong
+	}
+
+	return removeProfaneWords(text), nil
+}
+
+func removeProfaneWords(str string) string {
+	splittedWords := strings.Split(str, " ")
+	for i, word := range splittedWords {
+		if replace, found := badWords[strings.ToLower(word)]; found {
+			splittedWords[i] = replace
+		}
+	}
+
+	return strings.Join(splittedWords, " ")
+}
+
+// ParseHashtag accepts a hashtag with or without its #. Only letters, digits
+// and underscores are allowed, the hashtag is matched in the chirp bodies
+// with a regular expression.
+func ParseHashtag(hashtag string) (string, error) {
+	hashtag = strings.TrimPrefix(hashtag, "#")
+	if !hashtagPattern.MatchString(hashtag) {
+		return "", fmt.Errorf("hashtags can only have up to 64 letters, digits and underscores")
+	}
+	return hashtag, nil
+}
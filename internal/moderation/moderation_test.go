@@ -0,0 +1,90 @@
+package moderation
+
+import "testing"
+
+func TestModerate(t *testing.T) {
+	cases := []struct {
+		name      string
+		text      string
+		maxLength int
+		want      string
+		wantErr   error
+	}{
+		{
+			name:      "clean text",
+			text:      "I had something interesting for breakfast",
+			maxLength: 140,
+			want:      "I had something interesting for breakfast",
+		},
+		{
+			name:      "profane words",
+			text:      "I hear Mastodon is better than Chirpy. sharbert I need to migrate",
+			maxLength: 140,
+			want:      "I hear Mastodon is better than Chirpy. **** I need to migrate",
+		},
+		{
+			name:      "profane words in any case",
+			text:      "I really need a Kerfuffle to go to bed sooner, FORNAX !",
+			maxLength: 140,
+			want:      "I really need a **** to go to bed sooner, **** !",
+		},
+		{
+			name:      "punctuation is not stripped",
+			text:      "what a kerfuffle!",
+			maxLength: 140,
+			want:      "what a kerfuffle!",
+		},
+		{
+			name:      "exactly the limit",
+			text:      "12345",
+			maxLength: 5,
+			want:      "12345",
+		},
+		{
+			name:      "over the limit",
+			text:      "123456",
+			maxLength: 5,
+			wantErr:   ErrTooLong,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := Moderate(c.text, c.maxLength)
+			if err != c.wantErr {
+				t.Fatalf("Moderate() error = %v, want %v", err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("Moderate() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseHashtag(t *testing.T) {
+	cases := []struct {
+		name    string
+		hashtag string
+		want    string
+		wantErr bool
+	}{
+		{name: "without #", hashtag: "golang", want: "golang"},
+		{name: "with #", hashtag: "#golang", want: "golang"},
+		{name: "digits and underscores", hashtag: "go_1_24", want: "go_1_24"},
+		{name: "empty", hashtag: "#", wantErr: true},
+		{name: "spaces", hashtag: "go lang", wantErr: true},
+		{name: "regexp characters", hashtag: "go.*", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ParseHashtag(c.hashtag)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("ParseHashtag() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("ParseHashtag() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
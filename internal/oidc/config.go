@@ -0,0 +1,50 @@
+package oidc
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ProvidersFromEnv builds the providers listed in OIDC_PROVIDERS, e.g.
+// "google,okta". Each one is configured with OIDC_<NAME>_ISSUER,
+// OIDC_<NAME>_CLIENT_ID and OIDC_<NAME>_CLIENT_SECRET, and receives the user
+// back on OIDC_REDIRECT_BASE_URL/api/auth/oidc/<name>/callback.
+func ProvidersFromEnv() (map[string]*Provider, error) {
+	providers := map[string]*Provider{}
+
+	names := strings.TrimSpace(os.Getenv("OIDC_PROVIDERS"))
+	if names == "" {
+		return providers, nil
+	}
+
+	baseURL := strings.TrimSuffix(os.Getenv("OIDC_REDIRECT_BASE_URL"), "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("OIDC_REDIRECT_BASE_URL is required when OIDC_PROVIDERS is set")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		provider := &Provider{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  baseURL + "/api/auth/oidc/" + name + "/callback",
+			Client:       client,
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+
+		providers[name] = provider
+	}
+
+	return providers, nil
+}
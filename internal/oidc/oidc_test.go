@@ -0,0 +1,383 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lucashthiele/chirpy/internal/auth"
+)
+
+const (
+	testClientID     = "chirpy"
+	testClientSecret = "s3cr3t"
+	testRedirectURL  = "https://chirpy.test/api/auth/oidc/fake/callback"
+)
+
+// fakeProvider is a minimal OpenID Connect provider. Codes are registered with
+// issueCode and exchanged for an ID token built from the registered claims.
+type fakeProvider struct {
+	server *httptest.Server
+
+	mu     sync.Mutex
+	keyID  string
+	key    *rsa.PrivateKey
+	codes  map[string]fakeCode
+	issuer string
+}
+
+type fakeCode struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+
+	fake := &fakeProvider{codes: map[string]fakeCode{}}
+	fake.rotateKey(t, "key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Metadata{
+			Issuer:                fake.issuer,
+			AuthorizationEndpoint: fake.server.URL + "/authorize",
+			TokenEndpoint:         fake.server.URL + "/token",
+			JwksURI:               fake.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+
+		json.NewEncoder(w).Encode(jsonWebKeySet{Keys: []jsonWebKey{{
+			Kty: "RSA",
+			Kid: fake.keyID,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(fake.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(fake.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", fake.handleToken)
+
+	fake.server = httptest.NewServer(mux)
+	fake.issuer = fake.server.URL
+	t.Cleanup(fake.server.Close)
+
+	return fake
+}
+
+func (f *fakeProvider) rotateKey(t *testing.T, keyID string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keyID = keyID
+	f.key = key
+}
+
+func (f *fakeProvider) provider() *Provider {
+	return &Provider{
+		Name:         "fake",
+		Issuer:       f.issuer,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Client:       f.server.Client(),
+	}
+}
+
+func (f *fakeProvider) validClaims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            f.issuer,
+		"aud":            testClientID,
+		"sub":            "user-123",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          "jane@example.com",
+		"email_verified": true,
+	}
+}
+
+func (f *fakeProvider) issueCode(challenge string, claims jwt.MapClaims) string {
+	code := auth.MakeRefreshToken()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.codes[code] = fakeCode{challenge: challenge, claims: claims}
+
+	return code
+}
+
+func (f *fakeProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = f.keyID
+
+	signed, err := token.SignedString(f.key)
+	if err != nil {
+		t.Fatalf("error signing id token: %v", err)
+	}
+	return signed
+}
+
+func (f *fakeProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != testClientID || clientSecret != testClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(tokenResponse{Error: "invalid_client"})
+		return
+	}
+
+	r.ParseForm()
+
+	f.mu.Lock()
+	code, ok := f.codes[r.PostForm.Get("code")]
+	delete(f.codes, r.PostForm.Get("code"))
+	key, keyID := f.key, f.keyID
+	f.mu.Unlock()
+
+	if !ok || r.PostForm.Get("redirect_uri") != testRedirectURL || auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.challenge) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(tokenResponse{Error: "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, code.claims)
+	token.Header["kid"] = keyID
+	idToken, _ := token.SignedString(key)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "provider-access-token",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider := fake.provider()
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "the-state", "the-nonce", "the-challenge")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("invalid url: %v", err)
+	}
+	if !strings.HasPrefix(authURL, fake.server.URL+"/authorize?") {
+		t.Errorf("expected the discovered authorization endpoint, got %s", authURL)
+	}
+
+	expected := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"state":                 "the-state",
+		"nonce":                 "the-nonce",
+		"code_challenge":        "the-challenge",
+		"code_challenge_method": "S256",
+	}
+	for key, value := range expected {
+		if got := parsed.Query().Get(key); got != value {
+			t.Errorf("expected %s=%s, got %s", key, value, got)
+		}
+	}
+}
+
+func TestDiscoverIssuerMismatch(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider := fake.provider()
+	provider.Issuer = fake.server.URL + "/other"
+
+	_, err := provider.Discover(context.Background())
+	if err == nil {
+		t.Fatal("expected an error for a mismatched issuer")
+	}
+}
+
+func TestExchangeAndVerify(t *testing.T) {
+	fake := newFakeProvider(t)
+	nonce := auth.MakeRefreshToken()
+	verifier := auth.MakeRefreshToken()
+
+	cases := []struct {
+		name        string
+		claims      func() jwt.MapClaims
+		verifier    string
+		nonce       string
+		exchangeErr bool
+		verifyErr   bool
+	}{
+		{
+			name:     "valid id token",
+			claims:   func() jwt.MapClaims { return fake.validClaims(nonce) },
+			verifier: verifier,
+			nonce:    nonce,
+		},
+		{
+			name:        "wrong code verifier",
+			claims:      func() jwt.MapClaims { return fake.validClaims(nonce) },
+			verifier:    auth.MakeRefreshToken(),
+			nonce:       nonce,
+			exchangeErr: true,
+		},
+		{
+			name:      "wrong nonce",
+			claims:    func() jwt.MapClaims { return fake.validClaims(nonce) },
+			verifier:  verifier,
+			nonce:     "another-nonce",
+			verifyErr: true,
+		},
+		{
+			name: "wrong audience",
+			claims: func() jwt.MapClaims {
+				claims := fake.validClaims(nonce)
+				claims["aud"] = "someone-else"
+				return claims
+			},
+			verifier:  verifier,
+			nonce:     nonce,
+			verifyErr: true,
+		},
+		{
+			name: "wrong issuer",
+			claims: func() jwt.MapClaims {
+				claims := fake.validClaims(nonce)
+				claims["iss"] = "https://evil.test"
+				return claims
+			},
+			verifier:  verifier,
+			nonce:     nonce,
+			verifyErr: true,
+		},
+		{
+			name: "expired",
+			claims: func() jwt.MapClaims {
+				claims := fake.validClaims(nonce)
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return claims
+			},
+			verifier:  verifier,
+			nonce:     nonce,
+			verifyErr: true,
+		},
+		{
+			name: "no subject",
+			claims: func() jwt.MapClaims {
+				claims := fake.validClaims(nonce)
+				delete(claims, "sub")
+				return claims
+			},
+			verifier:  verifier,
+			nonce:     nonce,
+			verifyErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			provider := fake.provider()
+			code := fake.issueCode(auth.MakePKCEChallenge(verifier), c.claims())
+
+			idToken, err := provider.Exchange(context.Background(), code, c.verifier)
+			if (err != nil) != c.exchangeErr {
+				t.Fatalf("expected exchange error: %v, got %v", c.exchangeErr, err)
+			}
+			if c.exchangeErr {
+				return
+			}
+
+			claims, err := provider.VerifyIDToken(context.Background(), idToken, c.nonce)
+			if (err != nil) != c.verifyErr {
+				t.Fatalf("expected verify error: %v, got %v", c.verifyErr, err)
+			}
+			if c.verifyErr {
+				return
+			}
+
+			if claims.Subject != "user-123" || claims.Email != "jane@example.com" || !claims.EmailVerified {
+				t.Errorf("unexpected claims: %+v", claims)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenSignature(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider := fake.provider()
+	nonce := "the-nonce"
+
+	forger, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, fake.validClaims(nonce))
+	token.Header["kid"] = fake.keyID
+	forged, _ := token.SignedString(forger)
+
+	_, err = provider.VerifyIDToken(context.Background(), forged, nonce)
+	if err == nil {
+		t.Error("expected a token signed by another key to be rejected")
+	}
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, fake.validClaims(nonce))
+	none, _ := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	_, err = provider.VerifyIDToken(context.Background(), none, nonce)
+	if err == nil {
+		t.Error("expected an unsigned token to be rejected")
+	}
+}
+
+func TestVerifyIDTokenKeyRotation(t *testing.T) {
+	fake := newFakeProvider(t)
+	provider := fake.provider()
+	nonce := "the-nonce"
+
+	_, err := provider.VerifyIDToken(context.Background(), fake.sign(t, fake.validClaims(nonce)), nonce)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fake.rotateKey(t, "key-2")
+	rotated := fake.sign(t, fake.validClaims(nonce))
+
+	// the keys were fetched moments ago, so the new key id isn't looked up yet
+	_, err = provider.VerifyIDToken(context.Background(), rotated, nonce)
+	if err == nil {
+		t.Fatal("expected the unknown key to be rejected within the refresh interval")
+	}
+
+	provider.keys.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+
+	_, err = provider.VerifyIDToken(context.Background(), rotated, nonce)
+	if err != nil {
+		t.Errorf("expected the rotated key to be fetched, got %v", err)
+	}
+}
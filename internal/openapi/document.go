@@ -0,0 +1,345 @@
+package openapi
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/lucashthiele/chirpy/internal/apiversion"
+	"github.com/lucashthiele/chirpy/internal/model"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+	"github.com/lucashthiele/chirpy/pkg/response"
+	"github.com/oasdiff/yaml"
+)
+
+type route struct {
+	method    string
+	path      string
+	operation Operation
+	// version is zero for the routes that aren't versioned.
+	version apiversion.Version
+}
+
+// Router registers the routes of the API on a mux together with their
+// operations, which make up the document.
+type Router struct {
+	mux    *http.ServeMux
+	routes []route
+	// versioned matches the v1 patterns of the versioned routes, for
+	// Negotiate.
+	versioned *http.ServeMux
+}
+
+func NewRouter(mux *http.ServeMux) *Router {
+	return &Router{mux: mux, versioned: http.NewServeMux()}
+}
+
+// HandleFunc registers handler for pattern, which must have a method, and
+// documents it with op.
+func (r *Router) HandleFunc(pattern string, op Operation, handler http.HandlerFunc) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		panic("openapi: the pattern " + pattern + " has no method")
+	}
+
+	r.mux.HandleFunc(pattern, handler)
+	r.routes = append(r.routes, route{method: method, path: path, operation: op})
+}
+
+// HandleVersions registers handler for pattern, which must be under /api,
+// in every version of the API, /api/chirps in v1 and /api/v2/chirps in v2.
+// The handler finds the version it serves with apiversion.FromContext.
+func (r *Router) HandleVersions(pattern string, op Operation, handler http.HandlerFunc) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		panic("openapi: the pattern " + pattern + " has no method")
+	}
+
+	for _, version := range []apiversion.Version{apiversion.V1, apiversion.V2} {
+		r.mux.HandleFunc(method+" "+version.Path(path), func(res http.ResponseWriter, req *http.Request) {
+			version.SetHeaders(res.Header())
+			ctx := apiversion.NewContext(req.Context(), version)
+			// v1 decodes bodies like it always did, the validate tags and
+			// unknown fields are only checked from v2 on
+			if version >= apiversion.V2 {
+				ctx = parser.NewContext(ctx, parser.Strict())
+			}
+			handler(res, req.WithContext(ctx))
+		})
+		r.routes = append(r.routes, route{method: method, path: version.Path(path), operation: op, version: version})
+	}
+	r.versioned.HandleFunc(pattern, func(http.ResponseWriter, *http.Request) {})
+}
+
+// Negotiate serves the requests to the v1 path of a versioned route in the
+// version their Accept header asks for.
+func (r *Router) Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if _, pattern := r.versioned.Handler(req); pattern == "" {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		res.Header().Add("Vary", "Accept")
+		version, ok := apiversion.FromAccept(req.Header.Get("Accept"))
+		if !ok || version == apiversion.V1 {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		negotiated := req.Clone(req.Context())
+		negotiated.URL.Path = version.Path(req.URL.Path)
+		if req.URL.RawPath != "" {
+			negotiated.URL.RawPath = version.Path(req.URL.RawPath)
+		}
+		next.ServeHTTP(res, negotiated)
+	})
+}
+
+var pathParam = regexp.MustCompile(`\{([^}.$]+)\}`)
+
+// Document builds the document of the registered routes and checks it's
+// valid.
+func (r *Router) Document() (*openapi3.T, error) {
+	doc := newDocument()
+
+	for _, route := range r.routes {
+		op := route.operation
+		if !slices.ContainsFunc(doc.Tags, func(tag *openapi3.Tag) bool { return tag.Name == op.Tag }) {
+			doc.Tags = append(doc.Tags, &openapi3.Tag{Name: op.Tag})
+		}
+
+		operation, err := buildOperation(route.path, op, route.version)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", route.method, route.path, err)
+		}
+
+		pathItem := doc.Paths.Value(route.path)
+		if pathItem == nil {
+			pathItem = &openapi3.PathItem{}
+			doc.Paths.Set(route.path, pathItem)
+		}
+		if pathItem.GetOperation(route.method) != nil {
+			return nil, fmt.Errorf("%s %s is documented twice", route.method, route.path)
+		}
+		pathItem.SetOperation(route.method, operation)
+	}
+
+	err := doc.Validate(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// YAML is the document of the registered routes as YAML.
+func (r *Router) YAML() ([]byte, error) {
+	doc, err := r.Document()
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(doc)
+}
+
+// deprecationHeaders are sent by the successful responses of deprecated
+// versions.
+var deprecationHeaders = map[string]string{
+	"Deprecation": "When this version of the API was deprecated, as @<unix time>",
+	"Sunset":      "When this version of the API stops being served",
+}
+
+func buildOperation(path string, op Operation, version apiversion.Version) (*openapi3.Operation, error) {
+	if op.ID == "" || op.Tag == "" || len(op.Responses) == 0 {
+		return nil, fmt.Errorf("the operation needs an ID, a tag and responses")
+	}
+
+	id := op.ID
+	if version > apiversion.V1 {
+		id += strings.ToUpper(version.String())
+	}
+	operation := &openapi3.Operation{
+		OperationID: id,
+		Deprecated:  version.Deprecated(),
+		Tags:        []string{op.Tag},
+		Summary:     op.Summary,
+		Description: op.Description,
+		Responses:   openapi3.NewResponsesWithCapacity(len(op.Responses) + 1),
+	}
+
+	if len(op.Security) > 0 {
+		security := openapi3.NewSecurityRequirements()
+		for _, scheme := range op.Security {
+			requirement := openapi3.NewSecurityRequirement()
+			if scheme != Anonymous {
+				requirement = requirement.Authenticate(string(scheme))
+			}
+			security.With(requirement)
+		}
+		operation.Security = security
+	}
+
+	params := op.Parameters
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		name := match[1]
+		declared := slices.ContainsFunc(params, func(p Parameter) bool {
+			return p.In == openapi3.ParameterInPath && p.Name == name
+		})
+		if !declared {
+			params = append(params, Path(name, "", openapi3.NewStringSchema()))
+		}
+	}
+	for _, p := range params {
+		param := &openapi3.Parameter{
+			Name:        p.Name,
+			In:          p.In,
+			Description: p.Description,
+			Required:    p.Required,
+			Schema:      p.Schema.NewRef(),
+		}
+		operation.Parameters = append(operation.Parameters, &openapi3.ParameterRef{Value: param})
+	}
+
+	if op.Request != nil {
+		contentType := op.Request.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		schema := RequestSchemaOf(op.Request.Type)
+		if version >= apiversion.V2 {
+			schema = StrictRequestSchemaOf(op.Request.Type)
+		}
+		body := openapi3.NewRequestBody().
+			WithDescription(op.Request.Description).
+			WithRequired(true).
+			WithSchema(schema, []string{contentType})
+		operation.RequestBody = &openapi3.RequestBodyRef{Value: body}
+	}
+
+	responses := slices.Clone(op.Responses)
+	defaults := []Response{internalServerError}
+	if op.Request != nil && op.Request.ContentType == "" {
+		defaults = append(defaults, invalidBody, bodyTooLarge)
+		if version >= apiversion.V2 {
+			defaults = append(defaults, invalidFields)
+		}
+	}
+	for _, resp := range defaults {
+		if !slices.ContainsFunc(responses, func(r Response) bool { return r.Status == resp.Status }) {
+			responses = append(responses, resp)
+		}
+	}
+	for _, resp := range responses {
+		status := strconv.Itoa(resp.Status)
+		if operation.Responses.Value(status) != nil {
+			return nil, fmt.Errorf("the %s response is documented twice", status)
+		}
+
+		// v2 answers errors with problem details
+		if version >= apiversion.V2 && resp.Type == (model.ErrorResponse{}) {
+			resp.Type, resp.ContentType = model.Problem{}, response.ProblemContentType
+		}
+
+		response := openapi3.NewResponse().WithDescription(resp.Description)
+		if resp.Type != nil || resp.Content != nil {
+			response.Content = openapi3.NewContent()
+		}
+		if resp.Type != nil {
+			contentType := resp.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			response.Content[contentType] = openapi3.NewMediaType().WithSchema(responseSchema(resp.Type, version))
+		}
+		if resp.Content != nil {
+			for contentType, schema := range resp.Content {
+				mediaType := openapi3.NewMediaType()
+				if schema != nil {
+					mediaType.WithSchema(schema)
+				}
+				response.Content[contentType] = mediaType
+			}
+		}
+		headers := resp.Headers
+		if version.Deprecated() && resp.Status < 300 {
+			headers = make(map[string]string, len(resp.Headers)+len(deprecationHeaders))
+			maps.Copy(headers, resp.Headers)
+			maps.Copy(headers, deprecationHeaders)
+		}
+		for name, description := range headers {
+			if response.Headers == nil {
+				response.Headers = openapi3.Headers{}
+			}
+			header := &openapi3.Header{Parameter: openapi3.Parameter{
+				Description: description,
+				Schema:      openapi3.NewStringSchema().NewRef(),
+			}}
+			response.Headers[name] = &openapi3.HeaderRef{Value: header}
+		}
+		operation.Responses.Set(status, &openapi3.ResponseRef{Value: response})
+	}
+
+	return operation, nil
+}
+
+// responseSchema is the schema of the JSON body of type v, lists are
+// enveloped from v2 on.
+func responseSchema(v any, version apiversion.Version) *openapi3.Schema {
+	if version < apiversion.V2 || reflect.TypeOf(v).Kind() != reflect.Slice {
+		return SchemaOf(v)
+	}
+
+	schema := SchemaOf(response.List{})
+	schema.Properties["data"] = SchemaOf(v).NewRef()
+	return schema
+}
+
+func newDocument() *openapi3.T {
+	return &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:   "Chirpy API",
+			Version: "1.0.0",
+			Description: "API specification for the Chirpy backend service. Generated from the routes of the server, don't edit it by hand. " +
+				"Every response has an X-Request-ID header, which is the one the request sent or a new one, to find the request in the logs.",
+		},
+		Servers: openapi3.Servers{
+			{URL: "http://localhost:42069", Description: "Local dev"},
+		},
+		Paths: openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			SecuritySchemes: openapi3.SecuritySchemes{
+				string(BearerAuth): &openapi3.SecuritySchemeRef{Value: openapi3.NewJWTSecurityScheme().WithDescription(
+					"A JWT from /api/login, or a personal access token (chirpy_pat_...). Personal access tokens need the chirps:write scope " +
+						"to create or delete chirps, profile:write to update the user, messages:read and messages:write for direct messages " +
+						"and webhooks:write to manage webhook endpoints; requests without it get a 403.",
+				)},
+				string(RefreshToken): &openapi3.SecuritySchemeRef{Value: openapi3.NewSecurityScheme().
+					WithType("http").
+					WithScheme("bearer").
+					WithDescription("The refresh token from /api/login.")},
+				string(PolkaAPIKey): &openapi3.SecuritySchemeRef{Value: apiKeyScheme("Authorization", "Authorization: ApiKey <api-key>")},
+				string(AdminAPIKey): &openapi3.SecuritySchemeRef{Value: apiKeyScheme("Authorization", "Authorization: ApiKey <admin-key>")},
+				string(PolkaSignature): &openapi3.SecuritySchemeRef{Value: apiKeyScheme("X-Polka-Signature",
+					`X-Polka-Signature: v1=<hex hmac-sha256 of "<timestamp>.<event ID>.<body>">`,
+				)},
+			},
+		},
+	}
+}
+
+func apiKeyScheme(header, description string) *openapi3.SecurityScheme {
+	return openapi3.NewSecurityScheme().
+		WithType("apiKey").
+		WithIn(openapi3.ParameterInHeader).
+		WithName(header).
+		WithDescription(description)
+}
@@ -0,0 +1,81 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lucashthiele/chirpy/internal/apiversion"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+func TestHandleVersions(t *testing.T) {
+	mux := http.NewServeMux()
+	router := NewRouter(mux)
+	router.HandleVersions("GET /api/things", Operation{
+		ID:        "listThings",
+		Tag:       "Things",
+		Responses: []Response{JSON(http.StatusOK, "The things", []thing{})},
+	}, func(res http.ResponseWriter, req *http.Request) {
+		response.RespondWithList(res, req, []thing{{ID: "1", Name: "one"}}, nil)
+	})
+	handler := router.Negotiate(mux)
+
+	cases := []struct {
+		name           string
+		path           string
+		accept         string
+		wantVersion    string
+		wantDeprecated bool
+		wantEnvelope   bool
+	}{
+		{name: "v1", path: "/api/things", wantVersion: "v1", wantDeprecated: true},
+		{name: "v2", path: "/api/v2/things", wantVersion: "v2", wantEnvelope: true},
+		{name: "v2 asked for", path: "/api/things", accept: apiversion.V2.MediaType(), wantVersion: "v2", wantEnvelope: true},
+		{name: "v1 asked for", path: "/api/things", accept: apiversion.V1.MediaType(), wantVersion: "v1", wantDeprecated: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			if c.accept != "" {
+				req.Header.Set("Accept", c.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Chirpy-Version"); got != c.wantVersion {
+				t.Errorf("Chirpy-Version = %q, want %q", got, c.wantVersion)
+			}
+			if deprecated := rec.Header().Get("Deprecation") != ""; deprecated != c.wantDeprecated {
+				t.Errorf("deprecated = %v, want %v", deprecated, c.wantDeprecated)
+			}
+
+			var body any
+			err := json.Unmarshal(rec.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if _, enveloped := body.(map[string]any); enveloped != c.wantEnvelope {
+				t.Errorf("body = %s, want enveloped %v", rec.Body, c.wantEnvelope)
+			}
+		})
+	}
+
+	doc, err := router.Document()
+	if err != nil {
+		t.Fatalf("Document() error = %v", err)
+	}
+	v1 := doc.Paths.Value("/api/things").Get
+	v2 := doc.Paths.Value("/api/v2/things").Get
+	if !v1.Deprecated || v2.Deprecated {
+		t.Errorf("deprecated = %v in v1 and %v in v2, want only v1", v1.Deprecated, v2.Deprecated)
+	}
+	if v2.OperationID != "listThingsV2" {
+		t.Errorf("v2 operation ID = %q, want listThingsV2", v2.OperationID)
+	}
+	if _, ok := v2.Responses.Value("200").Value.Content["application/json"].Schema.Value.Properties["data"]; !ok {
+		t.Errorf("the v2 list isn't enveloped in the document")
+	}
+}
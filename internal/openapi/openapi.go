@@ -0,0 +1,150 @@
+// Package openapi builds the OpenAPI document of the API from the routes it
+// serves and the Go types of their requests and responses, and checks the
+// traffic of the routes against it.
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/lucashthiele/chirpy/internal/model"
+)
+
+// Security is a security scheme of the document.
+type Security string
+
+const (
+	// Anonymous lets a request without credentials through, next to the
+	// other schemes of an operation.
+	Anonymous      Security = ""
+	BearerAuth     Security = "bearerAuth"
+	RefreshToken   Security = "refreshToken"
+	AdminAPIKey    Security = "adminApiKey"
+	PolkaAPIKey    Security = "polkaApiKey"
+	PolkaSignature Security = "polkaSignature"
+)
+
+var (
+	Authenticated = []Security{BearerAuth}
+	// OptionallyAuthenticated operations answer anonymous requests too.
+	OptionallyAuthenticated = []Security{Anonymous, BearerAuth}
+)
+
+// Operation documents a route. Operations without Security are public.
+type Operation struct {
+	ID          string
+	Tag         string
+	Summary     string
+	Description string
+	// Security lists the schemes that are accepted, any of them is enough.
+	Security []Security
+	// Parameters of the path are documented as strings when they aren't
+	// listed.
+	Parameters []Parameter
+	Request    *Request
+	Responses  []Response
+}
+
+type Parameter struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Schema      *openapi3.Schema
+}
+
+// Request is the body of a request.
+type Request struct {
+	Description string
+	// ContentType defaults to application/json.
+	ContentType string
+	// Type is a value of the Go type the body is decoded into.
+	Type any
+}
+
+type Response struct {
+	Status      int
+	Description string
+	// Type is a value of the Go type encoded as the JSON body, nil when
+	// there is no body or it isn't JSON.
+	Type any
+	// ContentType of the JSON body, defaults to application/json.
+	ContentType string
+	// Content lists the schemas of bodies that aren't JSON by content type,
+	// a nil schema leaves the body unchecked.
+	Content map[string]*openapi3.Schema
+	// Headers maps the headers of the response to their description.
+	Headers map[string]string
+}
+
+func Query(name, description string, schema *openapi3.Schema) Parameter {
+	return Parameter{Name: name, In: openapi3.ParameterInQuery, Description: description, Schema: schema}
+}
+
+func RequiredQuery(name, description string, schema *openapi3.Schema) Parameter {
+	param := Query(name, description, schema)
+	param.Required = true
+	return param
+}
+
+func Header(name, description string, schema *openapi3.Schema) Parameter {
+	return Parameter{Name: name, In: openapi3.ParameterInHeader, Description: description, Schema: schema}
+}
+
+func Path(name, description string, schema *openapi3.Schema) Parameter {
+	return Parameter{Name: name, In: openapi3.ParameterInPath, Description: description, Required: true, Schema: schema}
+}
+
+// JSON is a response with the JSON encoding of the type of v.
+func JSON(status int, description string, v any) Response {
+	return Response{Status: status, Description: description, Type: v}
+}
+
+// Error is a response with a model.ErrorResponse.
+func Error(status int, description string) Response {
+	return JSON(status, description, model.ErrorResponse{})
+}
+
+// Empty is a response without a body.
+func Empty(status int, description string) Response {
+	return Response{Status: status, Description: description}
+}
+
+// Content is a response whose body isn't JSON, it can have any of the
+// content types. Only the content type of these bodies is checked.
+func Content(status int, description string, contentTypes ...string) Response {
+	content := map[string]*openapi3.Schema{}
+	for _, contentType := range contentTypes {
+		content[contentType] = nil
+	}
+	return Response{Status: status, Description: description, Content: content}
+}
+
+// internalServerError is documented for every operation, any of them can
+// fail.
+var internalServerError = Error(http.StatusInternalServerError, "Something went wrong")
+
+// invalidBody, bodyTooLarge and invalidFields are documented for the
+// operations with a JSON body, which parser.ParseBody decodes. Only v2
+// checks the validate tags of the body, and answers invalid fields.
+var (
+	invalidBody   = Error(http.StatusBadRequest, "The body isn't JSON of the right shape")
+	bodyTooLarge  = Error(http.StatusRequestEntityTooLarge, "The body is too large")
+	invalidFields = Error(http.StatusUnprocessableEntity, "Some fields are invalid, they are all listed")
+)
+
+// Unauthorized is the response of MiddlewareAuth to a missing or invalid
+// token.
+var Unauthorized = Error(http.StatusUnauthorized, "Unauthorized")
+
+// SessionRequired is the response of RequireSession to personal access
+// tokens and OAuth tokens.
+var SessionRequired = Error(http.StatusForbidden, "Personal access tokens and OAuth tokens can't be used here")
+
+// IdempotencyKey, IdempotencyKeyInUse and IdempotencyKeyReused document the
+// operations behind MiddlewareIdempotency.
+var (
+	IdempotencyKey       = Header("Idempotency-Key", "A key unique to this request. Retries sent with the same key within 24 hours get the response of the first request, with an Idempotent-Replayed header. Must be a random UUID when the request isn't authenticated.", openapi3.NewStringSchema().WithMaxLength(255))
+	IdempotencyKeyInUse  = Error(http.StatusConflict, "A request with the same Idempotency-Key is still being handled, retry after the Retry-After header")
+	IdempotencyKeyReused = Error(http.StatusUnprocessableEntity, "The Idempotency-Key was used for another request, or in v2 some fields are invalid")
+)
@@ -0,0 +1,205 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/pkg/parser"
+)
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	uuidType      = reflect.TypeFor[uuid.UUID]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// reflector turns Go types into the schema of their JSON encoding.
+//
+// The fields of responses are required unless they are omitempty, since the
+// handlers always send them, and responses can't have other fields. The
+// fields of requests are only required when they are tagged
+// validate:"required", and other fields are ignored like encoding/json does
+// unless the request is strict. The other rules of parser.Rules become
+// formats and lengths. Fields tagged enum:"a,b" only take these values, and
+// the description tag describes a field.
+type reflector struct {
+	response bool
+	strict   bool
+	// seen holds the structs being reflected, to stop at recursive types.
+	seen []reflect.Type
+}
+
+// SchemaOf is the schema of the JSON encoding of v in a response.
+func SchemaOf(v any) *openapi3.Schema {
+	r := &reflector{response: true}
+	return r.schema(reflect.TypeOf(v))
+}
+
+// RequestSchemaOf is the schema of the JSON a request is decoded into v
+// from.
+func RequestSchemaOf(v any) *openapi3.Schema {
+	r := &reflector{}
+	return r.schema(reflect.TypeOf(v))
+}
+
+// StrictRequestSchemaOf is RequestSchemaOf for requests that can't have
+// other fields, see parser.Strict.
+func StrictRequestSchemaOf(v any) *openapi3.Schema {
+	r := &reflector{strict: true}
+	return r.schema(reflect.TypeOf(v))
+}
+
+func (r *reflector) schema(t reflect.Type) *openapi3.Schema {
+	if t == nil {
+		return anySchema()
+	}
+
+	switch t {
+	case timeType:
+		return openapi3.NewDateTimeSchema()
+	case uuidType:
+		return openapi3.NewUUIDSchema()
+	}
+
+	if t.Kind() == reflect.Pointer {
+		return r.schema(t.Elem()).WithNullable()
+	}
+	// types that encode themselves can be anything
+	if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		return anySchema()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return openapi3.NewStringSchema()
+	case reflect.Bool:
+		return openapi3.NewBoolSchema()
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return openapi3.NewInt64Schema()
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return openapi3.NewInt32Schema()
+	case reflect.Float32, reflect.Float64:
+		return openapi3.NewFloat64Schema()
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return openapi3.NewBytesSchema()
+		}
+		return openapi3.NewArraySchema().WithItems(r.schema(t.Elem()))
+	case reflect.Map:
+		return openapi3.NewObjectSchema().WithAdditionalProperties(r.schema(t.Elem()))
+	case reflect.Struct:
+		return r.object(t)
+	}
+
+	// interfaces and anything else can be any value
+	return anySchema()
+}
+
+func anySchema() *openapi3.Schema {
+	return &openapi3.Schema{Nullable: true}
+}
+
+func (r *reflector) object(t reflect.Type) *openapi3.Schema {
+	if slices.Contains(r.seen, t) {
+		return openapi3.NewObjectSchema()
+	}
+	r.seen = append(r.seen, t)
+	defer func() { r.seen = r.seen[:len(r.seen)-1] }()
+
+	schema := openapi3.NewObjectSchema()
+	if r.response || r.strict {
+		schema = schema.WithoutAdditionalProperties()
+	}
+	r.fields(schema, t)
+	slices.Sort(schema.Required)
+
+	return schema
+}
+
+// fields adds the fields of the struct t to schema, with the fields of its
+// embedded structs like encoding/json.
+func (r *reflector) fields(schema *openapi3.Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && options == "" {
+			continue
+		}
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				r.fields(schema, fieldType)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := r.schema(fieldType)
+		if enum := field.Tag.Get("enum"); enum != "" {
+			for value := range strings.SplitSeq(enum, ",") {
+				property.Enum = append(property.Enum, value)
+			}
+		}
+		property.Description = field.Tag.Get("description")
+		rules := parser.Rules(field)
+		if !r.response {
+			applyRules(property, rules)
+		}
+		schema.WithProperty(name, property)
+
+		if r.required(rules, options) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func (r *reflector) required(rules []parser.Rule, jsonOptions string) bool {
+	if !r.response {
+		return slices.ContainsFunc(rules, func(rule parser.Rule) bool { return rule.Name == "required" })
+	}
+
+	return !slices.Contains(strings.Split(jsonOptions, ","), "omitempty")
+}
+
+// applyRules describes the rules other than required in the schema of the
+// field.
+func applyRules(property *openapi3.Schema, rules []parser.Rule) {
+	for _, rule := range rules {
+		switch rule.Name {
+		case "email":
+			property.Format = "email"
+		case "uuid":
+			property.Format = "uuid"
+		case "min", "max":
+			limit, err := strconv.ParseUint(rule.Param, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch {
+			case property.Type.Is(openapi3.TypeString) && rule.Name == "min":
+				property.MinLength = limit
+			case property.Type.Is(openapi3.TypeString):
+				property.MaxLength = &limit
+			case rule.Name == "min":
+				property.MinItems = limit
+			default:
+				property.MaxItems = &limit
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package openapi
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type embedded struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type example struct {
+	embedded
+	ID       uuid.UUID       `json:"id"`
+	Name     string          `json:"name" validate:"required" description:"The name"`
+	Kind     string          `json:"kind" enum:"a,b"`
+	Count    int32           `json:"count,omitempty"`
+	Parent   *example        `json:"parent"`
+	Tags     []string        `json:"tags"`
+	Extra    json.RawMessage `json:"extra"`
+	Skipped  string          `json:"-"`
+	internal string
+}
+
+func TestSchemaOf(t *testing.T) {
+	cases := []struct {
+		name         string
+		schema       func() []string
+		wantRequired []string
+	}{
+		{
+			name:         "response",
+			schema:       func() []string { return SchemaOf(example{}).Required },
+			wantRequired: []string{"created_at", "extra", "id", "kind", "name", "parent", "tags"},
+		},
+		{
+			name:         "request",
+			schema:       func() []string { return RequestSchemaOf(example{}).Required },
+			wantRequired: []string{"name"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.schema()
+			if !slices.Equal(got, c.wantRequired) {
+				t.Errorf("required = %v, want %v", got, c.wantRequired)
+			}
+		})
+	}
+}
+
+func TestSchemaOfProperties(t *testing.T) {
+	schema := SchemaOf(example{})
+
+	if _, ok := schema.Properties["Skipped"]; ok {
+		t.Errorf("fields tagged json:\"-\" are in the schema")
+	}
+	if _, ok := schema.Properties["internal"]; ok {
+		t.Errorf("unexported fields are in the schema")
+	}
+	if schema.AdditionalProperties.Has == nil || *schema.AdditionalProperties.Has {
+		t.Errorf("responses allow additional properties")
+	}
+	if RequestSchemaOf(example{}).AdditionalProperties.Has != nil {
+		t.Errorf("requests don't allow additional properties")
+	}
+
+	cases := []struct {
+		property     string
+		wantType     string
+		wantFormat   string
+		wantNullable bool
+	}{
+		{property: "created_at", wantType: "string", wantFormat: "date-time"},
+		{property: "id", wantType: "string", wantFormat: "uuid"},
+		{property: "count", wantType: "integer", wantFormat: "int32"},
+		{property: "parent", wantType: "object", wantNullable: true},
+		{property: "tags", wantType: "array"},
+		{property: "extra", wantNullable: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.property, func(t *testing.T) {
+			ref := schema.Properties[c.property]
+			if ref == nil {
+				t.Fatalf("%s is not in the schema", c.property)
+			}
+
+			property := ref.Value
+			typ := ""
+			if property.Type != nil && len(*property.Type) > 0 {
+				typ = (*property.Type)[0]
+			}
+			if typ != c.wantType || property.Format != c.wantFormat || property.Nullable != c.wantNullable {
+				t.Errorf("%s = %s %s nullable %v, want %s %s nullable %v", c.property, typ, property.Format, property.Nullable, c.wantType, c.wantFormat, c.wantNullable)
+			}
+		})
+	}
+
+	kind := schema.Properties["kind"].Value
+	if !slices.Equal(kind.Enum, []any{"a", "b"}) {
+		t.Errorf("kind enum = %v, want [a b]", kind.Enum)
+	}
+	if name := schema.Properties["name"].Value; name.Description != "The name" {
+		t.Errorf("name description = %q, want %q", name.Description, "The name")
+	}
+}
+
+type rules struct {
+	Email string   `json:"email" validate:"required,email"`
+	ID    string   `json:"id" validate:"uuid"`
+	Name  string   `json:"name" validate:"min=2,max=5"`
+	Tags  []string `json:"tags" validate:"max=3"`
+}
+
+func TestRequestSchemaOfRules(t *testing.T) {
+	schema := RequestSchemaOf(rules{})
+
+	if got := schema.Properties["email"].Value.Format; got != "email" {
+		t.Errorf("email format = %q, want email", got)
+	}
+	if got := schema.Properties["id"].Value.Format; got != "uuid" {
+		t.Errorf("id format = %q, want uuid", got)
+	}
+	name := schema.Properties["name"].Value
+	if name.MinLength != 2 || name.MaxLength == nil || *name.MaxLength != 5 {
+		t.Errorf("name length = %d..%v, want 2..5", name.MinLength, name.MaxLength)
+	}
+	tags := schema.Properties["tags"].Value
+	if tags.MaxItems == nil || *tags.MaxItems != 3 {
+		t.Errorf("tags max items = %v, want 3", tags.MaxItems)
+	}
+	if strict := StrictRequestSchemaOf(rules{}); strict.AdditionalProperties.Has == nil || *strict.AdditionalProperties.Has {
+		t.Errorf("strict requests allow additional properties")
+	}
+}
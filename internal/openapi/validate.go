@@ -0,0 +1,209 @@
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+func init() {
+	for _, contentType := range []string{"application/activity+json", "application/jrd+json", "application/feed+json"} {
+		openapi3filter.RegisterBodyDecoder(contentType, openapi3filter.JSONBodyDecoder)
+	}
+}
+
+// maxCheckedBody is the largest response body that is checked, longer ones
+// like streams are let through.
+const maxCheckedBody = 1 << 20
+
+// RequestError is a request that doesn't match the document.
+type RequestError struct {
+	Err error
+}
+
+func (e *RequestError) Error() string {
+	return "request doesn't match the OpenAPI document: " + e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// ResponseError is a response of a handler that doesn't match the document.
+type ResponseError struct {
+	Status int
+	Err    error
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%d response doesn't match the OpenAPI document: %s", e.Status, e.Err)
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
+type matchKey struct{}
+
+type match struct {
+	route  *routers.Route
+	params map[string]string
+}
+
+// Validate checks the requests to next and its responses against doc, and
+// reports every mismatch with a *RequestError or a *ResponseError. Requests
+// are served either way. Requests to routes outside of doc aren't checked.
+func Validate(doc *openapi3.T, next http.Handler, report func(req *http.Request, err error)) http.Handler {
+	// the routes are matched by a mux of their own, so they are matched like
+	// the mux serving them does
+	matcher := http.NewServeMux()
+	for path, pathItem := range doc.Paths.Map() {
+		for method, operation := range pathItem.Operations() {
+			route := &routers.Route{
+				Spec:      doc,
+				Path:      path,
+				PathItem:  pathItem,
+				Method:    method,
+				Operation: operation,
+			}
+			matcher.HandleFunc(method+" "+path, func(res http.ResponseWriter, req *http.Request) {
+				m := req.Context().Value(matchKey{}).(*match)
+				m.route = route
+				m.params = map[string]string{}
+				for _, param := range operation.Parameters {
+					if param.Value.In == openapi3.ParameterInPath {
+						m.params[param.Value.Name] = req.PathValue(param.Value.Name)
+					}
+				}
+			})
+		}
+	}
+
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		m := &match{}
+		matcher.ServeHTTP(discardWriter{}, req.WithContext(context.WithValue(req.Context(), matchKey{}, m)))
+		if m.route == nil {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: m.params,
+			Route:      m.route,
+			Options: &openapi3filter.Options{
+				// the handlers check the credentials, and requests are
+				// served as they came
+				AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+				SkipSettingDefaults: true,
+				MultiError:          true,
+			},
+		}
+		err := openapi3filter.ValidateRequest(req.Context(), input)
+		if err != nil {
+			report(req, &RequestError{Err: err})
+		}
+
+		recorder := &recordingWriter{ResponseWriter: res}
+		next.ServeHTTP(recorder, req)
+		if recorder.hijacked || recorder.truncated {
+			return
+		}
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		header := res.Header().Clone()
+		body := recorder.body.Bytes()
+		if status == http.StatusNoContent || status == http.StatusNotModified {
+			body = nil
+		}
+		// like net/http, which sniffs bodies sent without a content type
+		if header.Get("Content-Type") == "" && len(body) > 0 {
+			header.Set("Content-Type", http.DetectContentType(body))
+		}
+
+		err = openapi3filter.ValidateResponse(req.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 status,
+			Header:                 header,
+			Body:                   io.NopCloser(bytes.NewReader(body)),
+			Options: &openapi3filter.Options{
+				IncludeResponseStatus: true,
+				MultiError:            true,
+			},
+		})
+		if err != nil {
+			report(req, &ResponseError{Status: status, Err: err})
+		}
+	})
+}
+
+// recordingWriter keeps the status and body of a response as it's written.
+type recordingWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	truncated bool
+	hijacked  bool
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.body.Len()+len(b) > maxCheckedBody {
+		w.truncated = true
+	} else if !w.truncated {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *recordingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer can't be hijacked")
+	}
+	w.hijacked = true
+	return hijacker.Hijack()
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+type discardWriter struct{}
+
+func (discardWriter) Header() http.Header {
+	return http.Header{}
+}
+
+func (discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (discardWriter) WriteHeader(int) {}
@@ -0,0 +1,183 @@
+package openapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type thing struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type thingParams struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name            string
+		method          string
+		path            string
+		body            string
+		handler         http.HandlerFunc
+		wantRequestErr  bool
+		wantResponseErr bool
+	}{
+		{
+			name:   "matching response",
+			method: http.MethodGet,
+			path:   "/things/1",
+			handler: func(res http.ResponseWriter, req *http.Request) {
+				res.Header().Set("Content-Type", "application/json")
+				res.Write([]byte(`{"id":"1","name":"one"}`))
+			},
+		},
+		{
+			name:   "missing field",
+			method: http.MethodGet,
+			path:   "/things/1",
+			handler: func(res http.ResponseWriter, req *http.Request) {
+				res.Header().Set("Content-Type", "application/json")
+				res.Write([]byte(`{"id":"1"}`))
+			},
+			wantResponseErr: true,
+		},
+		{
+			name:   "undocumented field",
+			method: http.MethodGet,
+			path:   "/things/1",
+			handler: func(res http.ResponseWriter, req *http.Request) {
+				res.Header().Set("Content-Type", "application/json")
+				res.Write([]byte(`{"id":"1","name":"one","is_chirpy_red":true}`))
+			},
+			wantResponseErr: true,
+		},
+		{
+			name:   "undocumented status",
+			method: http.MethodGet,
+			path:   "/things/1",
+			handler: func(res http.ResponseWriter, req *http.Request) {
+				res.WriteHeader(http.StatusTeapot)
+			},
+			wantResponseErr: true,
+		},
+		{
+			name:   "documented error",
+			method: http.MethodGet,
+			path:   "/things/1",
+			handler: func(res http.ResponseWriter, req *http.Request) {
+				res.Header().Set("Content-Type", "application/json")
+				res.WriteHeader(http.StatusNotFound)
+				res.Write([]byte(`{"error":"Not found"}`))
+			},
+		},
+		{
+			name:   "valid request",
+			method: http.MethodPost,
+			path:   "/things",
+			body:   `{"name":"one"}`,
+			handler: func(res http.ResponseWriter, req *http.Request) {
+				res.WriteHeader(http.StatusNoContent)
+			},
+		},
+		{
+			name:   "invalid request",
+			method: http.MethodPost,
+			path:   "/things",
+			body:   `{}`,
+			handler: func(res http.ResponseWriter, req *http.Request) {
+				res.WriteHeader(http.StatusNoContent)
+			},
+			wantRequestErr: true,
+		},
+		{
+			name:   "undocumented route",
+			method: http.MethodGet,
+			path:   "/other",
+			handler: func(res http.ResponseWriter, req *http.Request) {
+				res.WriteHeader(http.StatusTeapot)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			router := NewRouter(mux)
+			router.HandleFunc("GET /things/{thingID}", Operation{
+				ID:         "getThing",
+				Tag:        "Things",
+				Parameters: []Parameter{Path("thingID", "", openapi3.NewStringSchema())},
+				Responses: []Response{
+					JSON(http.StatusOK, "The thing", thing{}),
+					Error(http.StatusNotFound, "Not found"),
+				},
+			}, c.handler)
+			router.HandleFunc("POST /things", Operation{
+				ID:        "createThing",
+				Tag:       "Things",
+				Request:   &Request{Type: thingParams{}},
+				Responses: []Response{Empty(http.StatusNoContent, "Created")},
+			}, c.handler)
+			mux.HandleFunc("/other", c.handler)
+
+			doc, err := router.Document()
+			if err != nil {
+				t.Fatalf("Document() error = %v", err)
+			}
+
+			var requestErr, responseErr bool
+			handler := Validate(doc, mux, func(req *http.Request, err error) {
+				requestErr = requestErr || errors.As(err, new(*RequestError))
+				responseErr = responseErr || errors.As(err, new(*ResponseError))
+			})
+
+			req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+			if c.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if requestErr != c.wantRequestErr {
+				t.Errorf("request error = %v, want %v", requestErr, c.wantRequestErr)
+			}
+			if responseErr != c.wantResponseErr {
+				t.Errorf("response error = %v, want %v", responseErr, c.wantResponseErr)
+			}
+		})
+	}
+}
+
+func TestDocumentErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		ops  []Operation
+	}{
+		{name: "no responses", ops: []Operation{{ID: "a", Tag: "A"}}},
+		{name: "duplicate status", ops: []Operation{{ID: "a", Tag: "A", Responses: []Response{Empty(200, "ok"), Empty(200, "ok")}}}},
+		{name: "duplicate operation id", ops: []Operation{
+			{ID: "a", Tag: "A", Responses: []Response{Empty(200, "ok")}},
+			{ID: "a", Tag: "A", Responses: []Response{Empty(200, "ok")}},
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			router := NewRouter(http.NewServeMux())
+			for i, op := range c.ops {
+				router.HandleFunc("GET /things/"+string(rune('a'+i)), op, func(http.ResponseWriter, *http.Request) {})
+			}
+
+			_, err := router.Document()
+			if err == nil {
+				t.Errorf("Document() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned for endpoints on addresses that aren't
+// reachable from the internet, webhooks would let users make the server
+// call its own network.
+var ErrPrivateAddress = errors.New("the address isn't public")
+
+// nonPublicPrefixes are the special-purpose ranges that netip.Addr has no
+// method for.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT, and some cloud metadata services
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, can reach private IPv4 addresses
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("2002::/16"),       // 6to4, can embed private IPv4 addresses
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("fec0::/10"),       // deprecated site-local
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2001::/32"),       // Teredo, can embed private IPv4 addresses
+	netip.MustParsePrefix("::ffff:0:0:0/96"), // IPv4-translated
+}
+
+// IsPublicAddr reports whether webhooks can be delivered to addr. Loopback,
+// private, link-local, which has the metadata service of cloud providers,
+// and the other special-purpose addresses can't.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL returns ErrPrivateAddress when the host of rawURL resolves to an
+// address that isn't public. Deliveries check the address they connect to
+// again, the host can resolve to another address by then.
+func CheckURL(ctx context.Context, rawURL string) error {
+	endpointURL, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", endpointURL.Hostname())
+	if err != nil {
+		return fmt.Errorf("the host couldn't be resolved: %w", err)
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddr(addr) {
+			return ErrPrivateAddress
+		}
+	}
+
+	return nil
+}
+
+// NewClient returns the client webhooks are delivered with. It refuses to
+// connect to addresses that aren't public, once the host is resolved, and
+// doesn't follow redirects.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("connecting to %s: %w", address, ErrPrivateAddress)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		// no proxy, the dialer would check the address of the proxy
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
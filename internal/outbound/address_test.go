@@ -0,0 +1,92 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	cases := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{addr: "127.0.0.1", want: false},
+		{addr: "::1", want: false},
+		{addr: "0.0.0.0", want: false},
+		{addr: "10.1.2.3", want: false},
+		{addr: "172.16.0.1", want: false},
+		{addr: "192.168.1.1", want: false},
+		{addr: "169.254.169.254", want: false},
+		{addr: "100.100.100.200", want: false},
+		{addr: "fd00:ec2::254", want: false},
+		{addr: "fe80::1", want: false},
+		{addr: "::ffff:127.0.0.1", want: false},
+		{addr: "64:ff9b::a00:1", want: false},
+		{addr: "224.0.0.1", want: false},
+		{addr: "255.255.255.255", want: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.addr, func(t *testing.T) {
+			if got := IsPublicAddr(netip.MustParseAddr(tc.addr)); got != tc.want {
+				t.Errorf("IsPublicAddr(%s) = %v, want %v", tc.addr, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	cases := []struct {
+		url     string
+		wantErr error
+	}{
+		{url: "https://93.184.216.34/hooks"},
+		{url: "https://127.0.0.1/hooks", wantErr: ErrPrivateAddress},
+		{url: "https://169.254.169.254/latest/meta-data", wantErr: ErrPrivateAddress},
+		{url: "https://[::1]:8443/hooks", wantErr: ErrPrivateAddress},
+		{url: "https://10.0.0.8/hooks", wantErr: ErrPrivateAddress},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.url, func(t *testing.T) {
+			err := CheckURL(context.Background(), tc.url)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("CheckURL(%s) error = %v, want %v", tc.url, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestNewClientRefusesPrivateAddresses checks the address connected to, a
+// host that resolved to a public address when its endpoint was created can
+// resolve to a private one by the time of the delivery.
+func TestNewClientRefusesPrivateAddresses(t *testing.T) {
+	reached := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	status, err := Deliver(context.Background(), NewClient(time.Second), Request{
+		DeliveryID: uuid.New(),
+		URL:        server.URL,
+		Secret:     "whsec_test",
+		Event:      EventChirpCreated,
+		Payload:    []byte(`{}`),
+	}, time.Now())
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("Deliver() error = %v, want %v", err, ErrPrivateAddress)
+	}
+	if status != 0 || reached {
+		t.Errorf("Deliver() reached the server on %s", server.URL)
+	}
+}
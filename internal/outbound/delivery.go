@@ -0,0 +1,85 @@
+package outbound
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/auth"
+)
+
+const (
+	SignatureHeader  = "X-Chirpy-Signature"
+	TimestampHeader  = "X-Chirpy-Timestamp"
+	EventHeader      = "X-Chirpy-Event"
+	DeliveryIDHeader = "X-Chirpy-Delivery-ID"
+
+	MaxAttempts      = 8
+	DisableThreshold = 20 // consecutive failed attempts before an endpoint is disabled
+
+	baseBackoff = time.Second * 30
+	maxBackoff  = time.Hour * 6
+)
+
+type Request struct {
+	DeliveryID uuid.UUID
+	URL        string
+	Secret     string
+	Event      string
+	Payload    []byte
+}
+
+// Backoff returns how long to wait before retrying after the given attempt:
+// 30s, 1m, 2m, ... capped at 6h.
+func Backoff(attempt int32) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	backoff := baseBackoff
+	for i := int32(1); i < attempt; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return backoff
+}
+
+// Deliver POSTs the signed payload to the endpoint. Receivers verify it by
+// computing the HMAC-SHA256 of "<timestamp>.<body>" with their secret. It
+// returns the response status, if any, and an error unless the endpoint
+// answered with a 2xx.
+func Deliver(ctx context.Context, client *http.Client, r Request, now time.Time) (int, error) {
+	timestamp := now.Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(r.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(EventHeader, r.Event)
+	req.Header.Set(DeliveryIDHeader, r.DeliveryID.String())
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, "v1="+auth.SignWebhook(r.Secret, timestamp, r.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
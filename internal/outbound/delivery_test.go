@@ -0,0 +1,139 @@
+package outbound
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/auth"
+)
+
+func TestDeliver(t *testing.T) {
+	secret := "whsec_test"
+	payload := []byte(`{"event":"chirp.created","data":{"body":"Hello, world!"}}`)
+	deliveryID := uuid.New()
+
+	cases := []struct {
+		name       string
+		status     int
+		wantStatus int
+		wantErr    bool
+	}{
+		{
+			name:       "endpoint accepts delivery",
+			status:     http.StatusNoContent,
+			wantStatus: http.StatusNoContent,
+			wantErr:    false,
+		},
+		{
+			name:       "endpoint fails",
+			status:     http.StatusInternalServerError,
+			wantStatus: http.StatusInternalServerError,
+			wantErr:    true,
+		},
+		{
+			name:       "endpoint redirects",
+			status:     http.StatusMovedPermanently,
+			wantStatus: http.StatusMovedPermanently,
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotHeaders http.Header
+			var gotBody []byte
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotHeaders = r.Header.Clone()
+				gotBody, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			now := time.Now()
+			status, err := Deliver(context.Background(), server.Client(), Request{
+				DeliveryID: deliveryID,
+				URL:        server.URL,
+				Secret:     secret,
+				Event:      EventChirpCreated,
+				Payload:    payload,
+			}, now)
+			if tc.wantErr && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if status != tc.wantStatus {
+				t.Errorf("got status %d, want %d", status, tc.wantStatus)
+			}
+
+			if string(gotBody) != string(payload) {
+				t.Errorf("got body %q, want %q", gotBody, payload)
+			}
+			if gotHeaders.Get(EventHeader) != EventChirpCreated {
+				t.Errorf("got event header %q, want %q", gotHeaders.Get(EventHeader), EventChirpCreated)
+			}
+			if gotHeaders.Get(DeliveryIDHeader) != deliveryID.String() {
+				t.Errorf("got delivery id header %q, want %q", gotHeaders.Get(DeliveryIDHeader), deliveryID)
+			}
+
+			timestamp, err := strconv.ParseInt(gotHeaders.Get(TimestampHeader), 10, 64)
+			if err != nil {
+				t.Fatalf("invalid timestamp header: %v", err)
+			}
+			signature := strings.TrimPrefix(gotHeaders.Get(SignatureHeader), "v1=")
+			err = auth.ValidateWebhookSignature(secret, timestamp, gotBody, signature, time.Minute, now)
+			if err != nil {
+				t.Errorf("signature does not verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeliverUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	status, err := Deliver(context.Background(), http.DefaultClient, Request{
+		DeliveryID: uuid.New(),
+		URL:        url,
+		Secret:     "whsec_test",
+		Event:      EventChirpDeleted,
+		Payload:    []byte(`{}`),
+	}, time.Now())
+	if err == nil {
+		t.Error("expected error but got none")
+	}
+	if status != 0 {
+		t.Errorf("got status %d, want 0", status)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		attempt int32
+		want    time.Duration
+	}{
+		{attempt: 1, want: 30 * time.Second},
+		{attempt: 2, want: time.Minute},
+		{attempt: 3, want: 2 * time.Minute},
+		{attempt: 8, want: 64 * time.Minute},
+		{attempt: 20, want: 6 * time.Hour},
+	}
+
+	for _, tc := range cases {
+		t.Run(strconv.Itoa(int(tc.attempt)), func(t *testing.T) {
+			if got := Backoff(tc.attempt); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
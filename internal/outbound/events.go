@@ -0,0 +1,64 @@
+package outbound
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/database"
+)
+
+const (
+	// EventChirpCreated is sent by the chirps handlers when the user chirps.
+	EventChirpCreated = "chirp.created"
+	// EventChirpDeleted is sent by the chirps handlers when the user deletes
+	// a chirp.
+	EventChirpDeleted = "chirp.deleted"
+	// EventUserFollowed is sent by the follows handlers once someone follows
+	// the user, after the request is approved for private accounts.
+	EventUserFollowed = "user.followed"
+	// EventUserUpgraded is sent by the Polka webhook when the user upgrades to
+	// Chirpy Red.
+	EventUserUpgraded = "user.upgraded"
+)
+
+// Events are the events endpoints can subscribe to. Only add an event here
+// once something sends it, endpoints subscribed to it would never hear back.
+var Events = []string{
+	EventChirpCreated,
+	EventChirpDeleted,
+	EventUserFollowed,
+	EventUserUpgraded,
+}
+
+type payload struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+func IsValidEvent(event string) bool {
+	return slices.Contains(Events, event)
+}
+
+// Enqueue queues event for every enabled endpoint userID registered for it.
+// Pass a transaction-bound db so the delivery only exists if the change that
+// caused it was committed.
+func Enqueue(ctx context.Context, db *database.Queries, userID uuid.UUID, event string, data any) error {
+	encoded, err := json.Marshal(payload{
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		Event:   event,
+		Payload: encoded,
+		UserID:  userID,
+	})
+}
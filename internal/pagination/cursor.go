@@ -0,0 +1,122 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Cursor points at the last item of a page of results ordered by time and
+// then ID, newest first. The next page starts right after it.
+type Cursor struct {
+	Time time.Time
+	ID   uuid.UUID
+}
+
+// Start is the cursor of the first page, every item comes after it.
+func Start() Cursor {
+	return Cursor{
+		Time: time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC),
+		ID:   uuid.Max,
+	}
+}
+
+// Encode turns the cursor into an opaque string clients pass back as is.
+func (c Cursor) Encode() string {
+	raw := c.Time.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func Decode(encoded string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	rawTime, rawID, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, rawTime)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	return Cursor{Time: t, ID: id}, nil
+}
+
+// FromRequest reads the cursor and limit query parameters, falling back to the
+// first page and DefaultLimit.
+func FromRequest(req *http.Request) (Cursor, int, error) {
+	cursor := Start()
+	if encoded := req.URL.Query().Get("cursor"); encoded != "" {
+		var err error
+		cursor, err = Decode(encoded)
+		if err != nil {
+			return Cursor{}, 0, err
+		}
+	}
+
+	limit := DefaultLimit
+	if rawLimit := req.URL.Query().Get("limit"); rawLimit != "" {
+		var err error
+		limit, err = strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return Cursor{}, 0, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+		}
+	}
+
+	return cursor, limit, nil
+}
+
+// SetNextLink adds a Link header pointing at the next page, when the
+// current page was full and there might be more. It returns the page for the
+// body of v2 lists.
+func SetNextLink(res http.ResponseWriter, req *http.Request, next Cursor, count, limit int) *response.Page {
+	page := &response.Page{Limit: limit}
+	if count < limit {
+		return page
+	}
+
+	encoded := next.Encode()
+	page.NextCursor = &encoded
+
+	query := req.URL.Query()
+	query.Set("cursor", encoded)
+	query.Set("limit", strconv.Itoa(limit))
+
+	res.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, req.URL.Path, query.Encode()))
+
+	return page
+}
+
+// Parameters documents the query parameters FromRequest reads.
+func Parameters(cursorDescription string) []openapi.Parameter {
+	return []openapi.Parameter{
+		openapi.Query("cursor", cursorDescription, openapi3.NewStringSchema()),
+		openapi.Query("limit", "", openapi3.NewIntegerSchema().WithMin(1).WithMax(MaxLimit).WithDefault(DefaultLimit)),
+	}
+}
+
+// LinkHeader documents the header SetNextLink sets.
+var LinkHeader = map[string]string{
+	"Link": `Link to the next page with rel="next", only set when the page is full.`,
+}
@@ -0,0 +1,83 @@
+package pagination
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := Cursor{
+		Time: time.Date(2025, time.May, 15, 8, 19, 18, 31988000, time.UTC),
+		ID:   uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+	}
+
+	decoded, err := Decode(cursor.Encode())
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !decoded.Time.Equal(cursor.Time) || decoded.ID != cursor.ID {
+		t.Errorf("Decode() = %v, want %v", decoded, cursor)
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	valid := Cursor{Time: time.Unix(1747297158, 0), ID: uuid.New()}
+
+	cases := []struct {
+		name      string
+		query     string
+		wantStart bool
+		wantLimit int
+		wantErr   bool
+	}{
+		{name: "first page", query: "", wantStart: true, wantLimit: DefaultLimit},
+		{name: "cursor and limit", query: "?cursor=" + valid.Encode() + "&limit=5", wantLimit: 5},
+		{name: "max limit", query: "?limit=100", wantStart: true, wantLimit: 100},
+		{name: "limit too big", query: "?limit=101", wantErr: true},
+		{name: "zero limit", query: "?limit=0", wantErr: true},
+		{name: "limit not a number", query: "?limit=ten", wantErr: true},
+		{name: "cursor not base64", query: "?cursor=!!", wantErr: true},
+		{name: "cursor without id", query: "?cursor=MjAyNS0wNS0xNVQwODoxOToxOFo", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/conversations"+c.query, nil)
+
+			cursor, limit, err := FromRequest(req)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("FromRequest() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if c.wantErr {
+				return
+			}
+			if limit != c.wantLimit {
+				t.Errorf("FromRequest() limit = %d, want %d", limit, c.wantLimit)
+			}
+			if (cursor == Start()) != c.wantStart {
+				t.Errorf("FromRequest() cursor = %v, wantStart %v", cursor, c.wantStart)
+			}
+		})
+	}
+}
+
+func TestSetNextLink(t *testing.T) {
+	next := Cursor{Time: time.Unix(1747297158, 0), ID: uuid.New()}
+
+	req := httptest.NewRequest("GET", "/api/conversations?limit=2", nil)
+	res := httptest.NewRecorder()
+	SetNextLink(res, req, next, 1, 2)
+	if link := res.Header().Get("Link"); link != "" {
+		t.Errorf("partial page got Link %q", link)
+	}
+
+	res = httptest.NewRecorder()
+	SetNextLink(res, req, next, 2, 2)
+	want := `</api/conversations?cursor=` + next.Encode() + `&limit=2>; rel="next"`
+	if link := res.Header().Get("Link"); link != want {
+		t.Errorf("Link = %q, want %q", link, want)
+	}
+}
@@ -0,0 +1,51 @@
+// Package requestid gives every request an ID, sent back in the X-Request-ID
+// header and in error bodies, to find the request in the logs.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const Header = "X-Request-ID"
+
+// maxLength is the longest ID kept from a client.
+const maxLength = 128
+
+type contextKey struct{}
+
+// Middleware keeps the ID the client sent in X-Request-ID, or makes one up
+// when it didn't send a usable one.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(Header)
+		if !valid(id) {
+			id = uuid.NewString()
+		}
+
+		res.Header().Set(Header, id)
+		next.ServeHTTP(res, req.WithContext(context.WithValue(req.Context(), contextKey{}, id)))
+	})
+}
+
+// FromContext is the ID of the request, empty outside of Middleware.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// valid tells whether a client's ID is short and printable enough to be
+// logged and sent back.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
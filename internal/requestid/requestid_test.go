@@ -0,0 +1,45 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	cases := []struct {
+		name     string
+		id       string
+		wantKept bool
+	}{
+		{name: "none"},
+		{name: "client's", id: "abc-123", wantKept: true},
+		{name: "too long", id: strings.Repeat("a", maxLength+1)},
+		{name: "not printable", id: "abc\x00def"},
+		{name: "with spaces", id: "abc def"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got string
+			handler := Middleware(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				got = FromContext(req.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if c.id != "" {
+				req.Header.Set(Header, c.id)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got == "" || rec.Header().Get(Header) != got {
+				t.Errorf("ID = %q, header = %q, want the same non-empty ID", got, rec.Header().Get(Header))
+			}
+			if kept := got == c.id; kept != c.wantKept {
+				t.Errorf("ID = %q, want the client's %v", got, c.wantKept)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+
+	authz "github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/handlers/auth"
+	pb "github.com/lucashthiele/chirpy/pkg/pb/chirpy/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// authService mirrors POST /api/login, /api/refresh and /api/revoke. The
+// refresh token goes in the request instead of the authorization metadata.
+type authService struct {
+	pb.UnimplementedAuthServiceServer
+	cfg *config.ApiConfig
+}
+
+func (s *authService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	user, err := s.cfg.Db.GetUserByEmail(ctx, req.GetEmail())
+	if err == sql.ErrNoRows {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	err = authz.CheckPassword(user.HashedPassword, req.GetPassword())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+
+	session, err := auth.NewSession(ctx, s.cfg, user)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	isChirpyRed, err := s.cfg.Db.IsChirpyRed(ctx, user.ID)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return &pb.LoginResponse{
+		User:         toUser(user.ID, user.CreatedAt, user.UpdatedAt, user.Email, isChirpyRed, user.IsPrivate),
+		Token:        session.Token,
+		RefreshToken: session.RefreshToken,
+	}, nil
+}
+
+func (s *authService) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
+	token, err := auth.RefreshAccessToken(ctx, s.cfg, req.GetRefreshToken())
+	if err == config.ErrUnauthorized {
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return &pb.RefreshTokenResponse{Token: token}, nil
+}
+
+func (s *authService) RevokeRefreshToken(ctx context.Context, req *pb.RevokeRefreshTokenRequest) (*pb.RevokeRefreshTokenResponse, error) {
+	err := s.cfg.Db.RevokeRefreshToken(ctx, req.GetRefreshToken())
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return &pb.RevokeRefreshTokenResponse{}, nil
+}
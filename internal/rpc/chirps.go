@@ -0,0 +1,305 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/handlers/chirps"
+	"github.com/lucashthiele/chirpy/internal/moderation"
+	"github.com/lucashthiele/chirpy/internal/pagination"
+	"github.com/lucashthiele/chirpy/internal/stream"
+	pb "github.com/lucashthiele/chirpy/pkg/pb/chirpy/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// chirpService mirrors /api/chirps and the chirp streams.
+type chirpService struct {
+	pb.UnimplementedChirpServiceServer
+	cfg *config.ApiConfig
+}
+
+// chirpJSON is a chirp as the REST API and the chirp events encode it.
+type chirpJSON struct {
+	Id          uuid.UUID   `json:"id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	Body        string      `json:"body"`
+	UserId      uuid.UUID   `json:"user_id"`
+	Visibility  string      `json:"visibility"`
+	ReplyPolicy string      `json:"reply_policy"`
+	Mentions    []uuid.UUID `json:"mentions"`
+	ReplyToId   *uuid.UUID  `json:"reply_to_id"`
+}
+
+func toChirp(chirp database.Chirp) *pb.Chirp {
+	var replyToId *uuid.UUID
+	if chirp.ReplyToID.Valid {
+		replyToId = &chirp.ReplyToID.UUID
+	}
+
+	return chirpJSON{
+		Id:          chirp.ID,
+		CreatedAt:   chirp.CreatedAt,
+		UpdatedAt:   chirp.UpdatedAt,
+		Body:        chirp.Body,
+		UserId:      chirp.UserID,
+		Visibility:  chirp.Visibility,
+		ReplyPolicy: chirp.ReplyPolicy,
+		Mentions:    chirp.Mentions,
+		ReplyToId:   replyToId,
+	}.toChirp()
+}
+
+func (c chirpJSON) toChirp() *pb.Chirp {
+	mentions := make([]string, len(c.Mentions))
+	for i, mention := range c.Mentions {
+		mentions[i] = mention.String()
+	}
+
+	replyToId := ""
+	if c.ReplyToId != nil {
+		replyToId = c.ReplyToId.String()
+	}
+
+	return &pb.Chirp{
+		Id:          c.Id.String(),
+		CreatedAt:   timestamppb.New(c.CreatedAt),
+		UpdatedAt:   timestamppb.New(c.UpdatedAt),
+		Body:        c.Body,
+		UserId:      c.UserId.String(),
+		Visibility:  c.Visibility,
+		ReplyPolicy: c.ReplyPolicy,
+		Mentions:    mentions,
+		ReplyToId:   replyToId,
+	}
+}
+
+// chirpError turns the errors of the chirps package into statuses.
+func chirpError(err error) error {
+	chirpErr, ok := err.(*chirps.Error)
+	if !ok {
+		return internalError(err)
+	}
+	return status.Error(codeFromHTTP(chirpErr.Status), chirpErr.Message)
+}
+
+func parseID(name, value string) (uuid.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, fmt.Sprintf("%s must be a UUID", name))
+	}
+	return id, nil
+}
+
+func (s *chirpService) CreateChirp(ctx context.Context, req *pb.CreateChirpRequest) (*pb.Chirp, error) {
+	params := &chirps.CreateParams{
+		Body:        req.GetBody(),
+		Visibility:  req.GetVisibility(),
+		ReplyPolicy: req.GetReplyPolicy(),
+	}
+
+	for _, mention := range req.GetMentions() {
+		id, err := parseID("mentions", mention)
+		if err != nil {
+			return nil, err
+		}
+		params.Mentions = append(params.Mentions, id)
+	}
+
+	if req.GetReplyToId() != "" {
+		id, err := parseID("reply_to_id", req.GetReplyToId())
+		if err != nil {
+			return nil, err
+		}
+		params.ReplyToId = &id
+	}
+
+	chirp, err := chirps.CreateChirp(ctx, config.ViewerID(ctx), params)
+	if err != nil {
+		return nil, chirpError(err)
+	}
+
+	return toChirp(chirp), nil
+}
+
+func (s *chirpService) GetChirp(ctx context.Context, req *pb.GetChirpRequest) (*pb.Chirp, error) {
+	id, err := parseID("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	chirp, err := s.cfg.Db.GetVisibleChirpByID(ctx, database.GetVisibleChirpByIDParams{
+		ID:       id,
+		ViewerID: config.ViewerID(ctx),
+	})
+	if err == sql.ErrNoRows {
+		return nil, status.Error(codes.NotFound, "Not found")
+	}
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return toChirp(chirp), nil
+}
+
+// ListChirps pages through the chirps the viewer can see, newest first.
+func (s *chirpService) ListChirps(ctx context.Context, req *pb.ListChirpsRequest) (*pb.ListChirpsResponse, error) {
+	arg := database.ListVisibleChirpsPageParams{
+		ViewerID: config.ViewerID(ctx),
+	}
+
+	if req.GetAuthorId() != "" {
+		id, err := parseID("author_id", req.GetAuthorId())
+		if err != nil {
+			return nil, err
+		}
+		arg.AuthorID = id
+	}
+
+	if req.GetHashtag() != "" {
+		hashtag, err := moderation.ParseHashtag(req.GetHashtag())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		arg.Hashtag = hashtag
+	}
+
+	limit := int(req.GetPageSize())
+	if limit == 0 {
+		limit = pagination.DefaultLimit
+	}
+	if limit < 1 || limit > pagination.MaxLimit {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("page_size must be between 1 and %d", pagination.MaxLimit))
+	}
+
+	cursor := pagination.Start()
+	if req.GetPageToken() != "" {
+		var err error
+		cursor, err = pagination.Decode(req.GetPageToken())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+	arg.BeforeTime = cursor.Time
+	arg.BeforeID = cursor.ID
+	arg.RowLimit = int32(limit + 1)
+
+	page, err := s.cfg.Db.ListVisibleChirpsPage(ctx, arg)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	resp := &pb.ListChirpsResponse{}
+	if len(page) > limit {
+		page = page[:limit]
+		last := page[limit-1]
+		resp.NextPageToken = pagination.Cursor{Time: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	resp.Chirps = make([]*pb.Chirp, len(page))
+	for i, chirp := range page {
+		resp.Chirps[i] = toChirp(chirp)
+	}
+
+	return resp, nil
+}
+
+func (s *chirpService) DeleteChirp(ctx context.Context, req *pb.DeleteChirpRequest) (*pb.DeleteChirpResponse, error) {
+	id, err := parseID("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	err = chirps.DeleteChirp(ctx, config.ViewerID(ctx), id)
+	if err != nil {
+		return nil, chirpError(err)
+	}
+
+	return &pb.DeleteChirpResponse{}, nil
+}
+
+// StreamChirps sends the chirps of an author, or of the viewer's timeline, as
+// they're created and deleted, like GET /api/stream/chirps and
+// /api/stream/timeline. Events missed since last_event_id are sent first.
+func (s *chirpService) StreamChirps(req *pb.StreamChirpsRequest, srv grpc.ServerStreamingServer[pb.ChirpEvent]) error {
+	ctx := srv.Context()
+	viewerId := config.ViewerID(ctx)
+
+	var include func(event stream.ChirpEvent, relation stream.Relation) bool
+	switch {
+	case req.GetTimeline() && req.GetAuthorId() != "":
+		return status.Error(codes.InvalidArgument, "author_id and timeline can't be used together")
+	case req.GetTimeline():
+		if viewerId == uuid.Nil {
+			return status.Error(codes.Unauthenticated, "the timeline requires authentication")
+		}
+		include = func(event stream.ChirpEvent, relation stream.Relation) bool {
+			return stream.InTimeline(viewerId, event, relation)
+		}
+	default:
+		authorId, err := parseID("author_id", req.GetAuthorId())
+		if err != nil {
+			return err
+		}
+		include = func(event stream.ChirpEvent, relation stream.Relation) bool {
+			return event.AuthorID == authorId
+		}
+	}
+
+	subscription, missed := s.cfg.ChirpEvents.Subscribe(req.GetLastEventId())
+	defer s.cfg.ChirpEvents.Unsubscribe(subscription)
+
+	relations := stream.NewRelationCache(s.cfg.Db, viewerId)
+	send := func(event stream.Event) error {
+		relation, err := relations.Get(ctx, event.Payload.AuthorID)
+		if err != nil {
+			return internalError(err)
+		}
+		if !stream.CanSee(viewerId, event.Payload, relation) || !include(event.Payload, relation) {
+			return nil
+		}
+
+		chirp := chirpJSON{}
+		err = json.Unmarshal(event.Payload.Chirp, &chirp)
+		if err != nil {
+			return internalError(err)
+		}
+
+		return srv.Send(&pb.ChirpEvent{
+			Id:    event.ID,
+			Type:  event.Type,
+			Chirp: chirp.toChirp(),
+		})
+	}
+
+	for _, event := range missed {
+		err := send(event)
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "the stream fell too far behind")
+			}
+			err := send(event)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
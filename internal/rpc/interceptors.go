@@ -0,0 +1,127 @@
+package rpc
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	authz "github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	pb "github.com/lucashthiele/chirpy/pkg/pb/chirpy/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// access is who can call a method.
+type access struct {
+	// required rejects calls without a token, the others run anonymously
+	required bool
+	// scope is the scope tokens with scopes must have, like RequireScope
+	scope string
+}
+
+// methods lists every method served with its access, anything else is
+// refused. Tokens are checked for every method that gets one, so a bad token
+// fails even where authentication is optional.
+var methods = map[string]access{
+	pb.AuthService_Login_FullMethodName:              {},
+	pb.AuthService_RefreshToken_FullMethodName:       {},
+	pb.AuthService_RevokeRefreshToken_FullMethodName: {},
+
+	pb.UserService_CreateUser_FullMethodName: {},
+	pb.UserService_UpdateUser_FullMethodName: {required: true, scope: authz.ScopeProfileWrite},
+	pb.UserService_GetUser_FullMethodName:    {},
+
+	pb.ChirpService_CreateChirp_FullMethodName:  {required: true, scope: authz.ScopeChirpsWrite},
+	pb.ChirpService_GetChirp_FullMethodName:     {scope: authz.ScopeChirpsRead},
+	pb.ChirpService_ListChirps_FullMethodName:   {scope: authz.ScopeChirpsRead},
+	pb.ChirpService_DeleteChirp_FullMethodName:  {required: true, scope: authz.ScopeChirpsWrite},
+	pb.ChirpService_StreamChirps_FullMethodName: {scope: authz.ScopeChirpsRead},
+
+	"/grpc.health.v1.Health/Check": {},
+	"/grpc.health.v1.Health/Watch": {},
+	"/grpc.health.v1.Health/List":  {},
+}
+
+// authenticator checks the bearer token in the authorization metadata the
+// same way MiddlewareAuth checks the Authorization header.
+type authenticator struct {
+	cfg *config.ApiConfig
+}
+
+func (a *authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	rule, ok := methods[method]
+	if !ok {
+		return ctx, status.Error(codes.Unimplemented, "unknown method")
+	}
+
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		if rule.required {
+			return ctx, status.Error(codes.Unauthenticated, "no bearer token in metadata")
+		}
+		return ctx, nil
+	}
+
+	token, found := strings.CutPrefix(values[0], "Bearer ")
+	if !found || token == "" {
+		return ctx, status.Error(codes.Unauthenticated, "malformed authorization metadata")
+	}
+
+	ctx, err := a.cfg.Authenticate(ctx, token)
+	if err == config.ErrUnauthorized {
+		return ctx, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+	if err != nil {
+		return ctx, internalError(err)
+	}
+
+	if rule.scope != "" && !config.HasScope(ctx, rule.scope) {
+		return ctx, status.Error(codes.PermissionDenied, "insufficient scope: requires "+rule.scope)
+	}
+
+	return ctx, nil
+}
+
+func (a *authenticator) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream is a stream with the context authenticate returned.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	log.Printf("gRPC %s %s %s", info.FullMethod, status.Code(err), time.Since(start))
+	return resp, err
+}
+
+func logStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	log.Printf("gRPC %s %s %s", info.FullMethod, status.Code(err), time.Since(start))
+	return err
+}
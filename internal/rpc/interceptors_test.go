@@ -0,0 +1,160 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	authz "github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	pb "github.com/lucashthiele/chirpy/pkg/pb/chirpy/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthenticate(t *testing.T) {
+	const secret = "test-secret"
+	userId := uuid.New()
+
+	token, err := authz.MakeJWT(userId, secret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	readOnly, err := authz.MakeScopedJWT(userId, secret, time.Hour, "client", []string{authz.ScopeChirpsRead})
+	if err != nil {
+		t.Fatalf("MakeScopedJWT() error = %v", err)
+	}
+	profileOnly, err := authz.MakeScopedJWT(userId, secret, time.Hour, "client", []string{authz.ScopeProfileWrite})
+	if err != nil {
+		t.Fatalf("MakeScopedJWT() error = %v", err)
+	}
+	otherSecret, err := authz.MakeJWT(userId, "other-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	cases := []struct {
+		name          string
+		method        string
+		authorization string
+		wantCode      codes.Code
+		wantViewer    uuid.UUID
+	}{
+		{
+			name:     "public method without a token",
+			method:   pb.AuthService_Login_FullMethodName,
+			wantCode: codes.OK,
+		},
+		{
+			name:          "optional auth with a token",
+			method:        pb.ChirpService_GetChirp_FullMethodName,
+			authorization: "Bearer " + token,
+			wantCode:      codes.OK,
+			wantViewer:    userId,
+		},
+		{
+			name:          "optional auth with a bad token",
+			method:        pb.ChirpService_GetChirp_FullMethodName,
+			authorization: "Bearer " + otherSecret,
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:     "required auth without a token",
+			method:   pb.ChirpService_CreateChirp_FullMethodName,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:          "required auth with a malformed header",
+			method:        pb.ChirpService_CreateChirp_FullMethodName,
+			authorization: token,
+			wantCode:      codes.Unauthenticated,
+		},
+		{
+			name:          "required auth with a token",
+			method:        pb.ChirpService_CreateChirp_FullMethodName,
+			authorization: "Bearer " + token,
+			wantCode:      codes.OK,
+			wantViewer:    userId,
+		},
+		{
+			name:          "token without the scope",
+			method:        pb.ChirpService_DeleteChirp_FullMethodName,
+			authorization: "Bearer " + readOnly,
+			wantCode:      codes.PermissionDenied,
+		},
+		{
+			name:          "read with the scope",
+			method:        pb.ChirpService_ListChirps_FullMethodName,
+			authorization: "Bearer " + readOnly,
+			wantCode:      codes.OK,
+			wantViewer:    userId,
+		},
+		{
+			name:          "get without the read scope",
+			method:        pb.ChirpService_GetChirp_FullMethodName,
+			authorization: "Bearer " + profileOnly,
+			wantCode:      codes.PermissionDenied,
+		},
+		{
+			name:          "list without the read scope",
+			method:        pb.ChirpService_ListChirps_FullMethodName,
+			authorization: "Bearer " + profileOnly,
+			wantCode:      codes.PermissionDenied,
+		},
+		{
+			name:          "stream without the read scope",
+			method:        pb.ChirpService_StreamChirps_FullMethodName,
+			authorization: "Bearer " + profileOnly,
+			wantCode:      codes.PermissionDenied,
+		},
+		{
+			name:     "unknown method",
+			method:   "/chirpy.v1.ChirpService/LikeChirp",
+			wantCode: codes.Unimplemented,
+		},
+	}
+
+	a := &authenticator{cfg: &config.ApiConfig{AppSecret: secret}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			if c.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", c.authorization))
+			}
+
+			ctx, err := a.authenticate(ctx, c.method)
+			if code := status.Code(err); code != c.wantCode {
+				t.Fatalf("authenticate() code = %s, want %s (error: %v)", code, c.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if viewer := config.ViewerID(ctx); viewer != c.wantViewer {
+				t.Errorf("ViewerID() = %s, want %s", viewer, c.wantViewer)
+			}
+		})
+	}
+}
+
+func TestCodeFromHTTP(t *testing.T) {
+	cases := []struct {
+		status int
+		want   codes.Code
+	}{
+		{400, codes.InvalidArgument},
+		{401, codes.Unauthenticated},
+		{403, codes.PermissionDenied},
+		{404, codes.NotFound},
+		{418, codes.Unknown},
+	}
+
+	for _, c := range cases {
+		if got := codeFromHTTP(c.status); got != c.want {
+			t.Errorf("codeFromHTTP(%d) = %s, want %s", c.status, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Package rpc serves the chirp, user and auth operations of the REST API over
+// gRPC, with the contracts in proto/chirpy/v1.
+package rpc
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/lucashthiele/chirpy/internal/config"
+	pb "github.com/lucashthiele/chirpy/pkg/pb/chirpy/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// NewServer returns a gRPC server with every service registered, along with
+// the standard health service.
+func NewServer(cfg *config.ApiConfig) *grpc.Server {
+	auth := &authenticator{cfg: cfg}
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(logUnary, auth.unary),
+		grpc.ChainStreamInterceptor(logStream, auth.stream),
+	)
+
+	pb.RegisterAuthServiceServer(server, &authService{cfg: cfg})
+	pb.RegisterUserServiceServer(server, &userService{cfg: cfg})
+	pb.RegisterChirpServiceServer(server, &chirpService{cfg: cfg})
+
+	healthServer := health.NewServer()
+	for name := range server.GetServiceInfo() {
+		healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	return server
+}
+
+// internalError logs err and hides it from the client, like
+// RespondWithInternalServerError.
+func internalError(err error) error {
+	log.Printf("Internal Server Error: %s", err)
+	return status.Error(codes.Internal, "something went wrong")
+}
+
+// codeFromHTTP maps the status a REST handler would answer with to a gRPC
+// code.
+func codeFromHTTP(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Unknown
+	}
+}
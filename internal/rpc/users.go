@@ -0,0 +1,111 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	authz "github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	pb "github.com/lucashthiele/chirpy/pkg/pb/chirpy/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// userService mirrors POST and PUT /api/users, and adds a lookup by ID for
+// the profiles the REST API only shows through GraphQL.
+type userService struct {
+	pb.UnimplementedUserServiceServer
+	cfg *config.ApiConfig
+}
+
+func toUser(id uuid.UUID, createdAt, updatedAt sql.NullTime, email string, isChirpyRed, isPrivate bool) *pb.User {
+	return &pb.User{
+		Id:          id.String(),
+		CreatedAt:   timestamppb.New(createdAt.Time),
+		UpdatedAt:   timestamppb.New(updatedAt.Time),
+		Email:       email,
+		IsChirpyRed: isChirpyRed,
+		IsPrivate:   isPrivate,
+	}
+}
+
+func (s *userService) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.User, error) {
+	hashedPassword, err := authz.HashPassword(req.GetPassword())
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	user, err := s.cfg.Db.CreateUser(ctx, database.CreateUserParams{
+		Email:          req.GetEmail(),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return toUser(user.ID, user.CreatedAt, user.UpdatedAt, user.Email, user.IsChirpyRed, user.IsPrivate), nil
+}
+
+func (s *userService) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.User, error) {
+	hashedPassword, err := authz.HashPassword(req.GetPassword())
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	user, err := s.cfg.Db.UpdateUserEmailAndPassword(ctx, database.UpdateUserEmailAndPasswordParams{
+		ID:             config.ViewerID(ctx),
+		Email:          req.GetEmail(),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return toUser(user.ID, user.CreatedAt, user.UpdatedAt, user.Email, user.IsChirpyRed, user.IsPrivate), nil
+}
+
+// GetUser looks a user up by ID. Users that blocked the viewer are not found,
+// and the email is only there for the viewer's own account.
+func (s *userService) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	id, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "id must be a UUID")
+	}
+
+	viewerId := config.ViewerID(ctx)
+	if viewerId != uuid.Nil && viewerId != id {
+		relation, err := s.cfg.Db.GetViewerRelation(ctx, database.GetViewerRelationParams{
+			ViewerID: viewerId,
+			AuthorID: id,
+		})
+		if err != nil {
+			return nil, internalError(err)
+		}
+		if relation.Blocked {
+			return nil, status.Error(codes.NotFound, "Not found")
+		}
+	}
+
+	user, err := s.cfg.Db.GetUserByID(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil, status.Error(codes.NotFound, "Not found")
+	}
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	isChirpyRed, err := s.cfg.Db.IsChirpyRed(ctx, user.ID)
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	result := toUser(user.ID, user.CreatedAt, user.UpdatedAt, user.Email, isChirpyRed, user.IsPrivate)
+	if user.ID != viewerId {
+		result.Email = ""
+	}
+
+	return result, nil
+}
@@ -0,0 +1,125 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/database"
+)
+
+// Channel is the Postgres NOTIFY channel chirp events are published on.
+const Channel = "chirp_events"
+
+const (
+	// replayBufferSize is how many of the latest events are kept to resume
+	// streams from their Last-Event-ID.
+	replayBufferSize = 256
+	// subscriberBufferSize is how many events a subscriber can fall behind
+	// before it is dropped.
+	subscriberBufferSize = 64
+)
+
+// ChirpEvent is a chirp being created or deleted. The author, visibility and
+// mentions are kept next to the chirp so subscribers can tell who may see it,
+// even once it is gone from the database.
+type ChirpEvent struct {
+	AuthorID   uuid.UUID       `json:"author_id"`
+	Visibility string          `json:"visibility"`
+	Mentions   []uuid.UUID     `json:"mentions"`
+	Chirp      json.RawMessage `json:"chirp"`
+}
+
+type Event struct {
+	ID      int64      `json:"id"`
+	Type    string     `json:"type"`
+	Payload ChirpEvent `json:"payload"`
+}
+
+type Subscription struct {
+	// Events is closed when the subscriber fell too far behind. It should
+	// reconnect with the ID of the last event it got.
+	Events <-chan Event
+	events chan Event
+}
+
+// Broker fans events out to every subscriber of this instance.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	replay      []Event
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+// Subscribe returns a subscription to the next events, along with the buffered
+// events that came after lastEventID. A lastEventID of 0 replays nothing.
+func (b *Broker) Subscribe(lastEventID int64) (*Subscription, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make(chan Event, subscriberBufferSize)
+	subscription := &Subscription{Events: events, events: events}
+	b.subscribers[subscription] = struct{}{}
+
+	missed := []Event{}
+	if lastEventID > 0 {
+		for _, event := range b.replay {
+			if event.ID > lastEventID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	return subscription, missed
+}
+
+func (b *Broker) Unsubscribe(subscription *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[subscription]; ok {
+		delete(b.subscribers, subscription)
+		close(subscription.events)
+	}
+}
+
+// Publish buffers the event for replay and hands it to every subscriber. It
+// never blocks, subscribers that can't keep up are dropped.
+func (b *Broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.replay = append(b.replay, event)
+	if len(b.replay) > replayBufferSize {
+		b.replay = b.replay[len(b.replay)-replayBufferSize:]
+	}
+
+	for subscription := range b.subscribers {
+		select {
+		case subscription.events <- event:
+		default:
+			delete(b.subscribers, subscription)
+			close(subscription.events)
+		}
+	}
+}
+
+// Notify publishes the event to every instance once the transaction db is
+// bound to commits, or right away outside of a transaction.
+func Notify(ctx context.Context, db *database.Queries, eventType string, event ChirpEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return db.NotifyChirpEvent(ctx, database.NotifyChirpEventParams{
+		EventType: eventType,
+		Payload:   payload,
+	})
+}
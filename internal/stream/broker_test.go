@@ -0,0 +1,90 @@
+package stream
+
+import (
+	"testing"
+)
+
+func TestBrokerPublish(t *testing.T) {
+	broker := NewBroker()
+	first, _ := broker.Subscribe(0)
+	second, _ := broker.Subscribe(0)
+
+	broker.Publish(Event{ID: 1, Type: "chirp.created"})
+
+	for i, subscription := range []*Subscription{first, second} {
+		event := <-subscription.Events
+		if event.ID != 1 {
+			t.Errorf("subscriber %d got event %d, want 1", i, event.ID)
+		}
+	}
+
+	broker.Unsubscribe(first)
+	if _, ok := <-first.Events; ok {
+		t.Errorf("unsubscribed events channel is still open")
+	}
+
+	broker.Publish(Event{ID: 2, Type: "chirp.deleted"})
+	if event := <-second.Events; event.ID != 2 {
+		t.Errorf("got event %d, want 2", event.ID)
+	}
+
+	// unsubscribing twice is harmless
+	broker.Unsubscribe(first)
+}
+
+func TestBrokerReplay(t *testing.T) {
+	broker := NewBroker()
+	for id := int64(1); id <= replayBufferSize+10; id++ {
+		broker.Publish(Event{ID: id})
+	}
+
+	cases := []struct {
+		name        string
+		lastEventID int64
+		wantFirst   int64
+		wantCount   int
+	}{
+		{name: "new stream", lastEventID: 0, wantCount: 0},
+		{name: "recent event", lastEventID: replayBufferSize + 5, wantFirst: replayBufferSize + 6, wantCount: 5},
+		{name: "latest event", lastEventID: replayBufferSize + 10, wantCount: 0},
+		{name: "older than the buffer", lastEventID: 3, wantFirst: 11, wantCount: replayBufferSize},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			subscription, missed := broker.Subscribe(c.lastEventID)
+			defer broker.Unsubscribe(subscription)
+
+			if len(missed) != c.wantCount {
+				t.Fatalf("replayed %d events, want %d", len(missed), c.wantCount)
+			}
+			if c.wantCount > 0 && missed[0].ID != c.wantFirst {
+				t.Errorf("first replayed event is %d, want %d", missed[0].ID, c.wantFirst)
+			}
+		})
+	}
+}
+
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	broker := NewBroker()
+	slow, _ := broker.Subscribe(0)
+	fast, _ := broker.Subscribe(0)
+
+	for id := int64(1); id <= subscriberBufferSize+1; id++ {
+		broker.Publish(Event{ID: id})
+		<-fast.Events
+	}
+
+	received := 0
+	for range slow.Events {
+		received++
+	}
+	if received != subscriberBufferSize {
+		t.Errorf("slow subscriber got %d events before being dropped, want %d", received, subscriberBufferSize)
+	}
+
+	broker.Publish(Event{ID: subscriberBufferSize + 2})
+	if event := <-fast.Events; event.ID != subscriberBufferSize+2 {
+		t.Errorf("fast subscriber got event %d, want %d", event.ID, subscriberBufferSize+2)
+	}
+}
@@ -0,0 +1,60 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	pingInterval         = time.Minute
+)
+
+// Listen publishes the events NOTIFYed on Channel by any instance to broker,
+// until ctx is cancelled. Notifications are only sent once the transaction
+// that made them commits.
+func Listen(ctx context.Context, dbURL string, broker *Broker) error {
+	listener := pq.NewListener(dbURL, minReconnectInterval, maxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Error listening for chirp events: %s", err)
+		}
+	})
+	defer listener.Close()
+
+	err := listener.Listen(Channel)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			go listener.Ping()
+		case notification := <-listener.Notify:
+			// a nil notification means the connection was re-established,
+			// whatever was sent in between is lost
+			if notification == nil {
+				continue
+			}
+
+			event := Event{}
+			err := json.Unmarshal([]byte(notification.Extra), &event)
+			if err != nil {
+				log.Printf("Error decoding chirp event: %s", err)
+				continue
+			}
+
+			broker.Publish(event)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package stream
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/database"
+)
+
+// relationTTL is how long the relation between the viewer and an author is
+// trusted before it is looked up again, so a block or a new follow shows up
+// in open streams soon enough.
+const relationTTL time.Duration = time.Second * 30
+
+type cachedRelation struct {
+	relation  Relation
+	fetchedAt time.Time
+}
+
+// RelationCache remembers what the authors of the events are to one viewer,
+// for as long as a stream is open. It is not safe for concurrent use.
+type RelationCache struct {
+	db        *database.Queries
+	viewerID  uuid.UUID
+	relations map[uuid.UUID]cachedRelation
+}
+
+func NewRelationCache(db *database.Queries, viewerID uuid.UUID) *RelationCache {
+	return &RelationCache{
+		db:        db,
+		viewerID:  viewerID,
+		relations: map[uuid.UUID]cachedRelation{},
+	}
+}
+
+func (c *RelationCache) Get(ctx context.Context, authorID uuid.UUID) (Relation, error) {
+	if authorID == c.viewerID {
+		return Relation{}, nil
+	}
+
+	cached, ok := c.relations[authorID]
+	if ok && time.Since(cached.fetchedAt) < relationTTL {
+		return cached.relation, nil
+	}
+
+	row, err := c.db.GetViewerRelation(ctx, database.GetViewerRelationParams{
+		ViewerID: c.viewerID,
+		AuthorID: authorID,
+	})
+	if err != nil {
+		return Relation{}, err
+	}
+
+	relation := Relation{
+		Muted:             row.Muted,
+		Following:         row.Following,
+		SeesPublic:        row.SeesPublic,
+		SeesFollowersOnly: row.SeesFollowersOnly,
+		SeesMentions:      row.SeesMentions,
+	}
+	c.relations[authorID] = cachedRelation{relation: relation, fetchedAt: time.Now()}
+
+	return relation, nil
+}
@@ -0,0 +1,45 @@
+package stream
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Relation is what the viewer and the author of a chirp are to each other.
+// The Sees fields are what the viewer can see of the author's chirps with
+// each visibility, as CHIRP_VISIBLE_TO decides for the chirp queries.
+type Relation struct {
+	Muted             bool
+	Following         bool
+	SeesPublic        bool
+	SeesFollowersOnly bool
+	SeesMentions      bool
+}
+
+// CanSee tells if the viewer can see the chirp of an event, which may not be
+// in the database anymore, from what they can see of its author.
+func CanSee(viewerID uuid.UUID, event ChirpEvent, relation Relation) bool {
+	if event.AuthorID == viewerID {
+		return true
+	}
+
+	switch event.Visibility {
+	case "mentioned-only":
+		return relation.SeesMentions && slices.Contains(event.Mentions, viewerID)
+	case "followers-only":
+		return relation.SeesFollowersOnly
+	default:
+		return relation.SeesPublic
+	}
+}
+
+// InTimeline tells if an event the viewer can see belongs in their home
+// timeline: their own chirps and the ones of the accounts they follow, minus
+// muted accounts and unlisted chirps.
+func InTimeline(viewerID uuid.UUID, event ChirpEvent, relation Relation) bool {
+	if event.AuthorID == viewerID {
+		return true
+	}
+	return relation.Following && !relation.Muted && event.Visibility != "unlisted"
+}
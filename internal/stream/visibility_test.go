@@ -0,0 +1,69 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCanSee(t *testing.T) {
+	viewer := uuid.New()
+	author := uuid.New()
+
+	cases := []struct {
+		name       string
+		viewer     uuid.UUID
+		visibility string
+		mentions   []uuid.UUID
+		relation   Relation
+		want       bool
+	}{
+		{name: "public", viewer: viewer, visibility: "public", relation: Relation{SeesPublic: true}, want: true},
+		{name: "public hidden", viewer: viewer, visibility: "public", want: false},
+		{name: "unlisted", viewer: viewer, visibility: "unlisted", relation: Relation{SeesPublic: true}, want: true},
+		{name: "own chirp", viewer: author, visibility: "mentioned-only", want: true},
+		{name: "followers only", viewer: viewer, visibility: "followers-only", relation: Relation{SeesPublic: true}, want: false},
+		{name: "followers only seen", viewer: viewer, visibility: "followers-only", relation: Relation{SeesFollowersOnly: true}, want: true},
+		{name: "mentioned only", viewer: viewer, visibility: "mentioned-only", mentions: []uuid.UUID{uuid.New()}, relation: Relation{SeesMentions: true}, want: false},
+		{name: "mentioned only mentioned", viewer: viewer, visibility: "mentioned-only", mentions: []uuid.UUID{viewer}, relation: Relation{SeesMentions: true}, want: true},
+		{name: "mentioned but blocked", viewer: viewer, visibility: "mentioned-only", mentions: []uuid.UUID{viewer}, want: false},
+		{name: "muted is still visible", viewer: viewer, visibility: "public", relation: Relation{SeesPublic: true, Muted: true}, want: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			event := ChirpEvent{AuthorID: author, Visibility: c.visibility, Mentions: c.mentions}
+			if got := CanSee(c.viewer, event, c.relation); got != c.want {
+				t.Errorf("CanSee() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestInTimeline(t *testing.T) {
+	viewer := uuid.New()
+	author := uuid.New()
+
+	cases := []struct {
+		name       string
+		author     uuid.UUID
+		visibility string
+		relation   Relation
+		want       bool
+	}{
+		{name: "own chirp", author: viewer, visibility: "unlisted", want: true},
+		{name: "followed", author: author, visibility: "public", relation: Relation{Following: true}, want: true},
+		{name: "not followed", author: author, visibility: "public", want: false},
+		{name: "followed but muted", author: author, visibility: "public", relation: Relation{Following: true, Muted: true}, want: false},
+		{name: "followed but unlisted", author: author, visibility: "unlisted", relation: Relation{Following: true}, want: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			event := ChirpEvent{AuthorID: c.author, Visibility: c.visibility}
+			if got := InTimeline(viewer, event, c.relation); got != c.want {
+				t.Errorf("InTimeline() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -1,21 +1,48 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	authz "github.com/lucashthiele/chirpy/internal/auth"
 	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/gateway"
 	"github.com/lucashthiele/chirpy/internal/handlers/auth"
 	"github.com/lucashthiele/chirpy/internal/handlers/chirps"
+	"github.com/lucashthiele/chirpy/internal/handlers/conversations"
+	"github.com/lucashthiele/chirpy/internal/handlers/federation"
+	"github.com/lucashthiele/chirpy/internal/handlers/graphql"
 	"github.com/lucashthiele/chirpy/internal/handlers/healthz"
+	"github.com/lucashthiele/chirpy/internal/handlers/oauth"
+	"github.com/lucashthiele/chirpy/internal/handlers/stream"
+	"github.com/lucashthiele/chirpy/internal/handlers/tokens"
 	"github.com/lucashthiele/chirpy/internal/handlers/users"
 	"github.com/lucashthiele/chirpy/internal/handlers/webhooks"
+	"github.com/lucashthiele/chirpy/internal/handlers/ws"
+	"github.com/lucashthiele/chirpy/internal/jobs"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/requestid"
+	"github.com/lucashthiele/chirpy/internal/rpc"
+	chirpstream "github.com/lucashthiele/chirpy/internal/stream"
 )
 
 const port string = "42069"
+const grpcPort string = "42070"
+
+const subscriptionExpiryInterval time.Duration = time.Minute * 5
+const webhookDeliveryInterval time.Duration = time.Second * 5
+const accountDeletionInterval time.Duration = time.Hour
+const userExportInterval time.Duration = time.Second * 30
+const messageKeyRotationInterval time.Duration = time.Minute * 10
+const activityPubDeliveryInterval time.Duration = time.Second * 5
+const idempotencyKeyExpiryInterval time.Duration = time.Hour
 
 func getFilepathRoot() http.Dir {
 	return http.Dir(".")
@@ -30,28 +57,120 @@ func setupSwagger(mux *http.ServeMux) {
 	mux.Handle("/api/swagger/", http.StripPrefix("/api/swagger/", fs))
 }
 
-func configureRoutes(mux *http.ServeMux, cfg *config.ApiConfig) {
+// configureRoutes registers the routes of the API, which make up its OpenAPI
+// document.
+func configureRoutes(mux *http.ServeMux, cfg *config.ApiConfig) *openapi.Router {
 	handler := http.StripPrefix("/app/", http.FileServer(getFilepathRoot()))
 	mux.Handle("/app/", cfg.MiddlewareMetricsInc(handler))
 
-	mux.HandleFunc("GET /api/healthz", healthz.HandleHealthz)
-
-	mux.HandleFunc("GET /admin/metrics", cfg.HandleMetrics())
-	mux.HandleFunc("POST /admin/reset", cfg.HandleReset())
-
-	mux.HandleFunc("POST /api/chirps", cfg.MiddlewareAuth(chirps.HandleCreateChirp))
-	mux.HandleFunc("GET /api/chirps", chirps.HandleGetAllChirps)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", chirps.HandleGetChirpByID)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.MiddlewareAuth(chirps.HandleDeleteChirp))
+	api := openapi.NewRouter(mux)
+
+	api.HandleFunc("GET /api/healthz", healthz.HealthzOperation, healthz.HandleHealthz)
+
+	api.HandleFunc("GET /admin/metrics", config.MetricsOperation, cfg.HandleMetrics())
+	api.HandleFunc("POST /admin/reset", config.ResetOperation, cfg.HandleReset())
+
+	api.HandleVersions("POST /api/chirps", chirps.CreateChirpOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeChirpsWrite, cfg.MiddlewareIdempotency(chirps.HandleCreateChirp))))
+	api.HandleVersions("GET /api/chirps", chirps.GetAllChirpsOperation, cfg.MiddlewareOptionalAuth(config.RequireScope(authz.ScopeChirpsRead, chirps.HandleGetAllChirps)))
+	api.HandleVersions("GET /api/chirps/{chirpID}", chirps.GetChirpByIDOperation, cfg.MiddlewareOptionalAuth(config.RequireScope(authz.ScopeChirpsRead, chirps.HandleGetChirpByID)))
+	api.HandleVersions("DELETE /api/chirps/{chirpID}", chirps.DeleteChirpOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeChirpsWrite, chirps.HandleDeleteChirp)))
+
+	// {feed} is feed.rss, feed.atom or feed.json, a pattern per file would
+	// conflict with GET /api/users/export/{exportID}
+	api.HandleFunc("GET /api/users/{userID}/{feed}", chirps.UserFeedOperation, chirps.HandleUserFeed)
+	api.HandleFunc("GET /api/hashtags/{hashtag}/{feed}", chirps.HashtagFeedOperation, chirps.HandleHashtagFeed)
+
+	api.HandleFunc("GET /api/stream/timeline", stream.TimelineStreamOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeChirpsRead, stream.HandleTimelineStream)))
+	api.HandleFunc("GET /api/stream/chirps", stream.ChirpStreamOperation, cfg.MiddlewareOptionalAuth(config.RequireScope(authz.ScopeChirpsRead, stream.HandleChirpStream)))
+	api.HandleFunc("GET /api/ws", ws.GatewayOperation, cfg.MiddlewareAuth(ws.HandleGateway))
+
+	api.HandleFunc("POST /api/graphql", graphql.GraphQLOperation, cfg.MiddlewareOptionalAuth(config.RequireScope(authz.ScopeChirpsRead, graphql.HandleGraphQL)))
+
+	api.HandleFunc("GET /.well-known/webfinger", federation.WebFingerOperation, federation.HandleWebFinger)
+	api.HandleFunc("GET /api/ap/users/{userID}", federation.GetActorOperation, federation.HandleGetActor)
+	api.HandleFunc("GET /api/ap/users/{userID}/outbox", federation.GetOutboxOperation, federation.HandleGetOutbox)
+	api.HandleFunc("GET /api/ap/users/{userID}/followers", federation.GetFollowersOperation, federation.HandleGetFollowers)
+	api.HandleFunc("POST /api/ap/users/{userID}/inbox", federation.UserInboxOperation, federation.HandleInbox)
+	api.HandleFunc("POST /api/ap/inbox", federation.SharedInboxOperation, federation.HandleInbox)
+	api.HandleFunc("GET /api/ap/chirps/{chirpID}", federation.GetNoteOperation, federation.HandleGetNote)
+
+	api.HandleVersions("POST /api/login", auth.LoginOperation, auth.HandleLogin)
+	api.HandleVersions("POST /api/login/magic", auth.RequestMagicLinkOperation, auth.HandleRequestMagicLink)
+	api.HandleFunc("GET /api/login/magic/verify", auth.VerifyMagicLinkOperation, auth.HandleVerifyMagicLink)
+	api.HandleVersions("POST /api/refresh", auth.RefreshTokenOperation, auth.HandleRefreshToken)
+	api.HandleVersions("POST /api/revoke", auth.RevokeRefreshTokenOperation, auth.HandleRevokeRefreshToken)
+	api.HandleFunc("GET /api/auth/oidc/{provider}/login", auth.OIDCLoginOperation, auth.HandleOIDCLogin)
+	api.HandleFunc("GET /api/auth/oidc/{provider}/callback", auth.OIDCCallbackOperation, auth.HandleOIDCCallback)
+
+	api.HandleVersions("POST /api/oauth/clients", oauth.CreateClientOperation, cfg.MiddlewareAuth(config.RequireSession(oauth.HandleCreateClient)))
+	api.HandleVersions("GET /api/oauth/clients", oauth.ListClientsOperation, cfg.MiddlewareAuth(config.RequireSession(oauth.HandleListClients)))
+	api.HandleVersions("DELETE /api/oauth/clients/{clientID}", oauth.DeleteClientOperation, cfg.MiddlewareAuth(config.RequireSession(oauth.HandleDeleteClient)))
+	api.HandleFunc("GET /api/oauth/authorize", oauth.AuthorizeOperation, oauth.HandleAuthorize)
+	api.HandleFunc("POST /api/oauth/authorize", oauth.AuthorizeDecisionOperation, oauth.HandleAuthorizeDecision)
+	api.HandleFunc("POST /api/oauth/token", oauth.TokenOperation, oauth.HandleToken)
+	api.HandleFunc("POST /api/oauth/revoke", oauth.RevokeOperation, oauth.HandleRevoke)
+	api.HandleFunc("POST /api/oauth/introspect", oauth.IntrospectOperation, oauth.HandleIntrospect)
+
+	api.HandleVersions("POST /api/users", users.CreateUserOperation, cfg.MiddlewareIdempotency(users.HandleCreateUsers))
+	api.HandleVersions("PUT /api/users", users.UpdateUserOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeProfileWrite, users.HandleUpdateUsers)))
+	api.HandleVersions("POST /api/users/{userID}/block", users.BlockUserOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeProfileWrite, users.HandleBlockUser)))
+	api.HandleVersions("DELETE /api/users/{userID}/block", users.UnblockUserOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeProfileWrite, users.HandleUnblockUser)))
+	api.HandleVersions("POST /api/users/{userID}/mute", users.MuteUserOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeProfileWrite, users.HandleMuteUser)))
+	api.HandleVersions("DELETE /api/users/{userID}/mute", users.UnmuteUserOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeProfileWrite, users.HandleUnmuteUser)))
+	api.HandleVersions("PUT /api/users/privacy", users.UpdatePrivacyOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeProfileWrite, users.HandleUpdatePrivacy)))
+	api.HandleVersions("POST /api/users/{userID}/follow", users.FollowUserOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeProfileWrite, users.HandleFollowUser)))
+	api.HandleVersions("DELETE /api/users/{userID}/follow", users.UnfollowUserOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeProfileWrite, users.HandleUnfollowUser)))
+	api.HandleVersions("GET /api/follow-requests", users.ListFollowRequestsOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeProfileWrite, users.HandleListFollowRequests)))
+	api.HandleVersions("POST /api/follow-requests/{userID}/approve", users.ApproveFollowRequestOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeProfileWrite, users.HandleApproveFollowRequest)))
+	api.HandleVersions("POST /api/follow-requests/{userID}/deny", users.DenyFollowRequestOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeProfileWrite, users.HandleDenyFollowRequest)))
+	api.HandleVersions("DELETE /api/users", users.DeleteUserOperation, cfg.MiddlewareAuth(config.RequireSession(users.HandleDeleteUser)))
+	api.HandleVersions("GET /api/users/deletion", users.GetDeletionOperation, cfg.MiddlewareAuth(config.RequireSession(users.HandleGetDeletion)))
+	api.HandleVersions("DELETE /api/users/deletion", users.CancelDeletionOperation, cfg.MiddlewareAuth(config.RequireSession(users.HandleCancelDeletion)))
+	api.HandleVersions("POST /api/users/export", users.CreateExportOperation, cfg.MiddlewareAuth(config.RequireSession(users.HandleCreateExport)))
+	api.HandleVersions("GET /api/users/export/{exportID}", users.GetExportOperation, cfg.MiddlewareAuth(config.RequireSession(users.HandleGetExport)))
+	api.HandleFunc("GET /api/exports/{exportID}/download", users.DownloadExportOperation, users.HandleDownloadExport)
+
+	api.HandleVersions("POST /api/conversations", conversations.CreateConversationOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeMessagesWrite, conversations.HandleCreateConversation)))
+	api.HandleVersions("GET /api/conversations", conversations.ListConversationsOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeMessagesRead, conversations.HandleListConversations)))
+	api.HandleVersions("GET /api/conversations/unread", conversations.GetUnreadCountOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeMessagesRead, conversations.HandleGetUnreadCount)))
+	api.HandleVersions("GET /api/conversations/{conversationID}/messages", conversations.ListMessagesOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeMessagesRead, conversations.HandleListMessages)))
+	api.HandleVersions("POST /api/conversations/{conversationID}/messages", conversations.SendMessageOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeMessagesWrite, conversations.HandleSendMessage)))
+	api.HandleVersions("POST /api/conversations/{conversationID}/read", conversations.MarkReadOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeMessagesWrite, conversations.HandleMarkRead)))
+
+	api.HandleVersions("POST /api/tokens", tokens.CreateTokenOperation, cfg.MiddlewareAuth(config.RequireSession(tokens.HandleCreateToken)))
+	api.HandleVersions("GET /api/tokens", tokens.ListTokensOperation, cfg.MiddlewareAuth(config.RequireSession(tokens.HandleListTokens)))
+	api.HandleVersions("DELETE /api/tokens/{tokenID}", tokens.RevokeTokenOperation, cfg.MiddlewareAuth(config.RequireSession(tokens.HandleRevokeToken)))
+
+	api.HandleFunc("POST /api/polka/webhooks", webhooks.PolkaWebhookOperation, cfg.MiddlewarePolka(cfg.MiddlewareWebhookLog("polka", cfg.MiddlewarePolkaDedupe(webhooks.HandlePolkaWebhook))))
+
+	api.HandleVersions("POST /api/webhooks/endpoints", webhooks.CreateEndpointOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeWebhooksWrite, webhooks.HandleCreateEndpoint)))
+	api.HandleVersions("GET /api/webhooks/endpoints", webhooks.ListEndpointsOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeWebhooksWrite, webhooks.HandleListEndpoints)))
+	api.HandleVersions("DELETE /api/webhooks/endpoints/{endpointID}", webhooks.DeleteEndpointOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeWebhooksWrite, webhooks.HandleDeleteEndpoint)))
+	api.HandleVersions("POST /api/webhooks/endpoints/{endpointID}/enable", webhooks.EnableEndpointOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeWebhooksWrite, webhooks.HandleEnableEndpoint)))
+	api.HandleVersions("GET /api/webhooks/endpoints/{endpointID}/attempts", webhooks.ListEndpointAttemptsOperation, cfg.MiddlewareAuth(config.RequireScope(authz.ScopeWebhooksWrite, webhooks.HandleListEndpointAttempts)))
+
+	api.HandleFunc("GET /admin/webhooks/events", webhooks.ListEventsOperation, cfg.MiddlewareAdmin(webhooks.HandleListEvents))
+	api.HandleFunc("GET /admin/webhooks/events/{eventID}", webhooks.GetEventOperation, cfg.MiddlewareAdmin(webhooks.HandleGetEvent))
+	api.HandleFunc("POST /admin/webhooks/events/{eventID}/replay", webhooks.ReplayEventOperation, cfg.MiddlewareAdmin(webhooks.HandleReplayEvent))
+
+	return api
+}
 
-	mux.HandleFunc("POST /api/login", auth.HandleLogin)
-	mux.HandleFunc("POST /api/refresh", auth.HandleRefreshToken)
-	mux.HandleFunc("POST /api/revoke", auth.HandleRevokeRefreshToken)
+// validateTraffic checks the requests and responses of the API against its
+// document, on the dev and test platforms. Mismatches are logged.
+func validateTraffic(cfg *config.ApiConfig, api *openapi.Router, handler http.Handler) (http.Handler, error) {
+	if cfg.Platform != "dev" && cfg.Platform != "test" {
+		return handler, nil
+	}
 
-	mux.HandleFunc("POST /api/users", users.HandleCreateUsers)
-	mux.HandleFunc("PUT /api/users", cfg.MiddlewareAuth(users.HandleUpdateUsers))
+	doc, err := api.Document()
+	if err != nil {
+		return nil, err
+	}
 
-	mux.HandleFunc("POST /api/polka/webhooks", cfg.MiddlewarePolka(webhooks.HandleUpgradeUser))
+	return openapi.Validate(doc, handler, func(req *http.Request, err error) {
+		log.Printf("%s %s: %s", req.Method, req.URL.Path, err)
+	}), nil
 }
 
 func main() {
@@ -68,11 +187,51 @@ func main() {
 	}
 	mux := http.NewServeMux()
 
-	configureRoutes(mux, cfg)
+	api := configureRoutes(mux, cfg)
 	setupSwagger(mux)
 
+	handler, err := validateTraffic(cfg, api, mux)
+	if err != nil {
+		fmt.Printf("Error building the OpenAPI document: %s", err.Error())
+		return
+	}
+
+	go jobs.RunSubscriptionExpiry(context.Background(), cfg, subscriptionExpiryInterval)
+	go jobs.RunWebhookDelivery(context.Background(), cfg, webhookDeliveryInterval)
+	go jobs.RunAccountDeletion(context.Background(), cfg, accountDeletionInterval)
+	go jobs.RunUserExports(context.Background(), cfg, userExportInterval)
+	go jobs.RunMessageKeyRotation(context.Background(), cfg, messageKeyRotationInterval)
+	go jobs.RunActivityPubDelivery(context.Background(), cfg, activityPubDeliveryInterval)
+	go jobs.RunIdempotencyKeyExpiry(context.Background(), cfg, idempotencyKeyExpiryInterval)
+	go func() {
+		err := chirpstream.Listen(context.Background(), os.Getenv("DB_URL"), cfg.ChirpEvents)
+		if err != nil {
+			log.Printf("Error listening for chirp events: %s", err)
+		}
+	}()
+	go func() {
+		err := gateway.Listen(context.Background(), os.Getenv("DB_URL"), cfg.Gateway, cfg.MessageKeys)
+		if err != nil {
+			log.Printf("Error listening for user events: %s", err)
+		}
+	}()
+
+	go func() {
+		listener, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			log.Printf("Error listening for gRPC: %s", err)
+			return
+		}
+
+		log.Printf("Serving gRPC on port: %s\n", grpcPort)
+		err = rpc.NewServer(cfg).Serve(listener)
+		if err != nil {
+			log.Printf("Error serving gRPC: %s", err)
+		}
+	}()
+
 	server := &http.Server{
-		Handler: mux,
+		Handler: requestid.Middleware(api.Negotiate(handler)),
 		Addr:    ":" + port,
 	}
 
@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/apiversion"
+	authz "github.com/lucashthiele/chirpy/internal/auth"
+	"github.com/lucashthiele/chirpy/internal/config"
+	"github.com/lucashthiele/chirpy/internal/database"
+	"github.com/lucashthiele/chirpy/internal/database/dbtest"
+	"github.com/lucashthiele/chirpy/internal/openapi"
+	"github.com/lucashthiele/chirpy/internal/requestid"
+	"github.com/lucashthiele/chirpy/pkg/client"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+var update = flag.Bool("update", false, "rewrite openapi.yaml from the routes")
+
+// newTestServer serves the routes of the API, failing the test when a
+// response doesn't match the OpenAPI document. Requests that reach the
+// database need DB_URL pointing at a migrated database.
+func newTestServer(t *testing.T) (*httptest.Server, *config.ApiConfig) {
+	t.Helper()
+
+	if os.Getenv("APP_SECRET") == "" {
+		t.Setenv("APP_SECRET", "test-secret")
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatalf("config.New() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	api := configureRoutes(mux, cfg)
+
+	doc, err := api.Document()
+	if err != nil {
+		t.Fatalf("Document() error = %v", err)
+	}
+
+	// some tests send invalid requests on purpose, only the responses have
+	// to match
+	handler := openapi.Validate(doc, mux, func(req *http.Request, err error) {
+		responseErr := &openapi.ResponseError{}
+		if errors.As(err, &responseErr) {
+			t.Errorf("%s %s: %s", req.Method, req.URL, err)
+		}
+	})
+
+	server := httptest.NewServer(requestid.Middleware(api.Negotiate(handler)))
+	t.Cleanup(server.Close)
+
+	return server, cfg
+}
+
+// useFakeDatabase answers the queries of the test server with queries
+// instead of Postgres, until the end of the test.
+func useFakeDatabase(t *testing.T, cfg *config.ApiConfig, queries map[string]dbtest.Query) *dbtest.DB {
+	t.Helper()
+
+	db, conn := dbtest.New(t, queries)
+	previousDb, previousConn := cfg.Db, cfg.DbConn
+	cfg.Db, cfg.DbConn = database.New(conn), conn
+	t.Cleanup(func() {
+		cfg.Db, cfg.DbConn = previousDb, previousConn
+	})
+
+	return db
+}
+
+func TestOpenAPI(t *testing.T) {
+	cfg := &config.ApiConfig{}
+	api := configureRoutes(http.NewServeMux(), cfg)
+
+	got, err := api.YAML()
+	if err != nil {
+		t.Fatalf("YAML() error = %v", err)
+	}
+
+	if *update {
+		err = os.WriteFile("openapi.yaml", got, 0644)
+		if err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	want, err := os.ReadFile("openapi.yaml")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("openapi.yaml is out of date with the routes, run make openapi")
+	}
+}
+
+// TestOpenAPIResponses sends requests that don't need a database, the test
+// server checks their responses against the document.
+func TestOpenAPIResponses(t *testing.T) {
+	server, cfg := newTestServer(t)
+	useFakeDatabase(t, cfg, map[string]dbtest.Query{
+		"GetUserByEmail": func([]driver.Value) (dbtest.Result, error) { return dbtest.NoRows, nil },
+	})
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		header     map[string]string
+		wantStatus int
+	}{
+		{name: "health", method: http.MethodGet, path: "/api/healthz", wantStatus: http.StatusOK},
+		{name: "metrics", method: http.MethodGet, path: "/admin/metrics", wantStatus: http.StatusOK},
+		{name: "create chirp without a token", method: http.MethodPost, path: "/api/chirps", body: `{"body":"hi"}`, wantStatus: http.StatusUnauthorized},
+		{name: "list chirps with a bad token", method: http.MethodGet, path: "/api/chirps", header: map[string]string{"Authorization": "Bearer not-a-jwt"}, wantStatus: http.StatusUnauthorized},
+		{name: "invalid hashtag", method: http.MethodGet, path: "/api/chirps?hashtag=not+a+hashtag", wantStatus: http.StatusBadRequest},
+		{name: "invalid author", method: http.MethodGet, path: "/api/chirps?author_id=nope", wantStatus: http.StatusBadRequest},
+		{name: "refresh without a token", method: http.MethodPost, path: "/api/refresh", wantStatus: http.StatusUnauthorized},
+		{name: "revoke without a token", method: http.MethodPost, path: "/api/revoke", wantStatus: http.StatusUnauthorized},
+		{name: "tokens with a bad token", method: http.MethodGet, path: "/api/tokens", header: map[string]string{"Authorization": "Bearer not-a-jwt"}, wantStatus: http.StatusUnauthorized},
+		{name: "stream without an author", method: http.MethodGet, path: "/api/stream/chirps", wantStatus: http.StatusBadRequest},
+		{name: "gateway without a token", method: http.MethodGet, path: "/api/ws", wantStatus: http.StatusUnauthorized},
+		{name: "graphql without a query", method: http.MethodPost, path: "/api/graphql", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "admin events without a key", method: http.MethodGet, path: "/admin/webhooks/events", wantStatus: http.StatusUnauthorized},
+		{name: "unknown oidc provider", method: http.MethodGet, path: "/api/auth/oidc/nope/login", wantStatus: http.StatusNotFound},
+		{name: "webfinger without a resource", method: http.MethodGet, path: "/.well-known/webfinger", wantStatus: http.StatusNotFound},
+		{name: "login with a body that isn't json", method: http.MethodPost, path: "/api/login", body: `nope`, wantStatus: http.StatusBadRequest},
+		// v1 doesn't check the validate tags, a missing password is a wrong one
+		{name: "login without a password", method: http.MethodPost, path: "/api/login", body: `{"email":"a@example.com"}`, wantStatus: http.StatusUnauthorized},
+		{name: "login with an unknown field", method: http.MethodPost, path: "/api/login", body: `{"email":"a@example.com","password":"x","admin":true}`, wantStatus: http.StatusUnauthorized},
+		{name: "v2 login without a password", method: http.MethodPost, path: "/api/v2/login", body: `{"email":"a@example.com"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "v2 login with an unknown field", method: http.MethodPost, path: "/api/v2/login", body: `{"email":"a@example.com","password":"x","admin":true}`, wantStatus: http.StatusBadRequest},
+		{name: "create user with a long idempotency key", method: http.MethodPost, path: "/api/users", body: `{}`, header: map[string]string{"Idempotency-Key": strings.Repeat("k", 256)}, wantStatus: http.StatusBadRequest},
+		{name: "create user too large with an idempotency key", method: http.MethodPost, path: "/api/users", body: `{"email":"` + strings.Repeat("a", 1<<20) + `"}`, header: map[string]string{"Idempotency-Key": uuid.NewString()}, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "graphql query too large", method: http.MethodPost, path: "/api/graphql", body: `{"query":"` + strings.Repeat("a", 1<<16) + `"}`, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var body io.Reader
+			if c.body != "" {
+				body = strings.NewReader(c.body)
+			}
+			req, err := http.NewRequest(c.method, server.URL+c.path, body)
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			for name, value := range c.header {
+				req.Header.Set(name, value)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != c.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, c.wantStatus)
+			}
+		})
+	}
+}
+
+// TestV1Compatibility pins what clients of v1 rely on: /api keeps answering
+// in v1 with bare error bodies, and says it's deprecated. v2 answers errors
+// with problem details.
+func TestV1Compatibility(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	cases := []struct {
+		name           string
+		method         string
+		path           string
+		accept         string
+		wantStatus     int
+		wantVersion    string
+		wantDeprecated bool
+	}{
+		{name: "v1 path", method: http.MethodGet, path: "/api/tokens", wantStatus: http.StatusUnauthorized, wantVersion: "v1", wantDeprecated: true},
+		{name: "v1 path asking for json", method: http.MethodPost, path: "/api/chirps", accept: "application/json", wantStatus: http.StatusUnauthorized, wantVersion: "v1", wantDeprecated: true},
+		{name: "v1 path asking for v1", method: http.MethodPost, path: "/api/refresh", accept: apiversion.V1.MediaType(), wantStatus: http.StatusUnauthorized, wantVersion: "v1", wantDeprecated: true},
+		{name: "v1 path asking for v2", method: http.MethodGet, path: "/api/tokens", accept: apiversion.V2.MediaType(), wantStatus: http.StatusUnauthorized, wantVersion: "v2"},
+		{name: "v1 path preferring v1", method: http.MethodGet, path: "/api/tokens", accept: "application/vnd.chirpy.v2+json;q=0.5, application/vnd.chirpy.v1+json", wantStatus: http.StatusUnauthorized, wantVersion: "v1", wantDeprecated: true},
+		{name: "v2 path", method: http.MethodGet, path: "/api/v2/tokens", wantStatus: http.StatusUnauthorized, wantVersion: "v2"},
+		{name: "v2 path with a path parameter", method: http.MethodDelete, path: "/api/v2/chirps/" + uuid.NewString(), wantStatus: http.StatusUnauthorized, wantVersion: "v2"},
+		{name: "unversioned route", method: http.MethodGet, path: "/api/healthz", accept: apiversion.V2.MediaType(), wantStatus: http.StatusOK},
+		{name: "unversioned route under v2", method: http.MethodGet, path: "/api/v2/healthz", wantStatus: http.StatusNotFound},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req, err := http.NewRequest(c.method, server.URL+c.path, nil)
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			if c.accept != "" {
+				req.Header.Set("Accept", c.accept)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != c.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, c.wantStatus)
+			}
+			if got := resp.Header.Get("Chirpy-Version"); got != c.wantVersion {
+				t.Errorf("Chirpy-Version = %q, want %q", got, c.wantVersion)
+			}
+			deprecated := resp.Header.Get("Deprecation") != "" && resp.Header.Get("Sunset") != ""
+			if deprecated != c.wantDeprecated {
+				t.Errorf("Deprecation = %q, Sunset = %q, want deprecated %v", resp.Header.Get("Deprecation"), resp.Header.Get("Sunset"), c.wantDeprecated)
+			}
+
+			if resp.Header.Get(requestid.Header) == "" {
+				t.Errorf("%s is empty", requestid.Header)
+			}
+			if c.wantVersion == "" {
+				return
+			}
+
+			body := map[string]any{}
+			err = json.NewDecoder(resp.Body).Decode(&body)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if c.wantVersion == "v2" {
+				if got := resp.Header.Get("Content-Type"); got != response.ProblemContentType {
+					t.Errorf("Content-Type = %q, want %s", got, response.ProblemContentType)
+				}
+				if body["code"] != string(response.CodeUnauthorized) || body["request_id"] != resp.Header.Get(requestid.Header) {
+					t.Errorf("body = %v, want an unauthorized problem with the request ID", body)
+				}
+				return
+			}
+			if got := resp.Header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			if _, ok := body["error"].(string); !ok || len(body) != 1 {
+				t.Errorf("body = %v, want only an error message", body)
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	anonymous := client.New(server.URL, client.WithRetries(0))
+	badToken := client.New(server.URL, client.WithRetries(0), client.WithToken("not-a-jwt"))
+
+	err := anonymous.Health(ctx)
+	if err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		call    func() error
+		wantErr error
+	}{
+		{
+			name: "create chirp without a token",
+			call: func() error {
+				_, err := anonymous.CreateChirp(ctx, client.CreateChirpParams{Body: "hello"})
+				return err
+			},
+			wantErr: client.ErrUnauthorized,
+		},
+		{
+			name: "list tokens with a bad token",
+			call: func() error {
+				_, err := badToken.ListTokens(ctx)
+				return err
+			},
+			wantErr: client.ErrUnauthorized,
+		},
+		{
+			name: "invalid hashtag",
+			call: func() error {
+				_, err := anonymous.ListChirps(ctx, client.ListChirpsOptions{Hashtag: "not a hashtag"})
+				return err
+			},
+			wantErr: client.ErrBadRequest,
+		},
+		{
+			name: "page too large",
+			call: func() error {
+				for _, err := range anonymous.Chirps(ctx, client.ListChirpsOptions{PageSize: 1000}) {
+					return err
+				}
+				return nil
+			},
+			wantErr: client.ErrBadRequest,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.call()
+			if !errors.Is(err, c.wantErr) {
+				t.Fatalf("error = %v, want %v", err, c.wantErr)
+			}
+
+			apiErr := &client.Error{}
+			if !errors.As(err, &apiErr) || apiErr.Message == "" {
+				t.Errorf("error = %#v, want an *Error with the message of the API", err)
+			}
+		})
+	}
+}
+
+// TestReadScopes checks that personal access tokens need chirps:read to read
+// chirps, whichever API they are read through.
+func TestReadScopes(t *testing.T) {
+	server, cfg := newTestServer(t)
+
+	token := "chirpy_pat_" + strings.Repeat("ab", 32)
+	useFakeDatabase(t, cfg, map[string]dbtest.Query{
+		"GetActivePersonalAccessToken": func(args []driver.Value) (dbtest.Result, error) {
+			if args[0] != authz.HashToken(token) {
+				return dbtest.NoRows, nil
+			}
+			now := time.Now()
+			return dbtest.Row(uuid.NewString(), now, now, uuid.NewString(), "ci", args[0], token[:17], "{"+authz.ScopeWebhooksWrite+"}", nil, nil, nil), nil
+		},
+		"TouchPersonalAccessToken": func([]driver.Value) (dbtest.Result, error) { return dbtest.Affected(1), nil },
+	})
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{name: "list chirps", method: http.MethodGet, path: "/api/chirps"},
+		{name: "list chirps in v2", method: http.MethodGet, path: "/api/v2/chirps"},
+		{name: "get a chirp", method: http.MethodGet, path: "/api/chirps/" + uuid.NewString()},
+		{name: "stream the timeline", method: http.MethodGet, path: "/api/stream/timeline"},
+		{name: "stream chirps", method: http.MethodGet, path: "/api/stream/chirps?author_id=" + uuid.NewString()},
+		{name: "graphql", method: http.MethodPost, path: "/api/graphql", body: `{"query":"{ viewer { id } }"}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req, err := http.NewRequest(c.method, server.URL+c.path, strings.NewReader(c.body))
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusForbidden {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestClientAgainstDatabase(t *testing.T) {
+	if os.Getenv("DB_URL") == "" {
+		t.Skip("DB_URL is not set")
+	}
+
+	server, cfg := newTestServer(t)
+	ctx := context.Background()
+
+	email := "sdk-" + uuid.NewString() + "@example.com"
+	c := client.New(server.URL)
+
+	user, err := c.CreateUser(ctx, email, "hunter2")
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	_, err = c.Login(ctx, email, "wrong")
+	if !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("Login() with a wrong password error = %v, want %v", err, client.ErrUnauthorized)
+	}
+
+	session, err := c.Login(ctx, email, "hunter2")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if session.ID != user.ID {
+		t.Fatalf("Login() user = %s, want %s", session.ID, user.ID)
+	}
+
+	created := make([]client.Chirp, 3)
+	for i := range created {
+		created[i], err = c.CreateChirp(ctx, client.CreateChirpParams{Body: "chirp from the sdk"})
+		if err != nil {
+			t.Fatalf("CreateChirp() error = %v", err)
+		}
+	}
+
+	got, err := c.GetChirp(ctx, created[0].ID)
+	if err != nil || got.Body != created[0].Body {
+		t.Fatalf("GetChirp() = %+v, %v, want %+v", got, err, created[0])
+	}
+
+	listed := []uuid.UUID{}
+	for chirp, err := range c.Chirps(ctx, client.ListChirpsOptions{AuthorID: user.ID, PageSize: 2}) {
+		if err != nil {
+			t.Fatalf("Chirps() error = %v", err)
+		}
+		listed = append(listed, chirp.ID)
+	}
+	if len(listed) != 3 || listed[0] != created[2].ID || listed[2] != created[0].ID {
+		t.Errorf("Chirps() = %v, want the created chirps newest first", listed)
+	}
+
+	// an expired access token is refreshed with the refresh token of the
+	// session
+	expired, err := authz.MakeJWT(user.ID, cfg.AppSecret, -time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	c.SetTokens(expired, session.RefreshToken)
+
+	err = c.DeleteChirp(ctx, created[0].ID)
+	if err != nil {
+		t.Fatalf("DeleteChirp() with an expired token error = %v", err)
+	}
+	if c.Token() == expired {
+		t.Errorf("Token() wasn't refreshed")
+	}
+
+	_, err = c.GetChirp(ctx, created[0].ID)
+	if !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("GetChirp() of a deleted chirp error = %v, want %v", err, client.ErrNotFound)
+	}
+
+	endpoint, err := c.CreateWebhookEndpoint(ctx, "https://example.com/hooks", []string{client.EventChirpCreated})
+	if err != nil {
+		t.Fatalf("CreateWebhookEndpoint() error = %v", err)
+	}
+	if endpoint.Secret == "" {
+		t.Errorf("CreateWebhookEndpoint() secret is empty")
+	}
+
+	endpoints, err := c.ListWebhookEndpoints(ctx)
+	if err != nil || len(endpoints) != 1 || endpoints[0].ID != endpoint.ID {
+		t.Errorf("ListWebhookEndpoints() = %+v, %v, want the created endpoint", endpoints, err)
+	}
+
+	err = c.DeleteWebhookEndpoint(ctx, endpoint.ID)
+	if err != nil {
+		t.Fatalf("DeleteWebhookEndpoint() error = %v", err)
+	}
+
+	// v1 sends lists bare, v2 in an envelope
+	for _, path := range []string{"/api/chirps?author_id=" + user.ID.String(), "/api/v2/chirps?author_id=" + user.ID.String()} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+
+		enveloped := bytes.HasPrefix(body, []byte(`{"data":[`))
+		if enveloped != strings.HasPrefix(path, "/api/v2/") {
+			t.Errorf("GET %s = %s, want lists enveloped in v2 only", path, body)
+		}
+	}
+
+	updated, err := c.UpdateUser(ctx, "new-"+email, "hunter3")
+	if err != nil || updated.Email != "new-"+email {
+		t.Fatalf("UpdateUser() = %+v, %v", updated, err)
+	}
+
+	refreshToken := c.RefreshToken()
+	err = c.Logout(ctx)
+	if err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+
+	c.SetTokens("", refreshToken)
+	_, err = c.Refresh(ctx)
+	if !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("Refresh() with a revoked token error = %v, want %v", err, client.ErrUnauthorized)
+	}
+}
+
+func TestIdempotencyAgainstDatabase(t *testing.T) {
+	if os.Getenv("DB_URL") == "" {
+		t.Skip("DB_URL is not set")
+	}
+
+	server, _ := newTestServer(t)
+	key := uuid.NewString()
+
+	post := func(body string) (*http.Response, []byte) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/users", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		req.Header.Set("Idempotency-Key", key)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		defer resp.Body.Close()
+		got, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+		return resp, got
+	}
+
+	body := `{"email":"idempotent-` + key + `@example.com","password":"hunter2"}`
+	first, firstBody := post(body)
+	if first.StatusCode != http.StatusCreated {
+		t.Fatalf("first request status = %d, want %d", first.StatusCode, http.StatusCreated)
+	}
+
+	retry, retryBody := post(body)
+	if retry.StatusCode != http.StatusCreated || !bytes.Equal(retryBody, firstBody) {
+		t.Errorf("retry = %d %s, want the first response %d %s", retry.StatusCode, retryBody, first.StatusCode, firstBody)
+	}
+	if retry.Header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry Idempotent-Replayed = %q, want true", retry.Header.Get("Idempotent-Replayed"))
+	}
+
+	reused, _ := post(`{"email":"other-` + key + `@example.com","password":"hunter2"}`)
+	if reused.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("reused key status = %d, want %d", reused.StatusCode, http.StatusUnprocessableEntity)
+	}
+}
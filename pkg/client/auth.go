@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Session is a logged in user with their tokens.
+type Session struct {
+	User
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Login logs in with a password, and authenticates the next requests as the
+// user.
+func (c *Client) Login(ctx context.Context, email, password string) (Session, error) {
+	session := Session{}
+	_, err := c.do(ctx, http.MethodPost, "/api/login", nil, credentials{Email: email, Password: password}, &session)
+	if err != nil {
+		return Session{}, err
+	}
+
+	c.SetTokens(session.Token, session.RefreshToken)
+	return session, nil
+}
+
+// RequestMagicLink emails a login link to email. It succeeds whether the
+// email belongs to a user or not.
+func (c *Client) RequestMagicLink(ctx context.Context, email string) error {
+	_, err := c.do(ctx, http.MethodPost, "/api/login/magic", nil, struct {
+		Email string `json:"email"`
+	}{Email: email}, nil)
+	return err
+}
+
+// Refresh gets a new access token with the refresh token, and uses it for the
+// next requests. Requests refresh on their own when the token expires, so
+// this is rarely needed.
+func (c *Client) Refresh(ctx context.Context) (string, error) {
+	resp, err := c.send(ctx, http.MethodPost, "/api/refresh", nil, nil, c.RefreshToken())
+	if err != nil {
+		return "", err
+	}
+
+	refreshed := struct {
+		Token string `json:"token"`
+	}{}
+	err = decode(resp, &refreshed)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.token = refreshed.Token
+	c.mu.Unlock()
+
+	return refreshed.Token, nil
+}
+
+// Logout revokes the refresh token and forgets the tokens of the session.
+func (c *Client) Logout(ctx context.Context) error {
+	resp, err := c.send(ctx, http.MethodPost, "/api/revoke", nil, nil, c.RefreshToken())
+	if err != nil {
+		return err
+	}
+
+	err = decode(resp, nil)
+	if err != nil {
+		return err
+	}
+
+	c.SetTokens("", "")
+	return nil
+}
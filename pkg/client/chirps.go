@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	VisibilityPublic        = "public"
+	VisibilityFollowersOnly = "followers-only"
+	VisibilityMentionedOnly = "mentioned-only"
+	VisibilityUnlisted      = "unlisted"
+)
+
+const (
+	ReplyPolicyEveryone  = "everyone"
+	ReplyPolicyFollowing = "following"
+	ReplyPolicyMentioned = "mentioned"
+)
+
+type Chirp struct {
+	ID          uuid.UUID   `json:"id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	Body        string      `json:"body"`
+	UserID      uuid.UUID   `json:"user_id"`
+	Visibility  string      `json:"visibility"`
+	ReplyPolicy string      `json:"reply_policy"`
+	Mentions    []uuid.UUID `json:"mentions"`
+	ReplyToID   *uuid.UUID  `json:"reply_to_id"`
+}
+
+// CreateChirpParams is a new chirp. Visibility and ReplyPolicy default to
+// public and everyone.
+type CreateChirpParams struct {
+	Body        string      `json:"body"`
+	Visibility  string      `json:"visibility,omitempty"`
+	ReplyPolicy string      `json:"reply_policy,omitempty"`
+	Mentions    []uuid.UUID `json:"mentions,omitempty"`
+	ReplyToID   *uuid.UUID  `json:"reply_to_id,omitempty"`
+}
+
+type ListChirpsOptions struct {
+	// AuthorID only lists the chirps of a user when set.
+	AuthorID uuid.UUID
+	// Hashtag only lists the chirps with the hashtag, with or without the #.
+	Hashtag string
+	// Descending lists the newest chirps first in ListChirps. Chirps always
+	// iterates newest first.
+	Descending bool
+	// PageSize is how many chirps Chirps fetches at once, 20 by default.
+	PageSize int
+}
+
+func (o ListChirpsOptions) query() url.Values {
+	query := url.Values{}
+	if o.AuthorID != uuid.Nil {
+		query.Set("author_id", o.AuthorID.String())
+	}
+	if o.Hashtag != "" {
+		query.Set("hashtag", o.Hashtag)
+	}
+	return query
+}
+
+func (c *Client) CreateChirp(ctx context.Context, params CreateChirpParams) (Chirp, error) {
+	chirp := Chirp{}
+	_, err := c.do(ctx, http.MethodPost, "/api/chirps", nil, params, &chirp)
+	return chirp, err
+}
+
+func (c *Client) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	chirp := Chirp{}
+	_, err := c.do(ctx, http.MethodGet, "/api/chirps/"+id.String(), nil, nil, &chirp)
+	return chirp, err
+}
+
+// ListChirps lists every chirp the user can see in one request, oldest first
+// unless opts says otherwise. Chirps pages through them instead.
+func (c *Client) ListChirps(ctx context.Context, opts ListChirpsOptions) ([]Chirp, error) {
+	query := opts.query()
+	if opts.Descending {
+		query.Set("sort", "desc")
+	}
+
+	chirps := []Chirp{}
+	_, err := c.do(ctx, http.MethodGet, "/api/chirps", query, nil, &chirps)
+	return chirps, err
+}
+
+// Chirps iterates over the chirps the user can see, newest first, a page at a
+// time. The iteration stops at the first error.
+func (c *Client) Chirps(ctx context.Context, opts ListChirpsOptions) iter.Seq2[Chirp, error] {
+	query := opts.query()
+	// asking for a limit is what makes the API answer with pages
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	return pages[Chirp](ctx, c, "/api/chirps", pageQuery(query, opts.PageSize))
+}
+
+func (c *Client) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/chirps/"+id.String(), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,341 @@
+// Package client is a Go client for the Chirpy REST API.
+//
+// A Client keeps the tokens of the session it logged in with, and refreshes
+// the access token through /api/refresh when the API answers 401. Failed
+// requests return an *Error, which matches the sentinel errors of its status
+// with errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRetries      = 2
+	defaultRetryBackoff = time.Millisecond * 200
+	maxRetryBackoff     = time.Second * 10
+	defaultPageSize     = 20
+)
+
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	retries      int
+	retryBackoff time.Duration
+
+	mu           sync.Mutex
+	token        string
+	refreshToken string
+
+	// refreshing makes concurrent requests that got a 401 wait for a single
+	// refresh
+	refreshing sync.Mutex
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sends the requests with httpClient instead of
+// http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times a request is retried after a network error
+// or a 429, 502, 503 or 504. Only GET, PUT and DELETE requests are retried,
+// since repeating them is safe. The default is 2, and 0 disables retries.
+func WithRetries(retries int) Option {
+	return func(c *Client) {
+		c.retries = retries
+	}
+}
+
+// WithRetryBackoff sets how long to wait before the first retry, which doubles
+// for every retry after it. A Retry-After header takes precedence.
+func WithRetryBackoff(backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retryBackoff = backoff
+	}
+}
+
+// WithToken authenticates requests with an access token or personal access
+// token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithRefreshToken lets the client get a new access token when the current one
+// expires.
+func WithRefreshToken(refreshToken string) Option {
+	return func(c *Client) {
+		c.refreshToken = refreshToken
+	}
+}
+
+// New returns a client for the API at baseURL, like "https://chirpy.example".
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		httpClient:   http.DefaultClient,
+		retries:      defaultRetries,
+		retryBackoff: defaultRetryBackoff,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Token returns the access token requests are sent with.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// RefreshToken returns the refresh token of the session, if there's one.
+func (c *Client) RefreshToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refreshToken
+}
+
+// SetTokens replaces the tokens of the session, for clients that keep them
+// between runs.
+func (c *Client) SetTokens(token, refreshToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	c.refreshToken = refreshToken
+}
+
+// do sends a request with body encoded as JSON, and decodes the response into
+// out when it's not nil. A 401 refreshes the access token and sends the
+// request again, once.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) (http.Header, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	token := c.Token()
+	resp, err := c.send(ctx, method, path, query, payload, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && c.canRefresh(path) {
+		drain(resp)
+
+		err = c.refresh(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err = c.send(ctx, method, path, query, payload, c.Token())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp.Header, decode(resp, out)
+}
+
+// decode closes the response after decoding it into out, or into an *Error
+// for error statuses.
+func decode(resp *http.Response, out any) error {
+	defer drain(resp)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errorFromResponse(resp)
+	}
+
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		err := json.NewDecoder(resp.Body).Decode(out)
+		if err != nil {
+			return fmt.Errorf("chirpy: decoding response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// send sends a request, retrying it while it's safe to.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, payload []byte, token string) (*http.Response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if ctx.Err() != nil {
+			if resp != nil {
+				drain(resp)
+			}
+			return nil, ctx.Err()
+		}
+		if attempt >= c.retries || !retryable(method, resp, err) {
+			return resp, err
+		}
+
+		wait := c.backoff(attempt, resp)
+		if resp != nil {
+			drain(resp)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func retryable(method string, resp *http.Response, err error) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, maxRetryBackoff)
+		}
+	}
+	return min(c.retryBackoff<<attempt, maxRetryBackoff)
+}
+
+// drain reads what's left of the body so the connection can be reused.
+func drain(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+}
+
+func (c *Client) canRefresh(path string) bool {
+	return c.RefreshToken() != "" && path != "/api/refresh" && path != "/api/login"
+}
+
+// refresh gets a new access token, unless another request already replaced
+// stale while this one waited.
+func (c *Client) refresh(ctx context.Context, stale string) error {
+	c.refreshing.Lock()
+	defer c.refreshing.Unlock()
+
+	if c.Token() != stale {
+		return nil
+	}
+
+	_, err := c.Refresh(ctx)
+	return err
+}
+
+// pages iterates over the items of a listing that links its next page in the
+// Link header, fetching pages as the loop goes.
+func pages[T any](ctx context.Context, c *Client, path string, query url.Values) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			var page []T
+			header, err := c.do(ctx, http.MethodGet, path, query, nil, &page)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			next, ok := nextPage(header.Get("Link"))
+			if !ok {
+				return
+			}
+			path = next.Path
+			query = next.Query()
+		}
+	}
+}
+
+// nextPage finds the rel="next" link of a Link header.
+func nextPage(header string) (*url.URL, bool) {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+
+		next, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return nil, false
+		}
+		return next, true
+	}
+	return nil, false
+}
+
+// pageQuery adds the page size to query, when it's set.
+func pageQuery(query url.Values, pageSize int) url.Values {
+	if pageSize > 0 {
+		query.Set("limit", strconv.Itoa(pageSize))
+	}
+	return query
+}
+
+// Health checks that the API is up.
+func (c *Client) Health(ctx context.Context) error {
+	resp, err := c.send(ctx, http.MethodGet, "/api/healthz", nil, nil, "")
+	if err != nil {
+		return err
+	}
+	return decode(resp, nil)
+}
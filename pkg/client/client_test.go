@@ -0,0 +1,261 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestErrorStatuses(t *testing.T) {
+	cases := []struct {
+		status      int
+		body        string
+		wantErr     error
+		wantMessage string
+	}{
+		{status: http.StatusBadRequest, body: `{"error":"chirp is too long"}`, wantErr: ErrBadRequest, wantMessage: "chirp is too long"},
+		{status: http.StatusUnauthorized, body: `{"error":"Unauthorized"}`, wantErr: ErrUnauthorized, wantMessage: "Unauthorized"},
+		{status: http.StatusForbidden, body: `{"error":"Forbidden"}`, wantErr: ErrForbidden, wantMessage: "Forbidden"},
+		{status: http.StatusNotFound, body: `{"error":"Not found"}`, wantErr: ErrNotFound, wantMessage: "Not found"},
+		{status: http.StatusConflict, body: `{"error":"already exists"}`, wantErr: ErrConflict, wantMessage: "already exists"},
+		{status: http.StatusInternalServerError, body: `{"error":"something went wrong"}`, wantErr: ErrServer, wantMessage: "something went wrong"},
+		{status: http.StatusBadGateway, body: `<html>bad gateway</html>`, wantErr: ErrServer, wantMessage: "Bad Gateway"},
+	}
+
+	for _, c := range cases {
+		t.Run(strconv.Itoa(c.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(c.status)
+				fmt.Fprint(w, c.body)
+			}))
+			defer server.Close()
+
+			_, err := New(server.URL, WithRetries(0)).GetChirp(context.Background(), uuid.New())
+			if !errors.Is(err, c.wantErr) {
+				t.Fatalf("GetChirp() error = %v, want %v", err, c.wantErr)
+			}
+
+			apiErr := &Error{}
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("GetChirp() error = %T, want *Error", err)
+			}
+			if apiErr.StatusCode != c.status || apiErr.Message != c.wantMessage {
+				t.Errorf("error = %d %q, want %d %q", apiErr.StatusCode, apiErr.Message, c.status, c.wantMessage)
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	cases := []struct {
+		name         string
+		method       string
+		statuses     []int
+		retries      int
+		wantAttempts int32
+		wantErr      error
+	}{
+		{
+			name:         "unavailable then ok",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			retries:      2,
+			wantAttempts: 3,
+		},
+		{
+			name:         "gives up after the retries",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			retries:      1,
+			wantAttempts: 2,
+			wantErr:      ErrUnavailable,
+		},
+		{
+			name:         "posts aren't retried",
+			method:       http.MethodPost,
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusOK},
+			retries:      2,
+			wantAttempts: 1,
+			wantErr:      ErrUnavailable,
+		},
+		{
+			name:         "client errors aren't retried",
+			method:       http.MethodGet,
+			statuses:     []int{http.StatusNotFound, http.StatusOK},
+			retries:      2,
+			wantAttempts: 1,
+			wantErr:      ErrNotFound,
+		},
+		{
+			name:         "too many requests",
+			method:       http.MethodDelete,
+			statuses:     []int{http.StatusTooManyRequests, http.StatusNoContent},
+			retries:      2,
+			wantAttempts: 2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			attempts := atomic.Int32{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := attempts.Add(1)
+				status := c.statuses[min(int(attempt), len(c.statuses))-1]
+				if status == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", "0")
+				}
+				w.WriteHeader(status)
+				fmt.Fprint(w, `{}`)
+			}))
+			defer server.Close()
+
+			client := New(server.URL, WithRetries(c.retries), WithRetryBackoff(time.Millisecond))
+			_, err := client.do(context.Background(), c.method, "/api/chirps", nil, nil, nil)
+			if !errors.Is(err, c.wantErr) {
+				t.Fatalf("do() error = %v, want %v", err, c.wantErr)
+			}
+			if got := attempts.Load(); got != c.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, c.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRefreshesExpiredTokens(t *testing.T) {
+	refreshes := atomic.Int32{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/refresh", func(w http.ResponseWriter, r *http.Request) {
+		refreshes.Add(1)
+		if r.Header.Get("Authorization") != "Bearer refresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"Unauthorized"}`)
+			return
+		}
+		fmt.Fprint(w, `{"token":"fresh"}`)
+	})
+	mux.HandleFunc("GET /api/tokens", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"Unauthorized"}`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cases := []struct {
+		name          string
+		refreshToken  string
+		wantErr       error
+		wantRefreshes int32
+		wantToken     string
+	}{
+		{name: "refreshed", refreshToken: "refresh", wantRefreshes: 1, wantToken: "fresh"},
+		{name: "revoked refresh token", refreshToken: "revoked", wantErr: ErrUnauthorized, wantRefreshes: 1, wantToken: "expired"},
+		{name: "no refresh token", wantErr: ErrUnauthorized, wantToken: "expired"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			refreshes.Store(0)
+			client := New(server.URL, WithToken("expired"), WithRefreshToken(c.refreshToken))
+
+			_, err := client.ListTokens(context.Background())
+			if !errors.Is(err, c.wantErr) {
+				t.Fatalf("ListTokens() error = %v, want %v", err, c.wantErr)
+			}
+			if got := refreshes.Load(); got != c.wantRefreshes {
+				t.Errorf("refreshes = %d, want %d", got, c.wantRefreshes)
+			}
+			if got := client.Token(); got != c.wantToken {
+				t.Errorf("Token() = %q, want %q", got, c.wantToken)
+			}
+		})
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	_, err := New(server.URL).ListChirps(ctx, ListChirpsOptions{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ListChirps() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestChirpsIteratesPages(t *testing.T) {
+	ids := make([]uuid.UUID, 5)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+
+	requests := atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("limit") != "2" {
+			t.Errorf("limit = %q, want 2", r.URL.Query().Get("limit"))
+		}
+
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		end := min(start+2, len(ids))
+		if end-start == 2 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?cursor=%d&limit=2>; rel="next"`, r.URL.Path, end))
+		}
+
+		fmt.Fprint(w, "[")
+		for i := start; i < end; i++ {
+			if i > start {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"id":%q}`, ids[i])
+		}
+		fmt.Fprint(w, "]")
+	}))
+	defer server.Close()
+
+	client := New(server.URL)
+
+	got := []uuid.UUID{}
+	for chirp, err := range client.Chirps(context.Background(), ListChirpsOptions{PageSize: 2}) {
+		if err != nil {
+			t.Fatalf("Chirps() error = %v", err)
+		}
+		got = append(got, chirp.ID)
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(ids) {
+		t.Errorf("chirps = %v, want %v", got, ids)
+	}
+	if requests.Load() != 3 {
+		t.Errorf("requests = %d, want 3", requests.Load())
+	}
+
+	// stopping early doesn't fetch the next pages
+	requests.Store(0)
+	for range client.Chirps(context.Background(), ListChirpsOptions{PageSize: 2}) {
+		break
+	}
+	if requests.Load() != 1 {
+		t.Errorf("requests after break = %d, want 1", requests.Load())
+	}
+}
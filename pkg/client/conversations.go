@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ConversationMember struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+type Conversation struct {
+	ID          uuid.UUID            `json:"id"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	IsGroup     bool                 `json:"is_group"`
+	Members     []ConversationMember `json:"members"`
+	UnreadCount int64                `json:"unread_count"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+// CreateConversation starts a conversation with other users, or returns the
+// one the user already has with a single participant.
+func (c *Client) CreateConversation(ctx context.Context, participantIDs ...uuid.UUID) (Conversation, error) {
+	conversation := Conversation{}
+	_, err := c.do(ctx, http.MethodPost, "/api/conversations", nil, struct {
+		ParticipantIds []uuid.UUID `json:"participant_ids"`
+	}{ParticipantIds: participantIDs}, &conversation)
+	return conversation, err
+}
+
+// Conversations iterates over the user's conversations, the ones with the
+// latest messages first. A pageSize of 0 uses the API's default.
+func (c *Client) Conversations(ctx context.Context, pageSize int) iter.Seq2[Conversation, error] {
+	return pages[Conversation](ctx, c, "/api/conversations", pageQuery(url.Values{}, pageSize))
+}
+
+// UnreadCount counts the messages from others the user hasn't read, across
+// every conversation.
+func (c *Client) UnreadCount(ctx context.Context) (int64, error) {
+	unread := struct {
+		UnreadCount int64 `json:"unread_count"`
+	}{}
+	_, err := c.do(ctx, http.MethodGet, "/api/conversations/unread", nil, nil, &unread)
+	return unread.UnreadCount, err
+}
+
+// Messages iterates over the messages of a conversation, newest first.
+func (c *Client) Messages(ctx context.Context, conversationID uuid.UUID, pageSize int) iter.Seq2[Message, error] {
+	return pages[Message](ctx, c, "/api/conversations/"+conversationID.String()+"/messages", pageQuery(url.Values{}, pageSize))
+}
+
+func (c *Client) SendMessage(ctx context.Context, conversationID uuid.UUID, body string) (Message, error) {
+	message := Message{}
+	_, err := c.do(ctx, http.MethodPost, "/api/conversations/"+conversationID.String()+"/messages", nil, struct {
+		Body string `json:"body"`
+	}{Body: body}, &message)
+	return message, err
+}
+
+// MarkRead marks every message of the conversation as read.
+func (c *Client) MarkRead(ctx context.Context, conversationID uuid.UUID) error {
+	_, err := c.do(ctx, http.MethodPost, "/api/conversations/"+conversationID.String()+"/read", nil, nil, nil)
+	return err
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/lucashthiele/chirpy/internal/model"
+)
+
+// maxErrorSize is the most of an error response that is read, in bytes.
+const maxErrorSize = 1 << 16
+
+var (
+	ErrBadRequest      = errors.New("chirpy: bad request")
+	ErrUnauthorized    = errors.New("chirpy: unauthorized")
+	ErrForbidden       = errors.New("chirpy: forbidden")
+	ErrNotFound        = errors.New("chirpy: not found")
+	ErrConflict        = errors.New("chirpy: conflict")
+	ErrTooManyRequests = errors.New("chirpy: too many requests")
+	ErrUnavailable     = errors.New("chirpy: service unavailable")
+	ErrServer          = errors.New("chirpy: server error")
+)
+
+// Error is an error response of the API.
+type Error struct {
+	StatusCode int
+	// Message is the error field of the response, or the status text when the
+	// response had none.
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("chirpy: %d %s", e.StatusCode, e.Message)
+}
+
+// Unwrap returns the sentinel error of the status, so errors.Is(err,
+// ErrNotFound) works.
+func (e *Error) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	case http.StatusServiceUnavailable:
+		return ErrUnavailable
+	}
+	if e.StatusCode >= http.StatusInternalServerError {
+		return ErrServer
+	}
+	return nil
+}
+
+func errorFromResponse(resp *http.Response) *Error {
+	apiErr := &Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorSize))
+	if err != nil {
+		return apiErr
+	}
+
+	errResp := model.ErrorResponse{}
+	err = json.Unmarshal(body, &errResp)
+	if err == nil && errResp.Error != "" {
+		apiErr.Message = errResp.Error
+	}
+
+	return apiErr
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeChirpsRead    = "chirps:read"
+	ScopeChirpsWrite   = "chirps:write"
+	ScopeMessagesRead  = "messages:read"
+	ScopeMessagesWrite = "messages:write"
+	ScopeProfileWrite  = "profile:write"
+	ScopeWebhooksWrite = "webhooks:write"
+)
+
+type PersonalAccessToken struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	// Token is only returned when the token is created.
+	Token string `json:"token,omitempty"`
+}
+
+// CreateTokenParams is a new personal access token. It never expires when
+// ExpiresInDays is 0.
+type CreateTokenParams struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"`
+}
+
+// CreateToken creates a personal access token, which needs a session from
+// Login.
+func (c *Client) CreateToken(ctx context.Context, params CreateTokenParams) (PersonalAccessToken, error) {
+	token := PersonalAccessToken{}
+	_, err := c.do(ctx, http.MethodPost, "/api/tokens", nil, params, &token)
+	return token, err
+}
+
+func (c *Client) ListTokens(ctx context.Context) ([]PersonalAccessToken, error) {
+	tokens := []PersonalAccessToken{}
+	_, err := c.do(ctx, http.MethodGet, "/api/tokens", nil, nil, &tokens)
+	return tokens, err
+}
+
+func (c *Client) RevokeToken(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/tokens/"+id.String(), nil, nil, nil)
+	return err
+}
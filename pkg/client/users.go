@@ -0,0 +1,185 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	FollowStatusPending  = "pending"
+	FollowStatusApproved = "approved"
+)
+
+const (
+	ExportStatusPending  = "pending"
+	ExportStatusBuilding = "building"
+	ExportStatusReady    = "ready"
+	ExportStatusFailed   = "failed"
+)
+
+type User struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	IsPrivate   bool      `json:"is_private"`
+}
+
+// Follow is a follow of another user, pending until they approve it when
+// their account is private.
+type Follow struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FollowRequest struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AccountDeletion struct {
+	RequestedAt time.Time `json:"requested_at"`
+	DeleteAfter time.Time `json:"delete_after"`
+}
+
+type Export struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Status      string     `json:"status"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	// DownloadURL is a signed path to the archive, set once it's ready.
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// CreateUser signs up a new user. It doesn't log in as them.
+func (c *Client) CreateUser(ctx context.Context, email, password string) (User, error) {
+	user := User{}
+	_, err := c.do(ctx, http.MethodPost, "/api/users", nil, credentials{Email: email, Password: password}, &user)
+	return user, err
+}
+
+// UpdateUser changes the email and password of the user.
+func (c *Client) UpdateUser(ctx context.Context, email, password string) (User, error) {
+	user := User{}
+	_, err := c.do(ctx, http.MethodPut, "/api/users", nil, credentials{Email: email, Password: password}, &user)
+	return user, err
+}
+
+// SetPrivacy makes the account private, so new followers need approval, or
+// public again.
+func (c *Client) SetPrivacy(ctx context.Context, isPrivate bool) (User, error) {
+	user := User{}
+	_, err := c.do(ctx, http.MethodPut, "/api/users/privacy", nil, struct {
+		IsPrivate bool `json:"is_private"`
+	}{IsPrivate: isPrivate}, &user)
+	return user, err
+}
+
+// DeleteAccount schedules the deletion of the account, which can be cancelled
+// until DeleteAfter.
+func (c *Client) DeleteAccount(ctx context.Context, password string) (AccountDeletion, error) {
+	deletion := AccountDeletion{}
+	_, err := c.do(ctx, http.MethodDelete, "/api/users", nil, struct {
+		Password string `json:"password"`
+	}{Password: password}, &deletion)
+	return deletion, err
+}
+
+func (c *Client) GetAccountDeletion(ctx context.Context) (AccountDeletion, error) {
+	deletion := AccountDeletion{}
+	_, err := c.do(ctx, http.MethodGet, "/api/users/deletion", nil, nil, &deletion)
+	return deletion, err
+}
+
+func (c *Client) CancelAccountDeletion(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/users/deletion", nil, nil, nil)
+	return err
+}
+
+func (c *Client) userAction(ctx context.Context, method string, userID uuid.UUID, action string) error {
+	_, err := c.do(ctx, method, "/api/users/"+userID.String()+"/"+action, nil, nil, nil)
+	return err
+}
+
+func (c *Client) BlockUser(ctx context.Context, userID uuid.UUID) error {
+	return c.userAction(ctx, http.MethodPost, userID, "block")
+}
+
+func (c *Client) UnblockUser(ctx context.Context, userID uuid.UUID) error {
+	return c.userAction(ctx, http.MethodDelete, userID, "block")
+}
+
+func (c *Client) MuteUser(ctx context.Context, userID uuid.UUID) error {
+	return c.userAction(ctx, http.MethodPost, userID, "mute")
+}
+
+func (c *Client) UnmuteUser(ctx context.Context, userID uuid.UUID) error {
+	return c.userAction(ctx, http.MethodDelete, userID, "mute")
+}
+
+func (c *Client) FollowUser(ctx context.Context, userID uuid.UUID) (Follow, error) {
+	follow := Follow{}
+	_, err := c.do(ctx, http.MethodPost, "/api/users/"+userID.String()+"/follow", nil, nil, &follow)
+	return follow, err
+}
+
+// UnfollowUser stops following a user, or withdraws a pending follow request.
+func (c *Client) UnfollowUser(ctx context.Context, userID uuid.UUID) error {
+	return c.userAction(ctx, http.MethodDelete, userID, "follow")
+}
+
+func (c *Client) ListFollowRequests(ctx context.Context) ([]FollowRequest, error) {
+	requests := []FollowRequest{}
+	_, err := c.do(ctx, http.MethodGet, "/api/follow-requests", nil, nil, &requests)
+	return requests, err
+}
+
+func (c *Client) ApproveFollowRequest(ctx context.Context, userID uuid.UUID) error {
+	_, err := c.do(ctx, http.MethodPost, "/api/follow-requests/"+userID.String()+"/approve", nil, nil, nil)
+	return err
+}
+
+func (c *Client) DenyFollowRequest(ctx context.Context, userID uuid.UUID) error {
+	_, err := c.do(ctx, http.MethodPost, "/api/follow-requests/"+userID.String()+"/deny", nil, nil, nil)
+	return err
+}
+
+// CreateExport queues an export of the user's data. Poll it with GetExport
+// until it's ready.
+func (c *Client) CreateExport(ctx context.Context) (Export, error) {
+	userExport := Export{}
+	_, err := c.do(ctx, http.MethodPost, "/api/users/export", nil, nil, &userExport)
+	return userExport, err
+}
+
+func (c *Client) GetExport(ctx context.Context, id uuid.UUID) (Export, error) {
+	userExport := Export{}
+	_, err := c.do(ctx, http.MethodGet, "/api/users/export/"+id.String(), nil, nil, &userExport)
+	return userExport, err
+}
+
+// DownloadExport opens the archive of a ready export. The caller must close
+// it.
+func (c *Client) DownloadExport(ctx context.Context, userExport Export) (io.ReadCloser, error) {
+	if userExport.DownloadURL == "" {
+		return nil, &Error{StatusCode: http.StatusNotFound, Message: "the export isn't ready"}
+	}
+
+	resp, err := c.send(ctx, http.MethodGet, userExport.DownloadURL, nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, decode(resp, nil)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventChirpCreated = "chirp.created"
+	EventChirpDeleted = "chirp.deleted"
+	EventUserFollowed = "user.followed"
+	EventUserUpgraded = "user.upgraded"
+)
+
+type WebhookEndpoint struct {
+	ID                  uuid.UUID  `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	// Secret signs the deliveries, it is only returned when the endpoint is
+	// created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookAttempt struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	DeliveryID     uuid.UUID `json:"delivery_id"`
+	Event          string    `json:"event"`
+	ResponseStatus *int32    `json:"response_status"`
+	Error          *string   `json:"error"`
+	DurationMs     int32     `json:"duration_ms"`
+}
+
+// CreateWebhookEndpoint subscribes url to events.
+func (c *Client) CreateWebhookEndpoint(ctx context.Context, url string, events []string) (WebhookEndpoint, error) {
+	endpoint := WebhookEndpoint{}
+	_, err := c.do(ctx, http.MethodPost, "/api/webhooks/endpoints", nil, struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}{URL: url, Events: events}, &endpoint)
+	return endpoint, err
+}
+
+func (c *Client) ListWebhookEndpoints(ctx context.Context) ([]WebhookEndpoint, error) {
+	endpoints := []WebhookEndpoint{}
+	_, err := c.do(ctx, http.MethodGet, "/api/webhooks/endpoints", nil, nil, &endpoints)
+	return endpoints, err
+}
+
+func (c *Client) DeleteWebhookEndpoint(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/webhooks/endpoints/"+id.String(), nil, nil, nil)
+	return err
+}
+
+// EnableWebhookEndpoint turns an endpoint back on after it was disabled for
+// failing too often.
+func (c *Client) EnableWebhookEndpoint(ctx context.Context, id uuid.UUID) (WebhookEndpoint, error) {
+	endpoint := WebhookEndpoint{}
+	_, err := c.do(ctx, http.MethodPost, "/api/webhooks/endpoints/"+id.String()+"/enable", nil, nil, &endpoint)
+	return endpoint, err
+}
+
+func (c *Client) ListWebhookAttempts(ctx context.Context, endpointID uuid.UUID) ([]WebhookAttempt, error) {
+	attempts := []WebhookAttempt{}
+	_, err := c.do(ctx, http.MethodGet, "/api/webhooks/endpoints/"+endpointID.String()+"/attempts", nil, nil, &attempts)
+	return attempts, err
+}
@@ -1,20 +1,138 @@
 package parser
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/lucashthiele/chirpy/pkg/response"
 )
 
-func ParseBody[T any](data io.ReadCloser, o *T) error {
-	body, err := io.ReadAll(data)
-	if err != nil {
-		return err
+// MaxBodySize is the largest body ParseBody reads, unless MaxBytes says
+// otherwise.
+const MaxBodySize = 1 << 20
+
+type options struct {
+	maxBytes int64
+	strict   bool
+}
+
+type Option func(*options)
+
+type contextKey struct{}
+
+// NewContext returns a context whose requests ParseBody decodes with opts,
+// before the options it is called with. Routers use it to set the options
+// of every request they serve.
+func NewContext(ctx context.Context, opts ...Option) context.Context {
+	return context.WithValue(ctx, contextKey{}, slices.Concat(optionsFromContext(ctx), opts))
+}
+
+func optionsFromContext(ctx context.Context) []Option {
+	opts, _ := ctx.Value(contextKey{}).([]Option)
+	return opts
+}
+
+// MaxBytes limits the body to n bytes instead of MaxBodySize.
+func MaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// Strict rejects bodies with fields o doesn't have, and checks the body
+// against the validate tags of o. Without it, unknown fields are ignored like
+// encoding/json does and the tags are left to the caller.
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
 	}
+}
 
-	err = json.Unmarshal(body, o)
+// ParseBody decodes the JSON body of req into o, with the options of the
+// request context and then opts. Its errors are *response.Error:
+// payload_too_large when the body is over the limit, bad_request when it
+// isn't JSON of the shape of o, and, with Strict, validation_failed listing
+// every field that broke a rule.
+func ParseBody[T any](res http.ResponseWriter, req *http.Request, o *T, opts ...Option) error {
+	options := options{maxBytes: MaxBodySize}
+	for _, opt := range slices.Concat(optionsFromContext(req.Context()), opts) {
+		opt(&options)
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(res, req.Body, options.maxBytes))
+	if options.strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	err := decoder.Decode(o)
 	if err != nil {
-		return err
+		return decodeError(err)
+	}
+	_, err = decoder.Token()
+	if err != io.EOF {
+		if err == nil {
+			return response.NewError(response.CodeBadRequest, "the body must be a single JSON value")
+		}
+		return decodeError(err)
 	}
 
-	return nil
+	if !options.strict {
+		return nil
+	}
+	return Validate(o)
+}
+
+// decodeError is the error the client gets for a body that couldn't be
+// decoded.
+func decodeError(err error) error {
+	tooLarge := &http.MaxBytesError{}
+	syntaxErr := &json.SyntaxError{}
+	typeErr := &json.UnmarshalTypeError{}
+
+	switch {
+	case errors.As(err, &tooLarge):
+		return response.Errorf(response.CodePayloadTooLarge, "the body can't be larger than %d bytes", tooLarge.Limit)
+	case err == io.EOF:
+		return response.NewError(response.CodeBadRequest, "the body is empty")
+	case err == io.ErrUnexpectedEOF, errors.As(err, &syntaxErr):
+		return response.NewError(response.CodeBadRequest, "the body must be valid JSON")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return response.InvalidField(typeErr.Field, "type", fmt.Sprintf("%s must be %s", typeErr.Field, jsonType(typeErr.Type)))
+	case errors.As(err, &typeErr):
+		return response.Errorf(response.CodeBadRequest, "the body must be %s", jsonType(typeErr.Type))
+	}
+
+	// encoding/json doesn't have a type for unknown fields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field = strings.Trim(field, `"`)
+		return response.InvalidField(field, "unknown", field+" is not a known field")
+	}
+
+	return response.NewError(response.CodeBadRequest, err.Error())
+}
+
+// jsonType is the JSON value the Go type t is decoded from.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	}
+	return "an object"
 }
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+type nested struct {
+	URL string `json:"url" validate:"required"`
+}
+
+type params struct {
+	Email    string      `json:"email" validate:"required,email"`
+	Name     string      `json:"name" validate:"min=2,max=5"`
+	ID       string      `json:"id" validate:"uuid"`
+	Tags     []string    `json:"tags" validate:"max=2"`
+	Private  *bool       `json:"private" validate:"required"`
+	Owner    uuid.UUID   `json:"owner"`
+	Endpoint *nested     `json:"endpoint"`
+	Mentions []uuid.UUID `json:"mentions"`
+}
+
+const valid = `{"email":"a@example.com","private":false}`
+
+func TestParseBody(t *testing.T) {
+	cases := []struct {
+		name       string
+		body       string
+		ctxOpts    []Option
+		opts       []Option
+		wantCode   response.Code
+		wantFields []string
+	}{
+		{name: "valid", body: valid},
+		{name: "valid with every field", body: `{"email":"a@example.com","name":"bob","id":"` + uuid.NewString() + `","tags":["a"],"private":true,"endpoint":{"url":"x"}}`, opts: []Option{Strict()}},
+		{name: "empty", body: "", wantCode: response.CodeBadRequest},
+		{name: "not json", body: "email=a", wantCode: response.CodeBadRequest},
+		{name: "cut short", body: `{"email":`, wantCode: response.CodeBadRequest},
+		{name: "two values", body: valid + valid, wantCode: response.CodeBadRequest},
+		{name: "not an object", body: `[]`, wantCode: response.CodeBadRequest},
+		{name: "wrong type", body: `{"email":1,"private":false}`, wantCode: response.CodeBadRequest, wantFields: []string{"email"}},
+		{name: "wrong nested type", body: `{"email":"a@example.com","private":false,"endpoint":{"url":1}}`, wantCode: response.CodeBadRequest, wantFields: []string{"endpoint.url"}},
+		{name: "bad uuid", body: `{"email":"a@example.com","private":false,"owner":"nope"}`, wantCode: response.CodeBadRequest},
+		{name: "unknown field", body: `{"email":"a@example.com","private":false,"admin":true}`},
+		{name: "unknown field when strict", body: `{"email":"a@example.com","private":false,"admin":true}`, opts: []Option{Strict()}, wantCode: response.CodeBadRequest, wantFields: []string{"admin"}},
+		{name: "unknown field strict from the context", body: `{"email":"a@example.com","private":false,"admin":true}`, ctxOpts: []Option{Strict()}, wantCode: response.CodeBadRequest, wantFields: []string{"admin"}},
+		{name: "invalid fields without Strict", body: `{"email":"nope","name":"a"}`},
+		{name: "invalid fields strict from the context", body: `{"email":"nope","private":false}`, ctxOpts: []Option{Strict()}, wantCode: response.CodeValidationFailed, wantFields: []string{"email"}},
+		{name: "too large", body: `{"email":"` + strings.Repeat("a", 100) + `@example.com"}`, opts: []Option{MaxBytes(64)}, wantCode: response.CodePayloadTooLarge},
+		{name: "every invalid field", body: `{"email":"nope","name":"a","id":"nope","tags":["a","b","c"],"endpoint":{}}`, opts: []Option{Strict()}, wantCode: response.CodeValidationFailed, wantFields: []string{"email", "name", "id", "tags", "private", "endpoint.url"}},
+		{name: "too long", body: `{"email":"a@example.com","private":false,"name":"bobbie"}`, opts: []Option{Strict()}, wantCode: response.CodeValidationFailed, wantFields: []string{"name"}},
+		{name: "email with a name", body: `{"email":"Bob <a@example.com>","private":false}`, opts: []Option{Strict()}, wantCode: response.CodeValidationFailed, wantFields: []string{"email"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(c.body))
+			if c.ctxOpts != nil {
+				req = req.WithContext(NewContext(req.Context(), c.ctxOpts...))
+			}
+
+			err := ParseBody(httptest.NewRecorder(), req, &params{}, c.opts...)
+
+			if c.wantCode == "" {
+				if err != nil {
+					t.Fatalf("ParseBody() error = %v", err)
+				}
+				return
+			}
+			apiErr := &response.Error{}
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("ParseBody() error = %v, want a %s *response.Error", err, c.wantCode)
+			}
+			if apiErr.Code != c.wantCode {
+				t.Errorf("code = %s (%s), want %s", apiErr.Code, apiErr.Message, c.wantCode)
+			}
+
+			fields := []string{}
+			for _, field := range apiErr.Fields {
+				fields = append(fields, field.Field)
+			}
+			if c.wantFields != nil && !slices.Equal(fields, c.wantFields) {
+				t.Errorf("fields = %v, want %v", fields, c.wantFields)
+			}
+		})
+	}
+}
+
+func TestRules(t *testing.T) {
+	field, _ := reflect.TypeFor[params]().FieldByName("Name")
+	got := Rules(field)
+	want := []Rule{{Name: "min", Param: "2"}, {Name: "max", Param: "5"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("Rules() = %v, want %v", got, want)
+	}
+}
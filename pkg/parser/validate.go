@@ -0,0 +1,175 @@
+package parser
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lucashthiele/chirpy/internal/model"
+	"github.com/lucashthiele/chirpy/pkg/response"
+)
+
+// Rule is one of the comma separated rules of a validate tag, like
+// required or min=8.
+type Rule struct {
+	Name  string
+	Param string
+}
+
+// Rules are the rules of the validate tag of field.
+//
+//   - required: strings, slices and maps can't be empty, pointers can't be
+//     null and other values can't be their zero value.
+//   - email: strings are an email address.
+//   - uuid: strings are a UUID.
+//   - min=n, max=n: strings have at least or at most n characters, slices
+//     and maps n elements.
+//
+// Only required applies to empty values, the other rules skip them.
+func Rules(field reflect.StructField) []Rule {
+	tag := field.Tag.Get("validate")
+	if tag == "" {
+		return nil
+	}
+
+	rules := []Rule{}
+	for rule := range strings.SplitSeq(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		rules = append(rules, Rule{Name: name, Param: param})
+	}
+	return rules
+}
+
+// Validate checks the fields of the struct o points to against their
+// validate tags. Its error is a validation_failed *response.Error listing
+// every field that broke a rule.
+func Validate(o any) error {
+	fields := validateStruct(reflect.ValueOf(o), "")
+	if len(fields) > 0 {
+		return response.ValidationError(fields...)
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string) []model.FieldError {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := []model.FieldError{}
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if (name == "-" && options == "") || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		value := v.Field(i)
+		if field.Anonymous && name == "" {
+			fields = append(fields, validateStruct(value, prefix)...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		name = prefix + name
+
+		fieldErr, ok := validateField(name, value, Rules(field))
+		if ok {
+			fields = append(fields, validateStruct(value, name+".")...)
+			continue
+		}
+		fields = append(fields, fieldErr)
+	}
+
+	return fields
+}
+
+// validateField checks value against the rules, stopping at the first one
+// it breaks.
+func validateField(name string, value reflect.Value, rules []Rule) (model.FieldError, bool) {
+	for _, rule := range rules {
+		if rule.Name == "required" {
+			if empty(value) {
+				return model.FieldError{Field: name, Code: rule.Name, Message: name + " is required"}, false
+			}
+			continue
+		}
+		if empty(value) {
+			continue
+		}
+
+		message := check(name, value, rule)
+		if message != "" {
+			return model.FieldError{Field: name, Code: rule.Name, Message: message}, false
+		}
+	}
+
+	return model.FieldError{}, true
+}
+
+func empty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// check is what's wrong with value according to the rule, empty when it
+// follows it.
+func check(name string, value reflect.Value, rule Rule) string {
+	for value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+
+	switch rule.Name {
+	case "email":
+		address, err := mail.ParseAddress(value.String())
+		if value.Kind() != reflect.String || err != nil || address.Address != value.String() {
+			return name + " must be an email address"
+		}
+	case "uuid":
+		_, err := uuid.Parse(value.String())
+		if value.Kind() != reflect.String || err != nil {
+			return name + " must be a UUID"
+		}
+	case "min", "max":
+		limit, err := strconv.Atoi(rule.Param)
+		if err != nil {
+			panic(fmt.Sprintf("parser: %s=%s of %s isn't a number", rule.Name, rule.Param, name))
+		}
+
+		var length int
+		unit := "elements"
+		switch value.Kind() {
+		case reflect.String:
+			length, unit = utf8.RuneCountInString(value.String()), "characters"
+		case reflect.Slice, reflect.Map, reflect.Array:
+			length = value.Len()
+		default:
+			panic(fmt.Sprintf("parser: %s of %s only applies to strings, slices and maps", rule.Name, name))
+		}
+		if rule.Name == "min" && length < limit {
+			return fmt.Sprintf("%s must have at least %d %s", name, limit, unit)
+		}
+		if rule.Name == "max" && length > limit {
+			return fmt.Sprintf("%s must have at most %d %s", name, limit, unit)
+		}
+	default:
+		panic(fmt.Sprintf("parser: unknown rule %s of %s", rule.Name, name))
+	}
+
+	return ""
+}
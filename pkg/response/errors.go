@@ -0,0 +1,163 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lucashthiele/chirpy/internal/model"
+)
+
+// Code tells errors apart for clients, it doesn't change once published.
+type Code string
+
+const (
+	CodeBadRequest       Code = "bad_request"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeForbidden        Code = "forbidden"
+	CodeNotFound         Code = "not_found"
+	CodeConflict         Code = "conflict"
+	CodePayloadTooLarge  Code = "payload_too_large"
+	CodeValidationFailed Code = "validation_failed"
+	CodeTooManyRequests  Code = "too_many_requests"
+	CodeInternal         Code = "internal_error"
+	CodeUnavailable      Code = "unavailable"
+
+	// CodeIdempotencyKeyInUse is a retry of a request that is still being
+	// handled, and CodeIdempotencyKeyReused an Idempotency-Key sent with
+	// another request.
+	CodeIdempotencyKeyInUse  Code = "idempotency_key_in_use"
+	CodeIdempotencyKeyReused Code = "idempotency_key_reused"
+)
+
+var statuses = map[Code]int{
+	CodeBadRequest:       http.StatusBadRequest,
+	CodeUnauthorized:     http.StatusUnauthorized,
+	CodeForbidden:        http.StatusForbidden,
+	CodeNotFound:         http.StatusNotFound,
+	CodeConflict:         http.StatusConflict,
+	CodePayloadTooLarge:  http.StatusRequestEntityTooLarge,
+	CodeValidationFailed: http.StatusUnprocessableEntity,
+	CodeTooManyRequests:  http.StatusTooManyRequests,
+	CodeInternal:         http.StatusInternalServerError,
+	CodeUnavailable:      http.StatusServiceUnavailable,
+
+	CodeIdempotencyKeyInUse:  http.StatusConflict,
+	CodeIdempotencyKeyReused: http.StatusUnprocessableEntity,
+}
+
+// statusCodes are the codes of errors answered with only a status.
+var statusCodes = map[int]Code{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusUnprocessableEntity:   CodeValidationFailed,
+	http.StatusTooManyRequests:       CodeTooManyRequests,
+	http.StatusInternalServerError:   CodeInternal,
+	http.StatusServiceUnavailable:    CodeUnavailable,
+}
+
+// Status is the HTTP status errors with the code are answered with.
+func (c Code) Status() int {
+	status, ok := statuses[c]
+	if !ok {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+// codeFor is the code of errors answered with status.
+func codeFor(status int) Code {
+	code, ok := statusCodes[status]
+	if ok {
+		return code
+	}
+	if status < http.StatusInternalServerError {
+		return CodeBadRequest
+	}
+	return CodeInternal
+}
+
+// Error is an error a handler answers with. Handlers return it, or respond
+// with it with RespondWithErr.
+type Error struct {
+	Code Code
+	// Message is sent to the client, except for internal errors.
+	Message string
+	// Fields lists what's wrong with each field of an invalid request.
+	Fields []model.FieldError
+	// Err is the cause of an internal error, logged but never sent.
+	Err error
+
+	// status overrides the status of the code, for the statuses without
+	// a code of their own.
+	status int
+}
+
+// Status is the HTTP status the error is answered with.
+func (e *Error) Status() int {
+	if e.status != 0 {
+		return e.status
+	}
+	return e.Code.Status()
+}
+
+func NewError(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Errorf is an error with the code and a formatted message.
+func Errorf(code Code, format string, args ...any) *Error {
+	return NewError(code, fmt.Sprintf(format, args...))
+}
+
+// ValidationError is a request whose fields broke the rules in fields. Its
+// message joins the messages of the fields.
+func ValidationError(fields ...model.FieldError) *Error {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	return &Error{Code: CodeValidationFailed, Message: strings.Join(messages, ", "), Fields: fields}
+}
+
+// InvalidField is a bad request because of the field, which broke the
+// rule.
+func InvalidField(field, rule, message string) *Error {
+	return &Error{
+		Code:    CodeBadRequest,
+		Message: message,
+		Fields:  []model.FieldError{{Field: field, Code: rule, Message: message}},
+	}
+}
+
+// Internal is an error the client can't do anything about, err is logged.
+func Internal(err error) *Error {
+	return &Error{Code: CodeInternal, Message: internalMessage, Err: err}
+}
+
+const internalMessage = "something went wrong"
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// asError is err as an *Error, errors that aren't one are internal.
+func asError(err error) *Error {
+	apiErr := &Error{}
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return Internal(err)
+}
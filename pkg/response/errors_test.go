@@ -0,0 +1,130 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lucashthiele/chirpy/internal/apiversion"
+	"github.com/lucashthiele/chirpy/internal/model"
+)
+
+func TestRespondWithErr(t *testing.T) {
+	cases := []struct {
+		name            string
+		version         apiversion.Version
+		err             error
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "v1",
+			version:         apiversion.V1,
+			err:             NewError(CodeNotFound, "Not found"),
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/json",
+			wantBody:        `{"error":"Not found"}`,
+		},
+		{
+			name:            "v1 internal",
+			version:         apiversion.V1,
+			err:             errors.New("connection refused"),
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: "application/json",
+			wantBody:        `{"error":"something went wrong"}`,
+		},
+		{
+			name:            "v2",
+			version:         apiversion.V2,
+			err:             NewError(CodeNotFound, "Not found"),
+			wantStatus:      http.StatusNotFound,
+			wantContentType: ProblemContentType,
+			wantBody:        `{"type":"about:blank","title":"Not Found","status":404,"detail":"Not found","instance":"/api/v2/things","code":"not_found","request_id":""}`,
+		},
+		{
+			name:            "v2 internal",
+			version:         apiversion.V2,
+			err:             errors.New("connection refused"),
+			wantStatus:      http.StatusInternalServerError,
+			wantContentType: ProblemContentType,
+			wantBody:        `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"something went wrong","instance":"/api/v2/things","code":"internal_error","request_id":""}`,
+		},
+		{
+			name:            "v2 fields",
+			version:         apiversion.V2,
+			err:             ValidationError(model.FieldError{Field: "email", Code: "email", Message: "email is invalid"}),
+			wantStatus:      http.StatusUnprocessableEntity,
+			wantContentType: ProblemContentType,
+			wantBody:        `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"email is invalid","instance":"/api/v2/things","code":"validation_failed","request_id":"","errors":[{"field":"email","code":"email","message":"email is invalid"}]}`,
+		},
+		{
+			name:            "v1 fields",
+			version:         apiversion.V1,
+			err:             ValidationError(model.FieldError{Field: "email", Code: "required", Message: "email is required"}, model.FieldError{Field: "password", Code: "required", Message: "password is required"}),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantBody:        `{"error":"email is required, password is required"}`,
+		},
+		{
+			name:            "wrapped",
+			version:         apiversion.V1,
+			err:             errors.Join(errors.New("loading the thing"), NewError(CodeForbidden, "Forbidden")),
+			wantStatus:      http.StatusForbidden,
+			wantContentType: "application/json",
+			wantBody:        `{"error":"Forbidden"}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v2/things", nil)
+			req = req.WithContext(apiversion.NewContext(req.Context(), c.version))
+			rec := httptest.NewRecorder()
+
+			RespondWithErr(rec, req, c.err)
+
+			if rec.Code != c.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, c.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); got != c.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, c.wantContentType)
+			}
+			if got := rec.Body.String(); got != c.wantBody {
+				t.Errorf("body = %s, want %s", got, c.wantBody)
+			}
+		})
+	}
+}
+
+func TestRespondWithError(t *testing.T) {
+	cases := []struct {
+		status   int
+		wantCode Code
+	}{
+		{status: http.StatusBadRequest, wantCode: CodeBadRequest},
+		{status: http.StatusServiceUnavailable, wantCode: CodeUnavailable},
+		{status: http.StatusTeapot, wantCode: CodeBadRequest},
+	}
+
+	for _, c := range cases {
+		t.Run(http.StatusText(c.status), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v2/things", nil)
+			req = req.WithContext(apiversion.NewContext(req.Context(), apiversion.V2))
+			rec := httptest.NewRecorder()
+
+			RespondWithError(rec, req, c.status, "oops")
+
+			problem := model.Problem{}
+			err := json.Unmarshal(rec.Body.Bytes(), &problem)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if rec.Code != c.status || problem.Status != c.status || problem.Code != string(c.wantCode) {
+				t.Errorf("status = %d, problem = %+v, want %d and %s", rec.Code, problem, c.status, c.wantCode)
+			}
+		})
+	}
+}
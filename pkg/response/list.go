@@ -0,0 +1,33 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/lucashthiele/chirpy/internal/apiversion"
+)
+
+// Page describes the page of a list in v2 of the API.
+type Page struct {
+	Limit int `json:"limit"`
+	// NextCursor is passed as the cursor to get the next page, it's null on
+	// the last page.
+	NextCursor *string `json:"next_cursor"`
+}
+
+// List is the body of lists in v2 of the API. v1 sends the bare items.
+type List struct {
+	Data any `json:"data"`
+	// Pagination is only set for lists served in pages.
+	Pagination *Page `json:"pagination,omitempty"`
+}
+
+// RespondWithList sends the items in the shape of the version the request
+// is served in. page is nil for lists that aren't paginated.
+func RespondWithList(w http.ResponseWriter, req *http.Request, items any, page *Page) {
+	if apiversion.FromContext(req.Context()) == apiversion.V1 {
+		RespondWithJSON(w, http.StatusOK, items)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, List{Data: items, Pagination: page})
+}
@@ -5,49 +5,109 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/lucashthiele/chirpy/internal/apiversion"
 	"github.com/lucashthiele/chirpy/internal/model"
+	"github.com/lucashthiele/chirpy/internal/requestid"
 )
 
+// ProblemContentType is the content type of errors in v2 of the API.
+const ProblemContentType = "application/problem+json"
+
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	resp, err := json.Marshal(payload)
 	if err != nil {
-		RespondWithInternalServerError(w, err)
+		respondWithErr(w, nil, Internal(err))
 		return
 	}
 
+	setJSONContentType(w)
 	w.WriteHeader(code)
 	w.Write(resp)
 }
 
-func RespondWithError(w http.ResponseWriter, code int, msg string) {
-	errResp := model.ErrorResponse{
-		Error: msg,
-	}
+// RespondWithError answers with the status, and the error code of the
+// status.
+func RespondWithError(w http.ResponseWriter, req *http.Request, code int, msg string) {
+	respondWithErr(w, req, &Error{Code: codeFor(code), Message: msg, status: code})
+}
 
-	resp, err := json.Marshal(errResp)
-	if err != nil {
-		RespondWithInternalServerError(w, err)
-		return
-	}
+// ErrorRecorder is implemented by response writers that want to keep the
+// underlying error of a 500 response, which is never sent to the client.
+type ErrorRecorder interface {
+	RecordError(err error)
+}
 
-	w.WriteHeader(code)
-	w.Write(resp)
+func RespondWithInternalServerError(w http.ResponseWriter, req *http.Request, err error) {
+	respondWithErr(w, req, Internal(err))
 }
 
-func RespondWithInternalServerError(w http.ResponseWriter, err error) {
-	log.Printf("Internal Server Error: %s", err)
+// RespondWithErr answers with err, errors that aren't an *Error are
+// internal. v1 of the API answers with an ErrorResponse and v2 with a
+// problem detail.
+func RespondWithErr(w http.ResponseWriter, req *http.Request, err error) {
+	respondWithErr(w, req, asError(err))
+}
 
-	errResp := model.ErrorResponse{
-		Error: "something went wrong",
+// respondWithErr answers a request with apiErr, req is nil when the request
+// isn't known, which answers in v1.
+func respondWithErr(w http.ResponseWriter, req *http.Request, apiErr *Error) {
+	version, requestID := apiversion.V1, ""
+	if req != nil {
+		version = apiversion.FromContext(req.Context())
+		requestID = requestid.FromContext(req.Context())
 	}
 
-	resp, err := json.Marshal(errResp)
+	status := apiErr.Status()
+	// v1 answered invalid fields with a 400 before they had a code
+	if version == apiversion.V1 && apiErr.Code == CodeValidationFailed {
+		status = http.StatusBadRequest
+	}
+	if status == http.StatusInternalServerError {
+		var cause error = apiErr
+		if apiErr.Err != nil {
+			cause = apiErr.Err
+		}
+		log.Printf("Internal Server Error: request %s: %s", requestID, cause)
+
+		if recorder, ok := w.(ErrorRecorder); ok {
+			recorder.RecordError(cause)
+		}
+	}
+
+	var body any = model.ErrorResponse{Error: apiErr.Message}
+	if version >= apiversion.V2 {
+		problem := model.Problem{
+			Type:      "about:blank",
+			Title:     http.StatusText(status),
+			Status:    status,
+			Detail:    apiErr.Message,
+			Code:      string(apiErr.Code),
+			RequestID: requestID,
+			Errors:    apiErr.Fields,
+		}
+		if req != nil {
+			problem.Instance = req.URL.Path
+		}
+		body = problem
+		w.Header().Set("Content-Type", ProblemContentType)
+	}
+
+	resp, err := json.Marshal(body)
 	if err != nil {
 		log.Printf("Error encoding response: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusInternalServerError)
+	setJSONContentType(w)
+	w.WriteHeader(status)
 	w.Write(resp)
 }
+
+// setJSONContentType leaves the content type of bodies that are a flavor of
+// JSON, like activity+json, to their handlers.
+func setJSONContentType(w http.ResponseWriter) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+}
@@ -0,0 +1,31 @@
+-- name: ScheduleAccountDeletion :one
+INSERT INTO ACCOUNT_DELETIONS (
+  USER_ID,
+  REQUESTED_AT,
+  DELETE_AFTER
+) VALUES (
+  $1,
+  NOW(),
+  $2
+)
+ON CONFLICT (USER_ID) DO UPDATE
+   SET USER_ID = EXCLUDED.USER_ID
+RETURNING *;
+
+-- name: GetAccountDeletion :one
+SELECT *
+  FROM ACCOUNT_DELETIONS
+ WHERE USER_ID = $1;
+
+-- name: CancelAccountDeletion :execrows
+DELETE FROM ACCOUNT_DELETIONS
+ WHERE USER_ID = $1;
+
+-- name: DeleteDueAccounts :many
+DELETE FROM USERS
+ WHERE ID IN (
+         SELECT USER_ID
+           FROM ACCOUNT_DELETIONS
+          WHERE DELETE_AFTER <= NOW()
+       )
+RETURNING ID;
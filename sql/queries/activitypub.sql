@@ -0,0 +1,106 @@
+-- name: GetActorKey :one
+SELECT *
+  FROM ACTOR_KEYS
+ WHERE USER_ID = $1;
+
+-- name: CreateActorKey :one
+INSERT INTO ACTOR_KEYS (
+  USER_ID,
+  CREATED_AT,
+  PUBLIC_KEY_PEM,
+  PRIVATE_KEY_PEM
+) VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3
+)
+ON CONFLICT (USER_ID) DO UPDATE
+   SET USER_ID = ACTOR_KEYS.USER_ID
+RETURNING *;
+
+-- name: GetRemoteActorByKeyID :one
+SELECT *
+  FROM REMOTE_ACTORS
+ WHERE KEY_ID = $1;
+
+-- name: UpsertRemoteActor :one
+INSERT INTO REMOTE_ACTORS (
+  ID,
+  FETCHED_AT,
+  INBOX,
+  SHARED_INBOX,
+  KEY_ID,
+  PUBLIC_KEY_PEM
+) VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4,
+  $5
+)
+ON CONFLICT (ID) DO UPDATE
+   SET FETCHED_AT = NOW(),
+       INBOX = EXCLUDED.INBOX,
+       SHARED_INBOX = EXCLUDED.SHARED_INBOX,
+       KEY_ID = EXCLUDED.KEY_ID,
+       PUBLIC_KEY_PEM = EXCLUDED.PUBLIC_KEY_PEM
+RETURNING *;
+
+-- name: DeleteRemoteActor :exec
+DELETE FROM REMOTE_ACTORS
+ WHERE ID = $1;
+
+-- name: CreateRemoteFollower :exec
+INSERT INTO REMOTE_FOLLOWERS (
+  USER_ID,
+  ACTOR_ID,
+  FOLLOW_ID,
+  CREATED_AT
+) VALUES (
+  $1,
+  $2,
+  $3,
+  NOW()
+)
+ON CONFLICT (USER_ID, ACTOR_ID) DO UPDATE
+   SET FOLLOW_ID = EXCLUDED.FOLLOW_ID;
+
+-- name: DeleteRemoteFollower :execrows
+DELETE FROM REMOTE_FOLLOWERS
+ WHERE ACTOR_ID = $1
+   AND FOLLOW_ID = $2;
+
+-- name: CountFollowers :one
+SELECT (SELECT COUNT(*) FROM FOLLOWS WHERE FOLLOWED_ID = sqlc.arg(user_id) AND STATUS = 'approved')
+     + (SELECT COUNT(*) FROM REMOTE_FOLLOWERS WHERE USER_ID = sqlc.arg(user_id)) AS COUNT;
+
+-- name: CreateRemoteReaction :exec
+INSERT INTO REMOTE_REACTIONS (
+  ACTIVITY_ID,
+  CREATED_AT,
+  TYPE,
+  ACTOR_ID,
+  CHIRP_ID
+) VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteRemoteReaction :exec
+DELETE FROM REMOTE_REACTIONS
+ WHERE ACTIVITY_ID = $1
+   AND ACTOR_ID = $2;
+
+-- name: ListLikeCounts :many
+SELECT CHIRP_ID,
+       COUNT(*) AS LIKE_COUNT
+  FROM REMOTE_REACTIONS
+ WHERE CHIRP_ID = ANY(sqlc.arg(chirp_ids)::UUID[])
+   AND TYPE = 'Like'
+ GROUP BY CHIRP_ID;
@@ -0,0 +1,89 @@
+-- name: EnqueueActivityPubDelivery :exec
+INSERT INTO ACTIVITYPUB_DELIVERIES (
+  ID,
+  CREATED_AT,
+  UPDATED_AT,
+  USER_ID,
+  KEY_ID,
+  INBOX,
+  ACTIVITY,
+  STATUS,
+  NEXT_ATTEMPT_AT
+) VALUES (
+  GEN_RANDOM_UUID(),
+  NOW(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4,
+  'pending',
+  NOW()
+);
+
+-- name: EnqueueActivityPubFollowerDeliveries :exec
+INSERT INTO ACTIVITYPUB_DELIVERIES (
+  ID,
+  CREATED_AT,
+  UPDATED_AT,
+  USER_ID,
+  KEY_ID,
+  INBOX,
+  ACTIVITY,
+  STATUS,
+  NEXT_ATTEMPT_AT
+)
+SELECT GEN_RANDOM_UUID(),
+       NOW(),
+       NOW(),
+       sqlc.arg(user_id),
+       sqlc.arg(key_id)::TEXT,
+       INBOX,
+       sqlc.arg(activity)::JSONB,
+       'pending',
+       NOW()
+  FROM (
+         SELECT DISTINCT REMOTE_ACTORS.SHARED_INBOX AS INBOX
+           FROM REMOTE_FOLLOWERS
+           JOIN REMOTE_ACTORS ON REMOTE_ACTORS.ID = REMOTE_FOLLOWERS.ACTOR_ID
+          WHERE REMOTE_FOLLOWERS.USER_ID = sqlc.arg(user_id)
+       ) AS INBOXES;
+
+-- name: ClaimDueActivityPubDeliveries :many
+UPDATE ACTIVITYPUB_DELIVERIES
+   SET ATTEMPTS = ATTEMPTS + 1,
+       LOCKED_UNTIL = NOW() + INTERVAL '1 minute',
+       UPDATED_AT = NOW()
+ WHERE ID IN (
+         SELECT ID
+           FROM ACTIVITYPUB_DELIVERIES
+          WHERE STATUS = 'pending'
+            AND NEXT_ATTEMPT_AT <= NOW()
+            AND (LOCKED_UNTIL IS NULL OR LOCKED_UNTIL < NOW())
+          ORDER BY NEXT_ATTEMPT_AT ASC
+          LIMIT $1
+            FOR UPDATE SKIP LOCKED
+       )
+RETURNING *;
+
+-- name: MarkActivityPubDeliverySucceeded :exec
+UPDATE ACTIVITYPUB_DELIVERIES
+   SET STATUS = 'delivered',
+       DELIVERED_AT = NOW(),
+       LOCKED_UNTIL = NULL,
+       UPDATED_AT = NOW()
+ WHERE ID = $1;
+
+-- name: RescheduleActivityPubDelivery :exec
+UPDATE ACTIVITYPUB_DELIVERIES
+   SET NEXT_ATTEMPT_AT = $1,
+       LOCKED_UNTIL = NULL,
+       UPDATED_AT = NOW()
+ WHERE ID = $2;
+
+-- name: MarkActivityPubDeliveryFailed :exec
+UPDATE ACTIVITYPUB_DELIVERIES
+   SET STATUS = 'failed',
+       LOCKED_UNTIL = NULL,
+       UPDATED_AT = NOW()
+ WHERE ID = $1;
@@ -0,0 +1,49 @@
+-- name: BlockUser :exec
+INSERT INTO USER_BLOCKS (
+  BLOCKER_ID,
+  BLOCKED_ID,
+  CREATED_AT
+) VALUES (
+  $1,
+  $2,
+  NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM USER_BLOCKS
+ WHERE BLOCKER_ID = $1
+   AND BLOCKED_ID = $2;
+
+-- name: MuteUser :exec
+INSERT INTO USER_MUTES (
+  MUTER_ID,
+  MUTED_ID,
+  CREATED_AT
+) VALUES (
+  $1,
+  $2,
+  NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM USER_MUTES
+ WHERE MUTER_ID = $1
+   AND MUTED_ID = $2;
+
+-- name: IsBlockedEitherWay :one
+SELECT EXISTS (
+  SELECT 1
+    FROM USER_BLOCKS
+   WHERE (BLOCKER_ID = $1 AND BLOCKED_ID = $2)
+      OR (BLOCKER_ID = $2 AND BLOCKED_ID = $1)
+);
+
+-- name: IsBlockedWithAny :one
+SELECT EXISTS (
+  SELECT 1
+    FROM USER_BLOCKS
+   WHERE (BLOCKER_ID = sqlc.arg(user_id) AND BLOCKED_ID = ANY(sqlc.arg(other_ids)::UUID[]))
+      OR (BLOCKER_ID = ANY(sqlc.arg(other_ids)::UUID[]) AND BLOCKED_ID = sqlc.arg(user_id))
+);
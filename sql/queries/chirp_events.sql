@@ -0,0 +1,6 @@
+-- name: NotifyChirpEvent :exec
+SELECT PG_NOTIFY('chirp_events', JSON_BUILD_OBJECT(
+  'id', NEXTVAL('CHIRP_EVENT_IDS'),
+  'type', sqlc.arg(event_type)::TEXT,
+  'payload', sqlc.arg(payload)::JSON
+)::TEXT);
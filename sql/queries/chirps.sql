@@ -4,13 +4,21 @@ INSERT INTO CHIRPS(
   CREATED_AT,
   UPDATED_AT,
   BODY,
-  USER_ID
+  USER_ID,
+  VISIBILITY,
+  REPLY_POLICY,
+  MENTIONS,
+  REPLY_TO_ID
 ) VALUES (
   GEN_RANDOM_UUID(),
   NOW(),
   NOW(),
   $1,
-  $2
+  $2,
+  $3,
+  $4,
+  $5,
+  $6
 )
 RETURNING *;
 
@@ -19,7 +27,11 @@ SELECT ID,
        CREATED_AT,
        UPDATED_AT,
        BODY,
-       USER_ID
+       USER_ID,
+       VISIBILITY,
+       REPLY_POLICY,
+       MENTIONS,
+       REPLY_TO_ID
   FROM CHIRPS
  WHERE USER_ID = COALESCE(NULLIF($1, '00000000-0000-0000-0000-000000000000'::UUID), USER_ID)
  ORDER BY CREATED_AT ASC;
@@ -29,10 +41,122 @@ SELECT ID,
        CREATED_AT,
        UPDATED_AT,
        BODY,
-       USER_ID
+       USER_ID,
+       VISIBILITY,
+       REPLY_POLICY,
+       MENTIONS,
+       REPLY_TO_ID
   FROM CHIRPS
  WHERE ID = $1;
 
 -- name: DeleteChirpByID :exec
 DELETE FROM CHIRPS
- WHERE ID = $1;
\ No newline at end of file
+ WHERE ID = $1;
+-- name: ListVisibleChirps :many
+SELECT ID,
+       CREATED_AT,
+       UPDATED_AT,
+       BODY,
+       USER_ID,
+       VISIBILITY,
+       REPLY_POLICY,
+       MENTIONS,
+       REPLY_TO_ID
+  FROM CHIRPS
+ WHERE USER_ID = COALESCE(NULLIF(sqlc.arg(author_id)::UUID, '00000000-0000-0000-0000-000000000000'::UUID), USER_ID)
+   AND CHIRP_VISIBLE_TO(CHIRPS.USER_ID, CHIRPS.VISIBILITY, CHIRPS.MENTIONS, sqlc.arg(viewer_id))
+   AND (
+         CHIRPS.USER_ID = sqlc.arg(author_id)::UUID
+         OR NOT EXISTS (
+              SELECT 1
+                FROM USER_MUTES
+               WHERE USER_MUTES.MUTER_ID = sqlc.arg(viewer_id)
+                 AND USER_MUTES.MUTED_ID = CHIRPS.USER_ID
+            )
+       )
+   AND (CHIRPS.VISIBILITY <> 'unlisted' OR CHIRPS.USER_ID = sqlc.arg(author_id)::UUID)
+   AND (
+         sqlc.arg(hashtag)::TEXT = ''
+         OR CHIRPS.BODY ~* ('(^|[^[:alnum:]_])#' || sqlc.arg(hashtag)::TEXT || '($|[^[:alnum:]_])')
+       )
+ ORDER BY CREATED_AT ASC;
+
+-- name: ListVisibleChirpsPage :many
+SELECT ID,
+       CREATED_AT,
+       UPDATED_AT,
+       BODY,
+       USER_ID,
+       VISIBILITY,
+       REPLY_POLICY,
+       MENTIONS,
+       REPLY_TO_ID
+  FROM CHIRPS
+ WHERE USER_ID = COALESCE(NULLIF(sqlc.arg(author_id)::UUID, '00000000-0000-0000-0000-000000000000'::UUID), USER_ID)
+   AND CHIRP_VISIBLE_TO(CHIRPS.USER_ID, CHIRPS.VISIBILITY, CHIRPS.MENTIONS, sqlc.arg(viewer_id))
+   AND (
+         CHIRPS.USER_ID = sqlc.arg(author_id)::UUID
+         OR NOT EXISTS (
+              SELECT 1
+                FROM USER_MUTES
+               WHERE USER_MUTES.MUTER_ID = sqlc.arg(viewer_id)
+                 AND USER_MUTES.MUTED_ID = CHIRPS.USER_ID
+            )
+       )
+   AND (
+         CHIRPS.VISIBILITY <> 'unlisted'
+         OR CHIRPS.USER_ID = sqlc.arg(author_id)::UUID
+         OR (sqlc.arg(timeline)::BOOLEAN AND CHIRPS.USER_ID = sqlc.arg(viewer_id))
+       )
+   AND (
+         NOT sqlc.arg(timeline)::BOOLEAN
+         OR CHIRPS.USER_ID = sqlc.arg(viewer_id)
+         OR EXISTS (
+              SELECT 1
+                FROM FOLLOWS
+               WHERE FOLLOWS.FOLLOWER_ID = sqlc.arg(viewer_id)
+                 AND FOLLOWS.FOLLOWED_ID = CHIRPS.USER_ID
+                 AND FOLLOWS.STATUS = 'approved'
+            )
+       )
+   AND (
+         sqlc.arg(hashtag)::TEXT = ''
+         OR CHIRPS.BODY ~* ('(^|[^[:alnum:]_])#' || sqlc.arg(hashtag)::TEXT || '($|[^[:alnum:]_])')
+       )
+   AND (CHIRPS.CREATED_AT, CHIRPS.ID) < (sqlc.arg(before_time)::TIMESTAMP, sqlc.arg(before_id)::UUID)
+ ORDER BY CHIRPS.CREATED_AT DESC, CHIRPS.ID DESC
+ LIMIT sqlc.arg(row_limit);
+
+-- name: GetVisibleChirpByID :one
+SELECT ID,
+       CREATED_AT,
+       UPDATED_AT,
+       BODY,
+       USER_ID,
+       VISIBILITY,
+       REPLY_POLICY,
+       MENTIONS,
+       REPLY_TO_ID
+  FROM CHIRPS
+ WHERE ID = sqlc.arg(id)
+   AND CHIRP_VISIBLE_TO(CHIRPS.USER_ID, CHIRPS.VISIBILITY, CHIRPS.MENTIONS, sqlc.arg(viewer_id));
+
+-- name: ListVisibleChirpsByIDs :many
+SELECT ID,
+       CREATED_AT,
+       UPDATED_AT,
+       BODY,
+       USER_ID,
+       VISIBILITY,
+       REPLY_POLICY,
+       MENTIONS,
+       REPLY_TO_ID
+  FROM CHIRPS
+ WHERE ID = ANY(sqlc.arg(ids)::UUID[])
+   AND CHIRP_VISIBLE_TO(CHIRPS.USER_ID, CHIRPS.VISIBILITY, CHIRPS.MENTIONS, sqlc.arg(viewer_id));
+
+-- name: CountVisibleChirpsByAuthor :one
+SELECT COUNT(*)
+  FROM CHIRPS
+ WHERE USER_ID = sqlc.arg(author_id)
+   AND CHIRP_VISIBLE_TO(CHIRPS.USER_ID, CHIRPS.VISIBILITY, CHIRPS.MENTIONS, sqlc.arg(viewer_id));
@@ -0,0 +1,89 @@
+-- name: GetDirectConversation :one
+SELECT *
+  FROM CONVERSATIONS
+ WHERE DIRECT_KEY = $1;
+
+-- name: CreateConversation :one
+INSERT INTO CONVERSATIONS (
+  ID,
+  CREATED_AT,
+  UPDATED_AT,
+  IS_GROUP,
+  DIRECT_KEY
+) VALUES (
+  GEN_RANDOM_UUID(),
+  NOW(),
+  NOW(),
+  $1,
+  $2
+)
+ON CONFLICT (DIRECT_KEY) DO UPDATE
+   SET UPDATED_AT = CONVERSATIONS.UPDATED_AT
+RETURNING *;
+
+-- name: AddConversationMember :exec
+INSERT INTO CONVERSATION_MEMBERS (
+  CONVERSATION_ID,
+  USER_ID,
+  JOINED_AT
+) VALUES (
+  $1,
+  $2,
+  NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetConversationForMember :one
+SELECT CONVERSATIONS.ID,
+       CONVERSATIONS.CREATED_AT,
+       CONVERSATIONS.UPDATED_AT,
+       CONVERSATIONS.IS_GROUP,
+       CONVERSATIONS.DIRECT_KEY
+  FROM CONVERSATIONS
+  JOIN CONVERSATION_MEMBERS ON CONVERSATION_MEMBERS.CONVERSATION_ID = CONVERSATIONS.ID
+ WHERE CONVERSATIONS.ID = $1
+   AND CONVERSATION_MEMBERS.USER_ID = $2;
+
+-- name: ListConversationsForMember :many
+SELECT CONVERSATIONS.ID,
+       CONVERSATIONS.CREATED_AT,
+       CONVERSATIONS.UPDATED_AT,
+       CONVERSATIONS.IS_GROUP,
+       (
+         SELECT COUNT(*)
+           FROM MESSAGES
+          WHERE MESSAGES.CONVERSATION_ID = CONVERSATIONS.ID
+            AND MESSAGES.SENDER_ID <> sqlc.arg(user_id)
+            AND MESSAGES.CREATED_AT > COALESCE(CONVERSATION_MEMBERS.LAST_READ_AT, '-infinity'::TIMESTAMP)
+       ) AS UNREAD_COUNT
+  FROM CONVERSATIONS
+  JOIN CONVERSATION_MEMBERS ON CONVERSATION_MEMBERS.CONVERSATION_ID = CONVERSATIONS.ID
+ WHERE CONVERSATION_MEMBERS.USER_ID = sqlc.arg(user_id)
+   AND (CONVERSATIONS.UPDATED_AT, CONVERSATIONS.ID) < (sqlc.arg(before_time)::TIMESTAMP, sqlc.arg(before_id)::UUID)
+ ORDER BY CONVERSATIONS.UPDATED_AT DESC, CONVERSATIONS.ID DESC
+ LIMIT sqlc.arg(row_limit);
+
+-- name: ListMembersOfConversations :many
+SELECT *
+  FROM CONVERSATION_MEMBERS
+ WHERE CONVERSATION_ID = ANY(sqlc.arg(conversation_ids)::UUID[])
+ ORDER BY JOINED_AT ASC, USER_ID ASC;
+
+-- name: CountUnreadMessages :one
+SELECT COUNT(*)
+  FROM MESSAGES
+  JOIN CONVERSATION_MEMBERS ON CONVERSATION_MEMBERS.CONVERSATION_ID = MESSAGES.CONVERSATION_ID
+ WHERE CONVERSATION_MEMBERS.USER_ID = sqlc.arg(user_id)
+   AND MESSAGES.SENDER_ID <> sqlc.arg(user_id)
+   AND MESSAGES.CREATED_AT > COALESCE(CONVERSATION_MEMBERS.LAST_READ_AT, '-infinity'::TIMESTAMP);
+
+-- name: MarkConversationRead :exec
+UPDATE CONVERSATION_MEMBERS
+   SET LAST_READ_AT = NOW()
+ WHERE CONVERSATION_ID = $1
+   AND USER_ID = $2;
+
+-- name: TouchConversation :exec
+UPDATE CONVERSATIONS
+   SET UPDATED_AT = NOW()
+ WHERE ID = $1;
@@ -0,0 +1,138 @@
+-- name: GetFollow :one
+SELECT *
+  FROM FOLLOWS
+ WHERE FOLLOWER_ID = $1
+   AND FOLLOWED_ID = $2;
+
+-- name: CreateFollow :one
+INSERT INTO FOLLOWS (
+  FOLLOWER_ID,
+  FOLLOWED_ID,
+  STATUS,
+  CREATED_AT,
+  UPDATED_AT
+) SELECT sqlc.arg(follower_id),
+         ID,
+         CASE WHEN IS_PRIVATE THEN 'pending' ELSE 'approved' END,
+         NOW(),
+         NOW()
+    FROM USERS
+   WHERE ID = sqlc.arg(followed_id)
+ON CONFLICT (FOLLOWER_ID, FOLLOWED_ID) DO UPDATE
+   SET UPDATED_AT = FOLLOWS.UPDATED_AT
+RETURNING *;
+
+-- name: DeleteFollow :execrows
+DELETE FROM FOLLOWS
+ WHERE FOLLOWER_ID = $1
+   AND FOLLOWED_ID = $2;
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM FOLLOWS
+ WHERE (FOLLOWER_ID = $1 AND FOLLOWED_ID = $2)
+    OR (FOLLOWER_ID = $2 AND FOLLOWED_ID = $1);
+
+-- name: ListPendingFollowRequests :many
+SELECT FOLLOWS.FOLLOWER_ID,
+       USERS.EMAIL,
+       FOLLOWS.CREATED_AT
+  FROM FOLLOWS
+  JOIN USERS ON USERS.ID = FOLLOWS.FOLLOWER_ID
+ WHERE FOLLOWS.FOLLOWED_ID = $1
+   AND FOLLOWS.STATUS = 'pending'
+ ORDER BY FOLLOWS.CREATED_AT ASC;
+
+-- name: ApproveFollowRequest :execrows
+UPDATE FOLLOWS
+   SET STATUS = 'approved',
+       UPDATED_AT = NOW()
+ WHERE FOLLOWER_ID = $1
+   AND FOLLOWED_ID = $2
+   AND STATUS = 'pending';
+
+-- name: DenyFollowRequest :execrows
+DELETE FROM FOLLOWS
+ WHERE FOLLOWER_ID = $1
+   AND FOLLOWED_ID = $2
+   AND STATUS = 'pending';
+
+-- name: ApproveAllFollowRequests :many
+UPDATE FOLLOWS
+   SET STATUS = 'approved',
+       UPDATED_AT = NOW()
+ WHERE FOLLOWED_ID = $1
+   AND STATUS = 'pending'
+RETURNING FOLLOWER_ID;
+
+-- name: ListFollowing :many
+SELECT *
+  FROM FOLLOWS
+ WHERE FOLLOWER_ID = $1
+   AND STATUS = 'approved'
+ ORDER BY CREATED_AT ASC;
+
+-- name: GetViewerRelation :one
+-- The SEES_ columns are what the viewer can see of the author's chirps with
+-- each visibility, SEES_MENTIONS for the chirps that mention them.
+SELECT EXISTS (
+         SELECT 1
+           FROM USER_BLOCKS
+          WHERE (BLOCKER_ID = sqlc.arg(viewer_id) AND BLOCKED_ID = sqlc.arg(author_id))
+             OR (BLOCKER_ID = sqlc.arg(author_id) AND BLOCKED_ID = sqlc.arg(viewer_id))
+       ) AS BLOCKED,
+       EXISTS (
+         SELECT 1
+           FROM USER_MUTES
+          WHERE MUTER_ID = sqlc.arg(viewer_id)
+            AND MUTED_ID = sqlc.arg(author_id)
+       ) AS MUTED,
+       EXISTS (
+         SELECT 1
+           FROM FOLLOWS
+          WHERE FOLLOWER_ID = sqlc.arg(viewer_id)
+            AND FOLLOWED_ID = sqlc.arg(author_id)
+            AND STATUS = 'approved'
+       ) AS FOLLOWING,
+       COALESCE((SELECT IS_PRIVATE FROM USERS WHERE ID = sqlc.arg(author_id)), FALSE) AS AUTHOR_PRIVATE,
+       COALESCE(CHIRP_VISIBLE_TO(sqlc.arg(author_id), 'public', '{}', sqlc.arg(viewer_id)), FALSE) AS SEES_PUBLIC,
+       COALESCE(CHIRP_VISIBLE_TO(sqlc.arg(author_id), 'followers-only', '{}', sqlc.arg(viewer_id)), FALSE) AS SEES_FOLLOWERS_ONLY,
+       COALESCE(CHIRP_VISIBLE_TO(sqlc.arg(author_id), 'mentioned-only', ARRAY[sqlc.arg(viewer_id)::UUID], sqlc.arg(viewer_id)), FALSE) AS SEES_MENTIONS;
+
+-- name: ListFollowersPage :many
+SELECT *
+  FROM FOLLOWS
+ WHERE FOLLOWED_ID = sqlc.arg(followed_id)
+   AND STATUS = 'approved'
+   AND (CREATED_AT, FOLLOWER_ID) < (sqlc.arg(before_time)::TIMESTAMP, sqlc.arg(before_id)::UUID)
+ ORDER BY CREATED_AT DESC, FOLLOWER_ID DESC
+ LIMIT sqlc.arg(row_limit);
+
+-- name: ListFollowingPage :many
+SELECT *
+  FROM FOLLOWS
+ WHERE FOLLOWER_ID = sqlc.arg(follower_id)
+   AND STATUS = 'approved'
+   AND (CREATED_AT, FOLLOWED_ID) < (sqlc.arg(before_time)::TIMESTAMP, sqlc.arg(before_id)::UUID)
+ ORDER BY CREATED_AT DESC, FOLLOWED_ID DESC
+ LIMIT sqlc.arg(row_limit);
+
+-- name: ListFollowCounts :many
+SELECT USERS.ID,
+       (
+         SELECT COUNT(*)
+           FROM FOLLOWS
+          WHERE FOLLOWS.FOLLOWED_ID = USERS.ID
+            AND FOLLOWS.STATUS = 'approved'
+       ) + (
+         SELECT COUNT(*)
+           FROM REMOTE_FOLLOWERS
+          WHERE REMOTE_FOLLOWERS.USER_ID = USERS.ID
+       ) AS FOLLOWER_COUNT,
+       (
+         SELECT COUNT(*)
+           FROM FOLLOWS
+          WHERE FOLLOWS.FOLLOWER_ID = USERS.ID
+            AND FOLLOWS.STATUS = 'approved'
+       ) AS FOLLOWING_COUNT
+  FROM USERS
+ WHERE USERS.ID = ANY(sqlc.arg(ids)::UUID[]);
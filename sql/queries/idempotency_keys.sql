@@ -0,0 +1,54 @@
+-- name: ClaimIdempotencyKey :execrows
+-- Expired keys, and keys whose request was abandoned before it got a
+-- response, are claimed again.
+INSERT INTO IDEMPOTENCY_KEYS (
+  SCOPE,
+  KEY,
+  FINGERPRINT,
+  LOCK_ID,
+  CREATED_AT,
+  EXPIRES_AT
+) VALUES (
+  sqlc.arg(scope),
+  sqlc.arg(key),
+  sqlc.arg(fingerprint),
+  sqlc.arg(lock_id),
+  NOW(),
+  sqlc.arg(expires_at)
+)
+ON CONFLICT (SCOPE, KEY) DO UPDATE
+   SET FINGERPRINT = EXCLUDED.FINGERPRINT,
+       LOCK_ID = EXCLUDED.LOCK_ID,
+       CREATED_AT = EXCLUDED.CREATED_AT,
+       EXPIRES_AT = EXCLUDED.EXPIRES_AT,
+       RESPONSE_STATUS = NULL,
+       RESPONSE_HEADERS = '{}',
+       RESPONSE_BODY = ''
+ WHERE IDEMPOTENCY_KEYS.EXPIRES_AT < NOW()
+    OR (IDEMPOTENCY_KEYS.RESPONSE_STATUS IS NULL AND IDEMPOTENCY_KEYS.CREATED_AT < sqlc.arg(abandoned_before));
+
+-- name: GetIdempotencyKey :one
+SELECT *
+  FROM IDEMPOTENCY_KEYS
+ WHERE SCOPE = $1
+   AND KEY = $2
+   AND EXPIRES_AT > NOW();
+
+-- name: SaveIdempotentResponse :exec
+UPDATE IDEMPOTENCY_KEYS
+   SET RESPONSE_STATUS = $3,
+       RESPONSE_HEADERS = $4,
+       RESPONSE_BODY = $5
+ WHERE SCOPE = $1
+   AND KEY = $2
+   AND LOCK_ID = $6;
+
+-- name: ReleaseIdempotencyKey :exec
+DELETE FROM IDEMPOTENCY_KEYS
+ WHERE SCOPE = $1
+   AND KEY = $2
+   AND LOCK_ID = $3;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM IDEMPOTENCY_KEYS
+ WHERE EXPIRES_AT < NOW();
@@ -0,0 +1,63 @@
+-- name: CountMagicLinkRequest :one
+-- Counts a request in the window of the email, starting a new window when
+-- the last one started before window_started_before. Returns no rows, and
+-- counts nothing, when max_requests were already made in the window.
+INSERT INTO MAGIC_LINK_RATE_LIMITS (
+  EMAIL,
+  WINDOW_START,
+  REQUESTS
+) VALUES (
+  sqlc.arg(email),
+  NOW(),
+  1
+)
+ON CONFLICT (EMAIL) DO UPDATE
+   SET WINDOW_START = CASE WHEN MAGIC_LINK_RATE_LIMITS.WINDOW_START < sqlc.arg(window_started_before)::TIMESTAMP
+                           THEN NOW()
+                           ELSE MAGIC_LINK_RATE_LIMITS.WINDOW_START
+                      END,
+       REQUESTS = CASE WHEN MAGIC_LINK_RATE_LIMITS.WINDOW_START < sqlc.arg(window_started_before)::TIMESTAMP
+                       THEN 1
+                       ELSE MAGIC_LINK_RATE_LIMITS.REQUESTS + 1
+                  END
+ WHERE MAGIC_LINK_RATE_LIMITS.WINDOW_START < sqlc.arg(window_started_before)::TIMESTAMP
+    OR MAGIC_LINK_RATE_LIMITS.REQUESTS < sqlc.arg(max_requests)::INTEGER
+RETURNING REQUESTS;
+
+-- name: DeleteMagicLinkRateLimitsBefore :exec
+DELETE FROM MAGIC_LINK_RATE_LIMITS
+ WHERE WINDOW_START < $1;
+
+-- name: CreateMagicLinkToken :exec
+INSERT INTO MAGIC_LINK_TOKENS (
+  TOKEN_HASH,
+  CREATED_AT,
+  USER_ID,
+  DEVICE_HASH,
+  EXPIRES_AT
+) VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4
+);
+
+-- name: ConsumeMagicLinkToken :one
+WITH CONSUMED AS (
+  UPDATE MAGIC_LINK_TOKENS
+     SET USED_AT = NOW()
+   WHERE TOKEN_HASH = $1
+     AND DEVICE_HASH = $2
+     AND USED_AT IS NULL
+     AND EXPIRES_AT > NOW()
+  RETURNING USER_ID
+)
+SELECT USERS.ID,
+       USERS.CREATED_AT,
+       USERS.UPDATED_AT,
+       USERS.EMAIL,
+       USERS.HASHED_PASSWORD,
+       USERS.IS_PRIVATE
+  FROM USERS
+  JOIN CONSUMED ON CONSUMED.USER_ID = USERS.ID;
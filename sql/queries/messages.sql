@@ -0,0 +1,40 @@
+-- name: CreateMessage :one
+INSERT INTO MESSAGES (
+  ID,
+  CREATED_AT,
+  CONVERSATION_ID,
+  SENDER_ID,
+  KEY_ID,
+  BODY
+) VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4,
+  $5
+)
+RETURNING *;
+
+-- name: ListMessages :many
+SELECT *
+  FROM MESSAGES
+ WHERE CONVERSATION_ID = sqlc.arg(conversation_id)
+   AND (CREATED_AT, ID) < (sqlc.arg(before_time)::TIMESTAMP, sqlc.arg(before_id)::UUID)
+ ORDER BY CREATED_AT DESC, ID DESC
+ LIMIT sqlc.arg(row_limit);
+
+-- name: ListMessagesNotEncryptedWith :many
+SELECT *
+  FROM MESSAGES
+ WHERE KEY_ID <> sqlc.arg(key_id)
+   AND ID > sqlc.arg(after_id)
+ ORDER BY ID ASC
+ LIMIT sqlc.arg(row_limit);
+
+-- name: ReencryptMessage :exec
+UPDATE MESSAGES
+   SET KEY_ID = sqlc.arg(new_key_id),
+       BODY = sqlc.arg(body)
+ WHERE ID = sqlc.arg(id)
+   AND KEY_ID = sqlc.arg(old_key_id);
@@ -0,0 +1,66 @@
+-- name: CreateOAuthClient :one
+INSERT INTO OAUTH_CLIENTS (
+  ID,
+  CREATED_AT,
+  UPDATED_AT,
+  OWNER_ID,
+  NAME,
+  SECRET_HASH,
+  REDIRECT_URIS,
+  SCOPES
+) VALUES (
+  $1,
+  NOW(),
+  NOW(),
+  $2,
+  $3,
+  $4,
+  $5,
+  $6
+)
+RETURNING *;
+
+-- name: GetOAuthClientByID :one
+SELECT *
+  FROM OAUTH_CLIENTS
+ WHERE ID = $1;
+
+-- name: ListOAuthClientsByOwner :many
+SELECT *
+  FROM OAUTH_CLIENTS
+ WHERE OWNER_ID = $1
+ ORDER BY CREATED_AT ASC;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM OAUTH_CLIENTS
+ WHERE ID = $1
+   AND OWNER_ID = $2;
+
+-- name: CreateAuthorizationCode :exec
+INSERT INTO OAUTH_AUTHORIZATION_CODES (
+  CODE_HASH,
+  CREATED_AT,
+  CLIENT_ID,
+  USER_ID,
+  REDIRECT_URI,
+  SCOPES,
+  CODE_CHALLENGE,
+  EXPIRES_AT
+) VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7
+);
+
+-- name: ConsumeAuthorizationCode :one
+UPDATE OAUTH_AUTHORIZATION_CODES
+   SET USED_AT = NOW()
+ WHERE CODE_HASH = $1
+   AND USED_AT IS NULL
+   AND EXPIRES_AT > NOW()
+RETURNING *;
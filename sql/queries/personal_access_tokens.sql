@@ -0,0 +1,50 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO PERSONAL_ACCESS_TOKENS (
+  ID,
+  CREATED_AT,
+  UPDATED_AT,
+  USER_ID,
+  NAME,
+  TOKEN_HASH,
+  TOKEN_PREFIX,
+  SCOPES,
+  EXPIRES_AT
+) VALUES (
+  GEN_RANDOM_UUID(),
+  NOW(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6
+)
+RETURNING *;
+
+-- name: ListPersonalAccessTokensByUser :many
+SELECT *
+  FROM PERSONAL_ACCESS_TOKENS
+ WHERE USER_ID = $1
+   AND REVOKED_AT IS NULL
+ ORDER BY CREATED_AT ASC;
+
+-- name: GetActivePersonalAccessToken :one
+SELECT *
+  FROM PERSONAL_ACCESS_TOKENS
+ WHERE TOKEN_HASH = $1
+   AND REVOKED_AT IS NULL
+   AND (EXPIRES_AT IS NULL OR EXPIRES_AT > NOW());
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE PERSONAL_ACCESS_TOKENS
+   SET LAST_USED_AT = NOW()
+ WHERE ID = $1;
+
+-- name: RevokePersonalAccessToken :execrows
+UPDATE PERSONAL_ACCESS_TOKENS
+   SET REVOKED_AT = NOW(),
+       UPDATED_AT = NOW()
+ WHERE ID = $1
+   AND USER_ID = $2
+   AND REVOKED_AT IS NULL;
@@ -0,0 +1,13 @@
+-- name: ClaimPolkaEvent :execrows
+INSERT INTO POLKA_EVENTS (
+  EVENT_ID,
+  CREATED_AT
+) VALUES (
+  $1,
+  NOW()
+)
+ON CONFLICT (EVENT_ID) DO NOTHING;
+
+-- name: ReleasePolkaEvent :exec
+DELETE FROM POLKA_EVENTS
+ WHERE EVENT_ID = $1;
@@ -5,19 +5,25 @@ INSERT INTO REFRESH_TOKEN (
   UPDATED_AT,
   USER_ID,
   EXPIRES_AT,
-  REVOKED_AT
+  REVOKED_AT,
+  CLIENT_ID,
+  SCOPES
 ) VALUES (
   $1,
   NOW(),
   NOW(),
   $2,
   $3,
-  $4
+  $4,
+  $5,
+  $6
 )
 RETURNING *;
 
 -- name: GetUserFromRefreshToken :one
-SELECT USER_ID
+SELECT USER_ID,
+       CLIENT_ID,
+       SCOPES
   FROM REFRESH_TOKEN
  WHERE TOKEN = $1
    AND EXPIRES_AT > NOW()
@@ -27,4 +33,9 @@ SELECT USER_ID
 UPDATE REFRESH_TOKEN
    SET REVOKED_AT = NOW(),
        UPDATED_AT = NOW()
- WHERE TOKEN = $1;
\ No newline at end of file
+ WHERE TOKEN = $1;
+
+-- name: GetRefreshToken :one
+SELECT *
+  FROM REFRESH_TOKEN
+ WHERE TOKEN = $1;
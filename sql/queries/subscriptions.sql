@@ -0,0 +1,76 @@
+-- name: GetSubscriptionByUserID :one
+SELECT *
+  FROM SUBSCRIPTIONS
+ WHERE USER_ID = $1
+   FOR UPDATE;
+
+-- name: SaveSubscription :one
+INSERT INTO SUBSCRIPTIONS (
+  ID,
+  USER_ID,
+  PLAN,
+  STATUS,
+  CURRENT_PERIOD_END,
+  CREATED_AT,
+  UPDATED_AT
+)
+SELECT GEN_RANDOM_UUID(),
+       ID,
+       sqlc.arg(plan)::TEXT,
+       sqlc.arg(status)::TEXT,
+       sqlc.arg(current_period_end)::TIMESTAMP,
+       NOW(),
+       NOW()
+  FROM USERS
+ WHERE ID = sqlc.arg(user_id)
+ON CONFLICT (USER_ID) DO UPDATE
+   SET PLAN = EXCLUDED.PLAN,
+       STATUS = EXCLUDED.STATUS,
+       CURRENT_PERIOD_END = EXCLUDED.CURRENT_PERIOD_END,
+       UPDATED_AT = NOW()
+RETURNING *;
+
+-- name: IsChirpyRed :one
+SELECT IS_CHIRPY_RED(sqlc.arg(user_id)::UUID) AS IS_CHIRPY_RED;
+
+-- name: ListChirpyRedUsers :many
+SELECT ID AS USER_ID
+  FROM USERS
+ WHERE ID = ANY(sqlc.arg(ids)::UUID[])
+   AND IS_CHIRPY_RED(ID);
+
+-- name: ExpireLapsedSubscriptions :many
+UPDATE SUBSCRIPTIONS
+   SET STATUS = 'expired',
+       UPDATED_AT = NOW()
+ WHERE STATUS <> 'expired'
+   AND CURRENT_PERIOD_END <= NOW()
+RETURNING *;
+
+-- name: CreateSubscriptionEvent :exec
+INSERT INTO SUBSCRIPTION_EVENTS (
+  ID,
+  SUBSCRIPTION_ID,
+  EVENT,
+  STATUS,
+  CURRENT_PERIOD_END,
+  CREATED_AT
+) VALUES (
+  GEN_RANDOM_UUID(),
+  $1,
+  $2,
+  $3,
+  $4,
+  NOW()
+);
+
+-- name: ListSubscriptionEvents :many
+SELECT ID,
+       SUBSCRIPTION_ID,
+       EVENT,
+       STATUS,
+       CURRENT_PERIOD_END,
+       CREATED_AT
+  FROM SUBSCRIPTION_EVENTS
+ WHERE SUBSCRIPTION_ID = $1
+ ORDER BY CREATED_AT ASC;
@@ -0,0 +1,2 @@
+-- name: NotifyUserEvent :exec
+SELECT PG_NOTIFY('user_events', sqlc.arg(payload)::TEXT);
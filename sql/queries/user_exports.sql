@@ -0,0 +1,54 @@
+-- name: CreateUserExport :one
+INSERT INTO USER_EXPORTS (
+  ID,
+  CREATED_AT,
+  UPDATED_AT,
+  USER_ID,
+  STATUS
+) VALUES (
+  GEN_RANDOM_UUID(),
+  NOW(),
+  NOW(),
+  $1,
+  'pending'
+)
+RETURNING *;
+
+-- name: GetUserExport :one
+SELECT *
+  FROM USER_EXPORTS
+ WHERE ID = $1;
+
+-- name: ClaimPendingUserExport :one
+UPDATE USER_EXPORTS
+   SET STATUS = 'building',
+       UPDATED_AT = NOW()
+ WHERE ID = (
+         SELECT ID
+           FROM USER_EXPORTS
+          WHERE STATUS = 'pending'
+             OR (STATUS = 'building' AND UPDATED_AT < NOW() - INTERVAL '10 minutes')
+          ORDER BY CREATED_AT ASC
+          LIMIT 1
+            FOR UPDATE SKIP LOCKED
+       )
+RETURNING *;
+
+-- name: CompleteUserExport :exec
+UPDATE USER_EXPORTS
+   SET STATUS = 'ready',
+       ARCHIVE = $1,
+       COMPLETED_AT = NOW(),
+       EXPIRES_AT = $2,
+       UPDATED_AT = NOW()
+ WHERE ID = $3;
+
+-- name: FailUserExport :exec
+UPDATE USER_EXPORTS
+   SET STATUS = 'failed',
+       UPDATED_AT = NOW()
+ WHERE ID = $1;
+
+-- name: DeleteExpiredUserExports :exec
+DELETE FROM USER_EXPORTS
+ WHERE EXPIRES_AT <= NOW();
@@ -0,0 +1,56 @@
+-- name: CreateUserIdentity :one
+INSERT INTO USER_IDENTITIES (
+  ID,
+  CREATED_AT,
+  USER_ID,
+  PROVIDER,
+  SUBJECT,
+  EMAIL
+) VALUES (
+  GEN_RANDOM_UUID(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4
+)
+RETURNING *;
+
+-- name: GetUserByIdentity :one
+SELECT USERS.ID,
+       USERS.CREATED_AT,
+       USERS.UPDATED_AT,
+       USERS.EMAIL,
+       USERS.HASHED_PASSWORD,
+       USERS.IS_PRIVATE
+  FROM USERS
+  JOIN USER_IDENTITIES ON USER_IDENTITIES.USER_ID = USERS.ID
+ WHERE USER_IDENTITIES.PROVIDER = $1
+   AND USER_IDENTITIES.SUBJECT = $2;
+
+-- name: CreateOIDCLoginState :exec
+INSERT INTO OIDC_LOGIN_STATES (
+  STATE_HASH,
+  CREATED_AT,
+  PROVIDER,
+  NONCE,
+  CODE_VERIFIER,
+  EXPIRES_AT
+) VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4,
+  $5
+);
+
+-- name: ConsumeOIDCLoginState :one
+DELETE FROM OIDC_LOGIN_STATES
+ WHERE STATE_HASH = $1
+   AND EXPIRES_AT > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredOIDCLoginStates :exec
+DELETE FROM OIDC_LOGIN_STATES
+ WHERE EXPIRES_AT <= NOW();
@@ -7,7 +7,12 @@ VALUES (
   $1,
   $2
 )
-RETURNING ID, CREATED_AT, UPDATED_AT, EMAIL, IS_CHIRPY_RED;
+RETURNING ID,
+          CREATED_AT,
+          UPDATED_AT,
+          EMAIL,
+          IS_CHIRPY_RED(ID) AS IS_CHIRPY_RED,
+          IS_PRIVATE;
 
 -- name: DeleteAllUsers :exec
 DELETE FROM USERS;
@@ -18,7 +23,7 @@ SELECT ID,
        UPDATED_AT,
        EMAIL,
        HASHED_PASSWORD,
-       IS_CHIRPY_RED
+       IS_PRIVATE
   FROM USERS
  WHERE EMAIL = $1;
 
@@ -27,9 +32,46 @@ UPDATE USERS
    SET EMAIL = $1,
        HASHED_PASSWORD = $2
  WHERE ID = $3
- RETURNING ID, CREATED_AT, UPDATED_AT, EMAIL, IS_CHIRPY_RED;
+ RETURNING ID,
+           CREATED_AT,
+           UPDATED_AT,
+           EMAIL,
+           IS_CHIRPY_RED(ID) AS IS_CHIRPY_RED,
+           IS_PRIVATE;
 
--- name: UpgradeUser :exec
+-- name: GetUserByID :one
+SELECT ID,
+       CREATED_AT,
+       UPDATED_AT,
+       EMAIL,
+       HASHED_PASSWORD,
+       IS_PRIVATE
+  FROM USERS
+ WHERE ID = $1;
+
+-- name: SetUserPrivacy :one
 UPDATE USERS
-   SET IS_CHIRPY_RED = TRUE
- WHERE ID = $1;
\ No newline at end of file
+   SET IS_PRIVATE = $1,
+       UPDATED_AT = NOW()
+ WHERE ID = $2
+ RETURNING ID,
+           CREATED_AT,
+           UPDATED_AT,
+           EMAIL,
+           IS_CHIRPY_RED(ID) AS IS_CHIRPY_RED,
+           IS_PRIVATE;
+
+-- name: CountExistingUsers :one
+SELECT COUNT(*)
+  FROM USERS
+ WHERE ID = ANY(sqlc.arg(ids)::UUID[]);
+
+-- name: ListUsersByIDs :many
+SELECT ID,
+       CREATED_AT,
+       UPDATED_AT,
+       EMAIL,
+       HASHED_PASSWORD,
+       IS_PRIVATE
+  FROM USERS
+ WHERE ID = ANY(sqlc.arg(ids)::UUID[]);
@@ -0,0 +1,95 @@
+-- name: EnqueueWebhookDeliveries :exec
+INSERT INTO WEBHOOK_DELIVERIES (
+  ID,
+  CREATED_AT,
+  UPDATED_AT,
+  ENDPOINT_ID,
+  EVENT,
+  PAYLOAD,
+  STATUS,
+  NEXT_ATTEMPT_AT
+)
+SELECT GEN_RANDOM_UUID(),
+       NOW(),
+       NOW(),
+       ID,
+       sqlc.arg(event)::TEXT,
+       sqlc.arg(payload)::JSONB,
+       'pending',
+       NOW()
+  FROM WEBHOOK_ENDPOINTS
+ WHERE USER_ID = sqlc.arg(user_id)
+   AND ENABLED
+   AND sqlc.arg(event)::TEXT = ANY(EVENTS);
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE WEBHOOK_DELIVERIES
+   SET ATTEMPTS = ATTEMPTS + 1,
+       LOCKED_UNTIL = NOW() + INTERVAL '1 minute',
+       UPDATED_AT = NOW()
+ WHERE ID IN (
+         SELECT ID
+           FROM WEBHOOK_DELIVERIES
+          WHERE STATUS = 'pending'
+            AND NEXT_ATTEMPT_AT <= NOW()
+            AND (LOCKED_UNTIL IS NULL OR LOCKED_UNTIL < NOW())
+          ORDER BY NEXT_ATTEMPT_AT ASC
+          LIMIT $1
+            FOR UPDATE SKIP LOCKED
+       )
+RETURNING *;
+
+-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE WEBHOOK_DELIVERIES
+   SET STATUS = 'delivered',
+       DELIVERED_AT = NOW(),
+       LOCKED_UNTIL = NULL,
+       UPDATED_AT = NOW()
+ WHERE ID = $1;
+
+-- name: RescheduleWebhookDelivery :exec
+UPDATE WEBHOOK_DELIVERIES
+   SET NEXT_ATTEMPT_AT = $1,
+       LOCKED_UNTIL = NULL,
+       UPDATED_AT = NOW()
+ WHERE ID = $2;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE WEBHOOK_DELIVERIES
+   SET STATUS = 'failed',
+       LOCKED_UNTIL = NULL,
+       UPDATED_AT = NOW()
+ WHERE ID = $1;
+
+-- name: CreateWebhookDeliveryAttempt :exec
+INSERT INTO WEBHOOK_DELIVERY_ATTEMPTS (
+  ID,
+  CREATED_AT,
+  DELIVERY_ID,
+  ENDPOINT_ID,
+  RESPONSE_STATUS,
+  ERROR,
+  DURATION_MS
+) VALUES (
+  GEN_RANDOM_UUID(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4,
+  $5
+);
+
+-- name: ListWebhookDeliveryAttempts :many
+SELECT WEBHOOK_DELIVERY_ATTEMPTS.ID,
+       WEBHOOK_DELIVERY_ATTEMPTS.CREATED_AT,
+       WEBHOOK_DELIVERY_ATTEMPTS.DELIVERY_ID,
+       WEBHOOK_DELIVERIES.EVENT,
+       WEBHOOK_DELIVERY_ATTEMPTS.RESPONSE_STATUS,
+       WEBHOOK_DELIVERY_ATTEMPTS.ERROR,
+       WEBHOOK_DELIVERY_ATTEMPTS.DURATION_MS
+  FROM WEBHOOK_DELIVERY_ATTEMPTS
+  JOIN WEBHOOK_DELIVERIES ON WEBHOOK_DELIVERIES.ID = WEBHOOK_DELIVERY_ATTEMPTS.DELIVERY_ID
+ WHERE WEBHOOK_DELIVERY_ATTEMPTS.ENDPOINT_ID = $1
+ ORDER BY WEBHOOK_DELIVERY_ATTEMPTS.CREATED_AT DESC
+ LIMIT 100;
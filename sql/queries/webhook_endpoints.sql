@@ -0,0 +1,63 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO WEBHOOK_ENDPOINTS (
+  ID,
+  CREATED_AT,
+  UPDATED_AT,
+  USER_ID,
+  URL,
+  SECRET,
+  EVENTS
+) VALUES (
+  GEN_RANDOM_UUID(),
+  NOW(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4
+)
+RETURNING *;
+
+-- name: ListWebhookEndpointsByUser :many
+SELECT *
+  FROM WEBHOOK_ENDPOINTS
+ WHERE USER_ID = $1
+ ORDER BY CREATED_AT ASC;
+
+-- name: GetWebhookEndpointByID :one
+SELECT *
+  FROM WEBHOOK_ENDPOINTS
+ WHERE ID = $1;
+
+-- name: DeleteWebhookEndpoint :exec
+DELETE FROM WEBHOOK_ENDPOINTS
+ WHERE ID = $1;
+
+-- name: EnableWebhookEndpoint :one
+UPDATE WEBHOOK_ENDPOINTS
+   SET ENABLED = TRUE,
+       CONSECUTIVE_FAILURES = 0,
+       DISABLED_AT = NULL,
+       UPDATED_AT = NOW()
+ WHERE ID = $1
+RETURNING *;
+
+-- name: RecordWebhookEndpointFailure :one
+UPDATE WEBHOOK_ENDPOINTS
+   SET CONSECUTIVE_FAILURES = CONSECUTIVE_FAILURES + 1,
+       UPDATED_AT = NOW()
+ WHERE ID = $1
+RETURNING CONSECUTIVE_FAILURES;
+
+-- name: ResetWebhookEndpointFailures :exec
+UPDATE WEBHOOK_ENDPOINTS
+   SET CONSECUTIVE_FAILURES = 0,
+       UPDATED_AT = NOW()
+ WHERE ID = $1;
+
+-- name: DisableWebhookEndpoint :exec
+UPDATE WEBHOOK_ENDPOINTS
+   SET ENABLED = FALSE,
+       DISABLED_AT = NOW(),
+       UPDATED_AT = NOW()
+ WHERE ID = $1;
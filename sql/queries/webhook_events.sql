@@ -0,0 +1,41 @@
+-- name: CreateWebhookEvent :one
+INSERT INTO WEBHOOK_EVENTS (
+  ID,
+  CREATED_AT,
+  UPDATED_AT,
+  SOURCE,
+  HEADERS,
+  BODY,
+  STATUS
+) VALUES (
+  GEN_RANDOM_UUID(),
+  NOW(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  'received'
+)
+RETURNING *;
+
+-- name: RecordWebhookEventResult :exec
+UPDATE WEBHOOK_EVENTS
+   SET STATUS = $1,
+       RESPONSE_STATUS = $2,
+       ERROR = $3,
+       ATTEMPTS = ATTEMPTS + 1,
+       UPDATED_AT = NOW()
+ WHERE ID = $4;
+
+-- name: GetWebhookEventByID :one
+SELECT *
+  FROM WEBHOOK_EVENTS
+ WHERE ID = $1;
+
+-- name: ListWebhookEvents :many
+SELECT *
+  FROM WEBHOOK_EVENTS
+ WHERE STATUS = COALESCE(NULLIF(sqlc.arg(status)::TEXT, ''), STATUS)
+   AND (CREATED_AT, ID) < (sqlc.arg(before_time)::TIMESTAMP, sqlc.arg(before_id)::UUID)
+ ORDER BY CREATED_AT DESC, ID DESC
+ LIMIT sqlc.arg(row_limit);
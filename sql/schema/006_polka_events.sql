@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE POLKA_EVENTS (
+  EVENT_ID TEXT PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE POLKA_EVENTS;
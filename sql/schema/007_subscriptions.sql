@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE SUBSCRIPTIONS (
+  ID UUID PRIMARY KEY,
+  USER_ID UUID NOT NULL UNIQUE,
+  PLAN TEXT NOT NULL,
+  STATUS TEXT NOT NULL,
+  CURRENT_PERIOD_END TIMESTAMP NOT NULL,
+  CREATED_AT TIMESTAMP NOT NULL,
+  UPDATED_AT TIMESTAMP NOT NULL,
+  CONSTRAINT FK_USERS
+  FOREIGN KEY (USER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE TABLE SUBSCRIPTION_EVENTS (
+  ID UUID PRIMARY KEY,
+  SUBSCRIPTION_ID UUID NOT NULL,
+  EVENT TEXT NOT NULL,
+  STATUS TEXT NOT NULL,
+  CURRENT_PERIOD_END TIMESTAMP NOT NULL,
+  CREATED_AT TIMESTAMP NOT NULL,
+  CONSTRAINT FK_SUBSCRIPTIONS
+  FOREIGN KEY (SUBSCRIPTION_ID)
+  REFERENCES SUBSCRIPTIONS(ID)
+  ON DELETE CASCADE
+);
+
+-- Users upgraded before subscriptions stayed red until they were downgraded,
+-- their subscriptions are marked as legacy and paid for good
+INSERT INTO SUBSCRIPTIONS (ID, USER_ID, PLAN, STATUS, CURRENT_PERIOD_END, CREATED_AT, UPDATED_AT)
+SELECT GEN_RANDOM_UUID(), ID, 'chirpy_red_legacy', 'active', TIMESTAMP '9999-12-31 00:00:00', NOW(), NOW()
+  FROM USERS
+ WHERE IS_CHIRPY_RED;
+
+-- +goose Down
+DROP TABLE SUBSCRIPTION_EVENTS;
+DROP TABLE SUBSCRIPTIONS;
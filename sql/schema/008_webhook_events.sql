@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE WEBHOOK_EVENTS (
+  ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  UPDATED_AT TIMESTAMP NOT NULL,
+  SOURCE TEXT NOT NULL,
+  HEADERS JSONB NOT NULL,
+  BODY BYTEA NOT NULL,
+  STATUS TEXT NOT NULL,
+  RESPONSE_STATUS INTEGER,
+  ERROR TEXT,
+  ATTEMPTS INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IDX_WEBHOOK_EVENTS_STATUS ON WEBHOOK_EVENTS (STATUS, CREATED_AT);
+
+-- +goose Down
+DROP TABLE WEBHOOK_EVENTS;
@@ -0,0 +1,60 @@
+-- +goose Up
+CREATE TABLE WEBHOOK_ENDPOINTS (
+  ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  UPDATED_AT TIMESTAMP NOT NULL,
+  USER_ID UUID NOT NULL,
+  URL TEXT NOT NULL,
+  SECRET TEXT NOT NULL,
+  EVENTS TEXT[] NOT NULL,
+  ENABLED BOOLEAN NOT NULL DEFAULT TRUE,
+  CONSECUTIVE_FAILURES INTEGER NOT NULL DEFAULT 0,
+  DISABLED_AT TIMESTAMP,
+  CONSTRAINT FK_USERS
+  FOREIGN KEY (USER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE TABLE WEBHOOK_DELIVERIES (
+  ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  UPDATED_AT TIMESTAMP NOT NULL,
+  ENDPOINT_ID UUID NOT NULL,
+  EVENT TEXT NOT NULL,
+  PAYLOAD JSONB NOT NULL,
+  STATUS TEXT NOT NULL,
+  ATTEMPTS INTEGER NOT NULL DEFAULT 0,
+  NEXT_ATTEMPT_AT TIMESTAMP NOT NULL,
+  LOCKED_UNTIL TIMESTAMP,
+  DELIVERED_AT TIMESTAMP,
+  CONSTRAINT FK_WEBHOOK_ENDPOINTS
+  FOREIGN KEY (ENDPOINT_ID)
+  REFERENCES WEBHOOK_ENDPOINTS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE INDEX IDX_WEBHOOK_DELIVERIES_DUE ON WEBHOOK_DELIVERIES (NEXT_ATTEMPT_AT) WHERE STATUS = 'pending';
+
+CREATE TABLE WEBHOOK_DELIVERY_ATTEMPTS (
+  ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  DELIVERY_ID UUID NOT NULL,
+  ENDPOINT_ID UUID NOT NULL,
+  RESPONSE_STATUS INTEGER,
+  ERROR TEXT,
+  DURATION_MS INTEGER NOT NULL,
+  CONSTRAINT FK_WEBHOOK_DELIVERIES
+  FOREIGN KEY (DELIVERY_ID)
+  REFERENCES WEBHOOK_DELIVERIES(ID)
+  ON DELETE CASCADE,
+  CONSTRAINT FK_WEBHOOK_ENDPOINTS
+  FOREIGN KEY (ENDPOINT_ID)
+  REFERENCES WEBHOOK_ENDPOINTS(ID)
+  ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE WEBHOOK_DELIVERY_ATTEMPTS;
+DROP TABLE WEBHOOK_DELIVERIES;
+DROP TABLE WEBHOOK_ENDPOINTS;
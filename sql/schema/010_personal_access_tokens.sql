@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE PERSONAL_ACCESS_TOKENS (
+  ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  UPDATED_AT TIMESTAMP NOT NULL,
+  USER_ID UUID NOT NULL,
+  NAME TEXT NOT NULL,
+  TOKEN_HASH TEXT NOT NULL UNIQUE,
+  TOKEN_PREFIX TEXT NOT NULL,
+  SCOPES TEXT[] NOT NULL,
+  EXPIRES_AT TIMESTAMP,
+  LAST_USED_AT TIMESTAMP,
+  REVOKED_AT TIMESTAMP,
+  CONSTRAINT FK_USERS
+  FOREIGN KEY (USER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE PERSONAL_ACCESS_TOKENS;
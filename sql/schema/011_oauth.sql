@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE OAUTH_CLIENTS (
+  ID TEXT PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  UPDATED_AT TIMESTAMP NOT NULL,
+  OWNER_ID UUID NOT NULL,
+  NAME TEXT NOT NULL,
+  SECRET_HASH TEXT,
+  REDIRECT_URIS TEXT[] NOT NULL,
+  SCOPES TEXT[] NOT NULL,
+  CONSTRAINT FK_USERS
+  FOREIGN KEY (OWNER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE TABLE OAUTH_AUTHORIZATION_CODES (
+  CODE_HASH TEXT PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  CLIENT_ID TEXT NOT NULL,
+  USER_ID UUID NOT NULL,
+  REDIRECT_URI TEXT NOT NULL,
+  SCOPES TEXT[] NOT NULL,
+  CODE_CHALLENGE TEXT NOT NULL,
+  EXPIRES_AT TIMESTAMP NOT NULL,
+  USED_AT TIMESTAMP,
+  CONSTRAINT FK_OAUTH_CLIENTS
+  FOREIGN KEY (CLIENT_ID)
+  REFERENCES OAUTH_CLIENTS(ID)
+  ON DELETE CASCADE,
+  CONSTRAINT FK_USERS
+  FOREIGN KEY (USER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+ALTER TABLE REFRESH_TOKEN ADD CLIENT_ID TEXT REFERENCES OAUTH_CLIENTS(ID) ON DELETE CASCADE;
+ALTER TABLE REFRESH_TOKEN ADD SCOPES TEXT[];
+
+-- +goose Down
+ALTER TABLE REFRESH_TOKEN DROP COLUMN SCOPES;
+ALTER TABLE REFRESH_TOKEN DROP COLUMN CLIENT_ID;
+DROP TABLE OAUTH_AUTHORIZATION_CODES;
+DROP TABLE OAUTH_CLIENTS;
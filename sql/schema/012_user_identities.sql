@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE USER_IDENTITIES (
+  ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  USER_ID UUID NOT NULL,
+  PROVIDER TEXT NOT NULL,
+  SUBJECT TEXT NOT NULL,
+  EMAIL TEXT NOT NULL,
+  UNIQUE (PROVIDER, SUBJECT),
+  CONSTRAINT FK_USERS
+  FOREIGN KEY (USER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE TABLE OIDC_LOGIN_STATES (
+  STATE_HASH TEXT PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  PROVIDER TEXT NOT NULL,
+  NONCE TEXT NOT NULL,
+  CODE_VERIFIER TEXT NOT NULL,
+  EXPIRES_AT TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE OIDC_LOGIN_STATES;
+DROP TABLE USER_IDENTITIES;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE MAGIC_LINK_TOKENS (
+  TOKEN_HASH TEXT PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  USER_ID UUID NOT NULL,
+  DEVICE_HASH TEXT NOT NULL,
+  EXPIRES_AT TIMESTAMP NOT NULL,
+  USED_AT TIMESTAMP,
+  CONSTRAINT FK_USERS
+  FOREIGN KEY (USER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE TABLE MAGIC_LINK_REQUESTS (
+  ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  EMAIL TEXT NOT NULL
+);
+
+CREATE INDEX MAGIC_LINK_REQUESTS_EMAIL_IDX ON MAGIC_LINK_REQUESTS (EMAIL, CREATED_AT);
+
+-- +goose Down
+DROP TABLE MAGIC_LINK_REQUESTS;
+DROP TABLE MAGIC_LINK_TOKENS;
@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE ACCOUNT_DELETIONS (
+  USER_ID UUID PRIMARY KEY,
+  REQUESTED_AT TIMESTAMP NOT NULL,
+  DELETE_AFTER TIMESTAMP NOT NULL,
+  CONSTRAINT FK_USERS
+  FOREIGN KEY (USER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE TABLE USER_EXPORTS (
+  ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  UPDATED_AT TIMESTAMP NOT NULL,
+  USER_ID UUID NOT NULL,
+  STATUS TEXT NOT NULL,
+  ARCHIVE BYTEA,
+  COMPLETED_AT TIMESTAMP,
+  EXPIRES_AT TIMESTAMP,
+  CONSTRAINT FK_USERS
+  FOREIGN KEY (USER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE INDEX USER_EXPORTS_PENDING_IDX ON USER_EXPORTS (CREATED_AT) WHERE STATUS = 'pending';
+
+-- +goose Down
+DROP TABLE USER_EXPORTS;
+DROP TABLE ACCOUNT_DELETIONS;
@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE USER_BLOCKS (
+  BLOCKER_ID UUID NOT NULL,
+  BLOCKED_ID UUID NOT NULL,
+  CREATED_AT TIMESTAMP NOT NULL,
+  PRIMARY KEY (BLOCKER_ID, BLOCKED_ID),
+  CHECK (BLOCKER_ID <> BLOCKED_ID),
+  CONSTRAINT FK_BLOCKER
+  FOREIGN KEY (BLOCKER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE,
+  CONSTRAINT FK_BLOCKED
+  FOREIGN KEY (BLOCKED_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE INDEX USER_BLOCKS_BLOCKED_IDX ON USER_BLOCKS (BLOCKED_ID);
+
+CREATE TABLE USER_MUTES (
+  MUTER_ID UUID NOT NULL,
+  MUTED_ID UUID NOT NULL,
+  CREATED_AT TIMESTAMP NOT NULL,
+  PRIMARY KEY (MUTER_ID, MUTED_ID),
+  CHECK (MUTER_ID <> MUTED_ID),
+  CONSTRAINT FK_MUTER
+  FOREIGN KEY (MUTER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE,
+  CONSTRAINT FK_MUTED
+  FOREIGN KEY (MUTED_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE USER_MUTES;
+DROP TABLE USER_BLOCKS;
@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE USERS ADD IS_PRIVATE BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE FOLLOWS (
+  FOLLOWER_ID UUID NOT NULL,
+  FOLLOWED_ID UUID NOT NULL,
+  STATUS TEXT NOT NULL,
+  CREATED_AT TIMESTAMP NOT NULL,
+  UPDATED_AT TIMESTAMP NOT NULL,
+  PRIMARY KEY (FOLLOWER_ID, FOLLOWED_ID),
+  CHECK (FOLLOWER_ID <> FOLLOWED_ID),
+  CONSTRAINT FK_FOLLOWER
+  FOREIGN KEY (FOLLOWER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE,
+  CONSTRAINT FK_FOLLOWED
+  FOREIGN KEY (FOLLOWED_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE INDEX FOLLOWS_FOLLOWED_IDX ON FOLLOWS (FOLLOWED_ID, STATUS);
+
+-- +goose Down
+DROP TABLE FOLLOWS;
+ALTER TABLE USERS DROP COLUMN IS_PRIVATE;
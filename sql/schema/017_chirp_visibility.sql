@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE CHIRPS ADD VISIBILITY TEXT NOT NULL DEFAULT 'public'
+  CHECK (VISIBILITY IN ('public', 'followers-only', 'mentioned-only', 'unlisted'));
+ALTER TABLE CHIRPS ADD REPLY_POLICY TEXT NOT NULL DEFAULT 'everyone'
+  CHECK (REPLY_POLICY IN ('everyone', 'following', 'mentioned'));
+ALTER TABLE CHIRPS ADD MENTIONS UUID[] NOT NULL DEFAULT '{}';
+ALTER TABLE CHIRPS ADD REPLY_TO_ID UUID;
+ALTER TABLE CHIRPS ADD CONSTRAINT FK_REPLY_TO
+  FOREIGN KEY (REPLY_TO_ID)
+  REFERENCES CHIRPS(ID)
+  ON DELETE SET NULL;
+
+CREATE INDEX CHIRPS_REPLY_TO_IDX ON CHIRPS (REPLY_TO_ID);
+
+-- +goose Down
+ALTER TABLE CHIRPS DROP COLUMN REPLY_TO_ID;
+ALTER TABLE CHIRPS DROP COLUMN MENTIONS;
+ALTER TABLE CHIRPS DROP COLUMN REPLY_POLICY;
+ALTER TABLE CHIRPS DROP COLUMN VISIBILITY;
@@ -0,0 +1,52 @@
+-- +goose Up
+CREATE TABLE CONVERSATIONS (
+  ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  UPDATED_AT TIMESTAMP NOT NULL,
+  IS_GROUP BOOLEAN NOT NULL,
+  -- both member IDs in order for 1:1 conversations, so there is only one per pair
+  DIRECT_KEY TEXT UNIQUE
+);
+
+CREATE TABLE CONVERSATION_MEMBERS (
+  CONVERSATION_ID UUID NOT NULL,
+  USER_ID UUID NOT NULL,
+  JOINED_AT TIMESTAMP NOT NULL,
+  LAST_READ_AT TIMESTAMP,
+  PRIMARY KEY (CONVERSATION_ID, USER_ID),
+  CONSTRAINT FK_CONVERSATION
+  FOREIGN KEY (CONVERSATION_ID)
+  REFERENCES CONVERSATIONS(ID)
+  ON DELETE CASCADE,
+  CONSTRAINT FK_USER
+  FOREIGN KEY (USER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE INDEX CONVERSATION_MEMBERS_USER_IDX ON CONVERSATION_MEMBERS (USER_ID);
+
+CREATE TABLE MESSAGES (
+  ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  CONVERSATION_ID UUID NOT NULL,
+  SENDER_ID UUID NOT NULL,
+  KEY_ID TEXT NOT NULL,
+  BODY BYTEA NOT NULL,
+  CONSTRAINT FK_CONVERSATION
+  FOREIGN KEY (CONVERSATION_ID)
+  REFERENCES CONVERSATIONS(ID)
+  ON DELETE CASCADE,
+  CONSTRAINT FK_SENDER
+  FOREIGN KEY (SENDER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE INDEX MESSAGES_CONVERSATION_IDX ON MESSAGES (CONVERSATION_ID, CREATED_AT DESC, ID DESC);
+CREATE INDEX MESSAGES_KEY_IDX ON MESSAGES (KEY_ID);
+
+-- +goose Down
+DROP TABLE MESSAGES;
+DROP TABLE CONVERSATION_MEMBERS;
+DROP TABLE CONVERSATIONS;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- IDs of the chirp events streamed to clients, shared by every instance so a
+-- stream can be resumed on any of them
+CREATE SEQUENCE CHIRP_EVENT_IDS;
+
+-- +goose Down
+DROP SEQUENCE CHIRP_EVENT_IDS;
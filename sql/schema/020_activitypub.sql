@@ -0,0 +1,83 @@
+-- +goose Up
+CREATE TABLE ACTOR_KEYS (
+  USER_ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  PUBLIC_KEY_PEM TEXT NOT NULL,
+  PRIVATE_KEY_PEM TEXT NOT NULL,
+  CONSTRAINT FK_USERS
+  FOREIGN KEY (USER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE TABLE REMOTE_ACTORS (
+  ID TEXT PRIMARY KEY,
+  FETCHED_AT TIMESTAMP NOT NULL,
+  INBOX TEXT NOT NULL,
+  SHARED_INBOX TEXT NOT NULL,
+  KEY_ID TEXT NOT NULL UNIQUE,
+  PUBLIC_KEY_PEM TEXT NOT NULL
+);
+
+CREATE TABLE REMOTE_FOLLOWERS (
+  USER_ID UUID NOT NULL,
+  ACTOR_ID TEXT NOT NULL,
+  FOLLOW_ID TEXT NOT NULL,
+  CREATED_AT TIMESTAMP NOT NULL,
+  PRIMARY KEY (USER_ID, ACTOR_ID),
+  CONSTRAINT FK_USERS
+  FOREIGN KEY (USER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE,
+  CONSTRAINT FK_REMOTE_ACTORS
+  FOREIGN KEY (ACTOR_ID)
+  REFERENCES REMOTE_ACTORS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE TABLE REMOTE_REACTIONS (
+  ACTIVITY_ID TEXT PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  TYPE TEXT NOT NULL CHECK (TYPE IN ('Like', 'Announce')),
+  ACTOR_ID TEXT NOT NULL,
+  CHIRP_ID UUID NOT NULL,
+  UNIQUE (TYPE, ACTOR_ID, CHIRP_ID),
+  CONSTRAINT FK_REMOTE_ACTORS
+  FOREIGN KEY (ACTOR_ID)
+  REFERENCES REMOTE_ACTORS(ID)
+  ON DELETE CASCADE,
+  CONSTRAINT FK_CHIRPS
+  FOREIGN KEY (CHIRP_ID)
+  REFERENCES CHIRPS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE INDEX IDX_REMOTE_REACTIONS_CHIRP ON REMOTE_REACTIONS (CHIRP_ID);
+
+CREATE TABLE ACTIVITYPUB_DELIVERIES (
+  ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  UPDATED_AT TIMESTAMP NOT NULL,
+  USER_ID UUID NOT NULL,
+  KEY_ID TEXT NOT NULL,
+  INBOX TEXT NOT NULL,
+  ACTIVITY JSONB NOT NULL,
+  STATUS TEXT NOT NULL,
+  ATTEMPTS INTEGER NOT NULL DEFAULT 0,
+  NEXT_ATTEMPT_AT TIMESTAMP NOT NULL,
+  LOCKED_UNTIL TIMESTAMP,
+  DELIVERED_AT TIMESTAMP,
+  CONSTRAINT FK_USERS
+  FOREIGN KEY (USER_ID)
+  REFERENCES USERS(ID)
+  ON DELETE CASCADE
+);
+
+CREATE INDEX IDX_ACTIVITYPUB_DELIVERIES_DUE ON ACTIVITYPUB_DELIVERIES (NEXT_ATTEMPT_AT) WHERE STATUS = 'pending';
+
+-- +goose Down
+DROP TABLE ACTIVITYPUB_DELIVERIES;
+DROP TABLE REMOTE_REACTIONS;
+DROP TABLE REMOTE_FOLLOWERS;
+DROP TABLE REMOTE_ACTORS;
+DROP TABLE ACTOR_KEYS;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- Requests sent with an Idempotency-Key and their response, which retries of
+-- the request get until the key expires. RESPONSE_STATUS is null while the
+-- request holding LOCK_ID is being handled.
+CREATE TABLE IDEMPOTENCY_KEYS (
+  SCOPE TEXT NOT NULL,
+  KEY TEXT NOT NULL,
+  FINGERPRINT TEXT NOT NULL,
+  LOCK_ID UUID NOT NULL,
+  CREATED_AT TIMESTAMP NOT NULL,
+  EXPIRES_AT TIMESTAMP NOT NULL,
+  RESPONSE_STATUS INTEGER,
+  RESPONSE_HEADERS JSONB NOT NULL DEFAULT '{}',
+  RESPONSE_BODY BYTEA NOT NULL DEFAULT '',
+  PRIMARY KEY (SCOPE, KEY)
+);
+
+CREATE INDEX IDX_IDEMPOTENCY_KEYS_EXPIRES_AT ON IDEMPOTENCY_KEYS (EXPIRES_AT);
+
+-- +goose Down
+DROP TABLE IDEMPOTENCY_KEYS;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE USERS DROP COLUMN IS_CHIRPY_RED;
+
+-- +goose Down
+ALTER TABLE USERS ADD IS_CHIRPY_RED BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE USERS
+   SET IS_CHIRPY_RED = EXISTS (
+         SELECT 1
+           FROM SUBSCRIPTIONS
+          WHERE SUBSCRIPTIONS.USER_ID = USERS.ID
+            AND SUBSCRIPTIONS.STATUS <> 'expired'
+            AND SUBSCRIPTIONS.CURRENT_PERIOD_END > NOW()
+       );
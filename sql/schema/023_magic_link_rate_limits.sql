@@ -0,0 +1,21 @@
+-- +goose Up
+-- One row per email counting the login links requested in the current
+-- window, so a request can be counted and limited with a single statement.
+DROP TABLE MAGIC_LINK_REQUESTS;
+
+CREATE TABLE MAGIC_LINK_RATE_LIMITS (
+  EMAIL TEXT PRIMARY KEY,
+  WINDOW_START TIMESTAMP NOT NULL,
+  REQUESTS INTEGER NOT NULL
+);
+
+-- +goose Down
+DROP TABLE MAGIC_LINK_RATE_LIMITS;
+
+CREATE TABLE MAGIC_LINK_REQUESTS (
+  ID UUID PRIMARY KEY,
+  CREATED_AT TIMESTAMP NOT NULL,
+  EMAIL TEXT NOT NULL
+);
+
+CREATE INDEX MAGIC_LINK_REQUESTS_EMAIL_IDX ON MAGIC_LINK_REQUESTS (EMAIL, CREATED_AT);
@@ -0,0 +1,42 @@
+-- +goose Up
+-- Whether VIEWER can see a chirp of AUTHOR: neither blocked the other, and
+-- the visibility of the chirp lets them. Every query reading chirps for a
+-- viewer goes by it, viewers who aren't logged in are the nil UUID.
+-- +goose StatementBegin
+CREATE FUNCTION CHIRP_VISIBLE_TO(AUTHOR UUID, CHIRP_VISIBILITY TEXT, CHIRP_MENTIONS UUID[], VIEWER UUID)
+RETURNS BOOLEAN
+LANGUAGE SQL
+STABLE
+AS $$
+  SELECT AUTHOR = VIEWER
+      OR (
+           NOT EXISTS (
+             SELECT 1
+               FROM USER_BLOCKS
+              WHERE (USER_BLOCKS.BLOCKER_ID = VIEWER AND USER_BLOCKS.BLOCKED_ID = AUTHOR)
+                 OR (USER_BLOCKS.BLOCKER_ID = AUTHOR AND USER_BLOCKS.BLOCKED_ID = VIEWER)
+           )
+           AND CASE CHIRP_VISIBILITY
+                 WHEN 'mentioned-only' THEN VIEWER = ANY(CHIRP_MENTIONS)
+                 WHEN 'followers-only' THEN EXISTS (
+                   SELECT 1
+                     FROM FOLLOWS
+                    WHERE FOLLOWS.FOLLOWER_ID = VIEWER
+                      AND FOLLOWS.FOLLOWED_ID = AUTHOR
+                      AND FOLLOWS.STATUS = 'approved'
+                 )
+                 ELSE NOT (SELECT IS_PRIVATE FROM USERS WHERE USERS.ID = AUTHOR)
+                   OR EXISTS (
+                        SELECT 1
+                          FROM FOLLOWS
+                         WHERE FOLLOWS.FOLLOWER_ID = VIEWER
+                           AND FOLLOWS.FOLLOWED_ID = AUTHOR
+                           AND FOLLOWS.STATUS = 'approved'
+                      )
+               END
+         )
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION CHIRP_VISIBLE_TO(UUID, TEXT, UUID[], UUID);
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Whether SUBSCRIBER is a Chirpy Red member: they have a subscription that
+-- hasn't expired and whose current period hasn't ended. Every query asking
+-- about Chirpy Red goes by it.
+-- +goose StatementBegin
+CREATE FUNCTION IS_CHIRPY_RED(SUBSCRIBER UUID)
+RETURNS BOOLEAN
+LANGUAGE SQL
+STABLE
+AS $$
+  SELECT EXISTS (
+           SELECT 1
+             FROM SUBSCRIPTIONS
+            WHERE SUBSCRIPTIONS.USER_ID = SUBSCRIBER
+              AND SUBSCRIPTIONS.STATUS <> 'expired'
+              AND SUBSCRIPTIONS.CURRENT_PERIOD_END > NOW()
+         )
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IS_CHIRPY_RED(UUID);